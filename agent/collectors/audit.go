@@ -0,0 +1,497 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// Actions émises par le collecteur d'audit, en plus de celles des
+// collecteurs de fichiers (create, modify, delete, chmod, chown)
+const (
+	ProcessActionExec       = "exec"
+	ConnectionActionConnect = "connect"
+	FileActionAccess        = "access"
+)
+
+// Sources des enregistrements d'audit
+const (
+	auditSourceNetlink = "netlink"
+	auditSourceFile    = "file"
+)
+
+const (
+	// maxPendingAuditRecords borne les enregistrements reçus du netlink en
+	// attente de collecte ; au-delà ils sont perdus et comptés
+	maxPendingAuditRecords = 65536
+
+	// auditUnset est la valeur de auid et ses pour un processus hors session
+	auditUnset = 4294967295
+
+	// Codes d'erreur (exit) utiles : permission refusée, connexion en cours
+	auditEPERM       = -1
+	auditEACCES      = -13
+	auditEINPROGRESS = -115
+)
+
+// errAuditOverrun signale des enregistrements perdus, tampon de réception plein
+var errAuditOverrun = errors.New("audit netlink receive buffer overrun, records lost")
+
+// AuditCollector lit les enregistrements du sous-système d'audit Linux, par
+// le groupe multicast netlink du noyau ou, à défaut, dans audit.log. Les
+// enregistrements d'un même appel système sont réassemblés par numéro de
+// série puis normalisés en événements process (exec), network (connect) ou
+// file, avec le contexte d'audit (auid, session, clé de la règle) dans
+// raw_data.audit. Les règles des options sont installées avec auditctl au
+// démarrage et retirées à l'arrêt.
+type AuditCollector struct {
+	logger   *utils.Logger
+	agentID  string
+	hostname string
+	options  AuditOptions
+
+	mu   sync.Mutex
+	next *AuditOptions
+
+	rules     *auditRuleSet
+	source    string // source en cours, vide si aucune n'a pu être ouverte
+	assembler *auditAssembler
+	users     map[int]string
+
+	// Source netlink : lecture continue par une goroutine
+	records chan auditRecord
+	lost    atomic.Int64
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	// Source fichier
+	tailer *logTailer
+}
+
+// NewAuditCollector crée un collecteur d'audit, installe ses règles et ouvre
+// sa source. Une source netlink imposée mais indisponible est une erreur.
+func NewAuditCollector(logger *utils.Logger, agentID, hostname string, options AuditOptions) (*AuditCollector, error) {
+	ac := &AuditCollector{
+		logger:   logger,
+		agentID:  agentID,
+		hostname: hostname,
+		options:  options,
+		rules:    newAuditRuleSet(logger),
+		users:    make(map[int]string),
+	}
+
+	if err := ac.open(); err != nil {
+		return nil, err
+	}
+	ac.rules.sync(options.Rules)
+	return ac, nil
+}
+
+// Configure prépare de nouveaux réglages, installés par apply
+func (ac *AuditCollector) Configure(options AuditOptions) {
+	ac.mu.Lock()
+	ac.next = &options
+	ac.mu.Unlock()
+}
+
+// apply installe de nouveaux réglages : les règles sont synchronisées et la
+// source rouverte si elle change
+func (ac *AuditCollector) apply(options AuditOptions) {
+	previous := ac.options
+	ac.options = options
+
+	if !reflect.DeepEqual(previous.Rules, options.Rules) {
+		ac.rules.sync(options.Rules)
+	}
+	if previous.Source != options.Source || previous.LogFile != options.LogFile || previous.OffsetsFile != options.OffsetsFile {
+		ac.closeSource()
+	}
+}
+
+// Collect émet les événements d'audit réassemblés depuis la collecte précédente
+func (ac *AuditCollector) Collect() ([]*models.Event, error) {
+	ac.logger.Debug("Starting audit collection...")
+
+	ac.mu.Lock()
+	if ac.next != nil {
+		ac.apply(*ac.next)
+		ac.next = nil
+	}
+	ac.mu.Unlock()
+
+	// Source fermée par un changement de réglages ou indisponible jusque-là
+	if ac.source == "" {
+		if err := ac.open(); err != nil {
+			return nil, err
+		}
+	}
+
+	var groups []*auditGroup
+	switch ac.source {
+	case auditSourceNetlink:
+		for n := len(ac.records); n > 0; n-- {
+			groups = append(groups, ac.assembler.add(<-ac.records)...)
+		}
+		if lost := ac.lost.Swap(0); lost > 0 {
+			ac.logger.Error("Audit: %d records lost, collection cannot keep up", lost)
+		}
+
+	case auditSourceFile:
+		// Position atteinte au cycle précédent, dont les événements sont envoyés
+		if err := ac.tailer.save(); err != nil {
+			ac.logger.Error("Failed to save audit log position: %v", err)
+		}
+		err := ac.tailer.read(ac.options.LogFile, false, func(line string) {
+			if record, ok := parseAuditLine(line); ok && isAuditRecordType(record.typ) {
+				groups = append(groups, ac.assembler.add(record)...)
+			}
+		})
+		if err != nil {
+			ac.logger.Error("Failed to read audit log %s: %v", ac.options.LogFile, err)
+		}
+	}
+	groups = append(groups, ac.assembler.endCycle()...)
+
+	var events []*models.Event
+	for _, group := range groups {
+		if event := ac.newEvent(group); event != nil {
+			events = append(events, event)
+		}
+	}
+
+	ac.logger.Info("Collected %d audit events", len(events))
+	return events, nil
+}
+
+// Close ferme la source et retire les règles installées par le collecteur
+func (ac *AuditCollector) Close() error {
+	ac.closeSource()
+	ac.rules.clear()
+	return nil
+}
+
+// open ouvre la source des options : le netlink, puis audit.log en mode auto
+func (ac *AuditCollector) open() error {
+	if ac.options.Source != auditSourceFile {
+		conn, err := openAuditNetlink()
+		if err == nil {
+			ac.startNetlink(conn)
+			ac.logger.Info("Audit: reading records from kernel netlink")
+			return nil
+		}
+		if ac.options.Source == auditSourceNetlink {
+			return err
+		}
+		ac.logger.Info("Audit: netlink unavailable (%v), reading %s", err, ac.options.LogFile)
+	}
+
+	ac.source = auditSourceFile
+	ac.assembler = newAuditAssembler(true)
+	ac.tailer = newLogTailer(ac.options.OffsetsFile, ac.logger)
+	return nil
+}
+
+// startNetlink lance la lecture continue du netlink
+func (ac *AuditCollector) startNetlink(conn *auditNetlink) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ac.source = auditSourceNetlink
+	ac.assembler = newAuditAssembler(false)
+	ac.records = make(chan auditRecord, maxPendingAuditRecords)
+	ac.cancel = cancel
+	ac.done = make(chan struct{})
+
+	go ac.receive(ctx, conn, ac.records, ac.done)
+}
+
+// receive lit les enregistrements du netlink jusqu'à l'annulation de ctx.
+// Elle ne doit jamais bloquer : le noyau perdrait des enregistrements.
+func (ac *AuditCollector) receive(ctx context.Context, conn *auditNetlink, records chan<- auditRecord, done chan<- struct{}) {
+	defer close(done)
+	defer conn.Close()
+
+	for ctx.Err() == nil {
+		batch, err := conn.receive()
+		if err != nil {
+			if errors.Is(err, errAuditOverrun) {
+				ac.lost.Add(1)
+				continue
+			}
+			ac.logger.Error("Audit netlink error: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for _, record := range batch {
+			select {
+			case records <- record:
+			default:
+				ac.lost.Add(1)
+			}
+		}
+	}
+}
+
+// closeSource ferme la source en cours ; les événements en cours de
+// réassemblage sont abandonnés
+func (ac *AuditCollector) closeSource() {
+	switch ac.source {
+	case auditSourceNetlink:
+		ac.cancel()
+		<-ac.done
+	case auditSourceFile:
+		if err := ac.tailer.save(); err != nil {
+			ac.logger.Error("Failed to save audit log position: %v", err)
+		}
+	}
+	ac.source = ""
+}
+
+// newEvent normalise un événement réassemblé ; nil s'il n'a pas
+// d'enregistrement SYSCALL, si sa clé est filtrée ou s'il n'est ni une
+// exécution, ni une connexion réseau, ni un accès à un fichier
+func (ac *AuditCollector) newEvent(group *auditGroup) *models.Event {
+	syscall, ok := group.first("SYSCALL")
+	if !ok {
+		return nil
+	}
+
+	// Plusieurs clés sont séparées par 0x01
+	key, _, _ := strings.Cut(syscall.str("key"), "\x01")
+	if !ac.options.Keys.Match(key) {
+		return nil
+	}
+
+	auditEvent := models.AuditEvent{
+		Source:  ac.source,
+		Serial:  group.serial,
+		Syscall: auditSyscallName(syscall.fields["arch"], syscall.fields["syscall"]),
+		Success: syscall.fields["success"] == "yes",
+		Exit:    syscall.int("exit", 0),
+		Key:     key,
+		UID:     int(syscall.int("uid", -1)),
+		EUID:    int(syscall.int("euid", -1)),
+		TTY:     syscall.str("tty"),
+	}
+	if auid := syscall.int("auid", auditUnset); auid >= 0 && auid != auditUnset {
+		id := int(auid)
+		auditEvent.AUID = &id
+		auditEvent.AuditUser = ac.lookupUser(id)
+	}
+	if ses := syscall.int("ses", auditUnset); ses >= 0 && ses != auditUnset {
+		id := int(ses)
+		auditEvent.Session = &id
+	}
+	if cwd, ok := group.first("CWD"); ok {
+		auditEvent.WorkingDir = cwd.str("cwd")
+	}
+	owners := make(map[string]int)
+	for _, path := range group.all("PATH") {
+		name := path.str("name")
+		if name == "" {
+			continue
+		}
+		if !filepath.IsAbs(name) && auditEvent.WorkingDir != "" {
+			name = filepath.Join(auditEvent.WorkingDir, name)
+		}
+		inode, _ := strconv.ParseUint(path.fields["inode"], 10, 64)
+		owners[name] = int(path.int("ouid", -1))
+		auditEvent.Paths = append(auditEvent.Paths, models.AuditPath{
+			Name:     name,
+			NameType: path.fields["nametype"],
+			Inode:    inode,
+			Mode:     path.fields["mode"],
+		})
+	}
+
+	// L'utilisateur de login reste attribué à travers sudo et su
+	username := auditEvent.AuditUser
+	if username == "" {
+		username = ac.lookupUser(auditEvent.UID)
+	}
+
+	event := &models.Event{
+		Timestamp:   syscall.timestamp,
+		AgentID:     ac.agentID,
+		Hostname:    ac.hostname,
+		ProcessName: syscall.str("comm"),
+		ProcessPID:  int(syscall.int("pid", 0)),
+		Username:    username,
+		RawData:     map[string]interface{}{},
+	}
+
+	if execve, ok := group.first("EXECVE"); ok {
+		// Les échecs d'exec sont surtout des recherches dans le PATH
+		if !auditEvent.Success && auditEvent.Exit != auditEACCES && auditEvent.Exit != auditEPERM {
+			return nil
+		}
+		processEvent := models.ProcessEvent{
+			Action:         ProcessActionExec,
+			PID:            event.ProcessPID,
+			Name:           event.ProcessName,
+			CommandLine:    auditCommandLine(execve),
+			ExecutablePath: syscall.str("exe"),
+			ParentPID:      int(syscall.int("ppid", 0)),
+			Username:       ac.lookupUser(auditEvent.UID),
+		}
+		if processEvent.CommandLine == "" {
+			if proctitle, ok := group.first("PROCTITLE"); ok {
+				processEvent.CommandLine = strings.ReplaceAll(proctitle.str("proctitle"), "\x00", " ")
+			}
+		}
+		event.EventType = models.EventTypeProcess
+		event.RawData["process"] = processEvent
+		event.Severity, event.Tags = ac.classifyExec(processEvent)
+	} else if sockaddr, ok := group.first("SOCKADDR"); ok {
+		family, address, port := auditSockaddr(sockaddr.fields["saddr"])
+		if family != "inet" && family != "inet6" {
+			return nil
+		}
+		state := "established"
+		switch {
+		case auditEvent.Exit == auditEINPROGRESS:
+			state = "in_progress"
+		case !auditEvent.Success:
+			state = "failed"
+		}
+		event.EventType = models.EventTypeNetwork
+		event.DestinationIP = address
+		event.RawData["network"] = models.NetworkEvent{
+			Action:         ConnectionActionConnect,
+			Protocol:       family,
+			DestIP:         address,
+			DestPort:       port,
+			State:          state,
+			PID:            event.ProcessPID,
+			ProcessName:    event.ProcessName,
+			ExecutablePath: syscall.str("exe"),
+			FirstSeen:      syscall.timestamp,
+		}
+		event.Severity, event.Tags = models.SeverityLow, []string{"audit", "audit_connect"}
+	} else if target, ok := auditTargetPath(auditEvent.Paths); ok {
+		fileEvent := models.FileEvent{
+			Path:      target.Name,
+			Action:    auditFileAction(auditEvent.Syscall, syscall, target),
+			Sensitive: isSensitivePath(target.Name),
+		}
+		if ouid := owners[target.Name]; ouid >= 0 {
+			fileEvent.Owner = ac.lookupUser(ouid)
+		}
+		event.EventType = models.EventTypeFile
+		event.RawData["file"] = fileEvent
+		event.Severity, event.Tags = ac.classifyFile(fileEvent, auditEvent.Success)
+	} else {
+		return nil
+	}
+
+	event.RawData["audit"] = auditEvent
+	if key != "" {
+		event.Tags = append(event.Tags, key)
+	}
+	return event
+}
+
+// classifyExec détermine la sévérité et les tags d'une exécution
+func (ac *AuditCollector) classifyExec(pe models.ProcessEvent) (models.Severity, []string) {
+	tags := []string{"audit", "audit_exec"}
+	severity := models.SeverityLow
+
+	for _, dir := range ac.options.SuspiciousDirs {
+		if strings.HasPrefix(pe.ExecutablePath, dir) {
+			tags = append(tags, "suspicious_path")
+			severity = models.SeverityHigh
+			break
+		}
+	}
+	return severity, tags
+}
+
+// classifyFile détermine la sévérité et les tags d'un accès à un fichier
+func (ac *AuditCollector) classifyFile(fe models.FileEvent, success bool) (models.Severity, []string) {
+	tags := []string{"audit", "audit_file", "file_" + fe.Action}
+	severity := models.SeverityLow
+
+	if fe.Sensitive {
+		tags = append(tags, "sensitive_file")
+		severity = models.SeverityMedium
+	}
+	if !success {
+		tags = append(tags, "access_denied")
+		if fe.Sensitive {
+			severity = models.SeverityHigh
+		}
+	}
+	return severity, tags
+}
+
+// lookupUser résout un uid en mettant le résultat en cache
+func (ac *AuditCollector) lookupUser(uid int) string {
+	if name, ok := ac.users[uid]; ok {
+		return name
+	}
+	name := lookupUser(uid)
+	ac.users[uid] = name
+	return name
+}
+
+// auditTargetPath choisit le chemin visé par un appel système : le premier
+// qui n'est pas le répertoire parent
+func auditTargetPath(paths []models.AuditPath) (models.AuditPath, bool) {
+	for _, path := range paths {
+		if path.NameType != "PARENT" {
+			return path, true
+		}
+	}
+	if len(paths) > 0 {
+		return paths[0], true
+	}
+	return models.AuditPath{}, false
+}
+
+// auditFileAction déduit l'action sur un fichier de l'appel système et du
+// type de chemin
+func auditFileAction(syscallName string, syscall auditRecord, target models.AuditPath) string {
+	switch target.NameType {
+	case "CREATE":
+		return "create"
+	case "DELETE":
+		return "delete"
+	}
+
+	switch syscallName {
+	case "chmod", "fchmod", "fchmodat":
+		return "chmod"
+	case "chown", "fchown", "fchownat", "lchown":
+		return "chown"
+	case "creat", "truncate", "ftruncate":
+		return "modify"
+	case "open", "openat":
+		// Drapeaux d'ouverture : a1 pour open, a2 pour openat (hexadécimal)
+		arg := "a1"
+		if syscallName == "openat" {
+			arg = "a2"
+		}
+		if flags, err := strconv.ParseUint(syscall.fields[arg], 16, 64); err == nil && flags&0x3 != 0 {
+			return "modify" // O_WRONLY ou O_RDWR
+		}
+	}
+	return FileActionAccess
+}
+
+// isAuditRecordType indique si un type d'enregistrement est exploité
+func isAuditRecordType(typ string) bool {
+	for _, known := range auditRecordTypes {
+		if typ == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package collectors
+
+import "errors"
+
+// auditNetlink n'existe que sous Linux : seul audit.log peut être lu
+type auditNetlink struct{}
+
+// openAuditNetlink échoue hors de Linux
+func openAuditNetlink() (*auditNetlink, error) {
+	return nil, errors.New("audit netlink is only available on Linux")
+}
+
+// receive ne retourne jamais d'enregistrement
+func (n *auditNetlink) receive() ([]auditRecord, error) {
+	return nil, nil
+}
+
+// Close ne fait rien
+func (n *auditNetlink) Close() error {
+	return nil
+}
@@ -0,0 +1,343 @@
+package collectors
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPendingAuditGroups borne les événements d'audit en cours de réassemblage
+const maxPendingAuditGroups = 10000
+
+// auditRecordTypes sont les types d'enregistrements d'audit exploités, par
+// numéro de message netlink
+var auditRecordTypes = map[uint16]string{
+	1300: "SYSCALL",
+	1302: "PATH",
+	1306: "SOCKADDR",
+	1307: "CWD",
+	1309: "EXECVE",
+	1320: "EOE",
+	1327: "PROCTITLE",
+}
+
+// auditSyscalls nomment les appels système utiles, par architecture (champ arch)
+var auditSyscalls = map[string]map[int]string{
+	"c000003e": { // x86_64
+		2: "open", 42: "connect", 59: "execve", 76: "truncate", 82: "rename", 85: "creat",
+		87: "unlink", 90: "chmod", 92: "chown", 94: "lchown", 105: "setuid", 113: "setreuid",
+		117: "setresuid", 257: "openat", 263: "unlinkat", 264: "renameat", 268: "fchmodat",
+		260: "fchownat", 316: "renameat2", 322: "execveat", 437: "openat2",
+	},
+	"c00000b7": { // aarch64
+		35: "unlinkat", 38: "renameat", 45: "truncate", 53: "fchmodat", 54: "fchownat",
+		56: "openat", 145: "setreuid", 146: "setuid", 147: "setresuid", 203: "connect",
+		221: "execve", 276: "renameat2", 281: "execveat", 437: "openat2",
+	},
+}
+
+// auditRecord est un enregistrement d'audit : un événement en compte
+// plusieurs, de même numéro de série (SYSCALL, EXECVE, PATH...)
+type auditRecord struct {
+	typ       string
+	timestamp time.Time
+	serial    uint64
+	fields    map[string]string // valeurs brutes : entre guillemets, hexadécimales ou numériques
+}
+
+// parseAuditLine découpe une ligne de audit.log :
+// « [node=host ]type=SYSCALL msg=audit(1700000000.123:456): arch=c000003e ... »
+func parseAuditLine(line string) (auditRecord, bool) {
+	if strings.HasPrefix(line, "node=") {
+		_, line, _ = strings.Cut(line, " ")
+	}
+	if !strings.HasPrefix(line, "type=") {
+		return auditRecord{}, false
+	}
+	typ, rest, ok := strings.Cut(line[len("type="):], " ")
+	if !ok || !strings.HasPrefix(rest, "msg=") {
+		return auditRecord{}, false
+	}
+	return parseAuditMessage(typ, rest[len("msg="):])
+}
+
+// parseAuditMessage découpe le texte d'un enregistrement, commun à audit.log
+// et au netlink : « audit(1700000000.123:456): champs »
+func parseAuditMessage(typ, msg string) (auditRecord, bool) {
+	if !strings.HasPrefix(msg, "audit(") {
+		return auditRecord{}, false
+	}
+	end := strings.IndexByte(msg, ')')
+	if end < 0 {
+		return auditRecord{}, false
+	}
+
+	stamp, serial, ok := strings.Cut(msg[len("audit("):end], ":")
+	if !ok {
+		return auditRecord{}, false
+	}
+	secs, millis, _ := strings.Cut(stamp, ".")
+	seconds, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return auditRecord{}, false
+	}
+	ms, _ := strconv.ParseInt(millis, 10, 64)
+	record := auditRecord{
+		typ:       typ,
+		timestamp: time.Unix(seconds, ms*int64(time.Millisecond)),
+	}
+	if record.serial, err = strconv.ParseUint(serial, 10, 64); err != nil {
+		return auditRecord{}, false
+	}
+
+	body := strings.TrimPrefix(msg[end+1:], ":")
+	// Format enrichi d'auditd : les champs interprétés suivent un séparateur 0x1d
+	if i := strings.IndexByte(body, 0x1d); i >= 0 {
+		body = body[:i]
+	}
+	record.fields = parseAuditFields(body)
+	return record, true
+}
+
+// parseAuditFields découpe les champs « clé=valeur » d'un enregistrement. Les
+// valeurs entre guillemets, qui peuvent contenir des espaces, gardent leurs
+// guillemets pour être distinguées des valeurs hexadécimales.
+func parseAuditFields(body string) map[string]string {
+	fields := make(map[string]string)
+	for body = strings.TrimSpace(body); body != ""; body = strings.TrimSpace(body) {
+		eq := strings.IndexByte(body, '=')
+		if eq <= 0 {
+			break
+		}
+		key := body[:eq]
+		body = body[eq+1:]
+
+		end := strings.IndexByte(body, ' ')
+		if body != "" && (body[0] == '"' || body[0] == '\'') {
+			if closing := strings.IndexByte(body[1:], body[0]); closing >= 0 {
+				end = closing + 2
+			}
+		}
+		if end < 0 || end > len(body) {
+			end = len(body)
+		}
+		fields[key] = body[:end]
+		body = body[end:]
+	}
+	return fields
+}
+
+// str retourne un champ texte décodé : les valeurs entre guillemets sont
+// prises telles quelles, les autres sont hexadécimales (elles contiennent
+// alors des espaces ou des caractères de contrôle)
+func (r auditRecord) str(name string) string {
+	return decodeAuditString(r.fields[name])
+}
+
+// int retourne un champ numérique, ou fallback s'il est absent ou invalide
+func (r auditRecord) int(name string, fallback int64) int64 {
+	n, err := strconv.ParseInt(r.fields[name], 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// decodeAuditString décode une valeur texte d'audit
+func decodeAuditString(raw string) string {
+	switch {
+	case raw == "" || raw == "(null)" || raw == "(none)" || raw == "?":
+		return ""
+	case len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"':
+		return raw[1 : len(raw)-1]
+	}
+	if decoded, err := hex.DecodeString(raw); err == nil {
+		return string(decoded)
+	}
+	return raw
+}
+
+// auditGroup regroupe les enregistrements d'un même événement
+type auditGroup struct {
+	serial  uint64
+	records []auditRecord
+	cycle   int // cycle de collecte du premier enregistrement
+}
+
+// first retourne le premier enregistrement d'un type, ok à false s'il n'y en a pas
+func (g *auditGroup) first(typ string) (auditRecord, bool) {
+	for _, record := range g.records {
+		if record.typ == typ {
+			return record, true
+		}
+	}
+	return auditRecord{}, false
+}
+
+// all retourne les enregistrements d'un type, dans l'ordre de réception
+func (g *auditGroup) all(typ string) []auditRecord {
+	var records []auditRecord
+	for _, record := range g.records {
+		if record.typ == typ {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// auditAssembler réassemble les événements d'audit par numéro de série. Un
+// événement est complet à la réception de son EOE ; auditd n'écrivant pas les
+// EOE dans audit.log, dont les enregistrements sont contigus, l'arrivée d'un
+// numéro de série supérieur complète aussi les précédents (flushOnNewSerial).
+// Un événement resté incomplet pendant tout un cycle de collecte est émis tel quel.
+type auditAssembler struct {
+	flushOnNewSerial bool
+	groups           map[uint64]*auditGroup
+	cycle            int
+}
+
+// newAuditAssembler crée un assembleur vide
+func newAuditAssembler(flushOnNewSerial bool) *auditAssembler {
+	return &auditAssembler{
+		flushOnNewSerial: flushOnNewSerial,
+		groups:           make(map[uint64]*auditGroup),
+	}
+}
+
+// add ajoute un enregistrement et retourne les événements qu'il complète
+func (a *auditAssembler) add(record auditRecord) []*auditGroup {
+	var complete []*auditGroup
+
+	if a.flushOnNewSerial {
+		for serial := range a.groups {
+			if serial < record.serial {
+				complete = append(complete, a.take(serial))
+			}
+		}
+	}
+
+	if record.typ == "EOE" {
+		if group := a.take(record.serial); group != nil {
+			complete = append(complete, group)
+		}
+		return sortAuditGroups(complete)
+	}
+
+	group, ok := a.groups[record.serial]
+	if !ok {
+		if len(a.groups) >= maxPendingAuditGroups {
+			complete = append(complete, a.take(a.oldest()))
+		}
+		group = &auditGroup{serial: record.serial, cycle: a.cycle}
+		a.groups[record.serial] = group
+	}
+	group.records = append(group.records, record)
+
+	return sortAuditGroups(complete)
+}
+
+// endCycle termine un cycle de collecte et retourne les événements restés
+// incomplets depuis le cycle précédent
+func (a *auditAssembler) endCycle() []*auditGroup {
+	var complete []*auditGroup
+	for serial, group := range a.groups {
+		if group.cycle < a.cycle {
+			complete = append(complete, a.take(serial))
+		}
+	}
+	a.cycle++
+	return sortAuditGroups(complete)
+}
+
+// take retire un événement en cours de réassemblage
+func (a *auditAssembler) take(serial uint64) *auditGroup {
+	group := a.groups[serial]
+	delete(a.groups, serial)
+	return group
+}
+
+// oldest retourne le plus petit numéro de série en cours de réassemblage
+func (a *auditAssembler) oldest() uint64 {
+	first := true
+	var oldest uint64
+	for serial := range a.groups {
+		if first || serial < oldest {
+			oldest, first = serial, false
+		}
+	}
+	return oldest
+}
+
+// sortAuditGroups trie des événements par numéro de série
+func sortAuditGroups(groups []*auditGroup) []*auditGroup {
+	sort.Slice(groups, func(i, j int) bool { return groups[i].serial < groups[j].serial })
+	return groups
+}
+
+// auditSyscallName nomme un appel système, ou retourne son numéro
+func auditSyscallName(arch, syscall string) string {
+	if n, err := strconv.Atoi(syscall); err == nil {
+		if name, ok := auditSyscalls[arch][n]; ok {
+			return name
+		}
+	}
+	return syscall
+}
+
+// auditCommandLine reconstitue la ligne de commande d'un enregistrement
+// EXECVE (a0, a1... ; un argument long est découpé en a1[0], a1[1]...)
+func auditCommandLine(record auditRecord) string {
+	argc := int(record.int("argc", 0))
+	args := make([]string, 0, argc)
+	for i := 0; i < argc; i++ {
+		key := "a" + strconv.Itoa(i)
+		if _, ok := record.fields[key]; ok {
+			args = append(args, record.str(key))
+			continue
+		}
+		var parts strings.Builder
+		for j := 0; ; j++ {
+			part, ok := record.fields[key+"["+strconv.Itoa(j)+"]"]
+			if !ok {
+				break
+			}
+			parts.WriteString(decodeAuditString(part))
+		}
+		args = append(args, parts.String())
+	}
+	return strings.Join(args, " ")
+}
+
+// auditSockaddr décode l'adresse hexadécimale d'un enregistrement SOCKADDR :
+// famille (inet, inet6, unix), adresse et port
+func auditSockaddr(saddr string) (family, address string, port int) {
+	data, err := hex.DecodeString(saddr)
+	if err != nil || len(data) < 2 {
+		return "", "", 0
+	}
+
+	switch binary.LittleEndian.Uint16(data[:2]) {
+	case 1: // AF_UNIX
+		path := data[2:]
+		if i := strings.IndexByte(string(path), 0); i > 0 {
+			path = path[:i]
+		}
+		return "unix", string(path), 0
+	case 2: // AF_INET
+		if len(data) < 8 {
+			return "", "", 0
+		}
+		return "inet", net.IP(data[4:8]).String(), int(binary.BigEndian.Uint16(data[2:4]))
+	case 10: // AF_INET6
+		if len(data) < 24 {
+			return "", "", 0
+		}
+		return "inet6", net.IP(data[8:24]).String(), int(binary.BigEndian.Uint16(data[2:4]))
+	default:
+		return "", "", 0
+	}
+}
@@ -0,0 +1,276 @@
+package collectors
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAuditLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		want   auditRecord
+		wantOK bool
+	}{
+		{
+			name: "syscall",
+			line: `type=SYSCALL msg=audit(1700000000.123:456): arch=c000003e syscall=59 success=yes exit=0 pid=1234 comm="bash" exe="/usr/bin/bash" key="exec"`,
+			want: auditRecord{typ: "SYSCALL", timestamp: time.Unix(1700000000, 123*int64(time.Millisecond)), serial: 456, fields: map[string]string{
+				"arch": "c000003e", "syscall": "59", "success": "yes", "exit": "0", "pid": "1234",
+				"comm": `"bash"`, "exe": `"/usr/bin/bash"`, "key": `"exec"`,
+			}},
+			wantOK: true,
+		},
+		{
+			name: "node prefix",
+			line: `node=web-01 type=CWD msg=audit(1700000000.000:7): cwd="/root"`,
+			want: auditRecord{typ: "CWD", timestamp: time.Unix(1700000000, 0), serial: 7, fields: map[string]string{
+				"cwd": `"/root"`,
+			}},
+			wantOK: true,
+		},
+		{
+			name: "hexadecimal values",
+			line: `type=EXECVE msg=audit(1700000000.5:8): argc=3 a0="sh" a1="-c" a2=6563686F20612062`,
+			want: auditRecord{typ: "EXECVE", timestamp: time.Unix(1700000000, 5*int64(time.Millisecond)), serial: 8, fields: map[string]string{
+				"argc": "3", "a0": `"sh"`, "a1": `"-c"`, "a2": "6563686F20612062",
+			}},
+			wantOK: true,
+		},
+		{
+			name: "single quotes and spaces inside quotes",
+			line: `type=USER_CMD msg=audit(1700000000.000:9): msg='cwd="/home/a b" cmd=id terminal=pts/0 res=success'`,
+			want: auditRecord{typ: "USER_CMD", timestamp: time.Unix(1700000000, 0), serial: 9, fields: map[string]string{
+				"msg": `'cwd="/home/a b" cmd=id terminal=pts/0 res=success'`,
+			}},
+			wantOK: true,
+		},
+		{
+			name: "unterminated quote",
+			line: `type=PATH msg=audit(1700000000.000:10): name="/tmp/x nametype=NORMAL`,
+			want: auditRecord{typ: "PATH", timestamp: time.Unix(1700000000, 0), serial: 10, fields: map[string]string{
+				"name": `"/tmp/x`, "nametype": "NORMAL",
+			}},
+			wantOK: true,
+		},
+		{
+			name: "enriched format",
+			line: "type=SYSCALL msg=audit(1700000000.000:11): arch=c000003e uid=0 auid=1000\x1dARCH=x86_64 UID=\"root\" AUID=\"alice\"",
+			want: auditRecord{typ: "SYSCALL", timestamp: time.Unix(1700000000, 0), serial: 11, fields: map[string]string{
+				"arch": "c000003e", "uid": "0", "auid": "1000",
+			}},
+			wantOK: true,
+		},
+		{
+			name: "empty values and trailing garbage",
+			line: `type=PROCTITLE msg=audit(1700000000.000:12): proctitle= tty=(none) junk`,
+			want: auditRecord{typ: "PROCTITLE", timestamp: time.Unix(1700000000, 0), serial: 12, fields: map[string]string{
+				"proctitle": "", "tty": "(none)",
+			}},
+			wantOK: true,
+		},
+		{name: "not an audit line", line: "Jan  1 00:00:00 host kernel: audit: type=1400"},
+		{name: "no message", line: "type=SYSCALL"},
+		{name: "no msg field", line: "type=SYSCALL arch=c000003e"},
+		{name: "no audit header", line: "type=SYSCALL msg=1700000000.000:1: arch=c000003e"},
+		{name: "unclosed header", line: "type=SYSCALL msg=audit(1700000000.000:1 arch=c000003e"},
+		{name: "no serial", line: "type=SYSCALL msg=audit(1700000000.000): arch=c000003e"},
+		{name: "bad timestamp", line: "type=SYSCALL msg=audit(now:1): arch=c000003e"},
+		{name: "bad serial", line: "type=SYSCALL msg=audit(1700000000.000:-1): arch=c000003e"},
+		{name: "empty", line: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseAuditLine(tt.line)
+			if ok != tt.wantOK {
+				t.Fatalf("parseAuditLine(%q) ok = %t, want %t", tt.line, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got.typ != tt.want.typ || got.serial != tt.want.serial || !got.timestamp.Equal(tt.want.timestamp) {
+				t.Errorf("parseAuditLine(%q) = %s %d %v, want %s %d %v",
+					tt.line, got.typ, got.serial, got.timestamp, tt.want.typ, tt.want.serial, tt.want.timestamp)
+			}
+			if !reflect.DeepEqual(got.fields, tt.want.fields) {
+				t.Errorf("parseAuditLine(%q) fields = %q, want %q", tt.line, got.fields, tt.want.fields)
+			}
+		})
+	}
+}
+
+func TestDecodeAuditString(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{`"/usr/bin/id"`, "/usr/bin/id"},
+		{`""`, ""},
+		{"2F746D702F6120622E7368", "/tmp/a b.sh"},
+		{"2f746d70", "/tmp"},
+		{"(null)", ""},
+		{"(none)", ""},
+		{"?", ""},
+		{"", ""},
+		{`"unterminated`, `"unterminated`},
+		{"ABC", "ABC"}, // longueur impaire : pas hexadécimal
+		{"pts0", "pts0"},
+	}
+
+	for _, tt := range tests {
+		if got := decodeAuditString(tt.raw); got != tt.want {
+			t.Errorf("decodeAuditString(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestAuditCommandLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   string
+	}{
+		{"quoted", map[string]string{"argc": "2", "a0": `"ls"`, "a1": `"-la"`}, "ls -la"},
+		{"hexadecimal", map[string]string{"argc": "3", "a0": `"sh"`, "a1": `"-c"`, "a2": hex.EncodeToString([]byte("echo a b"))}, "sh -c echo a b"},
+		{"split argument", map[string]string{
+			"argc": "2", "a0": `"python3"`, "a1_len": "12",
+			"a1[0]": hex.EncodeToString([]byte("print(")), "a1[1]": hex.EncodeToString([]byte("'hi')")),
+		}, "python3 print('hi')"},
+		{"missing argument", map[string]string{"argc": "3", "a0": `"cat"`, "a2": `"/etc/shadow"`}, "cat  /etc/shadow"},
+		{"no argc", map[string]string{"a0": `"ls"`}, ""},
+		{"invalid argc", map[string]string{"argc": "x", "a0": `"ls"`}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditCommandLine(auditRecord{typ: "EXECVE", fields: tt.fields}); got != tt.want {
+				t.Errorf("auditCommandLine(%v) = %q, want %q", tt.fields, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditSockaddr(t *testing.T) {
+	tests := []struct {
+		saddr       string
+		wantFamily  string
+		wantAddress string
+		wantPort    int
+	}{
+		{"020001BB0A0000010000000000000000", "inet", "10.0.0.1", 443},
+		{"0A001F900000000020010DB800000000000000000000000100000000", "inet6", "2001:db8::1", 8080},
+		{"0A0000160000000000000000000000000000000000000001", "inet6", "::1", 22},
+		{"01002F72756E2F646275732F736F636B657400", "unix", "/run/dbus/socket", 0},
+		{"0100002F6162737472616374", "unix", "\x00/abstract", 0},
+		{"1000", "", "", 0},     // AF_NETLINK
+		{"0200", "", "", 0},     // adresse tronquée
+		{"0A000016", "", "", 0}, // adresse tronquée
+		{"zz", "", "", 0},
+		{"", "", "", 0},
+	}
+
+	for _, tt := range tests {
+		family, address, port := auditSockaddr(tt.saddr)
+		if family != tt.wantFamily || address != tt.wantAddress || port != tt.wantPort {
+			t.Errorf("auditSockaddr(%q) = (%q, %q, %d), want (%q, %q, %d)",
+				tt.saddr, family, address, port, tt.wantFamily, tt.wantAddress, tt.wantPort)
+		}
+	}
+}
+
+func TestAuditSyscallName(t *testing.T) {
+	tests := []struct {
+		arch, syscall, want string
+	}{
+		{"c000003e", "59", "execve"},
+		{"c00000b7", "221", "execve"},
+		{"c000003e", "9999", "9999"},
+		{"40000003", "11", "11"}, // i386 : non nommé
+		{"c000003e", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := auditSyscallName(tt.arch, tt.syscall); got != tt.want {
+			t.Errorf("auditSyscallName(%q, %q) = %q, want %q", tt.arch, tt.syscall, got, tt.want)
+		}
+	}
+}
+
+func TestAuditAssembler(t *testing.T) {
+	// Une étape ajoute un enregistrement, ou termine le cycle si typ est vide ;
+	// want liste les événements émis, sous la forme série → nombre d'enregistrements
+	type step struct {
+		typ    string
+		serial uint64
+		want   [][2]int
+	}
+
+	tests := []struct {
+		name             string
+		flushOnNewSerial bool
+		steps            []step
+	}{
+		{
+			name: "netlink completes on EOE",
+			steps: []step{
+				{"SYSCALL", 1, nil},
+				{"SYSCALL", 2, nil},
+				{"EXECVE", 1, nil},
+				{"PATH", 1, nil},
+				{"EOE", 1, [][2]int{{1, 3}}},
+				{"EOE", 1, nil}, // déjà émis
+				{"EOE", 3, nil}, // jamais reçu
+			},
+		},
+		{
+			name: "incomplete events are flushed after a full cycle",
+			steps: []step{
+				{"SYSCALL", 1, nil},
+				{"", 0, nil},
+				{"SYSCALL", 2, nil},
+				{"", 0, [][2]int{{1, 1}}},
+				{"PATH", 2, nil},
+				{"", 0, [][2]int{{2, 2}}},
+				{"", 0, nil},
+			},
+		},
+		{
+			name:             "audit.log completes on a newer serial",
+			flushOnNewSerial: true,
+			steps: []step{
+				{"SYSCALL", 5, nil},
+				{"PATH", 5, nil},
+				{"SYSCALL", 7, [][2]int{{5, 2}}},
+				{"SYSCALL", 6, nil}, // hors d'ordre : 7 reste en cours
+				{"PATH", 7, [][2]int{{6, 1}}},
+				{"SYSCALL", 8, [][2]int{{7, 2}}},
+				{"EOE", 8, [][2]int{{8, 1}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assembler := newAuditAssembler(tt.flushOnNewSerial)
+			for i, s := range tt.steps {
+				var groups []*auditGroup
+				if s.typ == "" {
+					groups = assembler.endCycle()
+				} else {
+					groups = assembler.add(auditRecord{typ: s.typ, serial: s.serial})
+				}
+
+				var got [][2]int
+				for _, group := range groups {
+					got = append(got, [2]int{int(group.serial), len(group.records)})
+				}
+				if !reflect.DeepEqual(got, s.want) {
+					t.Errorf("step %d (%s %d): got %v, want %v", i, s.typ, s.serial, got, s.want)
+				}
+			}
+		})
+	}
+}
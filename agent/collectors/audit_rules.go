@@ -0,0 +1,106 @@
+package collectors
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// auditRuleAddFlags sont les options d'auditctl qui ajoutent une règle, avec
+// l'option qui la supprime
+var auditRuleAddFlags = map[string]string{
+	"-a": "-d", // règle d'appel système en fin de liste
+	"-A": "-d", // règle d'appel système en début de liste
+	"-w": "-W", // surveillance d'un chemin
+}
+
+// validateAuditRule vérifie qu'une règle, écrite comme les arguments
+// d'auditctl, ajoute une règle : les autres commandes (-D, -e...) modifieraient
+// la configuration d'audit de l'hôte
+func validateAuditRule(rule string) error {
+	args := strings.Fields(rule)
+	if len(args) < 2 {
+		return fmt.Errorf("%q is not an auditctl rule", rule)
+	}
+	if _, ok := auditRuleAddFlags[args[0]]; !ok {
+		return fmt.Errorf("%q must add a rule with -a, -A or -w", rule)
+	}
+	return nil
+}
+
+// auditRuleSet installe des règles d'audit avec auditctl et retient celles
+// qu'il a ajoutées, pour ne retirer que celles-là. Une règle déjà présente
+// appartient à l'administrateur et n'est jamais retirée.
+type auditRuleSet struct {
+	logger    *utils.Logger
+	installed map[string]bool
+}
+
+// newAuditRuleSet crée un ensemble de règles vide
+func newAuditRuleSet(logger *utils.Logger) *auditRuleSet {
+	return &auditRuleSet{logger: logger, installed: make(map[string]bool)}
+}
+
+// sync installe les règles absentes de l'ensemble et retire celles qui n'y
+// sont plus. Les échecs sont journalisés sans interrompre la collecte.
+func (s *auditRuleSet) sync(rules []string) {
+	wanted := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		wanted[rule] = true
+	}
+
+	for rule := range s.installed {
+		if !wanted[rule] {
+			s.remove(rule)
+		}
+	}
+
+	added := 0
+	for _, rule := range rules {
+		if s.installed[rule] {
+			continue
+		}
+		output, err := auditctl(strings.Fields(rule))
+		switch {
+		case err == nil:
+			s.installed[rule] = true
+			added++
+		case strings.Contains(output, "exists"):
+			s.logger.Debug("Audit rule already present, left in place: %s", rule)
+		default:
+			s.logger.Error("Failed to install audit rule %q: %v", rule, err)
+		}
+	}
+	if added > 0 {
+		s.logger.Info("Installed %d audit rules", added)
+	}
+}
+
+// clear retire toutes les règles installées
+func (s *auditRuleSet) clear() {
+	for rule := range s.installed {
+		s.remove(rule)
+	}
+}
+
+// remove retire une règle installée
+func (s *auditRuleSet) remove(rule string) {
+	args := strings.Fields(rule)
+	args[0] = auditRuleAddFlags[args[0]]
+	if _, err := auditctl(args); err != nil {
+		s.logger.Error("Failed to remove audit rule %q: %v", rule, err)
+	}
+	delete(s.installed, rule)
+}
+
+// auditctl exécute auditctl et retourne sa sortie
+func auditctl(args []string) (string, error) {
+	output, err := exec.Command("auditctl", args...).CombinedOutput()
+	text := strings.TrimSpace(string(output))
+	if err != nil && text != "" {
+		err = fmt.Errorf("%w: %s", err, text)
+	}
+	return text, err
+}
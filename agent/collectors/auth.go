@@ -0,0 +1,267 @@
+package collectors
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// Actions et résultats émis par le collecteur d'authentification
+const (
+	AuthActionLogin         = "login"
+	AuthActionInvalidUser   = "invalid_user"
+	AuthActionSudo          = "sudo"
+	AuthActionSu            = "su"
+	AuthActionSessionOpened = "session_opened"
+	AuthActionSessionClosed = "session_closed"
+	AuthActionFailure       = "auth_failure"
+
+	AuthOutcomeSuccess = "success"
+	AuthOutcomeFailure = "failure"
+)
+
+// maxTrackedFailureSources borne le nombre de sources d'échecs suivies
+const maxTrackedFailureSources = 10000
+
+// AuthCollector lit les journaux d'authentification (auth.log, secure) et
+// émet un événement par connexion SSH, commande sudo, changement
+// d'utilisateur su ou échec PAM. Les échecs sont comptés par source sur une
+// fenêtre glissante : une source qui insiste, ou qui réussit après avoir
+// échoué, passe en sévérité high.
+type AuthCollector struct {
+	logger   *utils.Logger
+	agentID  string
+	hostname string
+	options  AuthOptions
+
+	mu   sync.Mutex
+	next *AuthOptions
+
+	tailer   *logTailer
+	failures *failureTracker
+}
+
+// NewAuthCollector crée un collecteur d'authentification et charge les
+// positions de lecture enregistrées
+func NewAuthCollector(logger *utils.Logger, agentID, hostname string, options AuthOptions) *AuthCollector {
+	return &AuthCollector{
+		logger:   logger,
+		agentID:  agentID,
+		hostname: hostname,
+		options:  options,
+		tailer:   newLogTailer(options.OffsetsFile, logger),
+		failures: newFailureTracker(),
+	}
+}
+
+// Configure change les journaux lus et leurs filtres ; un nouveau
+// offsets_file reçoit les positions courantes
+func (ac *AuthCollector) Configure(options AuthOptions) {
+	ac.mu.Lock()
+	ac.next = &options
+	ac.mu.Unlock()
+}
+
+// Collect émet les activités d'authentification ajoutées aux journaux depuis
+// la collecte précédente
+func (ac *AuthCollector) Collect() ([]*models.Event, error) {
+	ac.logger.Debug("Starting auth collection...")
+
+	ac.mu.Lock()
+	if ac.next != nil {
+		ac.options = *ac.next
+		ac.tailer.stateFile = ac.options.OffsetsFile
+		ac.next = nil
+	}
+	ac.mu.Unlock()
+
+	// Positions du cycle précédent, dont les événements sont envoyés
+	ac.tailer.forget(ac.options.Paths)
+	if err := ac.tailer.save(); err != nil {
+		ac.logger.Error("Failed to save auth log positions: %v", err)
+	}
+
+	now := time.Now()
+	var events []*models.Event
+	for _, path := range ac.options.Paths {
+		err := ac.tailer.read(path, ac.options.FromBeginning, func(line string) {
+			if event := ac.parseLine(path, line, now); event != nil {
+				events = append(events, event)
+			}
+		})
+		if err != nil {
+			ac.logger.Error("Failed to read auth log %s: %v", path, err)
+		}
+	}
+	ac.failures.prune(now, ac.options.FailureWindow)
+
+	ac.logger.Info("Collected %d auth events", len(events))
+	return events, nil
+}
+
+// Close enregistre les positions de lecture
+func (ac *AuthCollector) Close() error {
+	return ac.tailer.save()
+}
+
+// parseLine construit l'événement d'une ligne de journal ; nil si la ligne
+// n'est pas une activité d'authentification ou si l'utilisateur est filtré
+func (ac *AuthCollector) parseLine(path, line string, now time.Time) *models.Event {
+	parsed, ok := parseSyslogLine(line, now)
+	if !ok {
+		return nil
+	}
+	authEvent, ok := parseAuthMessage(parsed.program, parsed.message)
+	if !ok {
+		return nil
+	}
+	authEvent.LogFile = path
+	authEvent.Message = line
+
+	// Les échecs sont comptés même pour les utilisateurs filtrés
+	key := failureKey(authEvent)
+	switch authEvent.Outcome {
+	case AuthOutcomeFailure:
+		authEvent.FailureCount = ac.failures.add(key, parsed.timestamp, ac.options.FailureWindow)
+	case AuthOutcomeSuccess:
+		if authEvent.Action == AuthActionLogin || authEvent.Action == AuthActionSu || authEvent.Action == AuthActionSudo {
+			authEvent.FailureCount = ac.failures.count(key, parsed.timestamp, ac.options.FailureWindow)
+			ac.failures.reset(key)
+		}
+	}
+
+	if !ac.options.Users.Match(authEvent.User) {
+		return nil
+	}
+
+	return &models.Event{
+		Timestamp:   parsed.timestamp,
+		AgentID:     ac.agentID,
+		Hostname:    ac.hostname,
+		EventType:   models.EventTypeAuth,
+		Severity:    ac.determineSeverity(*authEvent),
+		Username:    authEvent.User,
+		SourceIP:    authEvent.SourceIP,
+		ProcessName: parsed.program,
+		ProcessPID:  parsed.pid,
+		RawData: map[string]interface{}{
+			"auth": authEvent,
+		},
+		Tags: ac.generateTags(*authEvent),
+	}
+}
+
+// determineSeverity détermine la sévérité selon l'action et les échecs récents
+func (ac *AuthCollector) determineSeverity(ae models.AuthEvent) models.Severity {
+	switch {
+	case ae.FailureCount >= ac.options.FailureThreshold:
+		// Force brute en cours, ou réussie
+		return models.SeverityHigh
+	case ae.Outcome == AuthOutcomeFailure && (ae.Action == AuthActionSudo || ae.Action == AuthActionSu):
+		return models.SeverityMedium
+	case ae.Outcome == AuthOutcomeSuccess && ae.Action == AuthActionLogin && ae.User == "root":
+		return models.SeverityMedium
+	default:
+		return models.SeverityLow
+	}
+}
+
+// generateTags génère des tags basés sur l'activité
+func (ac *AuthCollector) generateTags(ae models.AuthEvent) []string {
+	tags := []string{"auth", "auth_" + ae.Action, "auth_" + ae.Outcome}
+
+	if ae.FailureCount >= ac.options.FailureThreshold {
+		if ae.Outcome == AuthOutcomeFailure {
+			tags = append(tags, "repeated_failures")
+		} else {
+			tags = append(tags, "success_after_failures")
+		}
+	}
+
+	if ae.Outcome == AuthOutcomeSuccess && ae.Action == AuthActionLogin && ae.User == "root" {
+		tags = append(tags, "root_login")
+	}
+
+	if ae.Outcome == AuthOutcomeSuccess && (ae.Action == AuthActionSudo || ae.Action == AuthActionSu) {
+		tags = append(tags, "privilege_change")
+	}
+
+	if strings.Contains(ae.Reason, "NOT in sudoers") {
+		tags = append(tags, "sudoers_violation")
+	}
+
+	return tags
+}
+
+// failureKey identifie la source d'un échec : l'adresse distante si elle est
+// connue, sinon le service et l'utilisateur
+func failureKey(ae *models.AuthEvent) string {
+	if ae.SourceIP != "" {
+		return "ip:" + ae.SourceIP
+	}
+	return "user:" + ae.Service + ":" + ae.User
+}
+
+// failureTracker compte les échecs récents de chaque source
+type failureTracker struct {
+	failures map[string][]time.Time
+}
+
+// newFailureTracker crée un compteur d'échecs vide
+func newFailureTracker() *failureTracker {
+	return &failureTracker{failures: make(map[string][]time.Time)}
+}
+
+// add enregistre un échec et retourne le nombre d'échecs de la source dans la
+// fenêtre. Au-delà de maxTrackedFailureSources, une nouvelle source est
+// comptée sans être enregistrée.
+func (ft *failureTracker) add(key string, at time.Time, window time.Duration) int {
+	recent := ft.recent(key, at, window)
+	if recent == nil && len(ft.failures) >= maxTrackedFailureSources {
+		return 1
+	}
+	ft.failures[key] = append(recent, at)
+	return len(ft.failures[key])
+}
+
+// count retourne le nombre d'échecs de la source dans la fenêtre
+func (ft *failureTracker) count(key string, at time.Time, window time.Duration) int {
+	return len(ft.recent(key, at, window))
+}
+
+// reset oublie les échecs d'une source après une authentification réussie
+func (ft *failureTracker) reset(key string) {
+	delete(ft.failures, key)
+}
+
+// prune oublie les échecs sortis de la fenêtre
+func (ft *failureTracker) prune(now time.Time, window time.Duration) {
+	for key := range ft.failures {
+		ft.recent(key, now, window)
+	}
+}
+
+// recent retire les échecs antérieurs à la fenêtre et retourne les autres
+func (ft *failureTracker) recent(key string, at time.Time, window time.Duration) []time.Time {
+	times, ok := ft.failures[key]
+	if !ok {
+		return nil
+	}
+
+	cutoff := at.Add(-window)
+	kept := times[:0]
+	for _, t := range times {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		delete(ft.failures, key)
+		return nil
+	}
+	ft.failures[key] = kept
+	return kept
+}
@@ -0,0 +1,319 @@
+package collectors
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/models"
+)
+
+// Motifs des messages d'authentification reconnus
+var (
+	sshAcceptedPattern    = regexp.MustCompile(`^Accepted (\S+) for (\S+) from (\S+) port (\d+)`)
+	sshFailedPattern      = regexp.MustCompile(`^Failed (\S+) for (invalid user )?(\S*) from (\S+) port (\d+)`)
+	sshInvalidUserPattern = regexp.MustCompile(`^Invalid user (\S*) from (\S+)(?: port (\d+))?`)
+	sshMaxAttemptsPattern = regexp.MustCompile(`^error: maximum authentication attempts exceeded for (invalid user )?(\S*) from (\S+) port (\d+)`)
+
+	pamPattern        = regexp.MustCompile(`^pam_\w+\(([^:)]+):(auth|session)\): (.*)$`)
+	pamSessionPattern = regexp.MustCompile(`^session (opened|closed) for user ([^\s(]+)(?:\(uid=\d+\))?(?: by ([^\s(]*)(?:\(uid=\d+\))?)?`)
+
+	suPattern       = regexp.MustCompile(`^(FAILED SU )?\(to (\S+)\) (\S+) on (\S+)`)
+	suLegacyPattern = regexp.MustCompile(`^(Successful|FAILED) su for (\S+) by (\S+)`)
+	suShadowPattern = regexp.MustCompile(`^([+-]) (\S+) (\S+):(\S+)$`)
+)
+
+// pamSessionServices sont les services PAM dont les sessions sont remontées ;
+// celles de sshd, sudo ou cron doubleraient d'autres événements ou seraient
+// trop bruyantes
+var pamSessionServices = map[string]bool{"su": true, "su-l": true, "login": true}
+
+// syslogLine est une ligne de journal au format syslog, découpée
+type syslogLine struct {
+	timestamp time.Time
+	program   string
+	pid       int
+	message   string
+}
+
+// parseSyslogLine découpe une ligne « Jan  2 10:30:00 host prog[pid]: message »
+// ou « 2024-01-02T10:30:00.123456+01:00 host prog[pid]: message ». Une date
+// sans année est placée dans l'année la plus récente qui ne soit pas dans le futur.
+func parseSyslogLine(line string, now time.Time) (syslogLine, bool) {
+	var parsed syslogLine
+	var rest string
+
+	if len(line) > 16 && line[3] == ' ' && line[15] == ' ' {
+		ts, err := time.ParseInLocation("Jan _2 15:04:05", line[:15], time.Local)
+		if err != nil {
+			return parsed, false
+		}
+		parsed.timestamp = time.Date(now.Year(), ts.Month(), ts.Day(), ts.Hour(), ts.Minute(), ts.Second(), 0, time.Local)
+		if parsed.timestamp.After(now.Add(24 * time.Hour)) {
+			parsed.timestamp = parsed.timestamp.AddDate(-1, 0, 0)
+		}
+		rest = line[16:]
+	} else {
+		field, after, ok := strings.Cut(line, " ")
+		if !ok {
+			return parsed, false
+		}
+		ts, err := time.Parse(time.RFC3339Nano, field)
+		if err != nil {
+			return parsed, false
+		}
+		parsed.timestamp = ts
+		rest = after
+	}
+
+	// Nom d'hôte, puis « programme[pid]: message »
+	_, rest, ok := strings.Cut(rest, " ")
+	if !ok {
+		return parsed, false
+	}
+	tag, message, ok := strings.Cut(rest, ": ")
+	if !ok {
+		return parsed, false
+	}
+
+	parsed.program = tag
+	if i := strings.IndexByte(tag, '['); i > 0 && strings.HasSuffix(tag, "]") {
+		parsed.program = tag[:i]
+		parsed.pid, _ = strconv.Atoi(tag[i+1 : len(tag)-1])
+	}
+	// OpenSSH 9.8+ authentifie dans un processus sshd-session
+	if parsed.program == "sshd-session" {
+		parsed.program = "sshd"
+	}
+	parsed.message = message
+
+	return parsed, true
+}
+
+// parseAuthMessage reconnaît un message d'authentification ; false si le
+// message n'en est pas un ou n'est pas remonté
+func parseAuthMessage(program, message string) (*models.AuthEvent, bool) {
+	if match := pamPattern.FindStringSubmatch(message); match != nil {
+		return parsePAM(match[1], match[2], match[3])
+	}
+
+	switch program {
+	case "sshd":
+		return parseSSHD(message)
+	case "sudo":
+		return parseSudo(message)
+	case "su":
+		return parseSu(message)
+	}
+	return nil, false
+}
+
+// parseSSHD reconnaît les connexions, échecs et utilisateurs inconnus de sshd
+func parseSSHD(message string) (*models.AuthEvent, bool) {
+	if match := sshAcceptedPattern.FindStringSubmatch(message); match != nil {
+		return &models.AuthEvent{
+			Action:     AuthActionLogin,
+			Outcome:    AuthOutcomeSuccess,
+			Service:    "sshd",
+			Method:     match[1],
+			User:       match[2],
+			SourceIP:   match[3],
+			SourcePort: atoi(match[4]),
+		}, true
+	}
+
+	if match := sshFailedPattern.FindStringSubmatch(message); match != nil {
+		event := &models.AuthEvent{
+			Action:     AuthActionLogin,
+			Outcome:    AuthOutcomeFailure,
+			Service:    "sshd",
+			Method:     match[1],
+			User:       match[3],
+			SourceIP:   match[4],
+			SourcePort: atoi(match[5]),
+			Reason:     "authentication failed",
+		}
+		if match[2] != "" {
+			event.Reason = "invalid user"
+		}
+		return event, true
+	}
+
+	if match := sshInvalidUserPattern.FindStringSubmatch(message); match != nil {
+		return &models.AuthEvent{
+			Action:     AuthActionInvalidUser,
+			Outcome:    AuthOutcomeFailure,
+			Service:    "sshd",
+			User:       match[1],
+			SourceIP:   match[2],
+			SourcePort: atoi(match[3]),
+			Reason:     "invalid user",
+		}, true
+	}
+
+	if match := sshMaxAttemptsPattern.FindStringSubmatch(message); match != nil {
+		return &models.AuthEvent{
+			Action:     AuthActionLogin,
+			Outcome:    AuthOutcomeFailure,
+			Service:    "sshd",
+			User:       match[2],
+			SourceIP:   match[3],
+			SourcePort: atoi(match[4]),
+			Reason:     "maximum authentication attempts exceeded",
+		}, true
+	}
+
+	return nil, false
+}
+
+// parseSudo reconnaît « alice : [motif ;] TTY=pts/0 ; PWD=/home/alice ; USER=root ; COMMAND=/usr/bin/id ».
+// Un motif avant les champs (mots de passe incorrects, utilisateur absent
+// des sudoers...) signale un échec.
+func parseSudo(message string) (*models.AuthEvent, bool) {
+	user, rest, ok := strings.Cut(strings.TrimSpace(message), " : ")
+	if !ok || user == "" || strings.ContainsAny(user, " \t") {
+		return nil, false
+	}
+
+	event := &models.AuthEvent{
+		Action:  AuthActionSudo,
+		Outcome: AuthOutcomeSuccess,
+		Service: "sudo",
+		User:    user,
+	}
+
+	fields := strings.Split(rest, " ; ")
+fields:
+	for i, field := range fields {
+		key, value, found := strings.Cut(field, "=")
+		switch {
+		case found && key == "COMMAND":
+			// La commande est le dernier champ et peut contenir « ; »
+			event.Command = strings.Join(append([]string{value}, fields[i+1:]...), " ; ")
+			break fields
+		case found && key == "TTY":
+			event.TTY = value
+		case found && key == "PWD":
+			event.WorkingDir = value
+		case found && key == "USER":
+			event.TargetUser = value
+		case !found && i == 0:
+			event.Outcome = AuthOutcomeFailure
+			event.Reason = strings.TrimSpace(field)
+		}
+	}
+
+	if event.Command == "" && event.TargetUser == "" {
+		return nil, false
+	}
+	return event, true
+}
+
+// parseSu reconnaît les changements d'utilisateur de su, selon les formats
+// de util-linux (« (to root) alice on pts/0 ») et des anciennes versions
+func parseSu(message string) (*models.AuthEvent, bool) {
+	event := &models.AuthEvent{
+		Action:  AuthActionSu,
+		Outcome: AuthOutcomeSuccess,
+		Service: "su",
+	}
+
+	if match := suPattern.FindStringSubmatch(message); match != nil {
+		event.TargetUser, event.User, event.TTY = match[2], match[3], match[4]
+		if match[1] != "" {
+			event.Outcome = AuthOutcomeFailure
+		}
+	} else if match := suLegacyPattern.FindStringSubmatch(message); match != nil {
+		event.TargetUser, event.User = match[2], match[3]
+		if match[1] == "FAILED" {
+			event.Outcome = AuthOutcomeFailure
+		}
+	} else if match := suShadowPattern.FindStringSubmatch(message); match != nil {
+		event.TTY, event.User, event.TargetUser = match[2], match[3], match[4]
+		if match[1] == "-" {
+			event.Outcome = AuthOutcomeFailure
+		}
+	} else {
+		return nil, false
+	}
+
+	if event.Outcome == AuthOutcomeFailure {
+		event.Reason = "authentication failed"
+	}
+	return event, true
+}
+
+// parsePAM reconnaît les échecs d'authentification et les ouvertures et
+// fermetures de session signalés par un module PAM
+func parsePAM(service, stage, message string) (*models.AuthEvent, bool) {
+	if stage == "session" {
+		if !pamSessionServices[service] {
+			return nil, false
+		}
+		match := pamSessionPattern.FindStringSubmatch(message)
+		if match == nil {
+			return nil, false
+		}
+
+		event := &models.AuthEvent{
+			Action:  AuthActionSessionOpened,
+			Outcome: AuthOutcomeSuccess,
+			Service: service,
+			User:    match[2],
+		}
+		if match[1] == "closed" {
+			event.Action = AuthActionSessionClosed
+		}
+		// su : l'utilisateur est celui qui change d'identité
+		if match[3] != "" {
+			event.User, event.TargetUser = match[3], match[2]
+		}
+		return event, true
+	}
+
+	// sshd signale lui-même chaque échec (Failed password...)
+	reason, details, _ := strings.Cut(message, "; ")
+	if reason != "authentication failure" || service == "sshd" {
+		return nil, false
+	}
+
+	event := &models.AuthEvent{
+		Action:  AuthActionFailure,
+		Outcome: AuthOutcomeFailure,
+		Service: service,
+		Reason:  reason,
+	}
+	// Comme pour su, l'utilisateur est l'auteur de la tentative (ruser, à
+	// défaut logname) et user le compte visé. Sans auteur connu (login), le
+	// compte visé est l'utilisateur.
+	var logname string
+	for _, field := range strings.Fields(details) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "user":
+			event.TargetUser = value
+		case "ruser":
+			event.User = value
+		case "logname":
+			logname = value
+		case "rhost":
+			event.SourceIP = value
+		case "tty":
+			event.TTY = value
+		}
+	}
+	if event.User == "" {
+		event.User = logname
+	}
+	if event.User == "" || event.User == event.TargetUser {
+		event.User, event.TargetUser = event.TargetUser, ""
+	}
+	return event, true
+}
+
+// atoi convertit un nombre optionnel, 0 s'il est absent ou invalide
+func atoi(value string) int {
+	n, _ := strconv.Atoi(value)
+	return n
+}
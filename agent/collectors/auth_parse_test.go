@@ -0,0 +1,244 @@
+package collectors
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/models"
+)
+
+func TestParseSyslogLine(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 10, 0, 0, time.Local)
+
+	tests := []struct {
+		name   string
+		line   string
+		want   syslogLine
+		wantOK bool
+	}{
+		{
+			name:   "traditional",
+			line:   "Jan  1 00:05:00 host sshd[1234]: Accepted password for alice from 10.0.0.1 port 22 ssh2",
+			want:   syslogLine{timestamp: time.Date(2024, 1, 1, 0, 5, 0, 0, time.Local), program: "sshd", pid: 1234, message: "Accepted password for alice from 10.0.0.1 port 22 ssh2"},
+			wantOK: true,
+		},
+		{
+			name:   "previous year",
+			line:   "Dec 31 23:59:00 host su: (to root) alice on pts/0",
+			want:   syslogLine{timestamp: time.Date(2023, 12, 31, 23, 59, 0, 0, time.Local), program: "su", message: "(to root) alice on pts/0"},
+			wantOK: true,
+		},
+		{
+			name:   "clock skew within a day",
+			line:   "Jan  1 23:00:00 host sudo: alice : TTY=pts/0 ; USER=root ; COMMAND=/usr/bin/id",
+			want:   syslogLine{timestamp: time.Date(2024, 1, 1, 23, 0, 0, 0, time.Local), program: "sudo", message: "alice : TTY=pts/0 ; USER=root ; COMMAND=/usr/bin/id"},
+			wantOK: true,
+		},
+		{
+			name:   "rfc3339",
+			line:   "2024-01-02T10:30:00.123456+01:00 host sshd-session[99]: Invalid user bob from 10.0.0.2 port 4242",
+			want:   syslogLine{timestamp: time.Date(2024, 1, 2, 9, 30, 0, 123456000, time.UTC), program: "sshd", pid: 99, message: "Invalid user bob from 10.0.0.2 port 4242"},
+			wantOK: true,
+		},
+		{
+			name:   "message with colons",
+			line:   "Jan  1 00:00:00 host login[7]: pam_unix(login:session): session opened for user root(uid=0) by LOGIN(uid=0)",
+			want:   syslogLine{timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), program: "login", pid: 7, message: "pam_unix(login:session): session opened for user root(uid=0) by LOGIN(uid=0)"},
+			wantOK: true,
+		},
+		{name: "bad date", line: "Foo  1 00:00:00 host sshd[1]: message"},
+		{name: "bad rfc3339", line: "2024-13-02T10:30:00Z host sshd[1]: message"},
+		{name: "no hostname", line: "2024-01-02T10:30:00Z"},
+		{name: "no tag", line: "Jan  1 00:00:00 host message without tag"},
+		{name: "empty", line: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseSyslogLine(tt.line, now)
+			if ok != tt.wantOK {
+				t.Fatalf("parseSyslogLine(%q) ok = %t, want %t", tt.line, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if !got.timestamp.Equal(tt.want.timestamp) {
+				t.Errorf("timestamp = %v, want %v", got.timestamp, tt.want.timestamp)
+			}
+			if got.program != tt.want.program || got.pid != tt.want.pid || got.message != tt.want.message {
+				t.Errorf("parseSyslogLine(%q) = %q[%d] %q, want %q[%d] %q",
+					tt.line, got.program, got.pid, got.message, tt.want.program, tt.want.pid, tt.want.message)
+			}
+		})
+	}
+}
+
+func TestParseAuthMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		program string
+		message string
+		want    *models.AuthEvent // nil : message non remonté
+	}{
+		// sshd
+		{
+			name: "ssh accepted", program: "sshd",
+			message: "Accepted publickey for alice from 192.0.2.10 port 50022 ssh2: ED25519 SHA256:abc",
+			want:    &models.AuthEvent{Action: AuthActionLogin, Outcome: AuthOutcomeSuccess, Service: "sshd", Method: "publickey", User: "alice", SourceIP: "192.0.2.10", SourcePort: 50022},
+		},
+		{
+			name: "ssh failed", program: "sshd",
+			message: "Failed password for root from 2001:db8::1 port 40000 ssh2",
+			want:    &models.AuthEvent{Action: AuthActionLogin, Outcome: AuthOutcomeFailure, Service: "sshd", Method: "password", User: "root", SourceIP: "2001:db8::1", SourcePort: 40000, Reason: "authentication failed"},
+		},
+		{
+			name: "ssh failed invalid user", program: "sshd",
+			message: "Failed password for invalid user admin from 198.51.100.7 port 1234 ssh2",
+			want:    &models.AuthEvent{Action: AuthActionLogin, Outcome: AuthOutcomeFailure, Service: "sshd", Method: "password", User: "admin", SourceIP: "198.51.100.7", SourcePort: 1234, Reason: "invalid user"},
+		},
+		{
+			name: "ssh failed empty user", program: "sshd",
+			message: "Failed none for invalid user  from 198.51.100.7 port 1234 ssh2",
+			want:    &models.AuthEvent{Action: AuthActionLogin, Outcome: AuthOutcomeFailure, Service: "sshd", Method: "none", SourceIP: "198.51.100.7", SourcePort: 1234, Reason: "invalid user"},
+		},
+		{
+			name: "ssh invalid user", program: "sshd",
+			message: "Invalid user oracle from 203.0.113.5 port 60000",
+			want:    &models.AuthEvent{Action: AuthActionInvalidUser, Outcome: AuthOutcomeFailure, Service: "sshd", User: "oracle", SourceIP: "203.0.113.5", SourcePort: 60000, Reason: "invalid user"},
+		},
+		{
+			name: "ssh invalid user without port", program: "sshd",
+			message: "Invalid user oracle from 203.0.113.5",
+			want:    &models.AuthEvent{Action: AuthActionInvalidUser, Outcome: AuthOutcomeFailure, Service: "sshd", User: "oracle", SourceIP: "203.0.113.5", Reason: "invalid user"},
+		},
+		{
+			name: "ssh max attempts", program: "sshd",
+			message: "error: maximum authentication attempts exceeded for invalid user test from 203.0.113.5 port 22 ssh2 [preauth]",
+			want:    &models.AuthEvent{Action: AuthActionLogin, Outcome: AuthOutcomeFailure, Service: "sshd", User: "test", SourceIP: "203.0.113.5", SourcePort: 22, Reason: "maximum authentication attempts exceeded"},
+		},
+		{name: "ssh disconnect", program: "sshd", message: "Disconnected from user alice 192.0.2.10 port 50022"},
+		{name: "ssh line from another program", program: "cron", message: "Accepted password for alice from 192.0.2.10 port 22 ssh2"},
+
+		// sudo
+		{
+			name: "sudo command", program: "sudo",
+			message: "   alice : TTY=pts/0 ; PWD=/home/alice ; USER=root ; COMMAND=/usr/bin/id",
+			want:    &models.AuthEvent{Action: AuthActionSudo, Outcome: AuthOutcomeSuccess, Service: "sudo", User: "alice", TargetUser: "root", TTY: "pts/0", WorkingDir: "/home/alice", Command: "/usr/bin/id"},
+		},
+		{
+			name: "sudo command with separators", program: "sudo",
+			message: "alice : TTY=pts/0 ; PWD=/ ; USER=root ; COMMAND=/bin/sh -c echo a ; echo b=c",
+			want:    &models.AuthEvent{Action: AuthActionSudo, Outcome: AuthOutcomeSuccess, Service: "sudo", User: "alice", TargetUser: "root", TTY: "pts/0", WorkingDir: "/", Command: "/bin/sh -c echo a ; echo b=c"},
+		},
+		{
+			name: "sudo failure", program: "sudo",
+			message: "mallory : 3 incorrect password attempts ; TTY=pts/1 ; PWD=/tmp ; USER=root ; COMMAND=/bin/bash",
+			want:    &models.AuthEvent{Action: AuthActionSudo, Outcome: AuthOutcomeFailure, Service: "sudo", User: "mallory", TargetUser: "root", TTY: "pts/1", WorkingDir: "/tmp", Command: "/bin/bash", Reason: "3 incorrect password attempts"},
+		},
+		{
+			name: "sudo not in sudoers", program: "sudo",
+			message: "eve : user NOT in sudoers ; TTY=pts/2 ; PWD=/home/eve ; USER=root ; COMMAND=/usr/bin/whoami",
+			want:    &models.AuthEvent{Action: AuthActionSudo, Outcome: AuthOutcomeFailure, Service: "sudo", User: "eve", TargetUser: "root", TTY: "pts/2", WorkingDir: "/home/eve", Command: "/usr/bin/whoami", Reason: "user NOT in sudoers"},
+		},
+		{name: "sudo without fields", program: "sudo", message: "alice : a password is required"},
+		{name: "sudo user with space", program: "sudo", message: "a b : TTY=pts/0 ; USER=root ; COMMAND=/usr/bin/id"},
+		{name: "sudo other message", program: "sudo", message: "unable to resolve host web-01"},
+
+		// su
+		{
+			name: "su util-linux", program: "su",
+			message: "(to root) alice on pts/0",
+			want:    &models.AuthEvent{Action: AuthActionSu, Outcome: AuthOutcomeSuccess, Service: "su", User: "alice", TargetUser: "root", TTY: "pts/0"},
+		},
+		{
+			name: "su util-linux failure", program: "su",
+			message: "FAILED SU (to root) alice on pts/0",
+			want:    &models.AuthEvent{Action: AuthActionSu, Outcome: AuthOutcomeFailure, Service: "su", User: "alice", TargetUser: "root", TTY: "pts/0", Reason: "authentication failed"},
+		},
+		{
+			name: "su legacy", program: "su",
+			message: "Successful su for postgres by root",
+			want:    &models.AuthEvent{Action: AuthActionSu, Outcome: AuthOutcomeSuccess, Service: "su", User: "root", TargetUser: "postgres"},
+		},
+		{
+			name: "su legacy failure", program: "su",
+			message: "FAILED su for root by alice",
+			want:    &models.AuthEvent{Action: AuthActionSu, Outcome: AuthOutcomeFailure, Service: "su", User: "alice", TargetUser: "root", Reason: "authentication failed"},
+		},
+		{
+			name: "su shadow", program: "su",
+			message: "+ pts/3 alice:root",
+			want:    &models.AuthEvent{Action: AuthActionSu, Outcome: AuthOutcomeSuccess, Service: "su", User: "alice", TargetUser: "root", TTY: "pts/3"},
+		},
+		{
+			name: "su shadow failure", program: "su",
+			message: "- pts/3 alice:root",
+			want:    &models.AuthEvent{Action: AuthActionSu, Outcome: AuthOutcomeFailure, Service: "su", User: "alice", TargetUser: "root", TTY: "pts/3", Reason: "authentication failed"},
+		},
+		{name: "su other message", program: "su", message: "No passwd entry for user 'nobody2'"},
+
+		// PAM : échecs d'authentification
+		{
+			name: "pam su failure", program: "su",
+			message: "pam_unix(su:auth): authentication failure; logname=alice uid=1000 euid=0 tty=/dev/pts/0 ruser=alice rhost=  user=root",
+			want:    &models.AuthEvent{Action: AuthActionFailure, Outcome: AuthOutcomeFailure, Service: "su", User: "alice", TargetUser: "root", TTY: "/dev/pts/0", Reason: "authentication failure"},
+		},
+		{
+			name: "pam logname fallback", program: "su",
+			message: "pam_unix(su-l:auth): authentication failure; logname=bob uid=1001 euid=0 tty=pts/1 ruser= rhost=  user=postgres",
+			want:    &models.AuthEvent{Action: AuthActionFailure, Outcome: AuthOutcomeFailure, Service: "su-l", User: "bob", TargetUser: "postgres", TTY: "pts/1", Reason: "authentication failure"},
+		},
+		{
+			name: "pam login without requester", program: "login",
+			message: "pam_unix(login:auth): authentication failure; logname= uid=0 euid=0 tty=tty1 ruser= rhost= user=alice",
+			want:    &models.AuthEvent{Action: AuthActionFailure, Outcome: AuthOutcomeFailure, Service: "login", User: "alice", TTY: "tty1", Reason: "authentication failure"},
+		},
+		{
+			name: "pam requester is target", program: "sudo",
+			message: "pam_unix(sudo:auth): authentication failure; logname=alice uid=1000 euid=0 tty=/dev/pts/0 ruser=alice rhost=  user=alice",
+			want:    &models.AuthEvent{Action: AuthActionFailure, Outcome: AuthOutcomeFailure, Service: "sudo", User: "alice", TTY: "/dev/pts/0", Reason: "authentication failure"},
+		},
+		{
+			name: "pam remote host", program: "vsftpd",
+			message: "pam_unix(vsftpd:auth): authentication failure; logname= uid=0 euid=0 tty=ftp ruser=anonymous rhost=198.51.100.9 user=ftpuser",
+			want:    &models.AuthEvent{Action: AuthActionFailure, Outcome: AuthOutcomeFailure, Service: "vsftpd", User: "anonymous", TargetUser: "ftpuser", SourceIP: "198.51.100.9", TTY: "ftp", Reason: "authentication failure"},
+		},
+		{name: "pam sshd failure", program: "sshd", message: "pam_unix(sshd:auth): authentication failure; logname= uid=0 euid=0 tty=ssh ruser= rhost=192.0.2.1  user=root"},
+		{name: "pam other auth message", program: "su", message: "pam_unix(su:auth): conversation failed"},
+
+		// PAM : sessions
+		{
+			name: "pam su session", program: "su",
+			message: "pam_unix(su:session): session opened for user root(uid=0) by alice(uid=1000)",
+			want:    &models.AuthEvent{Action: AuthActionSessionOpened, Outcome: AuthOutcomeSuccess, Service: "su", User: "alice", TargetUser: "root"},
+		},
+		{
+			name: "pam login session closed", program: "login",
+			message: "pam_unix(login:session): session closed for user alice",
+			want:    &models.AuthEvent{Action: AuthActionSessionClosed, Outcome: AuthOutcomeSuccess, Service: "login", User: "alice"},
+		},
+		{
+			name: "pam session without requester", program: "su",
+			message: "pam_unix(su-l:session): session opened for user postgres(uid=26) by (uid=0)",
+			want:    &models.AuthEvent{Action: AuthActionSessionOpened, Outcome: AuthOutcomeSuccess, Service: "su-l", User: "postgres"},
+		},
+		{name: "pam sshd session", program: "sshd", message: "pam_unix(sshd:session): session opened for user alice(uid=1000) by (uid=0)"},
+		{name: "pam cron session", program: "CRON", message: "pam_unix(cron:session): session closed for user root"},
+		{name: "pam other session message", program: "login", message: "pam_unix(login:session): bad username [x]"},
+
+		{name: "unknown program", program: "kernel", message: "audit: type=1400"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseAuthMessage(tt.program, tt.message)
+			if ok != (tt.want != nil) {
+				t.Fatalf("parseAuthMessage(%q, %q) ok = %t, want %t", tt.program, tt.message, ok, tt.want != nil)
+			}
+			if ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAuthMessage(%q, %q) =\n\t%+v\nwant\n\t%+v", tt.program, tt.message, *got, *tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package collectors
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileChangeTime retourne la date du dernier changement d'état d'un fichier
+// (ctime), en nanosecondes
+func fileChangeTime(info fs.FileInfo) int64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Ctimespec.Nano()
+	}
+	return 0
+}
@@ -0,0 +1,16 @@
+package collectors
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileChangeTime retourne la date du dernier changement d'état d'un fichier
+// (ctime), en nanosecondes. Contrairement à la date de modification, elle ne
+// peut pas être fixée depuis l'espace utilisateur.
+func fileChangeTime(info fs.FileInfo) int64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return stat.Ctim.Nano()
+	}
+	return 0
+}
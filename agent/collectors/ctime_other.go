@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package collectors
+
+import "io/fs"
+
+// fileChangeTime n'est lue que sous Linux et macOS
+func fileChangeTime(info fs.FileInfo) int64 {
+	return 0
+}
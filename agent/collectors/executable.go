@@ -0,0 +1,192 @@
+package collectors
+
+import (
+	"container/list"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// executableKey identifie le contenu d'un exécutable sans le relire : un
+// binaire remplacé change d'inode, un binaire réécrit sur place change de
+// ctime, que l'on ne peut pas restaurer comme la date de modification
+type executableKey struct {
+	path   string // seulement si l'inode est indisponible
+	device uint64 // les inodes ne sont uniques que par système de fichiers
+	inode  uint64
+	ctime  int64
+	mtime  int64
+	size   int64
+}
+
+// executableInfo est ce que l'on sait du binaire d'un processus
+type executableInfo struct {
+	sha256  string
+	md5     string
+	sha1    string
+	details models.ExecutableInfo
+}
+
+// executableInspector calcule les hash, les métadonnées ELF et le paquet
+// propriétaire des exécutables. Les résultats sont gardés dans un cache LRU :
+// un binaire n'est relu que lorsqu'il a été remplacé.
+type executableInspector struct {
+	logger      *utils.Logger
+	extraHashes []string
+	packages    packageDatabase // nil : paquet propriétaire non recherché
+
+	capacity int
+	order    *list.List // *executableCacheEntry, les plus récemment utilisés en tête
+	entries  map[executableKey]*list.Element
+}
+
+// executableCacheEntry est un élément du cache LRU
+type executableCacheEntry struct {
+	key  executableKey
+	info *executableInfo
+}
+
+// newExecutableInspector crée un inspecteur selon les options du collecteur de processus
+func newExecutableInspector(logger *utils.Logger, options ProcessOptions) *executableInspector {
+	ei := &executableInspector{
+		logger:      logger,
+		extraHashes: options.ExtraHashes,
+		capacity:    options.ExecutableCache,
+		order:       list.New(),
+		entries:     make(map[executableKey]*list.Element),
+	}
+	if options.PackageLookup {
+		ei.packages = newPackageDatabase(logger)
+	}
+	return ei
+}
+
+// inspect retourne les informations de l'exécutable d'un processus ; nil s'il
+// est illisible. Sous Linux le binaire est lu par /proc/<pid>/exe, qui reste
+// accessible s'il a été supprimé ou s'il vit dans un autre espace de noms.
+func (ei *executableInspector) inspect(pid int32, exe string) *executableInfo {
+	if exe == "" {
+		return nil
+	}
+
+	file := exe
+	if runtime.GOOS == "linux" {
+		file = "/proc/" + strconv.Itoa(int(pid)) + "/exe"
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		file = exe
+		if info, err = os.Stat(file); err != nil {
+			ei.logger.Debug("Failed to stat executable %s: %v", exe, err)
+			return nil
+		}
+	}
+
+	key := executableKey{
+		device: fileDevice(info),
+		inode:  fileInode(info),
+		ctime:  fileChangeTime(info),
+		mtime:  info.ModTime().UnixNano(),
+		size:   info.Size(),
+	}
+	if key.inode == 0 {
+		key.path = exe
+	}
+	if element, ok := ei.entries[key]; ok {
+		ei.order.MoveToFront(element)
+		return element.Value.(*executableCacheEntry).info
+	}
+
+	result, err := ei.read(file, exe, info)
+	if err != nil {
+		ei.logger.Debug("Failed to inspect executable %s: %v", exe, err)
+		return nil
+	}
+	ei.add(key, result)
+	return result
+}
+
+// read hache un exécutable et lit ses métadonnées
+func (ei *executableInspector) read(file, exe string, info fs.FileInfo) (*executableInfo, error) {
+	result := &executableInfo{
+		details: models.ExecutableInfo{Size: info.Size(), ModTime: info.ModTime()},
+	}
+
+	// Le paquet n'est cherché que si le fichier à ce chemin est bien le binaire
+	// exécuté : pas s'il a été supprimé ou remplacé, ni pour un processus
+	// d'un conteneur dont le chemin désigne un autre fichier sur l'hôte
+	var owner packageOwner
+	if ei.packages != nil && isSameFile(info, exe) {
+		var owned bool
+		if owner, owned = ei.packages.lookup(exe); owned {
+			result.details.PackageManager = owner.manager
+			result.details.Package = owner.name
+			result.details.PackageVersion = owner.version
+		}
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Un seul passage pour tous les hash ; le MD5 sert aussi à vérifier le
+	// binaire contre l'empreinte livrée par le paquet
+	sha256Hash := sha256.New()
+	writers := []io.Writer{sha256Hash}
+	var md5Hash, sha1Hash hash.Hash
+	if slices.Contains(ei.extraHashes, "md5") || owner.md5 != "" {
+		md5Hash = md5.New()
+		writers = append(writers, md5Hash)
+	}
+	if slices.Contains(ei.extraHashes, "sha1") {
+		sha1Hash = sha1.New()
+		writers = append(writers, sha1Hash)
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), f); err != nil {
+		return nil, err
+	}
+
+	result.sha256 = hex.EncodeToString(sha256Hash.Sum(nil))
+	if md5Hash != nil {
+		sum := hex.EncodeToString(md5Hash.Sum(nil))
+		if slices.Contains(ei.extraHashes, "md5") {
+			result.md5 = sum
+		}
+		result.details.PackageModified = owner.md5 != "" && owner.md5 != sum
+	}
+	if sha1Hash != nil {
+		result.sha1 = hex.EncodeToString(sha1Hash.Sum(nil))
+	}
+
+	readELFInfo(f, &result.details)
+	return result, nil
+}
+
+// add met un résultat en cache en évinçant le moins récemment utilisé
+func (ei *executableInspector) add(key executableKey, result *executableInfo) {
+	if ei.order.Len() >= ei.capacity {
+		oldest := ei.order.Back()
+		delete(ei.entries, oldest.Value.(*executableCacheEntry).key)
+		ei.order.Remove(oldest)
+	}
+	ei.entries[key] = ei.order.PushFront(&executableCacheEntry{key: key, info: result})
+}
+
+// isSameFile indique si path désigne le fichier décrit par info
+func isSameFile(info fs.FileInfo, path string) bool {
+	current, err := os.Stat(path)
+	return err == nil && os.SameFile(info, current)
+}
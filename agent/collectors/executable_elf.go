@@ -0,0 +1,104 @@
+package collectors
+
+import (
+	"debug/elf"
+	"encoding/hex"
+	"io"
+	"strings"
+
+	"github.com/luigi/xdr-platform/agent/models"
+)
+
+const (
+	// elfNoteGNUBuildID est le type de la note GNU portant l'identifiant de build
+	elfNoteGNUBuildID = 3
+
+	// maxELFNoteSize borne la lecture d'une section ou d'un segment de notes
+	maxELFNoteSize = 64 * 1024
+)
+
+// readELFInfo complète les métadonnées d'un binaire ELF ; les autres formats
+// (scripts, binaires d'autres systèmes) sont laissés tels quels
+func readELFInfo(r io.ReaderAt, details *models.ExecutableInfo) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return
+	}
+
+	details.Format = "elf"
+	details.Machine = strings.ToLower(strings.TrimPrefix(f.Machine.String(), "EM_"))
+
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(prog.Open(), 4096))
+		if err == nil {
+			details.Interpreter = strings.TrimRight(string(data), "\x00")
+		}
+		break
+	}
+
+	// Un exécutable lié dynamiquement nomme son chargeur
+	details.Linking = "static"
+	if details.Interpreter != "" {
+		details.Linking = "dynamic"
+	}
+	details.Stripped = f.Section(".symtab") == nil
+	details.BuildID = elfBuildID(f)
+}
+
+// elfBuildID cherche la note NT_GNU_BUILD_ID dans sa section, à défaut dans
+// les segments PT_NOTE (binaires dont les sections ont été retirées)
+func elfBuildID(f *elf.File) string {
+	if section := f.Section(".note.gnu.build-id"); section != nil {
+		data, err := io.ReadAll(io.LimitReader(section.Open(), maxELFNoteSize))
+		if err == nil {
+			return findGNUBuildID(f, data, section.Addralign)
+		}
+	}
+
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_NOTE {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(prog.Open(), maxELFNoteSize))
+		if err != nil {
+			continue
+		}
+		if id := findGNUBuildID(f, data, prog.Align); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// findGNUBuildID parcourt des notes ELF (en-tête namesz, descsz, type, puis
+// nom et contenu alignés) et retourne l'identifiant de build en hexadécimal
+func findGNUBuildID(f *elf.File, data []byte, align uint64) string {
+	if align != 8 {
+		align = 4
+	}
+	pad := func(n uint64) uint64 { return (n + align - 1) &^ (align - 1) }
+
+	for uint64(len(data)) >= 12 {
+		nameSize := uint64(f.ByteOrder.Uint32(data[0:4]))
+		descSize := uint64(f.ByteOrder.Uint32(data[4:8]))
+		noteType := f.ByteOrder.Uint32(data[8:12])
+
+		nameEnd := 12 + pad(nameSize)
+		descEnd := nameEnd + pad(descSize)
+		if nameEnd > uint64(len(data)) || nameEnd+descSize > uint64(len(data)) {
+			return ""
+		}
+
+		if noteType == elfNoteGNUBuildID && string(data[12:12+nameSize]) == "GNU\x00" {
+			return hex.EncodeToString(data[nameEnd : nameEnd+descSize])
+		}
+		if descEnd >= uint64(len(data)) {
+			return ""
+		}
+		data = data[descEnd:]
+	}
+	return ""
+}
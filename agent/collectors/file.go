@@ -0,0 +1,414 @@
+package collectors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+const (
+	// maxHashedFileSize borne la taille des fichiers hachés (au-delà : taille + mtime)
+	maxHashedFileSize = 64 * 1024 * 1024
+
+	// maxPendingPaths borne le nombre de chemins modifiés en attente entre deux collectes
+	maxPendingPaths = 10000
+)
+
+// sensitiveFiles sont les fichiers dont toute modification est critique
+var sensitiveFiles = map[string]bool{
+	"/etc/passwd":            true,
+	"/etc/shadow":            true,
+	"/etc/group":             true,
+	"/etc/gshadow":           true,
+	"/etc/sudoers":           true,
+	"/etc/ssh/sshd_config":   true,
+	"/etc/crontab":           true,
+	"/etc/ld.so.preload":     true,
+	"/etc/pam.d/common-auth": true,
+}
+
+// binaryDirs contiennent des exécutables système
+var binaryDirs = []string{"/bin/", "/sbin/", "/usr/bin/", "/usr/sbin/", "/usr/local/bin/", "/usr/local/sbin/"}
+
+// fileState est l'état de référence d'un fichier surveillé
+type fileState struct {
+	hash    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+	uid     int
+	gid     int
+}
+
+// FileCollector surveille l'intégrité des fichiers via inotify.
+// Les notifications marquent les chemins modifiés ; Collect compare ensuite
+// leur état courant à la baseline SHA-256, ce qui regroupe les rafales
+// d'écritures d'un même cycle en un seul événement.
+type FileCollector struct {
+	logger   *utils.Logger
+	agentID  string
+	hostname string
+	options  FileOptions
+	watcher  *fsnotify.Watcher
+
+	mu       sync.Mutex
+	baseline map[string]*fileState
+	pending  map[string]bool
+	dropped  int
+}
+
+// NewFileCollector crée un collecteur d'intégrité de fichiers sur les chemins
+// des options et calcule la baseline initiale. Les chemins exclus ne sont ni
+// surveillés ni hachés.
+func NewFileCollector(logger *utils.Logger, agentID, hostname string, options FileOptions) (*FileCollector, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create inotify watcher: %w", err)
+	}
+
+	fc := &FileCollector{
+		logger:   logger,
+		agentID:  agentID,
+		hostname: hostname,
+		options:  options,
+		watcher:  watcher,
+		baseline: make(map[string]*fileState),
+		pending:  make(map[string]bool),
+	}
+
+	for _, root := range options.Paths {
+		if _, err := os.Stat(root); err != nil {
+			logger.Error("File integrity: skipping %s: %v", root, err)
+			continue
+		}
+		fc.watchTree(root, false)
+	}
+
+	logger.Info("File integrity baseline computed: %d files under %v", len(fc.baseline), options.Paths)
+
+	go fc.watch()
+	return fc, nil
+}
+
+// Collect retourne les changements détectés depuis la dernière collecte
+func (fc *FileCollector) Collect() ([]*models.Event, error) {
+	fc.logger.Debug("Starting file integrity collection...")
+
+	fc.mu.Lock()
+	pending := fc.pending
+	fc.pending = make(map[string]bool)
+	dropped := fc.dropped
+	fc.dropped = 0
+	fc.mu.Unlock()
+
+	if dropped > 0 {
+		fc.logger.Error("File integrity: %d notifications dropped, pending queue full", dropped)
+	}
+
+	paths := make([]string, 0, len(pending))
+	for path := range pending {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var events []*models.Event
+	for _, path := range paths {
+		events = append(events, fc.diff(path)...)
+	}
+
+	fc.logger.Info("Collected %d file events", len(events))
+	return events, nil
+}
+
+// Close arrête la surveillance inotify
+func (fc *FileCollector) Close() error {
+	return fc.watcher.Close()
+}
+
+// watch consomme les notifications inotify et marque les chemins modifiés
+func (fc *FileCollector) watch() {
+	for {
+		select {
+		case ev, ok := <-fc.watcher.Events:
+			if !ok {
+				return
+			}
+			if fc.options.excluded(ev.Name) {
+				continue
+			}
+
+			// Un nouveau répertoire doit être surveillé à son tour
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Lstat(ev.Name); err == nil && info.IsDir() {
+					fc.watchTree(ev.Name, true)
+					continue
+				}
+			}
+
+			fc.markPending(ev.Name)
+
+		case err, ok := <-fc.watcher.Errors:
+			if !ok {
+				return
+			}
+			fc.logger.Error("File integrity watcher error: %v", err)
+		}
+	}
+}
+
+// watchTree ajoute une surveillance sur root et ses sous-répertoires.
+// Au démarrage les fichiers rejoignent la baseline ; pour un répertoire
+// apparu ensuite, ils sont marqués comme créés.
+func (fc *FileCollector) watchTree(root string, created bool) {
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			fc.logger.Debug("File integrity: cannot access %s: %v", path, err)
+			return nil
+		}
+
+		if path != root && fc.options.excluded(path) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() {
+			if err := fc.watcher.Add(path); err != nil {
+				fc.logger.Error("File integrity: failed to watch %s: %v", path, err)
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		if created {
+			fc.markPending(path)
+			return nil
+		}
+
+		if state, err := statFile(path); err == nil {
+			fc.mu.Lock()
+			fc.baseline[path] = state
+			fc.mu.Unlock()
+		}
+		return nil
+	})
+}
+
+// markPending enregistre un chemin à réévaluer lors de la prochaine collecte
+func (fc *FileCollector) markPending(path string) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if len(fc.pending) >= maxPendingPaths && !fc.pending[path] {
+		fc.dropped++
+		return
+	}
+	fc.pending[path] = true
+}
+
+// diff compare l'état courant d'un fichier à sa baseline et met celle-ci à jour
+func (fc *FileCollector) diff(path string) []*models.Event {
+	fc.mu.Lock()
+	old := fc.baseline[path]
+	fc.mu.Unlock()
+
+	current, err := statFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		fc.logger.Debug("File integrity: cannot read %s: %v", path, err)
+		return nil
+	}
+	if current == nil && old == nil {
+		return nil
+	}
+
+	fc.mu.Lock()
+	if current == nil {
+		delete(fc.baseline, path)
+	} else {
+		fc.baseline[path] = current
+	}
+	fc.mu.Unlock()
+
+	var events []*models.Event
+
+	switch {
+	case old == nil:
+		events = append(events, fc.newEvent(path, "create", nil, current))
+	case current == nil:
+		events = append(events, fc.newEvent(path, "delete", old, nil))
+	default:
+		if old.hash != current.hash || (old.hash == "" && (old.size != current.size || !old.modTime.Equal(current.modTime))) {
+			events = append(events, fc.newEvent(path, "modify", old, current))
+		}
+		if old.mode != current.mode {
+			events = append(events, fc.newEvent(path, "chmod", old, current))
+		}
+		if old.uid != current.uid || old.gid != current.gid {
+			events = append(events, fc.newEvent(path, "chown", old, current))
+		}
+	}
+
+	return events
+}
+
+// newEvent construit l'événement pour une action sur un fichier
+func (fc *FileCollector) newEvent(path, action string, old, current *fileState) *models.Event {
+	fileEvent := models.FileEvent{
+		Path:      path,
+		Action:    action,
+		Sensitive: isSensitivePath(path),
+	}
+
+	if old != nil {
+		fileEvent.OldHash = old.hash
+		fileEvent.OldMode = old.mode.String()
+		fileEvent.OldOwner = lookupUser(old.uid)
+		fileEvent.OldGroup = lookupGroup(old.gid)
+	}
+
+	var username string
+	if current != nil {
+		fileEvent.NewHash = current.hash
+		fileEvent.Size = current.size
+		fileEvent.Mode = current.mode.String()
+		fileEvent.Owner = lookupUser(current.uid)
+		fileEvent.Group = lookupGroup(current.gid)
+		username = fileEvent.Owner
+	}
+
+	// Ne garder les anciennes valeurs que si elles ont changé
+	if fileEvent.OldMode == fileEvent.Mode {
+		fileEvent.OldMode = ""
+	}
+	if fileEvent.OldOwner == fileEvent.Owner && fileEvent.OldGroup == fileEvent.Group {
+		fileEvent.OldOwner, fileEvent.OldGroup = "", ""
+	}
+
+	return &models.Event{
+		Timestamp: time.Now(),
+		AgentID:   fc.agentID,
+		Hostname:  fc.hostname,
+		EventType: models.EventTypeFile,
+		Severity:  fc.determineSeverity(fileEvent),
+		Username:  username,
+		RawData: map[string]interface{}{
+			"file": fileEvent,
+		},
+		Tags: fc.generateTags(fileEvent),
+	}
+}
+
+// determineSeverity détermine la sévérité selon le fichier et l'action
+func (fc *FileCollector) determineSeverity(fe models.FileEvent) models.Severity {
+	if fe.Sensitive {
+		if fe.Action == "create" {
+			return models.SeverityHigh
+		}
+		return models.SeverityCritical
+	}
+
+	// Binaire système remplacé ou supprimé
+	if isBinaryPath(fe.Path) && fe.Action != "chown" {
+		return models.SeverityMedium
+	}
+
+	return models.SeverityLow
+}
+
+// generateTags génère des tags basés sur le changement
+func (fc *FileCollector) generateTags(fe models.FileEvent) []string {
+	tags := []string{"file_integrity", "file_" + fe.Action}
+
+	if fe.Sensitive {
+		tags = append(tags, "sensitive_file")
+	}
+
+	if isBinaryPath(fe.Path) {
+		tags = append(tags, "system_binary")
+	}
+
+	return tags
+}
+
+// isSensitivePath indique si un chemin contient des identifiants ou privilèges
+func isSensitivePath(path string) bool {
+	if sensitiveFiles[path] {
+		return true
+	}
+	if strings.HasPrefix(path, "/etc/sudoers.d/") {
+		return true
+	}
+	base := filepath.Base(path)
+	return base == "authorized_keys" || base == "authorized_keys2"
+}
+
+// isBinaryPath indique si un chemin se trouve dans un répertoire d'exécutables
+func isBinaryPath(path string) bool {
+	for _, dir := range binaryDirs {
+		if strings.HasPrefix(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// statFile lit l'état d'un fichier régulier ; nil sans erreur si ce n'en est pas un
+func statFile(path string) (*fileState, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil
+	}
+
+	uid, gid := fileOwner(info)
+	state := &fileState{
+		size:    info.Size(),
+		mode:    info.Mode(),
+		modTime: info.ModTime(),
+		uid:     uid,
+		gid:     gid,
+	}
+
+	if info.Size() <= maxHashedFileSize {
+		hash, err := hashFile(path)
+		if err != nil {
+			return nil, err
+		}
+		state.hash = hash
+	}
+
+	return state, nil
+}
+
+// hashFile calcule le SHA-256 d'un fichier
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
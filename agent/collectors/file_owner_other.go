@@ -0,0 +1,20 @@
+//go:build !unix
+
+package collectors
+
+import "io/fs"
+
+// fileOwner n'est pas disponible sur cette plateforme
+func fileOwner(info fs.FileInfo) (int, int) {
+	return -1, -1
+}
+
+// lookupUser n'est pas disponible sur cette plateforme
+func lookupUser(uid int) string {
+	return ""
+}
+
+// lookupGroup n'est pas disponible sur cette plateforme
+func lookupGroup(gid int) string {
+	return ""
+}
@@ -0,0 +1,40 @@
+//go:build unix
+
+package collectors
+
+import (
+	"io/fs"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// fileOwner retourne l'uid et le gid propriétaires d'un fichier
+func fileOwner(info fs.FileInfo) (int, int) {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(stat.Uid), int(stat.Gid)
+	}
+	return -1, -1
+}
+
+// lookupUser résout un uid en nom d'utilisateur, ou le retourne tel quel
+func lookupUser(uid int) string {
+	if uid < 0 {
+		return ""
+	}
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(uid)
+}
+
+// lookupGroup résout un gid en nom de groupe, ou le retourne tel quel
+func lookupGroup(gid int) string {
+	if gid < 0 {
+		return ""
+	}
+	if g, err := user.LookupGroupId(strconv.Itoa(gid)); err == nil {
+		return g.Name
+	}
+	return strconv.Itoa(gid)
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package collectors
+
+import "io/fs"
+
+// fileInode n'est pas disponible sur cette plateforme : la rotation n'est
+// détectée que par troncature
+func fileInode(info fs.FileInfo) uint64 {
+	return 0
+}
+
+// fileDevice n'est pas disponible sur cette plateforme
+func fileDevice(info fs.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,25 @@
+//go:build unix
+
+package collectors
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileInode retourne l'inode d'un fichier, 0 s'il est indisponible
+func fileInode(info fs.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
+
+// fileDevice retourne le périphérique qui porte un fichier : un numéro
+// d'inode n'est unique que sur un même système de fichiers
+func fileDevice(info fs.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Dev)
+	}
+	return 0
+}
@@ -0,0 +1,370 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+const (
+	// maxPendingJournalEntries borne les entrées lues en attente de collecte ;
+	// au-delà, journalctl est ralenti plutôt que des entrées perdues
+	maxPendingJournalEntries = 10000
+
+	// journalRestartDelay espace les relances de journalctl
+	journalRestartDelay = 5 * time.Second
+)
+
+// journalEntry est une entrée du journal telle que produite par journalctl -o json
+type journalEntry map[string]interface{}
+
+// field retourne la valeur d'un champ. journalctl représente un champ binaire
+// par un tableau d'octets et un champ répété par un tableau de valeurs : seule
+// la première est retenue.
+func (e journalEntry) field(name string) string {
+	switch value := e[name].(type) {
+	case string:
+		return value
+	case []interface{}:
+		if len(value) == 0 {
+			return ""
+		}
+		if first, ok := value[0].(string); ok {
+			return first
+		}
+		data := make([]byte, 0, len(value))
+		for _, b := range value {
+			if n, ok := b.(float64); ok {
+				data = append(data, byte(n))
+			}
+		}
+		return string(data)
+	default:
+		return ""
+	}
+}
+
+// JournalCollector lit le journal systemd en suivant la sortie de
+// journalctl -o json --follow. Les entrées sont lues en continu et remises à
+// chaque collecte ; le curseur de la dernière entrée remise est persisté pour
+// reprendre après un redémarrage. Les filtres s'appliquent aux événements
+// émis, le curseur avance sur toutes les entrées.
+type JournalCollector struct {
+	logger   *utils.Logger
+	agentID  string
+	hostname string
+
+	options  JournalOptions
+	priority int
+
+	mu   sync.Mutex
+	next *JournalOptions
+
+	entries   chan journalEntry
+	collected string // curseur de la dernière entrée remise par Collect
+	saved     string // curseur enregistré dans options.CursorFile
+	users     map[int]string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewJournalCollector crée un collecteur du journal et lance journalctl à
+// partir du curseur enregistré, ou à la fin du journal s'il n'y en a pas
+func NewJournalCollector(logger *utils.Logger, agentID, hostname string, options JournalOptions) (*JournalCollector, error) {
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		return nil, fmt.Errorf("journalctl not found: %w", err)
+	}
+
+	jc := &JournalCollector{
+		logger:   logger,
+		agentID:  agentID,
+		hostname: hostname,
+		entries:  make(chan journalEntry, maxPendingJournalEntries),
+		users:    make(map[int]string),
+		done:     make(chan struct{}),
+	}
+	jc.apply(options)
+
+	if options.CursorFile != "" {
+		data, err := os.ReadFile(options.CursorFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Error("Failed to read journal cursor %s, starting from end of journal: %v", options.CursorFile, err)
+		}
+		jc.saved = strings.TrimSpace(string(data))
+		jc.collected = jc.saved
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	jc.cancel = cancel
+	go jc.run(ctx, jc.saved)
+
+	return jc, nil
+}
+
+// Configure change les filtres des entrées remises ; un nouveau cursor_file
+// reçoit le curseur courant
+func (jc *JournalCollector) Configure(options JournalOptions) {
+	jc.mu.Lock()
+	jc.next = &options
+	jc.mu.Unlock()
+}
+
+// apply installe les réglages ; ils ont été validés avec la configuration
+func (jc *JournalCollector) apply(options JournalOptions) {
+	jc.options = options
+	jc.priority, _ = parsePriority(options.Priority)
+}
+
+// Collect remet les entrées lues depuis la collecte précédente
+func (jc *JournalCollector) Collect() ([]*models.Event, error) {
+	jc.logger.Debug("Starting journal collection...")
+
+	jc.mu.Lock()
+	if jc.next != nil {
+		if jc.next.CursorFile != jc.options.CursorFile {
+			jc.saved = ""
+		}
+		jc.apply(*jc.next)
+		jc.next = nil
+	}
+	jc.mu.Unlock()
+
+	// Curseur du cycle précédent, dont les événements sont envoyés
+	if err := jc.saveCursor(); err != nil {
+		jc.logger.Error("Failed to save journal cursor: %v", err)
+	}
+
+	var events []*models.Event
+	for n := len(jc.entries); n > 0; n-- {
+		entry := <-jc.entries
+		if cursor := entry.field("__CURSOR"); cursor != "" {
+			jc.collected = cursor
+		}
+		if event := jc.newEvent(entry); event != nil {
+			events = append(events, event)
+		}
+	}
+
+	jc.logger.Info("Collected %d journal events", len(events))
+	return events, nil
+}
+
+// Close arrête journalctl et enregistre le curseur
+func (jc *JournalCollector) Close() error {
+	jc.cancel()
+	<-jc.done
+	return jc.saveCursor()
+}
+
+// saveCursor enregistre le curseur de la dernière entrée remise s'il a changé
+func (jc *JournalCollector) saveCursor() error {
+	if jc.options.CursorFile == "" || jc.collected == jc.saved {
+		return nil
+	}
+	if err := writeStateFile(jc.options.CursorFile, []byte(jc.collected+"\n")); err != nil {
+		return err
+	}
+	jc.saved = jc.collected
+	return nil
+}
+
+// run exécute journalctl et le relance s'il s'arrête, à partir de la
+// dernière entrée lue
+func (jc *JournalCollector) run(ctx context.Context, cursor string) {
+	defer close(jc.done)
+
+	for {
+		last, received, err := jc.follow(ctx, cursor)
+		if ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case received > 0:
+			cursor = last
+			jc.logger.Error("journalctl stopped, restarting in %s: %v", journalRestartDelay, err)
+		case cursor != "":
+			// Curseur refusé (journal purgé ou d'une autre machine)
+			jc.logger.Error("journalctl failed from saved cursor, restarting from end of journal in %s: %v", journalRestartDelay, err)
+			cursor = ""
+		default:
+			jc.logger.Error("journalctl stopped, restarting in %s: %v", journalRestartDelay, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(journalRestartDelay):
+		}
+	}
+}
+
+// follow lit les entrées de journalctl jusqu'à son arrêt et retourne le
+// curseur de la dernière entrée lue et le nombre d'entrées lues
+func (jc *JournalCollector) follow(ctx context.Context, cursor string) (string, int, error) {
+	args := []string{"--output=json", "--follow", "--no-pager"}
+	if cursor != "" {
+		args = append(args, "--after-cursor="+cursor)
+	} else {
+		args = append(args, "--lines=0")
+	}
+
+	cmd := exec.CommandContext(ctx, "journalctl", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", 0, err
+	}
+
+	var last string
+	received := 0
+	reader := bufio.NewReaderSize(stdout, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var entry journalEntry
+			if jsonErr := json.Unmarshal(line, &entry); jsonErr != nil {
+				jc.logger.Debug("Skipping invalid journal entry: %v", jsonErr)
+			} else {
+				select {
+				case jc.entries <- entry:
+				case <-ctx.Done():
+				}
+				last = entry.field("__CURSOR")
+				received++
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				cmd.Process.Kill()
+			}
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	err = cmd.Wait()
+	if err == nil {
+		err = fmt.Errorf("unexpected end of output")
+	}
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		err = fmt.Errorf("%w: %s", err, msg)
+	}
+	return last, received, err
+}
+
+// newEvent construit l'événement d'une entrée ; nil si elle est filtrée
+func (jc *JournalCollector) newEvent(entry journalEntry) *models.Event {
+	journalEvent := models.JournalEvent{
+		Message:     entry.field("MESSAGE"),
+		Priority:    6, // info, priorité par défaut de journald
+		Identifier:  entry.field("SYSLOG_IDENTIFIER"),
+		Unit:        entry.field("_SYSTEMD_UNIT"),
+		PID:         atoi(entry.field("_PID")),
+		Comm:        entry.field("_COMM"),
+		Executable:  entry.field("_EXE"),
+		CommandLine: entry.field("_CMDLINE"),
+		Transport:   entry.field("_TRANSPORT"),
+		BootID:      entry.field("_BOOT_ID"),
+		Cursor:      entry.field("__CURSOR"),
+	}
+	if priority, err := strconv.Atoi(entry.field("PRIORITY")); err == nil {
+		journalEvent.Priority = priority
+	}
+
+	if journalEvent.Priority > jc.priority ||
+		!jc.options.Units.Match(journalEvent.Unit) ||
+		!jc.options.Identifiers.Match(journalEvent.Identifier) {
+		return nil
+	}
+
+	var username string
+	if uid, err := strconv.Atoi(entry.field("_UID")); err == nil {
+		journalEvent.UID = &uid
+		username = jc.lookupUser(uid)
+	}
+
+	timestamp := time.Now()
+	if usec, err := strconv.ParseInt(entry.field("__REALTIME_TIMESTAMP"), 10, 64); err == nil {
+		timestamp = time.UnixMicro(usec)
+	}
+
+	processName := journalEvent.Comm
+	if processName == "" {
+		processName = journalEvent.Identifier
+	}
+
+	return &models.Event{
+		Timestamp:   timestamp,
+		AgentID:     jc.agentID,
+		Hostname:    jc.hostname,
+		EventType:   models.EventTypeJournal,
+		Severity:    journalSeverity(journalEvent.Priority),
+		ProcessName: processName,
+		ProcessPID:  journalEvent.PID,
+		Username:    username,
+		RawData: map[string]interface{}{
+			"journal": journalEvent,
+		},
+		Tags: jc.generateTags(journalEvent),
+	}
+}
+
+// lookupUser résout un uid en mettant le résultat en cache
+func (jc *JournalCollector) lookupUser(uid int) string {
+	if name, ok := jc.users[uid]; ok {
+		return name
+	}
+	name := lookupUser(uid)
+	jc.users[uid] = name
+	return name
+}
+
+// generateTags génère des tags basés sur l'entrée
+func (jc *JournalCollector) generateTags(je models.JournalEvent) []string {
+	tags := []string{"journal"}
+
+	if je.Priority >= 0 && je.Priority < len(journalPriorities) {
+		tags = append(tags, "priority_"+journalPriorities[je.Priority])
+	}
+
+	if je.Transport == "kernel" {
+		tags = append(tags, "kernel")
+	}
+
+	return tags
+}
+
+// journalSeverity traduit une priorité syslog en sévérité
+func journalSeverity(priority int) models.Severity {
+	switch {
+	case priority <= 2: // emerg, alert, crit
+		return models.SeverityCritical
+	case priority == 3: // err
+		return models.SeverityHigh
+	case priority == 4: // warning
+		return models.SeverityMedium
+	default:
+		return models.SeverityLow
+	}
+}
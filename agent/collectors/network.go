@@ -2,106 +2,322 @@ package collectors
 
 import (
 	"fmt"
+	"sort"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/luigi/xdr-platform/agent/models"
 	"github.com/luigi/xdr-platform/agent/utils"
 	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
 )
 
-// NetworkCollector collecte les informations réseau
+// Actions émises par le collecteur réseau
+const (
+	ConnectionActionOpened = "opened"
+	ConnectionActionClosed = "closed"
+)
+
+// trackedConnection est l'état conservé entre deux cycles pour une connexion ouverte
+type trackedConnection struct {
+	info     models.NetworkEvent
+	lastSeen time.Time
+}
+
+// NetworkCollector suit les connexions réseau par 5-tuple entre deux cycles
+// et n'émet que leurs ouvertures et fermetures, ainsi qu'un inventaire des
+// ports en écoute lorsqu'un nouveau listener apparaît. Toutes les connexions
+// sont suivies ; les filtres ne s'appliquent qu'aux événements émis, pour
+// qu'un changement de réglages ne produise pas de fausses fermetures.
 type NetworkCollector struct {
 	logger   *utils.Logger
 	agentID  string
 	hostname string
+
+	options        NetworkOptions
+	sensitivePorts map[int]bool
+	excludedPorts  map[int]bool
+
+	mu   sync.Mutex
+	next *NetworkOptions // options en attente, appliquées à la prochaine collecte
+
+	connections map[string]*trackedConnection // nil avant le premier cycle
+	listeners   map[string]models.ListeningPort
 }
 
 // NewNetworkCollector crée un nouveau collecteur réseau
-func NewNetworkCollector(logger *utils.Logger, agentID, hostname string) *NetworkCollector {
-	return &NetworkCollector{
+func NewNetworkCollector(logger *utils.Logger, agentID, hostname string, options NetworkOptions) *NetworkCollector {
+	nc := &NetworkCollector{
 		logger:   logger,
 		agentID:  agentID,
 		hostname: hostname,
 	}
+	nc.apply(options)
+	return nc
+}
+
+// Configure remplace les réglages à partir de la prochaine collecte
+func (nc *NetworkCollector) Configure(options NetworkOptions) {
+	nc.mu.Lock()
+	nc.next = &options
+	nc.mu.Unlock()
+}
+
+// apply installe les réglages et précalcule les ensembles de ports
+func (nc *NetworkCollector) apply(options NetworkOptions) {
+	nc.options = options
+	nc.sensitivePorts = make(map[int]bool, len(options.SensitivePorts))
+	for _, port := range options.SensitivePorts {
+		nc.sensitivePorts[port] = true
+	}
+	nc.excludedPorts = make(map[int]bool, len(options.ExcludePorts))
+	for _, port := range options.ExcludePorts {
+		nc.excludedPorts[port] = true
+	}
+}
+
+// reported indique si une connexion ou un listener donne lieu à un événement
+func (nc *NetworkCollector) reported(processName string, ports ...int) bool {
+	for _, port := range ports {
+		if nc.excludedPorts[port] {
+			return false
+		}
+	}
+	return nc.options.Processes.Match(processName)
 }
 
-// Collect collecte les connexions réseau actives
+// Collect émet les connexions ouvertes et fermées depuis le cycle précédent
 func (nc *NetworkCollector) Collect() ([]*models.Event, error) {
 	nc.logger.Debug("Starting network collection...")
 
-	connections, err := net.Connections("all")
+	nc.mu.Lock()
+	if nc.next != nil {
+		nc.apply(*nc.next)
+		nc.next = nil
+	}
+	nc.mu.Unlock()
+
+	connections, err := net.Connections("inet")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get network connections: %w", err)
 	}
 
+	now := time.Now()
+	firstCycle := nc.connections == nil
+	owners := make(map[int32]processOwner)
+
+	currentConns := make(map[string]*trackedConnection)
+	currentListeners := make(map[string]models.ListeningPort)
 	var events []*models.Event
+	var opened, closed int
 
 	for _, conn := range connections {
-		// Ignorer les connexions sans IP (listening sockets, etc.)
+		// Ignorer les connexions sans IP locale
 		if conn.Laddr.IP == "" {
 			continue
 		}
 
-		// Convertir le type de protocole en string
-		protocol := "unknown"
-		switch conn.Type {
-		case 1:
-			protocol = "tcp"
-		case 2:
-			protocol = "udp"
-		case 3:
-			protocol = "tcp6"
-		case 4:
-			protocol = "udp6"
+		protocol := connectionProtocol(conn)
+		owner := nc.resolveOwner(conn.Pid, owners)
+
+		// Socket en écoute : TCP LISTEN ou UDP sans pair distant
+		if conn.Status == "LISTEN" || (conn.Raddr.IP == "" && conn.Type == syscall.SOCK_DGRAM) {
+			listener := models.ListeningPort{
+				Protocol:       protocol,
+				IP:             conn.Laddr.IP,
+				Port:           int(conn.Laddr.Port),
+				PID:            int(conn.Pid),
+				ProcessName:    owner.name,
+				ExecutablePath: owner.exe,
+			}
+			currentListeners[fmt.Sprintf("%s|%s:%d", protocol, listener.IP, listener.Port)] = listener
+			continue
 		}
 
-		networkEvent := models.NetworkEvent{
-			Protocol:   protocol,
-			SourceIP:   conn.Laddr.IP,
-			SourcePort: int(conn.Laddr.Port),
-			DestIP:     conn.Raddr.IP,
-			DestPort:   int(conn.Raddr.Port),
-			State:      conn.Status,
+		if conn.Raddr.IP == "" {
+			continue
 		}
 
-		event := &models.Event{
-			Timestamp:     time.Now(),
-			AgentID:       nc.agentID,
-			Hostname:      nc.hostname,
-			EventType:     models.EventTypeNetwork,
-			Severity:      nc.determineSeverity(networkEvent),
-			SourceIP:      conn.Laddr.IP,
-			DestinationIP: conn.Raddr.IP,
-			ProcessPID:    int(conn.Pid),
-			RawData: map[string]interface{}{
-				"network": networkEvent,
-			},
-			Tags: nc.generateTags(networkEvent),
+		key := fmt.Sprintf("%s|%s:%d|%s:%d", protocol, conn.Laddr.IP, conn.Laddr.Port, conn.Raddr.IP, conn.Raddr.Port)
+
+		if tracked, ok := nc.connections[key]; ok {
+			tracked.info.State = conn.Status
+			tracked.lastSeen = now
+			currentConns[key] = tracked
+			continue
+		}
+
+		info := models.NetworkEvent{
+			Protocol:       protocol,
+			SourceIP:       conn.Laddr.IP,
+			SourcePort:     int(conn.Laddr.Port),
+			DestIP:         conn.Raddr.IP,
+			DestPort:       int(conn.Raddr.Port),
+			State:          conn.Status,
+			PID:            int(conn.Pid),
+			ProcessName:    owner.name,
+			ExecutablePath: owner.exe,
+			FirstSeen:      now,
+			Preexisting:    firstCycle,
+		}
+		currentConns[key] = &trackedConnection{info: info, lastSeen: now}
+
+		if !nc.reported(info.ProcessName, info.SourcePort, info.DestPort) {
+			continue
 		}
+		events = append(events, nc.newConnectionEvent(info, ConnectionActionOpened))
+		opened++
+	}
 
-		events = append(events, event)
+	// Les connexions connues absentes de ce cycle ont été fermées
+	for key, tracked := range nc.connections {
+		if _, open := currentConns[key]; open {
+			continue
+		}
+		info := tracked.info
+		if !nc.reported(info.ProcessName, info.SourcePort, info.DestPort) {
+			continue
+		}
+		info.DurationSeconds = now.Sub(info.FirstSeen).Seconds()
+		events = append(events, nc.newConnectionEvent(info, ConnectionActionClosed))
+		closed++
 	}
 
-	nc.logger.Info("Collected %d network events", len(events))
+	// Inventaire des ports en écoute au premier cycle et à chaque nouveau listener
+	var newListeners []models.ListeningPort
+	reportedListeners := make(map[string]models.ListeningPort, len(currentListeners))
+	for key, listener := range currentListeners {
+		if !nc.reported(listener.ProcessName, listener.Port) {
+			continue
+		}
+		reportedListeners[key] = listener
+		if _, known := nc.listeners[key]; !known {
+			newListeners = append(newListeners, listener)
+		}
+	}
+	if len(newListeners) > 0 {
+		events = append(events, nc.newListenerInventoryEvent(reportedListeners, newListeners, firstCycle))
+	}
+
+	nc.connections = currentConns
+	nc.listeners = currentListeners
+
+	nc.logger.Info("Collected %d network events (%d opened, %d closed, %d new listeners)",
+		len(events), opened, closed, len(newListeners))
 	return events, nil
 }
 
-// determineSeverity détermine la sévérité basée sur la connexion
-func (nc *NetworkCollector) determineSeverity(ne models.NetworkEvent) models.Severity {
-	// Ports sensibles
-	sensitivePorts := map[int]bool{
-		22: true, 23: true, 3389: true, // SSH, Telnet, RDP
-		445: true, 135: true, 139: true, // SMB, RPC
-		1433: true, 3306: true, 5432: true, // SQL Servers
+// processOwner identifie le processus propriétaire d'une socket
+type processOwner struct {
+	name string
+	exe  string
+}
+
+// resolveOwner résout le nom et l'exécutable d'un PID, une seule fois par cycle
+func (nc *NetworkCollector) resolveOwner(pid int32, cache map[int32]processOwner) processOwner {
+	if pid <= 0 {
+		return processOwner{}
+	}
+	if owner, ok := cache[pid]; ok {
+		return owner
 	}
 
-	if sensitivePorts[ne.DestPort] {
-		return models.SeverityMedium
+	var owner processOwner
+	if p, err := process.NewProcess(pid); err == nil {
+		owner.name, _ = p.Name()
+		owner.exe, _ = p.Exe()
 	}
+	cache[pid] = owner
+	return owner
+}
 
-	// Connexions vers Internet (non-privées)
-	if !nc.isPrivateIP(ne.DestIP) {
-		return models.SeverityLow
+// newConnectionEvent crée l'événement d'ouverture ou de fermeture d'une connexion
+func (nc *NetworkCollector) newConnectionEvent(info models.NetworkEvent, action string) *models.Event {
+	info.Action = action
+
+	return &models.Event{
+		Timestamp:     time.Now(),
+		AgentID:       nc.agentID,
+		Hostname:      nc.hostname,
+		EventType:     models.EventTypeNetwork,
+		Severity:      nc.determineSeverity(info),
+		SourceIP:      info.SourceIP,
+		DestinationIP: info.DestIP,
+		ProcessName:   info.ProcessName,
+		ProcessPID:    info.PID,
+		RawData: map[string]interface{}{
+			"network": info,
+		},
+		Tags: nc.generateTags(info),
+	}
+}
+
+// newListenerInventoryEvent crée l'inventaire des ports en écoute
+func (nc *NetworkCollector) newListenerInventoryEvent(all map[string]models.ListeningPort, added []models.ListeningPort, firstCycle bool) *models.Event {
+	listeners := make([]models.ListeningPort, 0, len(all))
+	for _, listener := range all {
+		listeners = append(listeners, listener)
+	}
+	sortListeners(listeners)
+	sortListeners(added)
+
+	// Un listener apparu après le démarrage mérite l'attention d'un analyste
+	severity := models.SeverityMedium
+	if firstCycle {
+		severity = models.SeverityLow
+	}
+
+	return &models.Event{
+		Timestamp: time.Now(),
+		AgentID:   nc.agentID,
+		Hostname:  nc.hostname,
+		EventType: models.EventTypeNetwork,
+		Severity:  severity,
+		RawData: map[string]interface{}{
+			"listeners": map[string]interface{}{
+				"new": added,
+				"all": listeners,
+			},
+		},
+		Tags: []string{"network_monitoring", "listener_inventory"},
+	}
+}
+
+// sortListeners trie les listeners par protocole puis port
+func sortListeners(listeners []models.ListeningPort) {
+	sort.Slice(listeners, func(i, j int) bool {
+		if listeners[i].Protocol != listeners[j].Protocol {
+			return listeners[i].Protocol < listeners[j].Protocol
+		}
+		if listeners[i].Port != listeners[j].Port {
+			return listeners[i].Port < listeners[j].Port
+		}
+		return listeners[i].IP < listeners[j].IP
+	})
+}
+
+// connectionProtocol déduit le protocole du type et de la famille de socket
+func connectionProtocol(conn net.ConnectionStat) string {
+	protocol := "unknown"
+	switch conn.Type {
+	case syscall.SOCK_STREAM:
+		protocol = "tcp"
+	case syscall.SOCK_DGRAM:
+		protocol = "udp"
+	}
+
+	if conn.Family == syscall.AF_INET6 {
+		protocol += "6"
+	}
+	return protocol
+}
+
+// determineSeverity détermine la sévérité basée sur la connexion
+func (nc *NetworkCollector) determineSeverity(ne models.NetworkEvent) models.Severity {
+	// Ports sensibles
+	if ne.Action == ConnectionActionOpened && nc.sensitivePorts[ne.DestPort] {
+		return models.SeverityMedium
 	}
 
 	return models.SeverityLow
@@ -133,7 +349,7 @@ func (nc *NetworkCollector) isPrivateIP(ip string) bool {
 
 // generateTags génère des tags basés sur la connexion
 func (nc *NetworkCollector) generateTags(ne models.NetworkEvent) []string {
-	tags := []string{"network_monitoring"}
+	tags := []string{"network_monitoring", "connection_" + ne.Action}
 
 	// Ajouter des tags basés sur le protocole
 	tags = append(tags, "protocol_"+ne.Protocol)
@@ -148,5 +364,9 @@ func (nc *NetworkCollector) generateTags(ne models.NetworkEvent) []string {
 		tags = append(tags, "external_connection")
 	}
 
+	if ne.Preexisting {
+		tags = append(tags, "preexisting")
+	}
+
 	return tags
 }
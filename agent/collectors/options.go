@@ -0,0 +1,463 @@
+package collectors
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/models"
+)
+
+// Filter sélectionne des noms par motifs glob (syntaxe de path.Match) : un
+// nom est retenu s'il correspond à un motif Include (ou si Include est vide)
+// et à aucun motif Exclude.
+type Filter struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// Match indique si un nom est retenu par le filtre
+func (f Filter) Match(name string) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, name) {
+		return false
+	}
+	return !matchAny(f.Exclude, name)
+}
+
+// Validate vérifie la syntaxe des motifs
+func (f Filter) Validate() error {
+	if err := validatePatterns(f.Include); err != nil {
+		return fmt.Errorf("include: %w", err)
+	}
+	if err := validatePatterns(f.Exclude); err != nil {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	return nil
+}
+
+// Thresholds fixe les pourcentages au-delà desquels une mesure passe en
+// sévérité medium puis high
+type Thresholds struct {
+	Medium float64 `yaml:"medium"`
+	High   float64 `yaml:"high"`
+}
+
+// severity retourne la sévérité d'une mesure
+func (t Thresholds) severity(value float64) models.Severity {
+	switch {
+	case value > t.High:
+		return models.SeverityHigh
+	case value > t.Medium:
+		return models.SeverityMedium
+	default:
+		return models.SeverityLow
+	}
+}
+
+// Validate vérifie que les seuils sont des pourcentages ordonnés
+func (t Thresholds) Validate() error {
+	if err := validatePercent("medium", t.Medium); err != nil {
+		return err
+	}
+	if err := validatePercent("high", t.High); err != nil {
+		return err
+	}
+	if t.Medium > t.High {
+		return fmt.Errorf("medium (%g) cannot exceed high (%g)", t.Medium, t.High)
+	}
+	return nil
+}
+
+// SystemOptions règle le collecteur système
+type SystemOptions struct {
+	CPU         Thresholds `yaml:"cpu"`
+	Memory      Thresholds `yaml:"memory"`
+	Disk        Thresholds `yaml:"disk"`
+	Mountpoints Filter     `yaml:"mountpoints"` // points de montage pris en compte
+}
+
+// DefaultSystemOptions retourne les réglages par défaut du collecteur système
+func DefaultSystemOptions() SystemOptions {
+	return SystemOptions{
+		CPU:    Thresholds{Medium: 60, High: 80},
+		Memory: Thresholds{Medium: 75, High: 90},
+		Disk:   Thresholds{Medium: 80, High: 90},
+	}
+}
+
+// Validate valide les réglages du collecteur système
+func (o SystemOptions) Validate() error {
+	if err := o.CPU.Validate(); err != nil {
+		return fmt.Errorf("cpu: %w", err)
+	}
+	if err := o.Memory.Validate(); err != nil {
+		return fmt.Errorf("memory: %w", err)
+	}
+	if err := o.Disk.Validate(); err != nil {
+		return fmt.Errorf("disk: %w", err)
+	}
+	if err := o.Mountpoints.Validate(); err != nil {
+		return fmt.Errorf("mountpoints.%w", err)
+	}
+	return nil
+}
+
+// NetworkOptions règle le collecteur réseau
+type NetworkOptions struct {
+	SensitivePorts []int  `yaml:"sensitive_ports"` // ports distants dont l'ouverture est en sévérité medium
+	ExcludePorts   []int  `yaml:"exclude_ports"`   // ports locaux ou distants ignorés
+	Processes      Filter `yaml:"processes"`       // noms des processus propriétaires
+}
+
+// DefaultNetworkOptions retourne les réglages par défaut du collecteur réseau
+func DefaultNetworkOptions() NetworkOptions {
+	return NetworkOptions{
+		SensitivePorts: []int{
+			22, 23, 3389, // SSH, Telnet, RDP
+			445, 135, 139, // SMB, RPC
+			1433, 3306, 5432, // SQL Servers
+		},
+	}
+}
+
+// Validate valide les réglages du collecteur réseau
+func (o NetworkOptions) Validate() error {
+	if err := validatePorts(o.SensitivePorts); err != nil {
+		return fmt.Errorf("sensitive_ports: %w", err)
+	}
+	if err := validatePorts(o.ExcludePorts); err != nil {
+		return fmt.Errorf("exclude_ports: %w", err)
+	}
+	if err := o.Processes.Validate(); err != nil {
+		return fmt.Errorf("processes.%w", err)
+	}
+	return nil
+}
+
+// ProcessOptions règle le collecteur de processus
+type ProcessOptions struct {
+	InventoryInterval time.Duration `yaml:"inventory_interval"` // 0 : inventaire uniquement au démarrage
+	Names             Filter        `yaml:"names"`              // noms des processus remontés
+	SuspiciousDirs    []string      `yaml:"suspicious_dirs"`    // répertoires d'où un binaire ne devrait pas s'exécuter
+	CPUHigh           float64       `yaml:"cpu_high"`           // % CPU au-delà duquel un processus est en sévérité high (tag high_cpu)
+	MemoryHigh        float64       `yaml:"memory_high"`        // % mémoire au-delà duquel un processus est en sévérité high (tag high_memory)
+	ConnectionsMedium int           `yaml:"connections_medium"` // connexions au-delà desquelles un processus est en sévérité medium (tag network_active)
+	ExtraHashes       []string      `yaml:"extra_hashes"`       // md5, sha1 : calculés en plus du SHA-256 des exécutables
+	ExecutableCache   int           `yaml:"executable_cache"`   // exécutables dont les hash et métadonnées restent en cache
+	PackageLookup     bool          `yaml:"package_lookup"`     // chercher le paquet dpkg ou rpm propriétaire des exécutables
+}
+
+// DefaultProcessOptions retourne les réglages par défaut du collecteur de processus
+func DefaultProcessOptions() ProcessOptions {
+	return ProcessOptions{
+		InventoryInterval: time.Hour,
+		SuspiciousDirs:    []string{"/tmp/", "/var/tmp/", "/dev/shm/"},
+		CPUHigh:           80,
+		MemoryHigh:        80,
+		ConnectionsMedium: 50,
+		ExecutableCache:   4096,
+		PackageLookup:     true,
+	}
+}
+
+// Validate valide les réglages du collecteur de processus
+func (o ProcessOptions) Validate() error {
+	if o.InventoryInterval < 0 {
+		return fmt.Errorf("inventory_interval cannot be negative")
+	}
+	if err := o.Names.Validate(); err != nil {
+		return fmt.Errorf("names.%w", err)
+	}
+	for _, dir := range o.SuspiciousDirs {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("suspicious_dirs: %q is not an absolute path", dir)
+		}
+	}
+	if err := validatePercent("cpu_high", o.CPUHigh); err != nil {
+		return err
+	}
+	if err := validatePercent("memory_high", o.MemoryHigh); err != nil {
+		return err
+	}
+	if o.ConnectionsMedium < 0 {
+		return fmt.Errorf("connections_medium cannot be negative")
+	}
+	for _, name := range o.ExtraHashes {
+		if name != "md5" && name != "sha1" {
+			return fmt.Errorf("extra_hashes: unknown hash %q, expected md5 or sha1", name)
+		}
+	}
+	if o.ExecutableCache < 1 {
+		return fmt.Errorf("executable_cache must be at least 1")
+	}
+	return nil
+}
+
+// FileOptions règle le collecteur d'intégrité de fichiers
+type FileOptions struct {
+	Paths   []string `yaml:"paths"`   // racines surveillées récursivement
+	Exclude []string `yaml:"exclude"` // motifs glob sur le chemin complet ou le nom
+}
+
+// DefaultFileOptions retourne les réglages par défaut du collecteur de fichiers
+func DefaultFileOptions() FileOptions {
+	return FileOptions{
+		Paths: []string{"/etc", "/usr/bin", "/root/.ssh"},
+	}
+}
+
+// Validate valide les réglages du collecteur de fichiers
+func (o FileOptions) Validate() error {
+	if len(o.Paths) == 0 {
+		return fmt.Errorf("paths cannot be empty")
+	}
+	for _, root := range o.Paths {
+		if !filepath.IsAbs(root) {
+			return fmt.Errorf("paths: %q is not an absolute path", root)
+		}
+	}
+	if err := validatePatterns(o.Exclude); err != nil {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	return nil
+}
+
+// excluded indique si un chemin correspond à un motif d'exclusion
+func (o FileOptions) excluded(p string) bool {
+	return matchAny(o.Exclude, p) || matchAny(o.Exclude, filepath.Base(p))
+}
+
+// AuthOptions règle le collecteur d'authentification
+type AuthOptions struct {
+	Paths            []string      `yaml:"paths"`             // journaux lus (absents : ignorés)
+	OffsetsFile      string        `yaml:"offsets_file"`      // positions de lecture persistées, vide : non persistées
+	FromBeginning    bool          `yaml:"from_beginning"`    // lire un journal jamais lu depuis son début
+	FailureThreshold int           `yaml:"failure_threshold"` // échecs d'une même source passant en sévérité high
+	FailureWindow    time.Duration `yaml:"failure_window"`    // fenêtre de comptage des échecs
+	Users            Filter        `yaml:"users"`             // utilisateurs remontés
+}
+
+// DefaultAuthOptions retourne les réglages par défaut du collecteur d'authentification
+func DefaultAuthOptions() AuthOptions {
+	return AuthOptions{
+		Paths:            []string{"/var/log/auth.log", "/var/log/secure"},
+		OffsetsFile:      "/var/lib/xdr-agent/auth-offsets.json",
+		FailureThreshold: 5,
+		FailureWindow:    5 * time.Minute,
+	}
+}
+
+// Validate valide les réglages du collecteur d'authentification
+func (o AuthOptions) Validate() error {
+	if len(o.Paths) == 0 {
+		return fmt.Errorf("paths cannot be empty")
+	}
+	for _, p := range o.Paths {
+		if !filepath.IsAbs(p) {
+			return fmt.Errorf("paths: %q is not an absolute path", p)
+		}
+	}
+	if o.OffsetsFile != "" && !filepath.IsAbs(o.OffsetsFile) {
+		return fmt.Errorf("offsets_file: %q is not an absolute path", o.OffsetsFile)
+	}
+	if o.FailureThreshold < 1 {
+		return fmt.Errorf("failure_threshold must be at least 1")
+	}
+	if o.FailureWindow <= 0 {
+		return fmt.Errorf("failure_window must be positive")
+	}
+	if err := o.Users.Validate(); err != nil {
+		return fmt.Errorf("users.%w", err)
+	}
+	return nil
+}
+
+// AuditOptions règle le collecteur d'audit
+type AuditOptions struct {
+	Source         string   `yaml:"source"`          // auto (netlink, à défaut log_file), netlink, file
+	LogFile        string   `yaml:"log_file"`        // journal d'auditd, lu à défaut du netlink
+	OffsetsFile    string   `yaml:"offsets_file"`    // position de lecture de log_file, vide : non persistée
+	Rules          []string `yaml:"rules"`           // règles installées au démarrage, en arguments d'auditctl
+	Keys           Filter   `yaml:"keys"`            // clés des règles dont les événements sont remontés
+	SuspiciousDirs []string `yaml:"suspicious_dirs"` // répertoires d'où un binaire ne devrait pas s'exécuter
+}
+
+// DefaultAuditOptions retourne les réglages par défaut du collecteur d'audit
+func DefaultAuditOptions() AuditOptions {
+	return AuditOptions{
+		Source:      "auto",
+		LogFile:     "/var/log/audit/audit.log",
+		OffsetsFile: "/var/lib/xdr-agent/audit-offsets.json",
+		Rules: []string{
+			"-a always,exit -F arch=b64 -S execve,execveat -k xdr_exec",
+			"-a always,exit -F arch=b64 -S connect -F a2!=110 -k xdr_connect", // hors sockets unix
+			"-w /etc/shadow -p rwa -k xdr_file",
+			"-w /etc/passwd -p wa -k xdr_file",
+			"-w /etc/sudoers -p wa -k xdr_file",
+			"-w /etc/sudoers.d/ -p wa -k xdr_file",
+			"-w /etc/ssh/sshd_config -p wa -k xdr_file",
+			"-w /root/.ssh/ -p rwa -k xdr_file",
+		},
+		Keys:           Filter{Include: []string{"xdr_*"}},
+		SuspiciousDirs: []string{"/tmp/", "/var/tmp/", "/dev/shm/"},
+	}
+}
+
+// Validate valide les réglages du collecteur d'audit
+func (o AuditOptions) Validate() error {
+	switch o.Source {
+	case "auto", "netlink", "file":
+	default:
+		return fmt.Errorf("source: unknown source %q, expected auto, netlink or file", o.Source)
+	}
+	if o.Source != "netlink" && !filepath.IsAbs(o.LogFile) {
+		return fmt.Errorf("log_file: %q is not an absolute path", o.LogFile)
+	}
+	if o.OffsetsFile != "" && !filepath.IsAbs(o.OffsetsFile) {
+		return fmt.Errorf("offsets_file: %q is not an absolute path", o.OffsetsFile)
+	}
+	for _, rule := range o.Rules {
+		if err := validateAuditRule(rule); err != nil {
+			return fmt.Errorf("rules: %w", err)
+		}
+	}
+	if err := o.Keys.Validate(); err != nil {
+		return fmt.Errorf("keys.%w", err)
+	}
+	for _, dir := range o.SuspiciousDirs {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("suspicious_dirs: %q is not an absolute path", dir)
+		}
+	}
+	return nil
+}
+
+// PersistenceOptions règle le collecteur des mécanismes de persistance
+type PersistenceOptions struct {
+	SnapshotFile string   `yaml:"snapshot_file"` // inventaire persisté entre deux démarrages, vide : non persisté
+	Mechanisms   Filter   `yaml:"mechanisms"`    // cron, systemd, rc_scripts, shell_profile, authorized_keys
+	Exclude      []string `yaml:"exclude"`       // motifs glob sur le chemin complet ou le nom
+}
+
+// DefaultPersistenceOptions retourne les réglages par défaut du collecteur
+// des mécanismes de persistance
+func DefaultPersistenceOptions() PersistenceOptions {
+	return PersistenceOptions{
+		SnapshotFile: "/var/lib/xdr-agent/persistence-snapshot.json",
+		Exclude:      []string{"*.dpkg-*", "*.rpmnew", "*.rpmsave", "*~"},
+	}
+}
+
+// Validate valide les réglages du collecteur des mécanismes de persistance
+func (o PersistenceOptions) Validate() error {
+	if o.SnapshotFile != "" && !filepath.IsAbs(o.SnapshotFile) {
+		return fmt.Errorf("snapshot_file: %q is not an absolute path", o.SnapshotFile)
+	}
+	if err := o.Mechanisms.Validate(); err != nil {
+		return fmt.Errorf("mechanisms.%w", err)
+	}
+	// Un motif include qui ne retient rien est une faute de frappe
+	for _, pattern := range o.Mechanisms.Include {
+		if !slices.ContainsFunc(persistenceMechanisms, func(name string) bool { return matchAny([]string{pattern}, name) }) {
+			return fmt.Errorf("mechanisms.include: %q matches no mechanism", pattern)
+		}
+	}
+	if err := validatePatterns(o.Exclude); err != nil {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	return nil
+}
+
+// excluded indique si un chemin correspond à un motif d'exclusion
+func (o PersistenceOptions) excluded(p string) bool {
+	return matchAny(o.Exclude, p) || matchAny(o.Exclude, filepath.Base(p))
+}
+
+// journalPriorities sont les noms des priorités syslog, de 0 à 7
+var journalPriorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// JournalOptions règle le collecteur du journal systemd
+type JournalOptions struct {
+	CursorFile  string `yaml:"cursor_file"` // position de lecture persistée, vide : non persistée
+	Priority    string `yaml:"priority"`    // priorité la moins grave remontée : nom (warning) ou valeur (4)
+	Units       Filter `yaml:"units"`       // unités systemd (_SYSTEMD_UNIT)
+	Identifiers Filter `yaml:"identifiers"` // identifiants syslog (SYSLOG_IDENTIFIER)
+}
+
+// DefaultJournalOptions retourne les réglages par défaut du collecteur du journal
+func DefaultJournalOptions() JournalOptions {
+	return JournalOptions{
+		CursorFile: "/var/lib/xdr-agent/journal-cursor",
+		Priority:   "notice",
+	}
+}
+
+// Validate valide les réglages du collecteur du journal
+func (o JournalOptions) Validate() error {
+	if o.CursorFile != "" && !filepath.IsAbs(o.CursorFile) {
+		return fmt.Errorf("cursor_file: %q is not an absolute path", o.CursorFile)
+	}
+	if _, err := parsePriority(o.Priority); err != nil {
+		return fmt.Errorf("priority: %w", err)
+	}
+	if err := o.Units.Validate(); err != nil {
+		return fmt.Errorf("units.%w", err)
+	}
+	if err := o.Identifiers.Validate(); err != nil {
+		return fmt.Errorf("identifiers.%w", err)
+	}
+	return nil
+}
+
+// parsePriority convertit une priorité syslog, par nom ou par valeur
+func parsePriority(priority string) (int, error) {
+	for value, name := range journalPriorities {
+		if priority == name || priority == strconv.Itoa(value) {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown priority %q, expected one of %s or 0-7",
+		priority, strings.Join(journalPriorities, ", "))
+}
+
+// matchAny indique si un nom correspond à l'un des motifs
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePatterns vérifie la syntaxe de motifs glob
+func validatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// validatePercent vérifie qu'un seuil est un pourcentage
+func validatePercent(name string, value float64) error {
+	if value < 0 || value > 100 {
+		return fmt.Errorf("%s must be between 0 and 100, got %g", name, value)
+	}
+	return nil
+}
+
+// validatePorts vérifie des numéros de port
+func validatePorts(ports []int) error {
+	for _, port := range ports {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port %d", port)
+		}
+	}
+	return nil
+}
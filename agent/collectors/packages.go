@@ -0,0 +1,224 @@
+package collectors
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+const (
+	dpkgInfoDir    = "/var/lib/dpkg/info"
+	dpkgStatusFile = "/var/lib/dpkg/status"
+
+	// rpmQueryTimeout borne une requête rpm (base verrouillée par une installation)
+	rpmQueryTimeout = 10 * time.Second
+)
+
+// dpkgIgnoredPrefixes ne contiennent pas d'exécutables : les écarter de
+// l'index en divise la taille par trois
+var dpkgIgnoredPrefixes = []string{"/usr/share/", "/usr/include/"}
+
+// packageOwner est le paquet qui a installé un fichier
+type packageOwner struct {
+	manager string // dpkg, rpm
+	name    string
+	version string
+	md5     string // empreinte du fichier livré par le paquet, vide si inconnue
+}
+
+// packageDatabase retrouve le paquet propriétaire d'un fichier
+type packageDatabase interface {
+	lookup(path string) (packageOwner, bool)
+}
+
+// newPackageDatabase retourne la base de paquets de l'hôte : dpkg, à défaut
+// rpm ; nil s'il n'y en a aucune
+func newPackageDatabase(logger *utils.Logger) packageDatabase {
+	if _, err := os.Stat(dpkgInfoDir); err == nil {
+		return &dpkgDatabase{logger: logger}
+	}
+	if path, err := exec.LookPath("rpm"); err == nil {
+		return &rpmDatabase{binary: path}
+	}
+	return nil
+}
+
+// dpkgDatabase lit directement les fichiers de dpkg : les listes de fichiers
+// des paquets (info/*.list) sont indexées et rechargées quand status change,
+// c'est-à-dire après chaque installation ou suppression
+type dpkgDatabase struct {
+	logger   *utils.Logger
+	loaded   time.Time         // date de modification de status au dernier chargement
+	owners   map[string]string // chemin → liste propriétaire (paquet[:architecture])
+	versions map[string]string // paquet et paquet:architecture → version installée
+}
+
+// lookup retourne le paquet propriétaire d'un fichier
+func (d *dpkgDatabase) lookup(path string) (packageOwner, bool) {
+	d.refresh()
+
+	for _, candidate := range usrMergeAliases(path) {
+		list, ok := d.owners[candidate]
+		if !ok {
+			continue
+		}
+		name, _, _ := strings.Cut(list, ":")
+		version, ok := d.versions[list]
+		if !ok {
+			version = d.versions[name]
+		}
+		return packageOwner{
+			manager: "dpkg",
+			name:    name,
+			version: version,
+			md5:     d.md5sum(list, candidate),
+		}, true
+	}
+	return packageOwner{}, false
+}
+
+// refresh recharge l'index si la base a changé depuis le dernier chargement
+func (d *dpkgDatabase) refresh() {
+	info, err := os.Stat(dpkgStatusFile)
+	if err != nil || info.ModTime().Equal(d.loaded) {
+		return
+	}
+
+	versions, err := readDpkgStatus()
+	if err != nil {
+		d.logger.Error("Failed to read dpkg status: %v", err)
+		return
+	}
+
+	lists, err := filepath.Glob(filepath.Join(dpkgInfoDir, "*.list"))
+	if err != nil {
+		return
+	}
+	owners := make(map[string]string)
+	for _, file := range lists {
+		list := strings.TrimSuffix(filepath.Base(file), ".list")
+		readLinesOf(file, func(line string) {
+			for _, prefix := range dpkgIgnoredPrefixes {
+				if strings.HasPrefix(line, prefix) {
+					return
+				}
+			}
+			owners[line] = list
+		})
+	}
+
+	d.owners, d.versions, d.loaded = owners, versions, info.ModTime()
+	d.logger.Debug("Loaded dpkg database: %d packages, %d files", len(versions), len(owners))
+}
+
+// md5sum retourne l'empreinte d'un fichier dans info/<liste>.md5sums, dont
+// les chemins n'ont pas de « / » initial
+func (d *dpkgDatabase) md5sum(list, path string) string {
+	var sum string
+	readLinesOf(filepath.Join(dpkgInfoDir, list+".md5sums"), func(line string) {
+		hash, file, ok := strings.Cut(line, "  ")
+		if ok && sum == "" && "/"+file == path {
+			sum = hash
+		}
+	})
+	return sum
+}
+
+// readDpkgStatus retourne la version des paquets installés
+func readDpkgStatus() (map[string]string, error) {
+	f, err := os.Open(dpkgStatusFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	versions := make(map[string]string)
+	var name, arch, version, status string
+	flush := func() {
+		if name != "" && strings.HasSuffix(status, " installed") {
+			versions[name] = version
+			versions[name+":"+arch] = version
+		}
+		name, arch, version, status = "", "", "", ""
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Package":
+			name = value
+		case "Architecture":
+			arch = value
+		case "Version":
+			version = value
+		case "Status":
+			status = value
+		}
+	}
+	flush()
+	return versions, scanner.Err()
+}
+
+// rpmDatabase interroge rpm, dont la base n'est pas lisible directement
+type rpmDatabase struct {
+	binary string
+}
+
+// lookup retourne le paquet propriétaire d'un fichier
+func (r *rpmDatabase) lookup(path string) (packageOwner, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), rpmQueryTimeout)
+	defer cancel()
+
+	// rpm -qf échoue pour un fichier qui n'appartient à aucun paquet
+	output, err := exec.CommandContext(ctx, r.binary, "-qf", "--queryformat", "%{NAME}\t%{VERSION}-%{RELEASE}\n", path).Output()
+	if err != nil {
+		return packageOwner{}, false
+	}
+	line, _, _ := strings.Cut(string(output), "\n")
+	name, version, ok := strings.Cut(line, "\t")
+	if !ok {
+		return packageOwner{}, false
+	}
+	return packageOwner{manager: "rpm", name: name, version: version}, true
+}
+
+// usrMergeAliases retourne un chemin et son équivalent de l'autre côté de la
+// fusion de /usr : les paquets peuvent déclarer /bin/ls alors que le
+// processus exécute /usr/bin/ls
+func usrMergeAliases(path string) []string {
+	if strings.HasPrefix(path, "/usr/") {
+		return []string{path, strings.TrimPrefix(path, "/usr")}
+	}
+	return []string{path, "/usr" + path}
+}
+
+// readLinesOf passe chaque ligne d'un fichier à handle ; un fichier illisible
+// n'a aucune ligne
+func readLinesOf(path string, handle func(line string)) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		handle(scanner.Text())
+	}
+}
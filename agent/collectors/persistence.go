@@ -0,0 +1,352 @@
+package collectors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// Mécanismes inventoriés et actions émises par le collecteur de persistance
+const (
+	PersistenceCron           = "cron"
+	PersistenceSystemd        = "systemd"
+	PersistenceRCScripts      = "rc_scripts"
+	PersistenceShellProfile   = "shell_profile"
+	PersistenceAuthorizedKeys = "authorized_keys"
+
+	PersistenceActionAdded   = "added"
+	PersistenceActionChanged = "changed"
+	PersistenceActionRemoved = "removed"
+)
+
+// persistenceMechanisms sont les noms acceptés par le filtre mechanisms
+var persistenceMechanisms = []string{
+	PersistenceCron, PersistenceSystemd, PersistenceRCScripts, PersistenceShellProfile, PersistenceAuthorizedKeys,
+}
+
+// PersistenceCollector inventorie les emplacements où un attaquant s'installe
+// durablement (crontabs, unités systemd, scripts de démarrage, profils shell,
+// clés SSH autorisées) et compare chaque inventaire au précédent : une entrée
+// ajoutée, modifiée ou supprimée donne un événement avec son contenu, son
+// propriétaire et les techniques MITRE ATT&CK correspondantes. L'inventaire
+// est conservé dans snapshot_file pour détecter aussi les changements
+// survenus pendant un arrêt de l'agent.
+type PersistenceCollector struct {
+	logger   *utils.Logger
+	agentID  string
+	hostname string
+	options  PersistenceOptions
+
+	mu   sync.Mutex
+	next *PersistenceOptions
+
+	snapshot *persistenceSnapshot // nil tant que l'inventaire de référence n'est pas établi
+	dirty    bool                 // inventaire modifié depuis son dernier enregistrement
+}
+
+// persistenceSnapshot est un inventaire, tel qu'il est conservé dans snapshot_file
+type persistenceSnapshot struct {
+	Mechanisms []string                     `json:"mechanisms"` // mécanismes inventoriés
+	Entries    map[string]*persistenceEntry `json:"entries"`    // par chemin
+}
+
+// NewPersistenceCollector crée un collecteur de persistance et charge
+// l'inventaire enregistré. Sans inventaire, la première collecte établit la
+// référence sans émettre d'événement.
+func NewPersistenceCollector(logger *utils.Logger, agentID, hostname string, options PersistenceOptions) *PersistenceCollector {
+	return &PersistenceCollector{
+		logger:   logger,
+		agentID:  agentID,
+		hostname: hostname,
+		options:  options,
+		snapshot: loadPersistenceSnapshot(options.SnapshotFile, logger),
+	}
+}
+
+// Configure change les mécanismes inventoriés et les exclusions ; un nouveau
+// snapshot_file reçoit l'inventaire courant
+func (pc *PersistenceCollector) Configure(options PersistenceOptions) {
+	pc.mu.Lock()
+	pc.next = &options
+	pc.mu.Unlock()
+}
+
+// Collect inventorie les mécanismes de persistance et émet les différences
+// avec l'inventaire précédent
+func (pc *PersistenceCollector) Collect() ([]*models.Event, error) {
+	pc.logger.Debug("Starting persistence collection...")
+
+	pc.mu.Lock()
+	if pc.next != nil {
+		if pc.next.SnapshotFile != pc.options.SnapshotFile {
+			pc.dirty = true
+		}
+		pc.options = *pc.next
+		pc.next = nil
+	}
+	pc.mu.Unlock()
+
+	// Inventaire du cycle précédent, dont les événements sont envoyés
+	if pc.dirty {
+		if err := pc.save(); err != nil {
+			pc.logger.Error("Failed to save persistence snapshot: %v", err)
+		}
+	}
+
+	current := pc.scan()
+	if pc.snapshot == nil {
+		pc.snapshot, pc.dirty = current, true
+		pc.logger.Info("Persistence baseline computed: %d entries", len(current.Entries))
+		return nil, nil
+	}
+
+	events := pc.diff(pc.snapshot, current)
+	if len(events) > 0 || len(current.Entries) != len(pc.snapshot.Entries) ||
+		!slices.Equal(current.Mechanisms, pc.snapshot.Mechanisms) {
+		pc.dirty = true
+	}
+	pc.snapshot = current
+
+	pc.logger.Info("Collected %d persistence events", len(events))
+	return events, nil
+}
+
+// Close enregistre l'inventaire s'il a changé
+func (pc *PersistenceCollector) Close() error {
+	if !pc.dirty {
+		return nil
+	}
+	return pc.save()
+}
+
+// scan inventorie les emplacements des mécanismes retenus
+func (pc *PersistenceCollector) scan() *persistenceSnapshot {
+	snapshot := &persistenceSnapshot{Entries: make(map[string]*persistenceEntry)}
+	for _, mechanism := range persistenceMechanisms {
+		if pc.options.Mechanisms.Match(mechanism) {
+			snapshot.Mechanisms = append(snapshot.Mechanisms, mechanism)
+		}
+	}
+
+	homes := listHomeDirs()
+	seen := make(map[string]bool) // chemins réels : /lib peut n'être qu'un lien vers /usr/lib
+
+	for _, location := range persistenceLocations {
+		if !slices.Contains(snapshot.Mechanisms, location.mechanism) {
+			continue
+		}
+		for _, pattern := range location.expand(homes) {
+			matches, _ := filepath.Glob(pattern.path)
+			for _, path := range matches {
+				if pc.options.excluded(path) {
+					continue
+				}
+				resolved := path
+				if dir, err := filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+					resolved = filepath.Join(dir, filepath.Base(path))
+				}
+				if seen[resolved] {
+					continue
+				}
+
+				entry, err := readPersistenceEntry(path)
+				if err != nil {
+					pc.logger.Debug("Persistence: cannot read %s: %v", path, err)
+					continue
+				}
+				if entry == nil {
+					continue
+				}
+				entry.Mechanism, entry.User = location.mechanism, pattern.user
+				snapshot.Entries[path] = entry
+				seen[resolved] = true
+			}
+		}
+	}
+	return snapshot
+}
+
+// diff compare deux inventaires. Les entrées qui entrent dans le périmètre
+// (mécanisme nouvellement inventorié) ou qui en sortent (mécanisme filtré,
+// chemin exclu) sont prises en compte sans être signalées.
+func (pc *PersistenceCollector) diff(old, current *persistenceSnapshot) []*models.Event {
+	paths := make([]string, 0, len(current.Entries))
+	for path := range current.Entries {
+		paths = append(paths, path)
+	}
+	for path := range old.Entries {
+		if _, ok := current.Entries[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var events []*models.Event
+	for _, path := range paths {
+		before, after := old.Entries[path], current.Entries[path]
+		switch {
+		case before == nil:
+			if !slices.Contains(old.Mechanisms, after.Mechanism) {
+				continue
+			}
+			events = append(events, pc.newEvent(path, PersistenceActionAdded, nil, after))
+		case after == nil:
+			if !slices.Contains(current.Mechanisms, before.Mechanism) || pc.options.excluded(path) {
+				continue
+			}
+			events = append(events, pc.newEvent(path, PersistenceActionRemoved, before, nil))
+		case before.changed(after):
+			events = append(events, pc.newEvent(path, PersistenceActionChanged, before, after))
+		}
+	}
+	return events
+}
+
+// newEvent construit l'événement d'un changement. Une entrée supprimée est
+// décrite par son dernier état connu.
+func (pc *PersistenceCollector) newEvent(path, action string, old, current *persistenceEntry) *models.Event {
+	entry := current
+	if entry == nil {
+		entry = old
+	}
+
+	pe := models.PersistenceEvent{
+		Action:     action,
+		Mechanism:  entry.Mechanism,
+		Path:       path,
+		User:       entry.User,
+		Owner:      lookupUser(entry.UID),
+		Group:      lookupGroup(entry.GID),
+		Mode:       entry.Mode.String(),
+		Size:       entry.Size,
+		Hash:       entry.Hash,
+		Target:     entry.Target,
+		Content:    entry.Content,
+		Truncated:  entry.Truncated,
+		Techniques: persistenceTechniques(entry.Mechanism, path),
+	}
+
+	// Ne garder les anciennes valeurs que si elles ont changé
+	if action == PersistenceActionChanged {
+		if old.Hash != current.Hash {
+			pe.OldHash = old.Hash
+			pe.AddedLines, pe.RemovedLines = diffLines(old.Content, current.Content)
+		}
+		if old.Target != current.Target {
+			pe.OldTarget = old.Target
+		}
+		if old.Mode != current.Mode {
+			pe.OldMode = old.Mode.String()
+		}
+		if old.UID != current.UID {
+			pe.OldOwner = lookupUser(old.UID)
+		}
+	}
+
+	username := pe.User
+	if username == "" {
+		username = pe.Owner
+	}
+
+	return &models.Event{
+		Timestamp: time.Now(),
+		AgentID:   pc.agentID,
+		Hostname:  pc.hostname,
+		EventType: models.EventTypePersistence,
+		Severity:  pc.determineSeverity(pe),
+		Username:  username,
+		RawData: map[string]interface{}{
+			"persistence": pe,
+		},
+		Tags: pc.generateTags(pe),
+	}
+}
+
+// determineSeverity détermine la sévérité selon le mécanisme et le changement
+func (pc *PersistenceCollector) determineSeverity(pe models.PersistenceEvent) models.Severity {
+	newKeys := pe.Mechanism == PersistenceAuthorizedKeys &&
+		(pe.Action == PersistenceActionAdded || len(pe.AddedLines) > 0)
+
+	switch {
+	case pe.Action == PersistenceActionRemoved:
+		return models.SeverityLow
+	case newKeys && pe.User == "root":
+		return models.SeverityCritical
+	case newKeys:
+		return models.SeverityHigh
+	case isVendorUnit(pe.Path):
+		// Unités installées par les paquets : les mises à jour les modifient
+		if pe.Action == PersistenceActionAdded {
+			return models.SeverityMedium
+		}
+		return models.SeverityLow
+	case pe.Action == PersistenceActionAdded && pe.Mechanism != PersistenceShellProfile:
+		return models.SeverityHigh
+	default:
+		return models.SeverityMedium
+	}
+}
+
+// generateTags génère des tags basés sur le changement, dont les techniques
+// MITRE ATT&CK au format des règles Sigma (attack.t1053.003)
+func (pc *PersistenceCollector) generateTags(pe models.PersistenceEvent) []string {
+	tags := []string{"persistence", "persistence_" + pe.Mechanism, "persistence_" + pe.Action, "attack.persistence"}
+	for _, technique := range pe.Techniques {
+		tags = append(tags, "attack."+strings.ToLower(technique))
+	}
+	if isVendorUnit(pe.Path) {
+		tags = append(tags, "vendor_unit")
+	}
+	return tags
+}
+
+// save écrit l'inventaire de manière atomique
+func (pc *PersistenceCollector) save() error {
+	if pc.options.SnapshotFile == "" || pc.snapshot == nil {
+		pc.dirty = false
+		return nil
+	}
+
+	data, err := json.Marshal(pc.snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal persistence snapshot: %w", err)
+	}
+	if err := writeStateFile(pc.options.SnapshotFile, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write persistence snapshot: %w", err)
+	}
+	pc.dirty = false
+	return nil
+}
+
+// loadPersistenceSnapshot charge l'inventaire enregistré ; nil s'il n'y en a
+// pas ou qu'il est illisible (journalisé)
+func loadPersistenceSnapshot(path string, logger *utils.Logger) *persistenceSnapshot {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Error("Failed to read persistence snapshot %s: %v", path, err)
+		}
+		return nil
+	}
+
+	var snapshot persistenceSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil || snapshot.Entries == nil {
+		logger.Error("Invalid persistence snapshot %s, computing a new baseline: %v", path, err)
+		return nil
+	}
+	return &snapshot
+}
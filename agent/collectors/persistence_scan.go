@@ -0,0 +1,269 @@
+package collectors
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxPersistenceContent borne le contenu conservé et remonté d'une entrée
+const maxPersistenceContent = 64 * 1024
+
+// passwdFile liste les comptes dont les répertoires personnels sont inventoriés
+const passwdFile = "/etc/passwd"
+
+// persistenceLocation est un motif glob des fichiers d'un mécanisme. Un motif
+// commençant par « ~/ » est cherché dans le répertoire personnel de chaque compte.
+type persistenceLocation struct {
+	mechanism string
+	pattern   string
+}
+
+// persistenceLocations sont les emplacements inventoriés. /usr/lib précède
+// /lib pour que les unités des systèmes à /usr fusionné gardent ce chemin.
+var persistenceLocations = []persistenceLocation{
+	{PersistenceCron, "/etc/crontab"},
+	{PersistenceCron, "/etc/anacrontab"},
+	{PersistenceCron, "/etc/cron.d/*"},
+	{PersistenceCron, "/etc/cron.hourly/*"},
+	{PersistenceCron, "/etc/cron.daily/*"},
+	{PersistenceCron, "/etc/cron.weekly/*"},
+	{PersistenceCron, "/etc/cron.monthly/*"},
+	{PersistenceCron, "/var/spool/cron/*"},          // crontabs utilisateur (RHEL)
+	{PersistenceCron, "/var/spool/cron/crontabs/*"}, // crontabs utilisateur (Debian)
+
+	{PersistenceSystemd, "/etc/systemd/system/*"},
+	{PersistenceSystemd, "/etc/systemd/system/*/*"}, // activations (*.wants/) et surcharges (*.d/)
+	{PersistenceSystemd, "/etc/systemd/user/*"},
+	{PersistenceSystemd, "/etc/systemd/user/*/*"},
+	{PersistenceSystemd, "/usr/lib/systemd/system/*"},
+	{PersistenceSystemd, "/lib/systemd/system/*"},
+	{PersistenceSystemd, "~/.config/systemd/user/*"},
+	{PersistenceSystemd, "~/.config/systemd/user/*/*"},
+
+	{PersistenceRCScripts, "/etc/rc.local"},
+	{PersistenceRCScripts, "/etc/rc.d/rc.local"},
+	{PersistenceRCScripts, "/etc/init.d/*"},
+
+	{PersistenceShellProfile, "/etc/profile"},
+	{PersistenceShellProfile, "/etc/profile.d/*"},
+	{PersistenceShellProfile, "/etc/bash.bashrc"},
+	{PersistenceShellProfile, "/etc/bashrc"},
+	{PersistenceShellProfile, "/etc/zprofile"},
+	{PersistenceShellProfile, "/etc/zshrc"},
+	{PersistenceShellProfile, "/etc/zsh/zprofile"},
+	{PersistenceShellProfile, "/etc/zsh/zshrc"},
+	{PersistenceShellProfile, "~/.profile"},
+	{PersistenceShellProfile, "~/.bash_profile"},
+	{PersistenceShellProfile, "~/.bash_login"},
+	{PersistenceShellProfile, "~/.bashrc"},
+	{PersistenceShellProfile, "~/.bash_logout"},
+	{PersistenceShellProfile, "~/.zprofile"},
+	{PersistenceShellProfile, "~/.zshrc"},
+
+	{PersistenceAuthorizedKeys, "~/.ssh/authorized_keys"},
+	{PersistenceAuthorizedKeys, "~/.ssh/authorized_keys2"},
+}
+
+// vendorUnitDirs contiennent les unités systemd installées par les paquets
+var vendorUnitDirs = []string{"/usr/lib/systemd/", "/lib/systemd/"}
+
+// homeDir est le répertoire personnel d'un compte
+type homeDir struct {
+	user string
+	dir  string
+}
+
+// persistencePattern est un motif glob concret, avec le compte dont il
+// parcourt le répertoire personnel
+type persistencePattern struct {
+	path string
+	user string
+}
+
+// expand retourne les motifs concrets d'un emplacement
+func (l persistenceLocation) expand(homes []homeDir) []persistencePattern {
+	if !strings.HasPrefix(l.pattern, "~/") {
+		return []persistencePattern{{path: l.pattern}}
+	}
+	patterns := make([]persistencePattern, 0, len(homes))
+	for _, home := range homes {
+		patterns = append(patterns, persistencePattern{
+			path: filepath.Join(home.dir, l.pattern[len("~/"):]),
+			user: home.user,
+		})
+	}
+	return patterns
+}
+
+// listHomeDirs retourne les répertoires personnels des comptes de
+// /etc/passwd, y compris ceux des comptes de service : une clé SSH déposée
+// chez l'un d'eux est aussi une persistance. Un répertoire partagé n'est
+// retenu que pour son premier compte.
+func listHomeDirs() []homeDir {
+	f, err := os.Open(passwdFile)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var homes []homeDir
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// nom:mot de passe:uid:gid:gecos:répertoire:shell
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 7 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		dir := filepath.Clean(fields[5])
+		if !filepath.IsAbs(dir) || dir == "/" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		homes = append(homes, homeDir{user: fields[0], dir: dir})
+	}
+	return homes
+}
+
+// persistenceEntry est l'état inventorié d'un fichier de persistance
+type persistenceEntry struct {
+	Mechanism string      `json:"mechanism"`
+	User      string      `json:"user,omitempty"`
+	Hash      string      `json:"hash,omitempty"`
+	Size      int64       `json:"size"`
+	Mode      fs.FileMode `json:"mode"`
+	UID       int         `json:"uid"`
+	GID       int         `json:"gid"`
+	Target    string      `json:"target,omitempty"` // cible d'un lien symbolique
+	Content   string      `json:"content,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// changed indique si une entrée diffère de son état précédent
+func (e *persistenceEntry) changed(current *persistenceEntry) bool {
+	return e.Hash != current.Hash || e.Target != current.Target || e.Mode != current.Mode ||
+		e.UID != current.UID || e.GID != current.GID
+}
+
+// readPersistenceEntry lit l'état d'un fichier régulier ou d'un lien
+// symbolique (activation d'une unité) ; nil sans erreur pour les autres types
+func readPersistenceEntry(path string) (*persistenceEntry, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, gid := fileOwner(info)
+	entry := &persistenceEntry{
+		Size: info.Size(),
+		Mode: info.Mode(),
+		UID:  uid,
+		GID:  gid,
+	}
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if entry.Target, err = os.Readlink(path); err != nil {
+			return nil, err
+		}
+		return entry, nil
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Le contenu est borné, le hash porte sur tout le fichier
+	h := sha256.New()
+	content, err := io.ReadAll(io.LimitReader(io.TeeReader(f, h), maxPersistenceContent+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxPersistenceContent {
+		content = content[:maxPersistenceContent]
+		entry.Truncated = true
+		if _, err := io.Copy(h, f); err != nil {
+			return nil, err
+		}
+	}
+	entry.Hash = hex.EncodeToString(h.Sum(nil))
+	entry.Content = string(content)
+	return entry, nil
+}
+
+// persistenceTechniques retourne les techniques MITRE ATT&CK d'un mécanisme
+func persistenceTechniques(mechanism, path string) []string {
+	switch mechanism {
+	case PersistenceCron:
+		return []string{"T1053.003"} // Scheduled Task/Job: Cron
+	case PersistenceSystemd:
+		if strings.HasSuffix(path, ".timer") {
+			return []string{"T1053.006"} // Scheduled Task/Job: Systemd Timers
+		}
+		return []string{"T1543.002"} // Create or Modify System Process: Systemd Service
+	case PersistenceRCScripts:
+		return []string{"T1037.004"} // Boot or Logon Initialization Scripts: RC Scripts
+	case PersistenceShellProfile:
+		return []string{"T1546.004"} // Event Triggered Execution: Unix Shell Configuration Modification
+	case PersistenceAuthorizedKeys:
+		return []string{"T1098.004"} // Account Manipulation: SSH Authorized Keys
+	default:
+		return nil
+	}
+}
+
+// isVendorUnit indique si un chemin est une unité systemd installée par un paquet
+func isVendorUnit(path string) bool {
+	for _, dir := range vendorUnitDirs {
+		if strings.HasPrefix(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffLines retourne les lignes significatives (ni vides ni commentaires)
+// ajoutées et retirées entre deux contenus, sans tenir compte de leur ordre
+func diffLines(old, current string) (added, removed []string) {
+	remaining := make(map[string]int)
+	for _, line := range significantLines(old) {
+		remaining[line]++
+	}
+	for _, line := range significantLines(current) {
+		if remaining[line] > 0 {
+			remaining[line]--
+			continue
+		}
+		added = append(added, line)
+	}
+	for _, line := range significantLines(old) {
+		if remaining[line] > 0 {
+			remaining[line]--
+			removed = append(removed, line)
+		}
+	}
+	return added, removed
+}
+
+// significantLines retourne les lignes ni vides ni commentées d'un contenu
+func significantLines(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
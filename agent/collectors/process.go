@@ -2,6 +2,9 @@ package collectors
 
 import (
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/process"
@@ -9,56 +12,159 @@ import (
 	"github.com/luigi/xdr-platform/agent/utils"
 )
 
-// ProcessCollector collecte les informations sur les processus
+// maxParentChainDepth borne la remontée de la chaîne des parents
+const maxParentChainDepth = 8
+
+// Actions émises par le collecteur de processus
+const (
+	ProcessActionStart     = "start"
+	ProcessActionExit      = "exit"
+	ProcessActionInventory = "inventory"
+)
+
+// trackedProcess est l'état conservé entre deux cycles pour un processus vivant
+type trackedProcess struct {
+	createTime int64
+	info       models.ProcessEvent
+}
+
+// ProcessCollector suit le cycle de vie des processus : il conserve l'état
+// entre deux cycles et n'émet que les démarrages et les terminaisons, plus
+// un inventaire complet à basse fréquence. Tous les processus sont suivis ;
+// le filtre de noms ne s'applique qu'aux événements émis.
 type ProcessCollector struct {
 	logger   *utils.Logger
 	agentID  string
 	hostname string
+	options  ProcessOptions
+
+	mu   sync.Mutex
+	next *ProcessOptions // options en attente, appliquées à la prochaine collecte
+
+	known         map[int32]*trackedProcess // nil avant le premier cycle
+	lastInventory time.Time
+	executables   *executableInspector
 }
 
 // NewProcessCollector crée un nouveau collecteur de processus
-func NewProcessCollector(logger *utils.Logger, agentID, hostname string) *ProcessCollector {
+func NewProcessCollector(logger *utils.Logger, agentID, hostname string, options ProcessOptions) *ProcessCollector {
 	return &ProcessCollector{
-		logger:   logger,
-		agentID:  agentID,
-		hostname: hostname,
+		logger:      logger,
+		agentID:     agentID,
+		hostname:    hostname,
+		options:     options,
+		executables: newExecutableInspector(logger, options),
 	}
 }
 
-// Collect collecte les informations des processus actifs
+// Configure remplace les réglages à partir de la prochaine collecte
+func (pc *ProcessCollector) Configure(options ProcessOptions) {
+	pc.mu.Lock()
+	pc.next = &options
+	pc.mu.Unlock()
+}
+
+// Collect émet les démarrages et terminaisons de processus depuis le cycle précédent.
+// Le premier cycle établit l'état de référence et produit un inventaire complet.
 func (pc *ProcessCollector) Collect() ([]*models.Event, error) {
 	pc.logger.Debug("Starting process collection...")
 
+	pc.mu.Lock()
+	if pc.next != nil {
+		// Le cache des exécutables est vidé si les informations calculées changent
+		if !slices.Equal(pc.next.ExtraHashes, pc.options.ExtraHashes) ||
+			pc.next.ExecutableCache != pc.options.ExecutableCache || pc.next.PackageLookup != pc.options.PackageLookup {
+			pc.executables = newExecutableInspector(pc.logger, *pc.next)
+		}
+		pc.options, pc.next = *pc.next, nil
+	}
+	pc.mu.Unlock()
+
 	processes, err := process.Processes()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get processes: %w", err)
 	}
 
+	firstCycle := pc.known == nil
+	inventoryInterval := pc.options.InventoryInterval
+	inventory := firstCycle || (inventoryInterval > 0 && time.Since(pc.lastInventory) >= inventoryInterval)
+
+	current := make(map[int32]*trackedProcess, len(processes))
 	var events []*models.Event
+	var started, exited int
 
-	// Collecter les informations de tous les processus
 	for _, p := range processes {
-		procEvent, err := pc.collectProcessInfo(p)
+		createTime, err := p.CreateTime()
+		if err != nil {
+			// Le processus s'est probablement terminé entre-temps
+			continue
+		}
+
+		prev, known := pc.known[p.Pid]
+		if known && prev.createTime != createTime {
+			// PID réutilisé : l'ancien processus s'est terminé
+			if pc.options.Names.Match(prev.info.Name) {
+				events = append(events, pc.newEvent(pc.exitInfo(prev), ProcessActionExit))
+				exited++
+			}
+			known = false
+		}
+
+		if known && !inventory {
+			current[p.Pid] = prev
+			continue
+		}
+
+		info, err := pc.collectProcessInfo(p)
 		if err != nil {
 			// Log l'erreur mais continue avec les autres processus
 			pc.logger.Debug("Failed to collect process %d: %v", p.Pid, err)
+			if known {
+				// Toujours vivant : il ne doit pas passer pour terminé
+				current[p.Pid] = prev
+			}
 			continue
 		}
+		current[p.Pid] = &trackedProcess{createTime: createTime, info: info}
 
-		if procEvent != nil {
-			events = append(events, procEvent)
+		if !pc.options.Names.Match(info.Name) {
+			continue
+		}
+
+		if inventory {
+			events = append(events, pc.newEvent(info, ProcessActionInventory))
+		}
+
+		if !known && !firstCycle {
+			info.ParentChain = pc.parentChain(info.ParentPID, current)
+			events = append(events, pc.newEvent(info, ProcessActionStart))
+			started++
+		}
+	}
+
+	// Les processus connus absents de ce cycle se sont terminés
+	for pid, prev := range pc.known {
+		if _, alive := current[pid]; !alive && pc.options.Names.Match(prev.info.Name) {
+			events = append(events, pc.newEvent(pc.exitInfo(prev), ProcessActionExit))
+			exited++
 		}
 	}
 
-	pc.logger.Info("Collected %d process events", len(events))
+	pc.known = current
+	if inventory {
+		pc.lastInventory = time.Now()
+	}
+
+	pc.logger.Info("Collected %d process events (%d started, %d exited, inventory: %t)",
+		len(events), started, exited, inventory)
 	return events, nil
 }
 
 // collectProcessInfo collecte les informations d'un processus spécifique
-func (pc *ProcessCollector) collectProcessInfo(p *process.Process) (*models.Event, error) {
+func (pc *ProcessCollector) collectProcessInfo(p *process.Process) (models.ProcessEvent, error) {
 	name, err := p.Name()
 	if err != nil {
-		return nil, err
+		return models.ProcessEvent{}, err
 	}
 
 	cmdline, _ := p.Cmdline()
@@ -78,7 +184,12 @@ func (pc *ProcessCollector) collectProcessInfo(p *process.Process) (*models.Even
 		memBytes = memInfo.RSS
 	}
 
-	processEvent := models.ProcessEvent{
+	var statusName string
+	if len(status) > 0 {
+		statusName = status[0]
+	}
+
+	info := models.ProcessEvent{
 		PID:            int(p.Pid),
 		Name:           name,
 		CommandLine:    cmdline,
@@ -90,38 +201,105 @@ func (pc *ProcessCollector) collectProcessInfo(p *process.Process) (*models.Even
 		MemoryBytes:    memBytes,
 		CreateTime:     createTime,
 		NumThreads:     numThreads,
-		Status:         status[0],
+		Status:         statusName,
 		Connections:    len(connections),
 	}
 
-	// Créer l'événement
-	event := &models.Event{
+	if executable := pc.executables.inspect(p.Pid, exe); executable != nil {
+		details := executable.details
+		info.ExecutableHash = executable.sha256
+		info.ExecutableMD5 = executable.md5
+		info.ExecutableSHA1 = executable.sha1
+		info.Executable = &details
+	}
+
+	return info, nil
+}
+
+// parentChain remonte la chaîne des parents d'un processus
+func (pc *ProcessCollector) parentChain(ppid int, current map[int32]*trackedProcess) []models.ProcessAncestor {
+	var chain []models.ProcessAncestor
+
+	for pid := int32(ppid); pid > 0 && len(chain) < maxParentChainDepth; {
+		var ancestor models.ProcessAncestor
+		var next int32
+
+		if tracked, ok := current[pid]; ok {
+			ancestor = models.ProcessAncestor{PID: tracked.info.PID, Name: tracked.info.Name, ExecutablePath: tracked.info.ExecutablePath}
+			next = int32(tracked.info.ParentPID)
+		} else if tracked, ok := pc.known[pid]; ok {
+			ancestor = models.ProcessAncestor{PID: tracked.info.PID, Name: tracked.info.Name, ExecutablePath: tracked.info.ExecutablePath}
+			next = int32(tracked.info.ParentPID)
+		} else {
+			p, err := process.NewProcess(pid)
+			if err != nil {
+				break
+			}
+			name, _ := p.Name()
+			exe, _ := p.Exe()
+			next, _ = p.Ppid()
+			ancestor = models.ProcessAncestor{PID: int(pid), Name: name, ExecutablePath: exe}
+		}
+
+		chain = append(chain, ancestor)
+		if next == pid {
+			break
+		}
+		pid = next
+	}
+
+	return chain
+}
+
+// exitInfo prépare les informations d'un processus terminé à partir de son dernier état connu
+func (pc *ProcessCollector) exitInfo(tracked *trackedProcess) models.ProcessEvent {
+	info := tracked.info
+	info.ParentChain = nil
+	if info.CreateTime > 0 {
+		info.LifetimeSeconds = time.Since(time.UnixMilli(info.CreateTime)).Seconds()
+	}
+	return info
+}
+
+// newEvent crée l'événement pour une action sur un processus
+func (pc *ProcessCollector) newEvent(info models.ProcessEvent, action string) *models.Event {
+	info.Action = action
+
+	return &models.Event{
 		Timestamp:   time.Now(),
 		AgentID:     pc.agentID,
 		Hostname:    pc.hostname,
 		EventType:   models.EventTypeProcess,
-		Severity:    pc.determineSeverity(processEvent),
-		ProcessName: name,
-		ProcessPID:  int(p.Pid),
-		Username:    username,
+		Severity:    pc.determineSeverity(info),
+		ProcessName: info.Name,
+		ProcessPID:  info.PID,
+		Username:    info.Username,
 		RawData: map[string]interface{}{
-			"process": processEvent,
+			"process": info,
 		},
-		Tags: pc.generateTags(processEvent),
+		Tags: pc.generateTags(info),
 	}
-
-	return event, nil
 }
 
 // determineSeverity détermine la sévérité basée sur les métriques du processus
 func (pc *ProcessCollector) determineSeverity(pe models.ProcessEvent) models.Severity {
+	// Binaire lancé depuis un répertoire inscriptible ou supprimé du disque
+	if pe.Action == ProcessActionStart && (pc.isSuspiciousExecPath(pe.ExecutablePath) || strings.HasSuffix(pe.ExecutablePath, " (deleted)")) {
+		return models.SeverityHigh
+	}
+
+	// Binaire système différent de celui livré par son paquet
+	if pe.Executable != nil && pe.Executable.PackageModified {
+		return models.SeverityHigh
+	}
+
 	// Processus suspect : haute utilisation CPU ou mémoire
-	if pe.CPUPercent > 80.0 || pe.MemoryPercent > 80.0 {
+	if pe.CPUPercent > pc.options.CPUHigh || pe.MemoryPercent > pc.options.MemoryHigh {
 		return models.SeverityHigh
 	}
 
 	// Processus avec beaucoup de connexions réseau
-	if pe.Connections > 50 {
+	if pe.Connections > pc.options.ConnectionsMedium {
 		return models.SeverityMedium
 	}
 
@@ -130,20 +308,43 @@ func (pc *ProcessCollector) determineSeverity(pe models.ProcessEvent) models.Sev
 
 // generateTags génère des tags basés sur le processus
 func (pc *ProcessCollector) generateTags(pe models.ProcessEvent) []string {
-	tags := []string{"process_monitoring"}
+	tags := []string{"process_monitoring", "process_" + pe.Action}
 
-	// Ajouter des tags basés sur les caractéristiques
-	if pe.CPUPercent > 50.0 {
+	// Ajouter des tags basés sur les caractéristiques, aux seuils de la sévérité
+	if pe.CPUPercent > pc.options.CPUHigh {
 		tags = append(tags, "high_cpu")
 	}
 
-	if pe.MemoryPercent > 50.0 {
+	if pe.MemoryPercent > pc.options.MemoryHigh {
 		tags = append(tags, "high_memory")
 	}
 
-	if pe.Connections > 10 {
+	if pe.Connections > pc.options.ConnectionsMedium {
 		tags = append(tags, "network_active")
 	}
 
+	if pc.isSuspiciousExecPath(pe.ExecutablePath) {
+		tags = append(tags, "suspicious_path")
+	}
+
+	if pe.Executable != nil {
+		if pe.Executable.PackageModified {
+			tags = append(tags, "package_modified")
+		}
+		if pe.Executable.Linking == "static" {
+			tags = append(tags, "static_binary")
+		}
+	}
+
 	return tags
 }
+
+// isSuspiciousExecPath indique si un exécutable se trouve dans un répertoire suspect
+func (pc *ProcessCollector) isSuspiciousExecPath(path string) bool {
+	for _, dir := range pc.options.SuspiciousDirs {
+		if strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
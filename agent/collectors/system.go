@@ -3,6 +3,7 @@ package collectors
 import (
 	"fmt"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/cpu"
@@ -18,21 +19,39 @@ type SystemCollector struct {
 	logger   *utils.Logger
 	agentID  string
 	hostname string
+	options  SystemOptions
+
+	mu   sync.Mutex
+	next *SystemOptions // options en attente, appliquées à la prochaine collecte
 }
 
 // NewSystemCollector crée un nouveau collecteur système
-func NewSystemCollector(logger *utils.Logger, agentID, hostname string) *SystemCollector {
+func NewSystemCollector(logger *utils.Logger, agentID, hostname string, options SystemOptions) *SystemCollector {
 	return &SystemCollector{
 		logger:   logger,
 		agentID:  agentID,
 		hostname: hostname,
+		options:  options,
 	}
 }
 
+// Configure remplace les réglages à partir de la prochaine collecte
+func (sc *SystemCollector) Configure(options SystemOptions) {
+	sc.mu.Lock()
+	sc.next = &options
+	sc.mu.Unlock()
+}
+
 // Collect collecte les métriques système
 func (sc *SystemCollector) Collect() ([]*models.Event, error) {
 	sc.logger.Debug("Starting system collection...")
 
+	sc.mu.Lock()
+	if sc.next != nil {
+		sc.options, sc.next = *sc.next, nil
+	}
+	sc.mu.Unlock()
+
 	var events []*models.Event
 
 	// Collecter les informations CPU
@@ -96,19 +115,12 @@ func (sc *SystemCollector) collectCPUInfo() (*models.Event, error) {
 		},
 	}
 
-	severity := models.SeverityLow
-	if cpuPercent[0] > 80.0 {
-		severity = models.SeverityHigh
-	} else if cpuPercent[0] > 60.0 {
-		severity = models.SeverityMedium
-	}
-
 	event := &models.Event{
 		Timestamp: time.Now(),
 		AgentID:   sc.agentID,
 		Hostname:  sc.hostname,
 		EventType: models.EventTypeSystem,
-		Severity:  severity,
+		Severity:  sc.options.CPU.severity(cpuPercent[0]),
 		RawData: map[string]interface{}{
 			"system": systemEvent,
 		},
@@ -138,19 +150,12 @@ func (sc *SystemCollector) collectMemoryInfo() (*models.Event, error) {
 		},
 	}
 
-	severity := models.SeverityLow
-	if vmem.UsedPercent > 90.0 {
-		severity = models.SeverityHigh
-	} else if vmem.UsedPercent > 75.0 {
-		severity = models.SeverityMedium
-	}
-
 	event := &models.Event{
 		Timestamp: time.Now(),
 		AgentID:   sc.agentID,
 		Hostname:  sc.hostname,
 		EventType: models.EventTypeSystem,
-		Severity:  severity,
+		Severity:  sc.options.Memory.severity(vmem.UsedPercent),
 		RawData: map[string]interface{}{
 			"system": systemEvent,
 		},
@@ -171,6 +176,10 @@ func (sc *SystemCollector) collectDiskInfo() (*models.Event, error) {
 	var maxUsedPercent float64
 
 	for _, partition := range partitions {
+		if !sc.options.Mountpoints.Match(partition.Mountpoint) {
+			continue
+		}
+
 		usage, err := disk.Usage(partition.Mountpoint)
 		if err != nil {
 			continue
@@ -200,19 +209,12 @@ func (sc *SystemCollector) collectDiskInfo() (*models.Event, error) {
 		},
 	}
 
-	severity := models.SeverityLow
-	if maxUsedPercent > 90.0 {
-		severity = models.SeverityHigh
-	} else if maxUsedPercent > 80.0 {
-		severity = models.SeverityMedium
-	}
-
 	event := &models.Event{
 		Timestamp: time.Now(),
 		AgentID:   sc.agentID,
 		Hostname:  sc.hostname,
 		EventType: models.EventTypeSystem,
-		Severity:  severity,
+		Severity:  sc.options.Disk.severity(maxUsedPercent),
 		RawData: map[string]interface{}{
 			"system": systemEvent,
 		},
@@ -0,0 +1,223 @@
+package collectors
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+const (
+	// maxTailBytesPerCycle borne la lecture d'un fichier par collecte ; le
+	// reste est lu aux collectes suivantes
+	maxTailBytesPerCycle = 16 * 1024 * 1024
+
+	// maxTailLineLength tronque les lignes anormalement longues
+	maxTailLineLength = 64 * 1024
+)
+
+// tailPosition est la position de lecture persistée d'un fichier journal
+type tailPosition struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// logTailer lit les lignes complètes ajoutées à des fichiers journaux depuis
+// la lecture précédente. Il suit la rotation (nouvel inode : la fin de
+// l'ancien fichier est lue s'il est retrouvé à côté) et la troncature, et
+// conserve ses positions dans un fichier d'état pour reprendre après un
+// redémarrage sans relire ni perdre de lignes.
+type logTailer struct {
+	logger    *utils.Logger
+	stateFile string // vide : positions non persistées
+	positions map[string]*tailPosition
+}
+
+// newLogTailer crée un tailer et charge les positions enregistrées. Un
+// fichier d'état illisible est journalisé et ignoré : la lecture reprend
+// alors à la fin des fichiers.
+func newLogTailer(stateFile string, logger *utils.Logger) *logTailer {
+	t := &logTailer{
+		logger:    logger,
+		stateFile: stateFile,
+		positions: make(map[string]*tailPosition),
+	}
+	if stateFile == "" {
+		return t
+	}
+
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Error("Failed to read log positions %s: %v", stateFile, err)
+		}
+		return t
+	}
+	if err := json.Unmarshal(data, &t.positions); err != nil {
+		logger.Error("Invalid log positions file %s, starting from end of logs: %v", stateFile, err)
+		t.positions = make(map[string]*tailPosition)
+	}
+	return t
+}
+
+// read passe à handle chaque ligne complète ajoutée à path. Un fichier jamais
+// lu est pris à sa fin, sauf si fromBeginning est vrai. Un fichier absent
+// n'est pas une erreur.
+func (t *logTailer) read(path string, fromBeginning bool, handle func(line string)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	inode := fileInode(info)
+
+	pos, known := t.positions[path]
+	if !known {
+		pos = &tailPosition{Inode: inode}
+		if !fromBeginning {
+			pos.Offset = info.Size()
+		}
+		t.positions[path] = pos
+	}
+
+	if pos.Inode != inode {
+		// Rotation : terminer l'ancien fichier s'il a seulement été renommé
+		if rotated := findRotated(path, pos.Inode); rotated != "" {
+			if _, err := readLines(rotated, pos.Offset, maxTailBytesPerCycle, handle); err != nil {
+				t.logger.Error("Failed to read rotated log %s: %v", rotated, err)
+			}
+		}
+		pos.Inode, pos.Offset = inode, 0
+	}
+
+	// Troncature (copytruncate) : reprendre au début
+	if info.Size() < pos.Offset {
+		pos.Offset = 0
+	}
+
+	consumed, err := readLines(path, pos.Offset, maxTailBytesPerCycle, handle)
+	pos.Offset += consumed
+	return err
+}
+
+// forget oublie les positions des fichiers qui ne sont plus suivis
+func (t *logTailer) forget(paths []string) {
+	keep := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		keep[path] = true
+	}
+	for path := range t.positions {
+		if !keep[path] {
+			delete(t.positions, path)
+		}
+	}
+}
+
+// save écrit les positions de manière atomique
+func (t *logTailer) save() error {
+	if t.stateFile == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(t.positions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal log positions: %w", err)
+	}
+
+	if err := writeStateFile(t.stateFile, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write log positions: %w", err)
+	}
+	return nil
+}
+
+// writeStateFile écrit un fichier d'état de manière atomique (fichier
+// temporaire puis rename), en créant son répertoire au besoin.
+//
+// Les collecteurs qui reprennent là où ils s'étaient arrêtés (positions de
+// lecture, curseur du journal, inventaire de persistance) n'enregistrent
+// l'état atteint par une collecte qu'au début de la suivante, une fois ses
+// événements envoyés, et dans Close, que le scheduler appelle après le
+// dernier envoi : un arrêt brutal fait réémettre des événements plutôt
+// qu'en perdre.
+func writeStateFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// findRotated cherche, à côté de path, le fichier renommé qui porte encore
+// l'inode lu précédemment (auth.log.1, secure-20240102...). Les archives
+// compressées sont ignorées.
+func findRotated(path string, inode uint64) string {
+	if inode == 0 {
+		return ""
+	}
+
+	candidates, _ := filepath.Glob(path + "?*")
+	for _, candidate := range candidates {
+		switch filepath.Ext(candidate) {
+		case ".gz", ".xz", ".bz2", ".zst":
+			continue
+		}
+		if info, err := os.Stat(candidate); err == nil && fileInode(info) == inode {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// readLines lit les lignes complètes de path à partir de offset, dans la
+// limite de limit octets, et retourne le nombre d'octets consommés. Une
+// dernière ligne sans fin de ligne n'est pas consommée : elle est en cours
+// d'écriture et sera relue entière à la collecte suivante.
+func readLines(path string, offset, limit int64, handle func(line string)) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	reader := bufio.NewReaderSize(f, 64*1024)
+	var consumed int64
+	for consumed < limit {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return consumed, nil
+			}
+			return consumed, err
+		}
+		consumed += int64(len(line))
+
+		line = strings.TrimRight(line, "\r\n")
+		if len(line) > maxTailLineLength {
+			line = line[:maxTailLineLength]
+		}
+		if line != "" {
+			handle(line)
+		}
+	}
+	return consumed, nil
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/collectors"
+)
+
+// CollectorConfig regroupe les réglages communs à tous les collecteurs
+type CollectorConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"` // 0 : collection_interval
+}
+
+// IntervalOr retourne l'intervalle du collecteur, ou fallback s'il n'en a pas
+func (c CollectorConfig) IntervalOr(fallback time.Duration) time.Duration {
+	if c.Interval > 0 {
+		return c.Interval
+	}
+	return fallback
+}
+
+// validate valide les réglages communs
+func (c CollectorConfig) validate() error {
+	if c.Interval < 0 {
+		return fmt.Errorf("interval cannot be negative")
+	}
+	return nil
+}
+
+// SystemCollectorConfig configure le collecteur système
+type SystemCollectorConfig struct {
+	CollectorConfig          `yaml:",inline"`
+	collectors.SystemOptions `yaml:",inline"`
+}
+
+// NetworkCollectorConfig configure le collecteur réseau
+type NetworkCollectorConfig struct {
+	CollectorConfig           `yaml:",inline"`
+	collectors.NetworkOptions `yaml:",inline"`
+}
+
+// ProcessCollectorConfig configure le collecteur de processus
+type ProcessCollectorConfig struct {
+	CollectorConfig           `yaml:",inline"`
+	collectors.ProcessOptions `yaml:",inline"`
+}
+
+// FileCollectorConfig configure le collecteur d'intégrité de fichiers
+type FileCollectorConfig struct {
+	CollectorConfig        `yaml:",inline"`
+	collectors.FileOptions `yaml:",inline"`
+}
+
+// AuthCollectorConfig configure le collecteur d'authentification
+type AuthCollectorConfig struct {
+	CollectorConfig        `yaml:",inline"`
+	collectors.AuthOptions `yaml:",inline"`
+}
+
+// JournalCollectorConfig configure le collecteur du journal systemd
+type JournalCollectorConfig struct {
+	CollectorConfig           `yaml:",inline"`
+	collectors.JournalOptions `yaml:",inline"`
+}
+
+// AuditCollectorConfig configure le collecteur d'audit
+type AuditCollectorConfig struct {
+	CollectorConfig         `yaml:",inline"`
+	collectors.AuditOptions `yaml:",inline"`
+}
+
+// PersistenceCollectorConfig configure le collecteur des mécanismes de persistance
+type PersistenceCollectorConfig struct {
+	CollectorConfig               `yaml:",inline"`
+	collectors.PersistenceOptions `yaml:",inline"`
+}
+
+// Collectors regroupe la configuration de chaque collecteur
+type Collectors struct {
+	System      SystemCollectorConfig      `yaml:"system"`
+	Network     NetworkCollectorConfig     `yaml:"network"`
+	Process     ProcessCollectorConfig     `yaml:"process"`
+	File        FileCollectorConfig        `yaml:"file"`
+	Auth        AuthCollectorConfig        `yaml:"auth"`
+	Journal     JournalCollectorConfig     `yaml:"journal"`
+	Audit       AuditCollectorConfig       `yaml:"audit"`
+	Persistence PersistenceCollectorConfig `yaml:"persistence"`
+}
+
+// defaultCollectors retourne la configuration par défaut : tous les collecteurs
+// activés sauf le journal, qui double auth.log sur les hôtes où rsyslog tourne,
+// et l'audit, qui installe des règles dans le noyau
+func defaultCollectors() Collectors {
+	enabled := CollectorConfig{Enabled: true}
+	return Collectors{
+		System:      SystemCollectorConfig{CollectorConfig: enabled, SystemOptions: collectors.DefaultSystemOptions()},
+		Network:     NetworkCollectorConfig{CollectorConfig: enabled, NetworkOptions: collectors.DefaultNetworkOptions()},
+		Process:     ProcessCollectorConfig{CollectorConfig: enabled, ProcessOptions: collectors.DefaultProcessOptions()},
+		File:        FileCollectorConfig{CollectorConfig: enabled, FileOptions: collectors.DefaultFileOptions()},
+		Auth:        AuthCollectorConfig{CollectorConfig: enabled, AuthOptions: collectors.DefaultAuthOptions()},
+		Journal:     JournalCollectorConfig{JournalOptions: collectors.DefaultJournalOptions()},
+		Audit:       AuditCollectorConfig{AuditOptions: collectors.DefaultAuditOptions()},
+		Persistence: PersistenceCollectorConfig{CollectorConfig: enabled, PersistenceOptions: collectors.DefaultPersistenceOptions()},
+	}
+}
+
+// Enabled retourne les noms des collecteurs activés
+func (c Collectors) Enabled() []string {
+	var names []string
+	if c.System.Enabled {
+		names = append(names, "system")
+	}
+	if c.Network.Enabled {
+		names = append(names, "network")
+	}
+	if c.Process.Enabled {
+		names = append(names, "process")
+	}
+	if c.File.Enabled {
+		names = append(names, "file")
+	}
+	if c.Auth.Enabled {
+		names = append(names, "auth")
+	}
+	if c.Journal.Enabled {
+		names = append(names, "journal")
+	}
+	if c.Audit.Enabled {
+		names = append(names, "audit")
+	}
+	if c.Persistence.Enabled {
+		names = append(names, "persistence")
+	}
+	return names
+}
+
+// validate valide les collecteurs activés ; les erreurs sont préfixées par
+// le chemin de la clé dans le fichier de configuration
+func (c Collectors) validate() error {
+	if len(c.Enabled()) == 0 {
+		return fmt.Errorf("at least one collector must be enabled")
+	}
+
+	checks := []struct {
+		name     string
+		settings CollectorConfig
+		options  interface{ Validate() error }
+	}{
+		{"system", c.System.CollectorConfig, c.System.SystemOptions},
+		{"network", c.Network.CollectorConfig, c.Network.NetworkOptions},
+		{"process", c.Process.CollectorConfig, c.Process.ProcessOptions},
+		{"file", c.File.CollectorConfig, c.File.FileOptions},
+		{"auth", c.Auth.CollectorConfig, c.Auth.AuthOptions},
+		{"journal", c.Journal.CollectorConfig, c.Journal.JournalOptions},
+		{"audit", c.Audit.CollectorConfig, c.Audit.AuditOptions},
+		{"persistence", c.Persistence.CollectorConfig, c.Persistence.PersistenceOptions},
+	}
+	for _, check := range checks {
+		if !check.settings.Enabled {
+			continue
+		}
+		if err := check.settings.validate(); err != nil {
+			return fmt.Errorf("collectors.%s.%w", check.name, err)
+		}
+		if err := check.options.Validate(); err != nil {
+			return fmt.Errorf("collectors.%s.%w", check.name, err)
+		}
+	}
+	return nil
+}
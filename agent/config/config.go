@@ -3,100 +3,176 @@ package config
 import (
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/luigi/xdr-platform/agent/identity"
 )
 
-// Config contient toute la configuration de l'agent
+// DefaultConfigFile est chargé s'il existe et si AGENT_CONFIG_FILE n'est pas défini
+const DefaultConfigFile = "/etc/xdr-agent/agent.yaml"
+
+// Config contient toute la configuration de l'agent. Les tags yaml donnent
+// les clés du fichier de configuration.
 type Config struct {
 	// Agent configuration
-	AgentID              string
-	AgentVersion         string
-	Hostname             string
-	CollectionInterval   time.Duration
-	HeartbeatInterval    time.Duration
+	AgentID            string        `yaml:"-"`
+	AgentIDFromEnv     bool          `yaml:"-"`          // AGENT_ID fixé explicitement, prioritaire sur le fichier d'état
+	StateFile          string        `yaml:"state_file"` // identité persistante de l'agent
+	AgentVersion       string        `yaml:"version"`
+	Hostname           string        `yaml:"-"`
+	CollectionInterval time.Duration `yaml:"collection_interval"` // intervalle des collecteurs sans intervalle propre
+	HeartbeatInterval  time.Duration `yaml:"heartbeat_interval"`
+
+	// Fichier de configuration chargé, vide sans fichier
+	ConfigFile string `yaml:"-"`
+
+	// Sorties actives : kafka, http, syslog, file, stdout
+	Outputs []string `yaml:"outputs"`
 
 	// Kafka configuration
-	KafkaBrokers         []string
-	KafkaTopicRawEvents  string
-	KafkaGroupID         string
+	KafkaBrokers        []string      `yaml:"kafka_brokers"`
+	KafkaTopicRawEvents string        `yaml:"kafka_topic_raw_events"`
+	KafkaGroupID        string        `yaml:"kafka_group_id"`
+	KafkaTopicDLQ       string        `yaml:"kafka_topic_dlq"`
+	KafkaBatchTimeout   time.Duration `yaml:"kafka_batch_timeout"`
+	KafkaCompression    string        `yaml:"kafka_compression"`   // none, gzip, snappy, lz4, zstd
+	KafkaRequiredAcks   string        `yaml:"kafka_required_acks"` // none, leader, all
+	KafkaAsync          bool          `yaml:"kafka_async"`
+
+	// Kafka TLS et SASL
+	KafkaTLS                   bool   `yaml:"kafka_tls_enabled"`
+	KafkaTLSCAFile             string `yaml:"kafka_tls_ca_file"`
+	KafkaTLSCertFile           string `yaml:"kafka_tls_cert_file"`
+	KafkaTLSKeyFile            string `yaml:"kafka_tls_key_file"`
+	KafkaTLSInsecureSkipVerify bool   `yaml:"kafka_tls_insecure_skip_verify"`
+	KafkaSASLMechanism         string `yaml:"kafka_sasl_mechanism"` // plain, scram-sha-256, scram-sha-512
+	KafkaSASLUsername          string `yaml:"kafka_sasl_username"`
+	KafkaSASLPassword          string `yaml:"kafka_sasl_password"`
+
+	// Sortie HTTP(S) : POST de lots NDJSON
+	HTTPURL                string        `yaml:"http_url"`
+	HTTPAPIKey             string        `yaml:"http_api_key"`
+	HTTPCAFile             string        `yaml:"http_ca_file"`
+	HTTPInsecureSkipVerify bool          `yaml:"http_insecure_skip_verify"`
+	HTTPTimeout            time.Duration `yaml:"http_timeout"`
+	HTTPGzip               bool          `yaml:"http_gzip"`
+
+	// Sortie syslog (RFC 5424)
+	SyslogAddress  string `yaml:"syslog_address"`
+	SyslogNetwork  string `yaml:"syslog_network"` // tcp ou udp
+	SyslogFacility string `yaml:"syslog_facility"`
 
-	// Collectors configuration
-	EnableSystemCollector  bool
-	EnableNetworkCollector bool
-	EnableProcessCollector bool
+	// Sortie fichier JSON local avec rotation
+	FilePath       string `yaml:"file_path"`
+	FileMaxBytes   int64  `yaml:"file_max_bytes"`
+	FileMaxBackups int    `yaml:"file_max_backups"`
+
+	// Collecteurs : activation, intervalle et réglages propres à chacun
+	Collectors Collectors `yaml:"collectors"`
 
 	// Logging
-	LogLevel string
-	LogFile  string
+	LogLevel string `yaml:"log_level"`
+	LogFile  string `yaml:"log_file"`
 
 	// Performance
-	MaxEventsPerBatch int
-	BufferSize        int
+	MaxEventsPerBatch int `yaml:"max_events_per_batch"` // messages par écriture Kafka (envoi et vidage du spool)
+	BufferSize        int `yaml:"buffer_size"`          // événements par segment du spool
+
+	// Spool disque utilisé quand Kafka ou la sortie HTTP est injoignable
+	SpoolDir      string        `yaml:"spool_dir"`
+	SpoolMaxBytes int64         `yaml:"spool_max_bytes"`
+	SpoolMaxAge   time.Duration `yaml:"spool_max_age"`
 }
 
-// LoadConfig charge la configuration depuis les variables d'environnement
+// LoadConfig charge la configuration : valeurs par défaut, puis fichier de
+// configuration (AGENT_CONFIG_FILE, ou DefaultConfigFile s'il existe), puis
+// variables d'environnement, qui l'emportent sur le fichier
 func LoadConfig() (*Config, error) {
-	hostname, err := os.Hostname()
-	if err != nil {
-		hostname = "unknown-host"
-	}
+	config := defaultConfig()
 
-	// Générer un AgentID unique s'il n'existe pas
-	agentID := os.Getenv("AGENT_ID")
-	if agentID == "" || agentID == "auto-generated" {
-		agentID = fmt.Sprintf("agent-%s", uuid.New().String()[:8])
+	path := os.Getenv("AGENT_CONFIG_FILE")
+	if path == "" {
+		if _, err := os.Stat(DefaultConfigFile); err == nil {
+			path = DefaultConfigFile
+		}
+	}
+	if path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, err
+		}
+		config.ConfigFile = path
 	}
 
-	// Collection interval
-	collectionInterval, err := time.ParseDuration(getEnvOrDefault("AGENT_COLLECTION_INTERVAL", "30s"))
-	if err != nil {
-		collectionInterval = 30 * time.Second
+	if err := config.loadEnv(); err != nil {
+		return nil, err
 	}
+	config.KafkaSASLMechanism = strings.ToLower(config.KafkaSASLMechanism)
+
+	return config, nil
+}
 
-	// Heartbeat interval
-	heartbeatInterval, err := time.ParseDuration(getEnvOrDefault("AGENT_HEARTBEAT_INTERVAL", "60s"))
+// defaultConfig retourne la configuration par défaut
+func defaultConfig() *Config {
+	hostname, err := os.Hostname()
 	if err != nil {
-		heartbeatInterval = 60 * time.Second
+		hostname = "unknown-host"
 	}
 
-	config := &Config{
-		AgentID:              agentID,
-		AgentVersion:         getEnvOrDefault("AGENT_VERSION", "1.0.0"),
-		Hostname:             hostname,
-		CollectionInterval:   collectionInterval,
-		HeartbeatInterval:    heartbeatInterval,
+	return &Config{
+		StateFile:          "/var/lib/xdr-agent/state.json",
+		AgentVersion:       "1.0.0",
+		Hostname:           hostname,
+		CollectionInterval: 30 * time.Second,
+		HeartbeatInterval:  60 * time.Second,
+
+		Outputs: []string{"kafka"},
 
 		// Kafka configuration
-		KafkaBrokers:         []string{getEnvOrDefault("KAFKA_BROKERS", "localhost:9092")},
-		KafkaTopicRawEvents:  getEnvOrDefault("KAFKA_TOPIC_RAW_EVENTS", "raw-events"),
-		KafkaGroupID:         getEnvOrDefault("KAFKA_GROUP_ID", "xdr-agent-group"),
+		KafkaBrokers:        []string{"localhost:9092"},
+		KafkaTopicRawEvents: "raw-events",
+		KafkaGroupID:        "xdr-agent-group",
+		KafkaTopicDLQ:       "raw-events-dlq",
+		KafkaBatchTimeout:   time.Second,
+		KafkaCompression:    "none",
+		KafkaRequiredAcks:   "all",
+
+		// HTTP output
+		HTTPTimeout: 10 * time.Second,
+		HTTPGzip:    true,
+
+		// Syslog output
+		SyslogNetwork:  "udp",
+		SyslogFacility: "local0",
+
+		// File output
+		FilePath:       "/var/log/xdr-agent/events.ndjson",
+		FileMaxBytes:   100 * 1024 * 1024,
+		FileMaxBackups: 5,
 
 		// Collectors
-		EnableSystemCollector:  getEnvOrDefault("ENABLE_SYSTEM_COLLECTOR", "true") == "true",
-		EnableNetworkCollector: getEnvOrDefault("ENABLE_NETWORK_COLLECTOR", "true") == "true",
-		EnableProcessCollector: getEnvOrDefault("ENABLE_PROCESS_COLLECTOR", "true") == "true",
+		Collectors: defaultCollectors(),
 
 		// Logging
-		LogLevel: getEnvOrDefault("LOG_LEVEL", "info"),
-		LogFile:  getEnvOrDefault("LOG_FILE", ""),
+		LogLevel: "info",
 
 		// Performance
 		MaxEventsPerBatch: 100,
 		BufferSize:        1000,
-	}
 
-	return config, nil
+		// Spool
+		SpoolDir:      "/var/lib/xdr-agent/spool",
+		SpoolMaxBytes: 100 * 1024 * 1024,
+		SpoolMaxAge:   24 * time.Hour,
+	}
 }
 
-// getEnvOrDefault retourne la valeur d'une variable d'environnement ou une valeur par défaut
-func getEnvOrDefault(key, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+// ApplyIdentity utilise l'ID persistant de l'agent, sauf si AGENT_ID est fixé
+func (c *Config) ApplyIdentity(state *identity.State) {
+	if !c.AgentIDFromEnv {
+		c.AgentID = state.AgentID
 	}
-	return value
 }
 
 // Validate valide la configuration
@@ -107,22 +183,135 @@ func (c *Config) Validate() error {
 	if c.Hostname == "" {
 		return fmt.Errorf("hostname cannot be empty")
 	}
-	if len(c.KafkaBrokers) == 0 {
-		return fmt.Errorf("kafka_brokers cannot be empty")
+	if len(c.Outputs) == 0 {
+		return fmt.Errorf("outputs cannot be empty")
+	}
+	seen := make(map[string]bool)
+	for _, output := range c.Outputs {
+		if seen[output] {
+			return fmt.Errorf("output %q is listed twice", output)
+		}
+		seen[output] = true
+
+		switch output {
+		case "kafka":
+			if len(c.KafkaBrokers) == 0 {
+				return fmt.Errorf("kafka_brokers cannot be empty")
+			}
+			if err := c.validateKafka(); err != nil {
+				return err
+			}
+		case "http":
+			if c.HTTPURL == "" {
+				return fmt.Errorf("http_url is required by the http output")
+			}
+			if c.HTTPTimeout <= 0 {
+				return fmt.Errorf("http_timeout must be positive")
+			}
+		case "syslog":
+			if c.SyslogAddress == "" {
+				return fmt.Errorf("syslog_address is required by the syslog output")
+			}
+			if c.SyslogNetwork != "tcp" && c.SyslogNetwork != "udp" {
+				return fmt.Errorf("syslog_network must be tcp or udp")
+			}
+		case "file":
+			if c.FilePath == "" {
+				return fmt.Errorf("file_path is required by the file output")
+			}
+			if c.FileMaxBytes < 0 || c.FileMaxBackups < 0 {
+				return fmt.Errorf("file_max_bytes and file_max_backups cannot be negative")
+			}
+		case "stdout":
+		default:
+			return fmt.Errorf("unknown output %q (expected kafka, http, syslog, file or stdout)", output)
+		}
 	}
 	if c.CollectionInterval <= 0 {
 		return fmt.Errorf("collection_interval must be positive")
 	}
+	if c.HeartbeatInterval <= 0 {
+		return fmt.Errorf("heartbeat_interval must be positive")
+	}
+	if err := c.Collectors.validate(); err != nil {
+		return err
+	}
+	if c.MaxEventsPerBatch <= 0 {
+		return fmt.Errorf("max_events_per_batch must be positive")
+	}
+	if c.BufferSize <= 0 {
+		return fmt.Errorf("buffer_size must be positive")
+	}
+	if c.SpoolDir == "" {
+		return fmt.Errorf("spool_dir cannot be empty")
+	}
 	return nil
 }
 
+// validateKafka valide les options de production et de sécurité Kafka
+func (c *Config) validateKafka() error {
+	if c.KafkaBatchTimeout <= 0 {
+		return fmt.Errorf("kafka_batch_timeout must be positive")
+	}
+	switch c.KafkaCompression {
+	case "none", "gzip", "snappy", "lz4", "zstd":
+	default:
+		return fmt.Errorf("kafka_compression must be none, gzip, snappy, lz4 or zstd, got %q", c.KafkaCompression)
+	}
+	switch c.KafkaRequiredAcks {
+	case "none", "leader", "all":
+	default:
+		return fmt.Errorf("kafka_required_acks must be none, leader or all, got %q", c.KafkaRequiredAcks)
+	}
+	if (c.KafkaTLSCertFile == "") != (c.KafkaTLSKeyFile == "") {
+		return fmt.Errorf("kafka_tls_cert_file and kafka_tls_key_file must be set together")
+	}
+	if !c.KafkaTLS && (c.KafkaTLSCAFile != "" || c.KafkaTLSCertFile != "") {
+		return fmt.Errorf("kafka TLS files are set but kafka_tls_enabled is false")
+	}
+	switch c.KafkaSASLMechanism {
+	case "":
+	case "plain", "scram-sha-256", "scram-sha-512":
+		if c.KafkaSASLUsername == "" || c.KafkaSASLPassword == "" {
+			return fmt.Errorf("kafka_sasl_username and kafka_sasl_password are required by SASL %s", c.KafkaSASLMechanism)
+		}
+		if c.KafkaSASLMechanism == "plain" && !c.KafkaTLS {
+			return fmt.Errorf("SASL plain sends the password in clear text, enable kafka_tls_enabled")
+		}
+	default:
+		return fmt.Errorf("kafka_sasl_mechanism must be plain, scram-sha-256 or scram-sha-512, got %q", c.KafkaSASLMechanism)
+	}
+	return nil
+}
+
+// OutputsChanged indique si les réglages des sorties diffèrent entre deux
+// configurations. Tout champ qui n'est pas propre à l'agent, aux collecteurs
+// ou au logging est considéré comme un réglage des sorties.
+func (c *Config) OutputsChanged(other *Config) bool {
+	return !reflect.DeepEqual(c.outputSettings(), other.outputSettings())
+}
+
+// outputSettings retourne une copie de la configuration réduite aux sorties
+func (c *Config) outputSettings() Config {
+	settings := *c
+	settings.AgentID, settings.AgentIDFromEnv, settings.StateFile = "", false, ""
+	settings.AgentVersion, settings.Hostname, settings.ConfigFile = "", "", ""
+	settings.CollectionInterval, settings.HeartbeatInterval = 0, 0
+	settings.Collectors = Collectors{}
+	settings.LogLevel, settings.LogFile = "", ""
+	return settings
+}
+
 // String retourne une représentation string de la config (pour logging)
 func (c *Config) String() string {
 	return fmt.Sprintf(
-		"Agent{ID: %s, Hostname: %s, Version: %s, Interval: %s}",
+		"Agent{ID: %s, Hostname: %s, Version: %s, Interval: %s, Outputs: %v, Collectors: %v, File: %q}",
 		c.AgentID,
 		c.Hostname,
 		c.AgentVersion,
 		c.CollectionInterval,
+		c.Outputs,
+		c.Collectors.Enabled(),
+		c.ConfigFile,
 	)
 }
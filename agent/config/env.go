@@ -0,0 +1,227 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadEnv applique les variables d'environnement définies, prioritaires sur
+// le fichier de configuration. Une valeur invalide est une erreur : elle ne
+// retombe pas silencieusement sur la valeur par défaut.
+func (c *Config) loadEnv() error {
+	// Sans AGENT_ID, l'ID est lu dans le fichier d'état (voir ApplyIdentity)
+	if agentID := os.Getenv("AGENT_ID"); agentID != "" && agentID != "auto-generated" {
+		c.AgentID = agentID
+		c.AgentIDFromEnv = true
+	}
+
+	env := &envLoader{}
+
+	// Agent configuration
+	env.string("AGENT_STATE_FILE", &c.StateFile)
+	env.string("AGENT_VERSION", &c.AgentVersion)
+	env.duration("AGENT_COLLECTION_INTERVAL", &c.CollectionInterval)
+	env.duration("AGENT_HEARTBEAT_INTERVAL", &c.HeartbeatInterval)
+
+	env.list("AGENT_OUTPUTS", &c.Outputs)
+
+	// Kafka configuration
+	env.list("KAFKA_BROKERS", &c.KafkaBrokers)
+	env.string("KAFKA_TOPIC_RAW_EVENTS", &c.KafkaTopicRawEvents)
+	env.string("KAFKA_GROUP_ID", &c.KafkaGroupID)
+	env.string("KAFKA_TOPIC_DLQ", &c.KafkaTopicDLQ)
+	env.duration("KAFKA_BATCH_TIMEOUT", &c.KafkaBatchTimeout)
+	env.string("KAFKA_COMPRESSION", &c.KafkaCompression)
+	env.string("KAFKA_REQUIRED_ACKS", &c.KafkaRequiredAcks)
+	env.bool("KAFKA_ASYNC", &c.KafkaAsync)
+
+	// Kafka TLS et SASL
+	env.bool("KAFKA_TLS_ENABLED", &c.KafkaTLS)
+	env.string("KAFKA_TLS_CA_FILE", &c.KafkaTLSCAFile)
+	env.string("KAFKA_TLS_CERT_FILE", &c.KafkaTLSCertFile)
+	env.string("KAFKA_TLS_KEY_FILE", &c.KafkaTLSKeyFile)
+	env.bool("KAFKA_TLS_INSECURE_SKIP_VERIFY", &c.KafkaTLSInsecureSkipVerify)
+	env.string("KAFKA_SASL_MECHANISM", &c.KafkaSASLMechanism)
+	env.string("KAFKA_SASL_USERNAME", &c.KafkaSASLUsername)
+	env.string("KAFKA_SASL_PASSWORD", &c.KafkaSASLPassword)
+
+	// HTTP output
+	env.string("AGENT_HTTP_URL", &c.HTTPURL)
+	env.string("AGENT_HTTP_API_KEY", &c.HTTPAPIKey)
+	env.string("AGENT_HTTP_CA_FILE", &c.HTTPCAFile)
+	env.bool("AGENT_HTTP_INSECURE_SKIP_VERIFY", &c.HTTPInsecureSkipVerify)
+	env.duration("AGENT_HTTP_TIMEOUT", &c.HTTPTimeout)
+	env.bool("AGENT_HTTP_GZIP", &c.HTTPGzip)
+
+	// Syslog output
+	env.string("AGENT_SYSLOG_ADDRESS", &c.SyslogAddress)
+	env.string("AGENT_SYSLOG_NETWORK", &c.SyslogNetwork)
+	env.string("AGENT_SYSLOG_FACILITY", &c.SyslogFacility)
+
+	// File output
+	env.string("AGENT_FILE_PATH", &c.FilePath)
+	env.int64("AGENT_FILE_MAX_BYTES", &c.FileMaxBytes)
+	env.int("AGENT_FILE_MAX_BACKUPS", &c.FileMaxBackups)
+
+	// Collectors
+	env.bool("ENABLE_SYSTEM_COLLECTOR", &c.Collectors.System.Enabled)
+	env.bool("ENABLE_NETWORK_COLLECTOR", &c.Collectors.Network.Enabled)
+	env.bool("ENABLE_PROCESS_COLLECTOR", &c.Collectors.Process.Enabled)
+	env.bool("ENABLE_FILE_COLLECTOR", &c.Collectors.File.Enabled)
+	env.bool("ENABLE_AUTH_COLLECTOR", &c.Collectors.Auth.Enabled)
+	env.bool("ENABLE_JOURNAL_COLLECTOR", &c.Collectors.Journal.Enabled)
+	env.bool("ENABLE_AUDIT_COLLECTOR", &c.Collectors.Audit.Enabled)
+	env.bool("ENABLE_PERSISTENCE_COLLECTOR", &c.Collectors.Persistence.Enabled)
+	env.duration("SYSTEM_COLLECTOR_INTERVAL", &c.Collectors.System.Interval)
+	env.duration("NETWORK_COLLECTOR_INTERVAL", &c.Collectors.Network.Interval)
+	env.duration("PROCESS_COLLECTOR_INTERVAL", &c.Collectors.Process.Interval)
+	env.duration("FILE_COLLECTOR_INTERVAL", &c.Collectors.File.Interval)
+	env.duration("AUTH_COLLECTOR_INTERVAL", &c.Collectors.Auth.Interval)
+	env.duration("JOURNAL_COLLECTOR_INTERVAL", &c.Collectors.Journal.Interval)
+	env.duration("AUDIT_COLLECTOR_INTERVAL", &c.Collectors.Audit.Interval)
+	env.duration("PERSISTENCE_COLLECTOR_INTERVAL", &c.Collectors.Persistence.Interval)
+
+	// Process collector
+	env.duration("PROCESS_INVENTORY_INTERVAL", &c.Collectors.Process.InventoryInterval)
+	env.list("PROCESS_EXTRA_HASHES", &c.Collectors.Process.ExtraHashes)
+	env.bool("PROCESS_PACKAGE_LOOKUP", &c.Collectors.Process.PackageLookup)
+
+	// File integrity monitoring
+	env.list("FIM_PATHS", &c.Collectors.File.Paths)
+	env.list("FIM_EXCLUDE", &c.Collectors.File.Exclude)
+
+	// Auth collector
+	env.list("AUTH_LOG_PATHS", &c.Collectors.Auth.Paths)
+
+	// Journal collector
+	env.string("JOURNAL_PRIORITY", &c.Collectors.Journal.Priority)
+	env.list("JOURNAL_UNITS", &c.Collectors.Journal.Units.Include)
+	env.list("JOURNAL_IDENTIFIERS", &c.Collectors.Journal.Identifiers.Include)
+
+	// Audit collector
+	env.string("AUDIT_SOURCE", &c.Collectors.Audit.Source)
+	env.string("AUDIT_LOG_FILE", &c.Collectors.Audit.LogFile)
+
+	// Persistence collector
+	env.list("PERSISTENCE_MECHANISMS", &c.Collectors.Persistence.Mechanisms.Include)
+
+	// Logging
+	env.string("LOG_LEVEL", &c.LogLevel)
+	env.string("LOG_FILE", &c.LogFile)
+
+	// Performance
+	env.int("AGENT_MAX_EVENTS_PER_BATCH", &c.MaxEventsPerBatch)
+	env.int("AGENT_BUFFER_SIZE", &c.BufferSize)
+
+	// Spool
+	env.string("AGENT_SPOOL_DIR", &c.SpoolDir)
+	env.int64("AGENT_SPOOL_MAX_BYTES", &c.SpoolMaxBytes)
+	env.duration("AGENT_SPOOL_MAX_AGE", &c.SpoolMaxAge)
+
+	return env.err()
+}
+
+// envLoader lit les variables d'environnement définies (non vides) et
+// accumule les erreurs de conversion pour toutes les signaler à la fois
+type envLoader struct {
+	errs []error
+}
+
+// lookup retourne la valeur d'une variable définie et non vide
+func (l *envLoader) lookup(key string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(key))
+	return value, value != ""
+}
+
+// string applique une variable texte
+func (l *envLoader) string(key string, dst *string) {
+	if value, ok := l.lookup(key); ok {
+		*dst = value
+	}
+}
+
+// bool applique une variable booléenne (true, false, 1, 0...)
+func (l *envLoader) bool(key string, dst *bool) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid boolean %q (expected true or false)", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+// int applique une variable entière
+func (l *envLoader) int(key string, dst *int) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid integer %q", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+// int64 applique une variable entière 64 bits (tailles en octets)
+func (l *envLoader) int64(key string, dst *int64) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid integer %q", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+// duration applique une variable de durée (30s, 5m, 1h...)
+func (l *envLoader) duration(key string, dst *time.Duration) {
+	value, ok := l.lookup(key)
+	if !ok {
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid duration %q (expected a value such as 30s, 5m or 1h)", key, value))
+		return
+	}
+	*dst = parsed
+}
+
+// list applique une liste séparée par des virgules
+func (l *envLoader) list(key string, dst *[]string) {
+	if value, ok := l.lookup(key); ok {
+		*dst = splitList(value)
+	}
+}
+
+// err retourne les erreurs de conversion rencontrées
+func (l *envLoader) err() error {
+	if len(l.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid environment: %w", errors.Join(l.errs...))
+}
+
+// splitList découpe une liste séparée par des virgules en ignorant les entrées vides
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
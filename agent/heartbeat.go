@@ -0,0 +1,161 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/config"
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// Heartbeat construit les heartbeats de l'agent et mesure le débit
+// d'événements collectés entre deux heartbeats
+type Heartbeat struct {
+	mu         sync.Mutex
+	cfg        *config.Config
+	collectors []string
+	count      int       // événements collectés depuis le dernier heartbeat
+	lastSent   time.Time // date du dernier heartbeat
+}
+
+// NewHeartbeat crée un heartbeat pour les collecteurs activés
+func NewHeartbeat(cfg *config.Config, collectors []string) *Heartbeat {
+	return &Heartbeat{
+		cfg:        cfg,
+		collectors: collectors,
+		lastSent:   time.Now(),
+	}
+}
+
+// Update applique une configuration rechargée et la liste des collecteurs actifs
+func (hb *Heartbeat) Update(cfg *config.Config, collectors []string) {
+	hb.mu.Lock()
+	hb.cfg = cfg
+	hb.collectors = collectors
+	hb.mu.Unlock()
+}
+
+// current retourne la configuration et les collecteurs courants
+func (hb *Heartbeat) current() (*config.Config, []string) {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+	return hb.cfg, hb.collectors
+}
+
+// CountEvents comptabilise des événements collectés
+func (hb *Heartbeat) CountEvents(n int) {
+	hb.mu.Lock()
+	hb.count += n
+	hb.mu.Unlock()
+}
+
+// Event retourne l'événement heartbeat et remet à zéro le compteur d'événements
+func (hb *Heartbeat) Event(spoolDepth int) *models.Event {
+	now := time.Now()
+
+	hb.mu.Lock()
+	var rate float64
+	if elapsed := now.Sub(hb.lastSent); elapsed > 0 {
+		rate = float64(hb.count) / elapsed.Minutes()
+	}
+	hb.count = 0
+	hb.lastSent = now
+	cfg, collectors := hb.cfg, hb.collectors
+	hb.mu.Unlock()
+
+	return &models.Event{
+		Timestamp: now,
+		AgentID:   cfg.AgentID,
+		Hostname:  cfg.Hostname,
+		EventType: models.EventTypeSystem,
+		Severity:  models.SeverityLow,
+		RawData: map[string]interface{}{
+			"heartbeat":   true,
+			"version":     cfg.AgentVersion,
+			"spool_depth": spoolDepth,
+			"agent":       agentInfo(cfg, collectors, now, rate),
+		},
+		Tags: []string{"heartbeat"},
+	}
+}
+
+// Registration retourne l'événement d'enregistrement de l'agent, envoyé au
+// premier démarrage avec un nouvel ID. previousAgentID est l'ID remplacé par
+// un réenrôlement, vide pour un premier enrôlement.
+func (hb *Heartbeat) Registration(previousAgentID string) *models.Event {
+	now := time.Now()
+	cfg, collectors := hb.current()
+
+	reason := "first_start"
+	rawData := map[string]interface{}{
+		"registration": true,
+		"version":      cfg.AgentVersion,
+		"agent":        agentInfo(cfg, collectors, now, 0),
+	}
+	if previousAgentID != "" {
+		reason = "reenrollment"
+		rawData["previous_agent_id"] = previousAgentID
+	}
+	rawData["reason"] = reason
+
+	return &models.Event{
+		Timestamp: now,
+		AgentID:   cfg.AgentID,
+		Hostname:  cfg.Hostname,
+		EventType: models.EventTypeSystem,
+		Severity:  models.SeverityLow,
+		RawData:   rawData,
+		Tags:      []string{"agent_registration"},
+	}
+}
+
+// agentInfo décrit l'agent et son hôte ; les champs indisponibles restent vides
+func agentInfo(cfg *config.Config, collectors []string, now time.Time, rate float64) *models.AgentInfo {
+	info := &models.AgentInfo{
+		AgentID:           cfg.AgentID,
+		Hostname:          cfg.Hostname,
+		IPAddress:         primaryIPAddress(),
+		AgentVersion:      cfg.AgentVersion,
+		Status:            "running",
+		LastHeartbeat:     now,
+		HeartbeatInterval: cfg.HeartbeatInterval.Seconds(),
+		Collectors:        collectors,
+		EventRate:         rate,
+	}
+
+	if hostInfo, err := host.Info(); err == nil {
+		info.OSType = hostInfo.OS
+		info.OSVersion = strings.TrimSpace(hostInfo.Platform + " " + hostInfo.PlatformVersion)
+		info.KernelVersion = hostInfo.KernelVersion
+	}
+
+	return info
+}
+
+// primaryIPAddress retourne la première adresse IPv4 non loopback de l'hôte
+func primaryIPAddress() string {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				return ipNet.IP.String()
+			}
+		}
+	}
+
+	return ""
+}
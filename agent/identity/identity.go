@@ -0,0 +1,155 @@
+// Package identity conserve l'identité de l'agent entre deux démarrages.
+// L'ID est écrit dans un fichier d'état ; à défaut de fichier, il est dérivé
+// du machine-id de l'hôte pour rester stable même si l'état est perdu.
+package identity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Origine d'un ID d'agent
+const (
+	SourceMachineID = "machine-id"
+	SourceRandom    = "random"
+)
+
+// machineIDPaths sont lus dans l'ordre pour dériver l'ID d'un nouvel agent
+var machineIDPaths = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+// State est l'état persistant de l'agent
+type State struct {
+	AgentID   string    `json:"agent_id"`
+	Source    string    `json:"source"` // machine-id ou random
+	CreatedAt time.Time `json:"created_at"`
+
+	// PreviousAgentID est l'ID remplacé par le dernier réenrôlement
+	PreviousAgentID string `json:"previous_agent_id,omitempty"`
+
+	// RegisteredAgentID est le dernier ID annoncé par un événement
+	// d'enregistrement ; un ID différent doit être enregistré
+	RegisteredAgentID string     `json:"registered_agent_id,omitempty"`
+	RegisteredAt      *time.Time `json:"registered_at,omitempty"`
+
+	path string
+}
+
+// Load lit le fichier d'état, ou crée une nouvelle identité s'il n'existe pas.
+// Une identité qui n'a pas pu être écrite est retournée avec l'erreur
+// d'écriture : elle reste utilisable mais ne survivra pas au redémarrage si
+// elle n'est pas dérivée du machine-id. Un fichier illisible est une erreur :
+// l'agent ne doit pas changer d'identité silencieusement.
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		state := &State{path: path}
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+		}
+		if state.AgentID == "" {
+			return nil, fmt.Errorf("state file %s has no agent_id", path)
+		}
+		return state, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read state file %s: %w", path, err)
+	}
+
+	state := &State{path: path, CreatedAt: time.Now().UTC()}
+	if machineID := readMachineID(); machineID != "" {
+		state.AgentID = machineAgentID(machineID)
+		state.Source = SourceMachineID
+	} else {
+		state.AgentID = randomAgentID()
+		state.Source = SourceRandom
+	}
+
+	return state, state.Save()
+}
+
+// Reenroll remplace l'ID de l'agent par un nouvel ID aléatoire, enregistré au
+// prochain démarrage. Le machine-id n'est pas utilisé : un réenrôlement sert
+// justement à séparer des hôtes clonés qui le partagent.
+func (s *State) Reenroll() error {
+	s.PreviousAgentID = s.AgentID
+	s.AgentID = randomAgentID()
+	s.Source = SourceRandom
+	s.CreatedAt = time.Now().UTC()
+	return s.Save()
+}
+
+// NeedsRegistration indique si agentID n'a pas encore été annoncé
+func (s *State) NeedsRegistration(agentID string) bool {
+	return s.RegisteredAgentID != agentID
+}
+
+// MarkRegistered enregistre l'annonce de agentID
+func (s *State) MarkRegistered(agentID string, at time.Time) error {
+	at = at.UTC()
+	s.RegisteredAgentID = agentID
+	s.RegisteredAt = &at
+	return s.Save()
+}
+
+// Path retourne le chemin du fichier d'état
+func (s *State) Path() string {
+	return s.path
+}
+
+// Save écrit l'état de manière atomique (fichier temporaire puis rename)
+func (s *State) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// readMachineID retourne le machine-id de l'hôte, vide s'il est indisponible
+func readMachineID() string {
+	for _, path := range machineIDPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if id := strings.TrimSpace(string(data)); id != "" && id != "uninitialized" {
+			return id
+		}
+	}
+	return ""
+}
+
+// machineAgentID dérive l'ID de l'agent du machine-id. Le machine-id n'est pas
+// exposé tel quel (recommandation de machine-id(5)) ; l'empreinte garde 64
+// bits pour que deux hôtes d'une même flotte ne se confondent pas.
+func machineAgentID(machineID string) string {
+	sum := sha256.Sum256([]byte("xdr-agent:" + machineID))
+	return "agent-" + hex.EncodeToString(sum[:])[:16]
+}
+
+// randomAgentID génère un ID au format historique agent-<uuid8>
+func randomAgentID() string {
+	return fmt.Sprintf("agent-%s", uuid.New().String()[:8])
+}
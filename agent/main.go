@@ -2,13 +2,15 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/luigi/xdr-platform/agent/collectors"
 	"github.com/luigi/xdr-platform/agent/config"
+	"github.com/luigi/xdr-platform/agent/identity"
 	"github.com/luigi/xdr-platform/agent/models"
 	"github.com/luigi/xdr-platform/agent/shipper"
 	"github.com/luigi/xdr-platform/agent/utils"
@@ -25,6 +27,26 @@ func main() {
 		logger.Fatal("Failed to load configuration: %v", err)
 	}
 
+	// Avec la sortie stdout, les événements occupent la sortie standard et
+	// les logs passent sur stderr
+	useStderrForStdout(cfg, logger)
+
+	// xdr-agent reenroll : attribuer un nouvel ID à l'agent
+	if len(os.Args) > 1 && os.Args[1] == "reenroll" {
+		reenroll(cfg, logger)
+		return
+	}
+
+	// Charger l'identité persistante de l'agent
+	state, err := identity.Load(cfg.StateFile)
+	if state == nil {
+		logger.Fatal("Failed to load agent identity: %v", err)
+	}
+	if err != nil {
+		logger.Error("Failed to save agent identity, ID %s (%s) may change at next start: %v", state.AgentID, state.Source, err)
+	}
+	cfg.ApplyIdentity(state)
+
 	// Valider la configuration
 	if err := cfg.Validate(); err != nil {
 		logger.Fatal("Invalid configuration: %v", err)
@@ -32,40 +54,32 @@ func main() {
 
 	logger.Info("Configuration loaded: %s", cfg.String())
 
-	// Créer le shipper Kafka
-	kafkaShipper, err := shipper.NewKafkaShipper(cfg.KafkaBrokers, cfg.KafkaTopicRawEvents, logger)
+	// Créer les sorties
+	outputs, err := newShipper(cfg, logger)
 	if err != nil {
-		logger.Fatal("Failed to create Kafka shipper: %v", err)
+		logger.Fatal("Failed to create shipper: %v", err)
 	}
-	defer kafkaShipper.Close()
-
-	// Créer les collecteurs
-	var activeCollectors []Collector
+	eventShipper := shipper.NewReloadableShipper(outputs, logger)
+	defer eventShipper.Close()
 
-	if cfg.EnableSystemCollector {
-		systemCollector := collectors.NewSystemCollector(logger, cfg.AgentID, cfg.Hostname)
-		activeCollectors = append(activeCollectors, systemCollector)
-		logger.Info("System collector enabled")
-	}
+	// Démarrer les collecteurs, chacun à son intervalle
+	heartbeat := NewHeartbeat(cfg, nil)
+	scheduler := NewScheduler(eventShipper, heartbeat, logger)
+	scheduler.Apply(cfg)
+	defer scheduler.Stop()
 
-	if cfg.EnableNetworkCollector {
-		networkCollector := collectors.NewNetworkCollector(logger, cfg.AgentID, cfg.Hostname)
-		activeCollectors = append(activeCollectors, networkCollector)
-		logger.Info("Network collector enabled")
+	if len(scheduler.Names()) == 0 {
+		logger.Fatal("No collectors enabled, please enable at least one collector")
 	}
+	heartbeat.Update(cfg, scheduler.Names())
 
-	if cfg.EnableProcessCollector {
-		processCollector := collectors.NewProcessCollector(logger, cfg.AgentID, cfg.Hostname)
-		activeCollectors = append(activeCollectors, processCollector)
-		logger.Info("Process collector enabled")
-	}
+	logger.Info("Started %d collectors", len(scheduler.Names()))
 
-	if len(activeCollectors) == 0 {
-		logger.Fatal("No collectors enabled, please enable at least one collector")
+	// Annoncer un nouvel ID d'agent
+	if state.NeedsRegistration(cfg.AgentID) {
+		go registerAgent(cfg, state, heartbeat, eventShipper, logger)
 	}
 
-	logger.Info("Started %d collectors", len(activeCollectors))
-
 	// Context pour gérer l'arrêt gracieux
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -74,15 +88,25 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// Ticker pour la collecte périodique
-	collectionTicker := time.NewTicker(cfg.CollectionInterval)
-	defer collectionTicker.Stop()
+	// Channel pour capturer les demandes de rechargement de la configuration
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
 
 	// Ticker pour le heartbeat
 	heartbeatTicker := time.NewTicker(cfg.HeartbeatInterval)
 	defer heartbeatTicker.Stop()
 
-	logger.Info("Agent is running, collecting every %s", cfg.CollectionInterval)
+	agent := &agentRuntime{
+		cfg:             cfg,
+		state:           state,
+		shipper:         eventShipper,
+		scheduler:       scheduler,
+		heartbeat:       heartbeat,
+		heartbeatTicker: heartbeatTicker,
+		logger:          logger,
+	}
+
+	logger.Info("Agent is running, send SIGHUP to reload the configuration")
 	logger.Info("Press Ctrl+C to stop")
 
 	// Boucle principale
@@ -97,13 +121,12 @@ func main() {
 			cancel()
 			return
 
-		case <-collectionTicker.C:
-			// Collecter et envoyer les événements
-			go collectAndShip(activeCollectors, kafkaShipper, logger)
+		case <-reloadChan:
+			agent.reload()
 
 		case <-heartbeatTicker.C:
 			// Envoyer un heartbeat
-			go sendHeartbeat(cfg, kafkaShipper, logger)
+			go sendHeartbeat(heartbeat, eventShipper, logger)
 		}
 	}
 }
@@ -113,55 +136,170 @@ type Collector interface {
 	Collect() ([]*models.Event, error)
 }
 
-// collectAndShip collecte les événements de tous les collecteurs et les envoie
-func collectAndShip(collectors []Collector, shipper *shipper.KafkaShipper, logger *utils.Logger) {
-	logger.Debug("Starting collection cycle...")
+// collectAndShip exécute une collecte et envoie ses événements
+func collectAndShip(name string, collector Collector, shipper shipper.Shipper, heartbeat *Heartbeat, logger *utils.Logger) {
+	logger.Debug("Starting %s collection cycle...", name)
 
-	var allEvents []*models.Event
+	events, err := collector.Collect()
+	if err != nil {
+		logger.Error("%s collection failed: %v", name, err)
+		return
+	}
+	heartbeat.CountEvents(len(events))
 
-	// Collecter depuis tous les collecteurs
-	for _, collector := range collectors {
-		events, err := collector.Collect()
-		if err != nil {
-			logger.Error("Collection failed: %v", err)
-			continue
-		}
-		allEvents = append(allEvents, events...)
+	if len(events) == 0 {
+		logger.Debug("No %s events collected in this cycle", name)
+		return
 	}
 
-	if len(allEvents) == 0 {
-		logger.Debug("No events collected in this cycle")
+	// Envoyer les événements aux sorties
+	if err := shipper.Ship(events); err != nil {
+		logger.Error("Failed to ship %s events: %v", name, err)
 		return
 	}
 
-	// Envoyer tous les événements à Kafka
-	if err := shipper.Ship(allEvents); err != nil {
-		logger.Error("Failed to ship events: %v", err)
+	logger.Info("%s collection cycle completed: %d events shipped", name, len(events))
+}
+
+// reenroll remplace l'ID de l'agent dans le fichier d'état ; le nouvel ID est
+// enregistré au prochain démarrage
+func reenroll(cfg *config.Config, logger *utils.Logger) {
+	if cfg.AgentIDFromEnv {
+		logger.Fatal("AGENT_ID is set, unset it to re-enroll the agent")
+	}
+
+	state, err := identity.Load(cfg.StateFile)
+	if state == nil {
+		logger.Fatal("Failed to load agent identity: %v", err)
+	}
+
+	previous := state.AgentID
+	if err := state.Reenroll(); err != nil {
+		logger.Fatal("Failed to save new agent identity: %v", err)
+	}
+
+	logger.Info("Agent re-enrolled: %s replaces %s, restart the agent to register it", state.AgentID, previous)
+}
+
+// registerAgent envoie l'événement d'enregistrement d'un nouvel ID d'agent.
+// En cas d'échec, l'enregistrement est retenté au prochain démarrage.
+func registerAgent(cfg *config.Config, state *identity.State, heartbeat *Heartbeat, shipper shipper.Shipper, logger *utils.Logger) {
+	// L'ID précédent n'a de sens que si l'ID vient du fichier d'état
+	previous := ""
+	if !cfg.AgentIDFromEnv {
+		previous = state.PreviousAgentID
+	}
+
+	if err := shipper.Ship([]*models.Event{heartbeat.Registration(previous)}); err != nil {
+		logger.Error("Failed to register agent %s: %v", cfg.AgentID, err)
+		return
+	}
+
+	if err := state.MarkRegistered(cfg.AgentID, time.Now()); err != nil {
+		logger.Error("Failed to save agent registration: %v", err)
 		return
 	}
 
-	logger.Info("Collection cycle completed: %d events shipped", len(allEvents))
+	logger.Info("Agent %s registered", cfg.AgentID)
 }
 
 // sendHeartbeat envoie un heartbeat pour indiquer que l'agent est actif
-func sendHeartbeat(cfg *config.Config, shipper *shipper.KafkaShipper, logger *utils.Logger) {
+func sendHeartbeat(heartbeat *Heartbeat, shipper shipper.Shipper, logger *utils.Logger) {
 	logger.Debug("Sending heartbeat...")
 
-	// Créer un événement heartbeat
-	heartbeat := &models.Event{
-		Timestamp: time.Now(),
-		AgentID:   cfg.AgentID,
-		Hostname:  cfg.Hostname,
-		EventType: models.EventTypeSystem,
-		Severity:  models.SeverityLow,
-		RawData: map[string]interface{}{
-			"heartbeat": true,
-			"version":   cfg.AgentVersion,
-		},
-		Tags: []string{"heartbeat"},
-	}
-
-	if err := shipper.Ship([]*models.Event{heartbeat}); err != nil {
+	if err := shipper.Ship([]*models.Event{heartbeat.Event(shipper.SpoolDepth())}); err != nil {
 		logger.Error("Failed to send heartbeat: %v", err)
 	}
 }
+
+// newShipper crée les sorties configurées ; avec plusieurs sorties, chaque
+// lot est envoyé à toutes (fan-out). Kafka et HTTP ont chacun leur spool.
+func newShipper(cfg *config.Config, logger *utils.Logger) (shipper.Shipper, error) {
+	var shippers []shipper.Shipper
+	closeAll := func() {
+		for _, s := range shippers {
+			s.Close()
+		}
+	}
+
+	// Événements non sérialisables des sorties sans topic dead-letter
+	deadLetter := shipper.NewDeadLetterFile(filepath.Join(cfg.SpoolDir, "dead-letter.jsonl"), cfg.SpoolMaxBytes, logger)
+
+	for _, output := range cfg.Outputs {
+		var s shipper.Shipper
+		var err error
+
+		switch output {
+		case "kafka":
+			spool, spoolErr := shipper.NewSpool(cfg.SpoolDir, cfg.BufferSize, cfg.SpoolMaxBytes, cfg.SpoolMaxAge, logger)
+			if spoolErr != nil {
+				closeAll()
+				return nil, fmt.Errorf("failed to open kafka spool: %w", spoolErr)
+			}
+			s, err = shipper.NewKafkaShipper(shipper.KafkaOptions{
+				Brokers:      cfg.KafkaBrokers,
+				Topic:        cfg.KafkaTopicRawEvents,
+				DLQTopic:     cfg.KafkaTopicDLQ,
+				BatchSize:    cfg.MaxEventsPerBatch,
+				BatchTimeout: cfg.KafkaBatchTimeout,
+				Compression:  cfg.KafkaCompression,
+				RequiredAcks: cfg.KafkaRequiredAcks,
+				Async:        cfg.KafkaAsync,
+				Security: shipper.KafkaSecurity{
+					TLS:                cfg.KafkaTLS,
+					CAFile:             cfg.KafkaTLSCAFile,
+					CertFile:           cfg.KafkaTLSCertFile,
+					KeyFile:            cfg.KafkaTLSKeyFile,
+					InsecureSkipVerify: cfg.KafkaTLSInsecureSkipVerify,
+					SASLMechanism:      cfg.KafkaSASLMechanism,
+					SASLUsername:       cfg.KafkaSASLUsername,
+					SASLPassword:       cfg.KafkaSASLPassword,
+				},
+			}, spool, logger)
+			if err != nil {
+				spool.Close()
+			}
+
+		case "http":
+			spool, spoolErr := shipper.NewSpool(filepath.Join(cfg.SpoolDir, "http"), cfg.BufferSize, cfg.SpoolMaxBytes, cfg.SpoolMaxAge, logger)
+			if spoolErr != nil {
+				closeAll()
+				return nil, fmt.Errorf("failed to open http spool: %w", spoolErr)
+			}
+			s, err = shipper.NewHTTPShipper(shipper.HTTPOptions{
+				URL:                cfg.HTTPURL,
+				APIKey:             cfg.HTTPAPIKey,
+				CAFile:             cfg.HTTPCAFile,
+				InsecureSkipVerify: cfg.HTTPInsecureSkipVerify,
+				Timeout:            cfg.HTTPTimeout,
+				Gzip:               cfg.HTTPGzip,
+			}, cfg.MaxEventsPerBatch, spool, deadLetter, logger)
+			if err != nil {
+				spool.Close()
+			}
+
+		case "syslog":
+			s, err = shipper.NewSyslogShipper(cfg.SyslogNetwork, cfg.SyslogAddress, cfg.SyslogFacility, deadLetter, logger)
+
+		case "file":
+			s, err = shipper.NewFileShipper(cfg.FilePath, cfg.FileMaxBytes, cfg.FileMaxBackups, deadLetter, logger)
+
+		case "stdout":
+			s = shipper.NewStdoutShipper(deadLetter, logger)
+
+		default:
+			err = fmt.Errorf("unknown output %q", output)
+		}
+
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to create %s output: %w", output, err)
+		}
+		shippers = append(shippers, s)
+	}
+
+	if len(shippers) == 1 {
+		return shippers[0], nil
+	}
+	return shipper.NewMultiShipper(shippers...), nil
+}
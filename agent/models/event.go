@@ -6,10 +6,13 @@ import "time"
 type EventType string
 
 const (
-	EventTypeSystem  EventType = "system"
-	EventTypeNetwork EventType = "network"
-	EventTypeProcess EventType = "process"
-	EventTypeFile    EventType = "file"
+	EventTypeSystem      EventType = "system"
+	EventTypeNetwork     EventType = "network"
+	EventTypeProcess     EventType = "process"
+	EventTypeFile        EventType = "file"
+	EventTypeAuth        EventType = "auth"
+	EventTypeJournal     EventType = "journal"
+	EventTypePersistence EventType = "persistence"
 )
 
 // Severity représente la sévérité d'un événement
@@ -50,42 +53,213 @@ type SystemEvent struct {
 
 // NetworkEvent représente un événement réseau
 type NetworkEvent struct {
-	Protocol      string `json:"protocol"`
-	SourceIP      string `json:"source_ip"`
-	SourcePort    int    `json:"source_port"`
-	DestIP        string `json:"dest_ip"`
-	DestPort      int    `json:"dest_port"`
-	BytesSent     int64  `json:"bytes_sent"`
-	BytesReceived int64  `json:"bytes_received"`
-	State         string `json:"state"`
+	Action          string    `json:"action,omitempty"` // opened, closed
+	Protocol        string    `json:"protocol"`
+	SourceIP        string    `json:"source_ip"`
+	SourcePort      int       `json:"source_port"`
+	DestIP          string    `json:"dest_ip"`
+	DestPort        int       `json:"dest_port"`
+	BytesSent       int64     `json:"bytes_sent"`
+	BytesReceived   int64     `json:"bytes_received"`
+	State           string    `json:"state"`
+	PID             int       `json:"pid,omitempty"`
+	ProcessName     string    `json:"process_name,omitempty"`
+	ExecutablePath  string    `json:"executable_path,omitempty"`
+	FirstSeen       time.Time `json:"first_seen"`
+	DurationSeconds float64   `json:"duration_seconds,omitempty"`
+	Preexisting     bool      `json:"preexisting,omitempty"` // déjà ouverte au démarrage de l'agent
+}
+
+// ListeningPort représente un port en écoute sur l'hôte
+type ListeningPort struct {
+	Protocol       string `json:"protocol"`
+	IP             string `json:"ip"`
+	Port           int    `json:"port"`
+	PID            int    `json:"pid,omitempty"`
+	ProcessName    string `json:"process_name,omitempty"`
+	ExecutablePath string `json:"executable_path,omitempty"`
 }
 
 // ProcessEvent représente un événement de processus
 type ProcessEvent struct {
-	PID             int      `json:"pid"`
-	Name            string   `json:"name"`
-	CommandLine     string   `json:"command_line"`
-	ExecutablePath  string   `json:"executable_path"`
-	ParentPID       int      `json:"parent_pid"`
-	Username        string   `json:"username"`
-	CPUPercent      float64  `json:"cpu_percent"`
-	MemoryPercent   float64  `json:"memory_percent"`
-	MemoryBytes     uint64   `json:"memory_bytes"`
-	CreateTime      int64    `json:"create_time"`
-	NumThreads      int32    `json:"num_threads"`
-	Status          string   `json:"status"`
-	OpenFiles       []string `json:"open_files,omitempty"`
-	Connections     int      `json:"connections"`
+	Action          string            `json:"action,omitempty"` // start, exit, inventory
+	PID             int               `json:"pid"`
+	Name            string            `json:"name"`
+	CommandLine     string            `json:"command_line"`
+	ExecutablePath  string            `json:"executable_path"`
+	ExecutableHash  string            `json:"executable_hash,omitempty"` // SHA-256
+	ExecutableMD5   string            `json:"executable_md5,omitempty"`
+	ExecutableSHA1  string            `json:"executable_sha1,omitempty"`
+	Executable      *ExecutableInfo   `json:"executable,omitempty"`
+	ParentPID       int               `json:"parent_pid"`
+	ParentChain     []ProcessAncestor `json:"parent_chain,omitempty"`
+	Username        string            `json:"username"`
+	CPUPercent      float64           `json:"cpu_percent"`
+	MemoryPercent   float64           `json:"memory_percent"`
+	MemoryBytes     uint64            `json:"memory_bytes"`
+	CreateTime      int64             `json:"create_time"`
+	LifetimeSeconds float64           `json:"lifetime_seconds,omitempty"`
+	NumThreads      int32             `json:"num_threads"`
+	Status          string            `json:"status"`
+	OpenFiles       []string          `json:"open_files,omitempty"`
+	Connections     int               `json:"connections"`
+}
+
+// ExecutableInfo décrit le binaire d'un processus : format ELF et paquet
+// qui l'a installé
+type ExecutableInfo struct {
+	Size            int64     `json:"size"`
+	ModTime         time.Time `json:"mtime"`
+	Format          string    `json:"format,omitempty"`          // elf
+	Machine         string    `json:"machine,omitempty"`         // x86_64, aarch64...
+	BuildID         string    `json:"build_id,omitempty"`        // NT_GNU_BUILD_ID
+	Interpreter     string    `json:"interpreter,omitempty"`     // chargeur dynamique (PT_INTERP)
+	Linking         string    `json:"linking,omitempty"`         // static, dynamic
+	Stripped        bool      `json:"stripped,omitempty"`        // sans table des symboles
+	PackageManager  string    `json:"package_manager,omitempty"` // dpkg, rpm
+	Package         string    `json:"package,omitempty"`
+	PackageVersion  string    `json:"package_version,omitempty"`
+	PackageModified bool      `json:"package_modified,omitempty"` // contenu différent de celui livré par le paquet (dpkg)
+}
+
+// ProcessAncestor représente un parent dans la chaîne d'ascendance d'un processus
+type ProcessAncestor struct {
+	PID            int    `json:"pid"`
+	Name           string `json:"name"`
+	ExecutablePath string `json:"executable_path,omitempty"`
 }
 
-// AgentInfo représente les informations de l'agent
+// AgentInfo représente les informations de l'agent, publiées dans chaque
+// heartbeat (raw_data.agent) pour l'inventaire des agents
 type AgentInfo struct {
-	AgentID       string    `json:"agent_id"`
-	Hostname      string    `json:"hostname"`
-	IPAddress     string    `json:"ip_address"`
-	OSType        string    `json:"os_type"`
-	OSVersion     string    `json:"os_version"`
-	AgentVersion  string    `json:"agent_version"`
-	Status        string    `json:"status"`
-	LastHeartbeat time.Time `json:"last_heartbeat"`
+	AgentID           string    `json:"agent_id"`
+	Hostname          string    `json:"hostname"`
+	IPAddress         string    `json:"ip_address"`
+	OSType            string    `json:"os_type"`
+	OSVersion         string    `json:"os_version"`
+	KernelVersion     string    `json:"kernel_version"`
+	AgentVersion      string    `json:"agent_version"`
+	Status            string    `json:"status"`
+	LastHeartbeat     time.Time `json:"last_heartbeat"`
+	HeartbeatInterval float64   `json:"heartbeat_interval_seconds"`
+	Collectors        []string  `json:"collectors"`
+	EventRate         float64   `json:"event_rate"` // événements collectés par minute depuis le heartbeat précédent
+}
+
+// RejectedEvent représente un événement que l'agent n'a pas pu envoyer
+// sur le topic principal et qu'il route vers le topic dead-letter
+type RejectedEvent struct {
+	RejectedAt      time.Time `json:"rejected_at"`
+	Stage           string    `json:"stage"`
+	Reason          string    `json:"reason"`
+	Payload         string    `json:"payload"`
+	AgentID         string    `json:"agent_id,omitempty"`
+	SourceTopic     string    `json:"source_topic"`
+	SourcePartition int       `json:"source_partition"` // -1 : jamais écrit dans Kafka
+	SourceOffset    int64     `json:"source_offset"`    // -1 : jamais écrit dans Kafka
+}
+
+
+// FileEvent représente une modification détectée sur un fichier surveillé
+type FileEvent struct {
+	Path      string `json:"path"`
+	Action    string `json:"action"` // create, modify, delete, chmod, chown
+	OldHash   string `json:"old_hash,omitempty"`
+	NewHash   string `json:"new_hash,omitempty"`
+	Size      int64  `json:"size"`
+	Owner     string `json:"owner,omitempty"`
+	Group     string `json:"group,omitempty"`
+	OldOwner  string `json:"old_owner,omitempty"`
+	OldGroup  string `json:"old_group,omitempty"`
+	Mode      string `json:"mode,omitempty"`
+	OldMode   string `json:"old_mode,omitempty"`
+	Sensitive bool   `json:"sensitive"`
+}
+
+// AuthEvent représente une activité d'authentification lue dans les journaux
+// du système (sshd, sudo, su, PAM)
+type AuthEvent struct {
+	Action       string `json:"action"`  // login, invalid_user, sudo, su, session_opened, session_closed, auth_failure
+	Outcome      string `json:"outcome"` // success, failure
+	Service      string `json:"service"` // programme ou service PAM à l'origine de la ligne
+	User         string `json:"user,omitempty"`
+	TargetUser   string `json:"target_user,omitempty"` // sudo, su : utilisateur endossé
+	SourceIP     string `json:"source_ip,omitempty"`
+	SourcePort   int    `json:"source_port,omitempty"`
+	Method       string `json:"method,omitempty"` // password, publickey, keyboard-interactive...
+	Command      string `json:"command,omitempty"`
+	WorkingDir   string `json:"working_dir,omitempty"`
+	TTY          string `json:"tty,omitempty"`
+	Reason       string `json:"reason,omitempty"`        // motif d'échec
+	FailureCount int    `json:"failure_count,omitempty"` // échecs récents de la même source
+	LogFile      string `json:"log_file"`
+	Message      string `json:"message"` // ligne d'origine
+}
+
+// JournalEvent représente une entrée du journal systemd
+type JournalEvent struct {
+	Message     string `json:"message"`
+	Priority    int    `json:"priority"`             // 0 (emerg) à 7 (debug)
+	Identifier  string `json:"identifier,omitempty"` // SYSLOG_IDENTIFIER
+	Unit        string `json:"unit,omitempty"`       // _SYSTEMD_UNIT
+	PID         int    `json:"pid,omitempty"`
+	UID         *int   `json:"uid,omitempty"` // absent pour les messages du noyau
+	Comm        string `json:"comm,omitempty"`
+	Executable  string `json:"executable_path,omitempty"` // _EXE
+	CommandLine string `json:"command_line,omitempty"`
+	Transport   string `json:"transport,omitempty"` // journal, syslog, stdout, kernel...
+	BootID      string `json:"boot_id,omitempty"`
+	Cursor      string `json:"cursor"`
+}
+
+// AuditEvent représente le contexte d'un événement du sous-système d'audit
+// Linux (raw_data.audit), à côté de sa forme normalisée (process, network, file)
+type AuditEvent struct {
+	Source     string      `json:"source"` // netlink, file
+	Serial     uint64      `json:"serial"`
+	Syscall    string      `json:"syscall"`
+	Success    bool        `json:"success"`
+	Exit       int64       `json:"exit"`
+	Key        string      `json:"key,omitempty"`  // clé de la règle déclenchée
+	AUID       *int        `json:"auid,omitempty"` // utilisateur de login, absent s'il n'est pas défini
+	AuditUser  string      `json:"audit_user,omitempty"`
+	Session    *int        `json:"session,omitempty"`
+	UID        int         `json:"uid"`
+	EUID       int         `json:"euid"`
+	TTY        string      `json:"tty,omitempty"`
+	WorkingDir string      `json:"working_dir,omitempty"`
+	Paths      []AuditPath `json:"paths,omitempty"`
+}
+
+// AuditPath représente un chemin manipulé par l'appel système audité
+type AuditPath struct {
+	Name     string `json:"name"`
+	NameType string `json:"name_type,omitempty"` // NORMAL, CREATE, DELETE, PARENT...
+	Inode    uint64 `json:"inode,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+}
+
+// PersistenceEvent représente l'ajout, la modification ou la suppression d'un
+// mécanisme de persistance (crontab, unité systemd, script de démarrage,
+// profil shell, clés SSH autorisées)
+type PersistenceEvent struct {
+	Action       string   `json:"action"`    // added, changed, removed
+	Mechanism    string   `json:"mechanism"` // cron, systemd, rc_scripts, shell_profile, authorized_keys
+	Path         string   `json:"path"`
+	User         string   `json:"user,omitempty"` // compte dont le répertoire personnel contient l'entrée
+	Owner        string   `json:"owner,omitempty"`
+	Group        string   `json:"group,omitempty"`
+	OldOwner     string   `json:"old_owner,omitempty"`
+	Mode         string   `json:"mode,omitempty"`
+	OldMode      string   `json:"old_mode,omitempty"`
+	Size         int64    `json:"size"`
+	Hash         string   `json:"hash,omitempty"`
+	OldHash      string   `json:"old_hash,omitempty"`
+	Target       string   `json:"target,omitempty"` // cible d'un lien symbolique (unité activée)
+	OldTarget    string   `json:"old_target,omitempty"`
+	Content      string   `json:"content,omitempty"` // contenu complet ; pour une suppression, le dernier connu
+	Truncated    bool     `json:"truncated,omitempty"`
+	AddedLines   []string `json:"added_lines,omitempty"`
+	RemovedLines []string `json:"removed_lines,omitempty"`
+	Techniques   []string `json:"techniques"` // techniques MITRE ATT&CK (T1053.003...)
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/config"
+	"github.com/luigi/xdr-platform/agent/identity"
+	"github.com/luigi/xdr-platform/agent/shipper"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// agentRuntime regroupe ce que le rechargement de la configuration modifie
+type agentRuntime struct {
+	cfg             *config.Config
+	state           *identity.State
+	shipper         *shipper.ReloadableShipper
+	scheduler       *Scheduler
+	heartbeat       *Heartbeat
+	heartbeatTicker *time.Ticker
+	logger          *utils.Logger
+}
+
+// reload recharge la configuration (fichier et variables d'environnement)
+// sur SIGHUP. Le rechargement est complet ou n'a pas lieu : une configuration
+// invalide ou des sorties impossibles à créer laissent l'agent sur sa
+// configuration courante. Les événements en spool sont conservés.
+func (a *agentRuntime) reload() {
+	a.logger.Info("Reloading configuration...")
+
+	next, err := config.LoadConfig()
+	if err != nil {
+		a.logger.Error("Configuration reload failed, keeping current configuration: %v", err)
+		return
+	}
+	next.ApplyIdentity(a.state)
+	if err := next.Validate(); err != nil {
+		a.logger.Error("Invalid configuration, keeping current configuration: %v", err)
+		return
+	}
+
+	// L'identité de l'agent est chargée une seule fois, au démarrage
+	if next.StateFile != a.cfg.StateFile {
+		a.logger.Error("state_file cannot change without a restart, keeping %s", a.cfg.StateFile)
+		next.StateFile = a.cfg.StateFile
+	}
+
+	if next.OutputsChanged(a.cfg) {
+		current := a.cfg
+		err := a.shipper.Reload(
+			func() (shipper.Shipper, error) { return newShipper(next, a.logger) },
+			func() (shipper.Shipper, error) { return newShipper(current, a.logger) },
+		)
+		if err != nil {
+			a.logger.Error("Failed to reload outputs, keeping current configuration: %v", err)
+			return
+		}
+		useStderrForStdout(next, a.logger)
+		a.logger.Info("Outputs reloaded: %v", next.Outputs)
+	}
+
+	a.scheduler.Apply(next)
+	a.heartbeat.Update(next, a.scheduler.Names())
+	if next.HeartbeatInterval != a.cfg.HeartbeatInterval {
+		a.heartbeatTicker.Reset(next.HeartbeatInterval)
+	}
+
+	a.cfg = next
+	a.logger.Info("Configuration reloaded: %s", next.String())
+}
+
+// useStderrForStdout passe les logs sur stderr si la sortie stdout est active
+func useStderrForStdout(cfg *config.Config, logger *utils.Logger) {
+	for _, output := range cfg.Outputs {
+		if output == "stdout" {
+			logger.UseStderr()
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/collectors"
+	"github.com/luigi/xdr-platform/agent/config"
+	"github.com/luigi/xdr-platform/agent/shipper"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// collectorKind décrit comment créer et reconfigurer un type de collecteur
+type collectorKind struct {
+	name     string
+	settings func(c *config.Collectors) config.CollectorConfig
+	create   func(cfg *config.Config, logger *utils.Logger) (Collector, error)
+	// configure applique les réglages de cfg au collecteur ; false s'il doit
+	// être recréé pour les prendre en compte
+	configure func(collector Collector, previous, cfg *config.Config) bool
+}
+
+// collectorKinds liste les collecteurs connus, dans l'ordre de démarrage
+var collectorKinds = []collectorKind{
+	{
+		name:     "system",
+		settings: func(c *config.Collectors) config.CollectorConfig { return c.System.CollectorConfig },
+		create: func(cfg *config.Config, logger *utils.Logger) (Collector, error) {
+			return collectors.NewSystemCollector(logger, cfg.AgentID, cfg.Hostname, cfg.Collectors.System.SystemOptions), nil
+		},
+		configure: func(collector Collector, previous, cfg *config.Config) bool {
+			collector.(*collectors.SystemCollector).Configure(cfg.Collectors.System.SystemOptions)
+			return true
+		},
+	},
+	{
+		name:     "network",
+		settings: func(c *config.Collectors) config.CollectorConfig { return c.Network.CollectorConfig },
+		create: func(cfg *config.Config, logger *utils.Logger) (Collector, error) {
+			return collectors.NewNetworkCollector(logger, cfg.AgentID, cfg.Hostname, cfg.Collectors.Network.NetworkOptions), nil
+		},
+		configure: func(collector Collector, previous, cfg *config.Config) bool {
+			collector.(*collectors.NetworkCollector).Configure(cfg.Collectors.Network.NetworkOptions)
+			return true
+		},
+	},
+	{
+		name:     "process",
+		settings: func(c *config.Collectors) config.CollectorConfig { return c.Process.CollectorConfig },
+		create: func(cfg *config.Config, logger *utils.Logger) (Collector, error) {
+			return collectors.NewProcessCollector(logger, cfg.AgentID, cfg.Hostname, cfg.Collectors.Process.ProcessOptions), nil
+		},
+		configure: func(collector Collector, previous, cfg *config.Config) bool {
+			collector.(*collectors.ProcessCollector).Configure(cfg.Collectors.Process.ProcessOptions)
+			return true
+		},
+	},
+	{
+		name:     "file",
+		settings: func(c *config.Collectors) config.CollectorConfig { return c.File.CollectorConfig },
+		create: func(cfg *config.Config, logger *utils.Logger) (Collector, error) {
+			return collectors.NewFileCollector(logger, cfg.AgentID, cfg.Hostname, cfg.Collectors.File.FileOptions)
+		},
+		// Les surveillances inotify et la baseline dépendent des chemins
+		configure: func(collector Collector, previous, cfg *config.Config) bool {
+			return reflect.DeepEqual(previous.Collectors.File.FileOptions, cfg.Collectors.File.FileOptions)
+		},
+	},
+	{
+		name:     "auth",
+		settings: func(c *config.Collectors) config.CollectorConfig { return c.Auth.CollectorConfig },
+		create: func(cfg *config.Config, logger *utils.Logger) (Collector, error) {
+			return collectors.NewAuthCollector(logger, cfg.AgentID, cfg.Hostname, cfg.Collectors.Auth.AuthOptions), nil
+		},
+		configure: func(collector Collector, previous, cfg *config.Config) bool {
+			collector.(*collectors.AuthCollector).Configure(cfg.Collectors.Auth.AuthOptions)
+			return true
+		},
+	},
+	{
+		name:     "journal",
+		settings: func(c *config.Collectors) config.CollectorConfig { return c.Journal.CollectorConfig },
+		create: func(cfg *config.Config, logger *utils.Logger) (Collector, error) {
+			return collectors.NewJournalCollector(logger, cfg.AgentID, cfg.Hostname, cfg.Collectors.Journal.JournalOptions)
+		},
+		configure: func(collector Collector, previous, cfg *config.Config) bool {
+			collector.(*collectors.JournalCollector).Configure(cfg.Collectors.Journal.JournalOptions)
+			return true
+		},
+	},
+	{
+		name:     "audit",
+		settings: func(c *config.Collectors) config.CollectorConfig { return c.Audit.CollectorConfig },
+		create: func(cfg *config.Config, logger *utils.Logger) (Collector, error) {
+			return collectors.NewAuditCollector(logger, cfg.AgentID, cfg.Hostname, cfg.Collectors.Audit.AuditOptions)
+		},
+		// Toujours reconfiguré sur place : un second collecteur retirerait
+		// en s'arrêtant les règles d'audit partagées avec le nouveau
+		configure: func(collector Collector, previous, cfg *config.Config) bool {
+			collector.(*collectors.AuditCollector).Configure(cfg.Collectors.Audit.AuditOptions)
+			return true
+		},
+	},
+	{
+		name:     "persistence",
+		settings: func(c *config.Collectors) config.CollectorConfig { return c.Persistence.CollectorConfig },
+		create: func(cfg *config.Config, logger *utils.Logger) (Collector, error) {
+			return collectors.NewPersistenceCollector(logger, cfg.AgentID, cfg.Hostname, cfg.Collectors.Persistence.PersistenceOptions), nil
+		},
+		configure: func(collector Collector, previous, cfg *config.Config) bool {
+			collector.(*collectors.PersistenceCollector).Configure(cfg.Collectors.Persistence.PersistenceOptions)
+			return true
+		},
+	},
+}
+
+// scheduledCollector est un collecteur exécuté par sa propre boucle
+type scheduledCollector struct {
+	kind      collectorKind
+	collector Collector
+	interval  time.Duration
+	reset     chan time.Duration
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// Scheduler exécute chaque collecteur activé à son propre intervalle et
+// applique les changements de configuration : un collecteur conservé est
+// reconfiguré sans perdre son état, un collecteur arrêté ou recréé fait une
+// dernière collecte pour envoyer les événements en attente.
+type Scheduler struct {
+	shipper   shipper.Shipper
+	heartbeat *Heartbeat
+	logger    *utils.Logger
+
+	cfg     *config.Config
+	running map[string]*scheduledCollector
+}
+
+// NewScheduler crée un scheduler sans collecteur ; Apply les démarre
+func NewScheduler(eventShipper shipper.Shipper, heartbeat *Heartbeat, logger *utils.Logger) *Scheduler {
+	return &Scheduler{
+		shipper:   eventShipper,
+		heartbeat: heartbeat,
+		logger:    logger,
+		running:   make(map[string]*scheduledCollector),
+	}
+}
+
+// Apply démarre, reconfigure ou arrête les collecteurs selon cfg. Il n'est
+// appelé que depuis la boucle principale.
+func (s *Scheduler) Apply(cfg *config.Config) {
+	for _, kind := range collectorKinds {
+		settings := kind.settings(&cfg.Collectors)
+		interval := settings.IntervalOr(cfg.CollectionInterval)
+		current := s.running[kind.name]
+
+		switch {
+		case !settings.Enabled:
+			if current != nil {
+				s.stopCollector(current, true)
+				delete(s.running, kind.name)
+				s.logger.Info("%s collector disabled", kind.name)
+			}
+
+		case current == nil:
+			s.startCollector(kind, cfg, interval)
+
+		case !kind.configure(current.collector, s.cfg, cfg):
+			// Le nouveau collecteur démarre avant l'arrêt de l'ancien pour
+			// ne pas manquer de changement ; en cas d'échec l'ancien continue
+			if s.startCollector(kind, cfg, interval) {
+				s.stopCollector(current, true)
+			}
+
+		case interval != current.interval:
+			// Seule la boucle principale envoie : après la purge, il y a de la place
+			select {
+			case <-current.reset:
+			default:
+			}
+			current.reset <- interval
+			current.interval = interval
+			s.logger.Info("%s collector now collects every %s", kind.name, interval)
+		}
+	}
+	s.cfg = cfg
+}
+
+// Names retourne les noms des collecteurs en cours d'exécution
+func (s *Scheduler) Names() []string {
+	var names []string
+	for _, kind := range collectorKinds {
+		if _, ok := s.running[kind.name]; ok {
+			names = append(names, kind.name)
+		}
+	}
+	return names
+}
+
+// Stop arrête tous les collecteurs
+func (s *Scheduler) Stop() {
+	for name, sc := range s.running {
+		s.stopCollector(sc, false)
+		delete(s.running, name)
+	}
+}
+
+// startCollector crée un collecteur et lance sa boucle
+func (s *Scheduler) startCollector(kind collectorKind, cfg *config.Config, interval time.Duration) bool {
+	collector, err := kind.create(cfg, s.logger)
+	if err != nil {
+		s.logger.Error("Failed to start %s collector: %v", kind.name, err)
+		return false
+	}
+
+	sc := &scheduledCollector{
+		kind:      kind,
+		collector: collector,
+		interval:  interval,
+		reset:     make(chan time.Duration, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	s.running[kind.name] = sc
+	go s.run(sc, interval)
+
+	s.logger.Info("%s collector enabled, collecting every %s", kind.name, interval)
+	return true
+}
+
+// stopCollector arrête la boucle d'un collecteur après la collecte en cours,
+// fait une dernière collecte si flush est vrai, puis libère le collecteur
+func (s *Scheduler) stopCollector(sc *scheduledCollector, flush bool) {
+	close(sc.stop)
+	<-sc.done
+
+	if flush {
+		collectAndShip(sc.kind.name, sc.collector, s.shipper, s.heartbeat, s.logger)
+	}
+	if closer, ok := sc.collector.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			s.logger.Error("Failed to close %s collector: %v", sc.kind.name, err)
+		}
+	}
+}
+
+// run exécute les collectes d'un collecteur à son intervalle
+func (s *Scheduler) run(sc *scheduledCollector, interval time.Duration) {
+	defer close(sc.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sc.stop:
+			return
+		case interval = <-sc.reset:
+			ticker.Reset(interval)
+		case <-ticker.C:
+			collectAndShip(sc.kind.name, sc.collector, s.shipper, s.heartbeat, s.logger)
+		}
+	}
+}
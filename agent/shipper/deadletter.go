@@ -0,0 +1,103 @@
+package shipper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// DeadLetterFile conserve sur disque les événements que les sorties sans
+// topic dead-letter (http, syslog, file, stdout) ne peuvent pas sérialiser.
+// Chaque ligne a le format d'un message du topic dead-letter : republiée sur
+// ce topic, elle est enregistrée dans rejected_events et peut être rejouée.
+type DeadLetterFile struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64 // au-delà, les nouvelles entrées sont abandonnées
+	logger   *utils.Logger
+}
+
+// NewDeadLetterFile crée le fichier dead-letter ; il n'est ouvert qu'à la
+// première entrée. maxBytes <= 0 désactive la limite.
+func NewDeadLetterFile(path string, maxBytes int64, logger *utils.Logger) *DeadLetterFile {
+	return &DeadLetterFile{path: path, maxBytes: maxBytes, logger: logger}
+}
+
+// Write ajoute les entrées au fichier. Un échec est journalisé : l'envoi des
+// autres événements du lot ne doit pas en dépendre. Sur un DeadLetterFile
+// nil, les entrées sont perdues.
+func (d *DeadLetterFile) Write(rejected []*models.RejectedEvent) {
+	if len(rejected) == 0 || d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.write(rejected); err != nil {
+		d.logger.Error("Failed to write %d rejected events to %s, dropping them: %v", len(rejected), d.path, err)
+		return
+	}
+	d.logger.Info("Wrote %d rejected events to dead-letter file %s", len(rejected), d.path)
+}
+
+func (d *DeadLetterFile) write(rejected []*models.RejectedEvent) error {
+	var data []byte
+	for _, r := range rejected {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to marshal rejected event: %w", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+	file, err := os.OpenFile(d.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	defer file.Close()
+
+	if d.maxBytes > 0 {
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to stat dead-letter file: %w", err)
+		}
+		if info.Size()+int64(len(data)) > d.maxBytes {
+			return fmt.Errorf("dead-letter file would exceed %d bytes", d.maxBytes)
+		}
+	}
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("failed to write dead-letter file: %w", err)
+	}
+	return nil
+}
+
+// marshalRejected construit l'entrée dead-letter d'un événement que
+// json.Marshal refuse. Le payload reste du JSON (valeurs invalides remplacées
+// par null) pour que l'événement puisse être corrigé et rejoué.
+func marshalRejected(event *models.Event, reason error, sourceTopic string, logger *utils.Logger) *models.RejectedEvent {
+	payload, err := marshalSanitized(event)
+	if err != nil {
+		logger.Error("Failed to marshal sanitized event: %v", err)
+	}
+	return &models.RejectedEvent{
+		RejectedAt:      time.Now(),
+		Stage:           "marshal",
+		Reason:          reason.Error(),
+		Payload:         string(payload),
+		AgentID:         event.AgentID,
+		SourceTopic:     sourceTopic,
+		SourcePartition: -1,
+		SourceOffset:    -1,
+	}
+}
@@ -0,0 +1,143 @@
+package shipper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// FileShipper écrit les événements en NDJSON dans un fichier local.
+// Quand le fichier dépasse maxBytes, il est renommé en path.1 (path.1 en
+// path.2, etc.) et seules maxBackups archives sont conservées.
+type FileShipper struct {
+	mu         sync.Mutex
+	path       string
+	maxBytes   int64
+	maxBackups int
+	file       *os.File
+	size       int64
+	deadLetter *DeadLetterFile
+	logger     *utils.Logger
+}
+
+// NewFileShipper ouvre (ou crée) le fichier de sortie. maxBytes <= 0
+// désactive la rotation.
+func NewFileShipper(path string, maxBytes int64, maxBackups int, deadLetter *DeadLetterFile, logger *utils.Logger) (*FileShipper, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file output path cannot be empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create file output directory: %w", err)
+	}
+
+	fs := &FileShipper{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+		deadLetter: deadLetter,
+		logger:     logger,
+	}
+	if err := fs.open(); err != nil {
+		return nil, err
+	}
+
+	logger.Info("File shipper initialized with path: %s", path)
+	return fs, nil
+}
+
+func (fs *FileShipper) open() error {
+	file, err := os.OpenFile(fs.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open file output: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat file output: %w", err)
+	}
+	fs.file = file
+	fs.size = info.Size()
+	return nil
+}
+
+// Ship ajoute les événements au fichier, une ligne JSON par événement
+func (fs *FileShipper) Ship(events []*models.Event) error {
+	records := marshalEvents(events, "file", fs.deadLetter, fs.logger)
+	if len(records) == 0 {
+		return nil
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.file == nil {
+		if err := fs.open(); err != nil {
+			return err
+		}
+	}
+
+	for _, record := range records {
+		line := append(record, '\n')
+		if fs.maxBytes > 0 && fs.size > 0 && fs.size+int64(len(line)) > fs.maxBytes {
+			if err := fs.rotate(); err != nil {
+				return err
+			}
+		}
+		n, err := fs.file.Write(line)
+		fs.size += int64(n)
+		if err != nil {
+			return fmt.Errorf("failed to write file output: %w", err)
+		}
+	}
+
+	fs.logger.Debug("Wrote %d events to %s", len(records), fs.path)
+	return nil
+}
+
+// rotate décale les archives et rouvre un fichier vide
+func (fs *FileShipper) rotate() error {
+	if err := fs.file.Close(); err != nil {
+		fs.logger.Error("Failed to close file output before rotation: %v", err)
+	}
+	fs.file = nil
+
+	if fs.maxBackups <= 0 {
+		if err := os.Remove(fs.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove file output: %w", err)
+		}
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", fs.path, fs.maxBackups))
+		for i := fs.maxBackups - 1; i >= 1; i-- {
+			from := fmt.Sprintf("%s.%d", fs.path, i)
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", fs.path, i+1)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to rotate file output: %w", err)
+			}
+		}
+		if err := os.Rename(fs.path, fs.path+".1"); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate file output: %w", err)
+		}
+	}
+
+	return fs.open()
+}
+
+// SpoolDepth retourne 0 : la sortie fichier n'a pas de spool
+func (fs *FileShipper) SpoolDepth() int {
+	return 0
+}
+
+// Close ferme le fichier
+func (fs *FileShipper) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.file == nil {
+		return nil
+	}
+	err := fs.file.Close()
+	fs.file = nil
+	return err
+}
@@ -0,0 +1,262 @@
+package shipper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// HTTPOptions configure le shipper HTTP
+type HTTPOptions struct {
+	URL                string
+	APIKey             string // envoyée dans l'en-tête X-API-Key
+	CAFile             string // autorités de certification supplémentaires (PEM)
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+	Gzip               bool
+}
+
+// HTTPShipper envoie les événements par POST de lots NDJSON.
+// Comme pour Kafka, les lots refusés pour une raison temporaire (réseau,
+// 5xx, 429, authentification) sont conservés dans le spool.
+type HTTPShipper struct {
+	mu         sync.Mutex // sérialise les envois pour préserver l'ordre
+	client     *http.Client
+	options    HTTPOptions
+	spool      *Spool
+	deadLetter *DeadLetterFile // événements non sérialisables
+	logger     *utils.Logger
+	batchSize  int
+}
+
+// errPermanent signale un lot que le serveur refusera toujours (4xx) :
+// il est abandonné au lieu de bloquer le spool
+var errPermanent = errors.New("batch rejected by server")
+
+// errTooLarge signale un lot refusé pour sa taille (413) : il est renvoyé
+// en lots plus petits
+var errTooLarge = errors.New("batch too large for server")
+
+// NewHTTPShipper crée un shipper HTTP(S). batchSize borne le nombre
+// d'événements par requête ; spool peut être nil.
+func NewHTTPShipper(options HTTPOptions, batchSize int, spool *Spool, deadLetter *DeadLetterFile, logger *utils.Logger) (*HTTPShipper, error) {
+	endpoint, err := url.Parse(options.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid http output url %q", options.URL)
+	}
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("http batch size must be positive")
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: options.InsecureSkipVerify}
+	if options.CAFile != "" {
+		pem, err := os.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read http output CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", options.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+
+	logger.Info("HTTP shipper initialized with url: %s", endpoint.Redacted())
+
+	return &HTTPShipper{
+		client:     &http.Client{Transport: transport, Timeout: options.Timeout},
+		options:    options,
+		spool:      spool,
+		deadLetter: deadLetter,
+		logger:     logger,
+		batchSize:  batchSize,
+	}, nil
+}
+
+// Ship envoie un lot d'événements
+func (hs *HTTPShipper) Ship(events []*models.Event) error {
+	records := marshalEvents(events, "http", hs.deadLetter, hs.logger)
+	if len(records) == 0 {
+		return nil
+	}
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.spool == nil {
+		for start := 0; start < len(records); start += hs.batchSize {
+			if err := hs.send(records[start:min(start+hs.batchSize, len(records))]); err != nil {
+				return err
+			}
+		}
+		hs.logger.Info("Successfully shipped %d events over HTTP", len(records))
+		return nil
+	}
+
+	// Un backlog existe : les nouveaux événements passent derrière pour garder l'ordre
+	if hs.spool.Depth() > 0 {
+		if err := hs.spool.Append(records); err != nil {
+			return fmt.Errorf("failed to spool events: %w", err)
+		}
+		hs.drainSpool()
+		return nil
+	}
+
+	for start := 0; start < len(records); start += hs.batchSize {
+		batch := records[start:min(start+hs.batchSize, len(records))]
+		err := hs.send(batch)
+		if errors.Is(err, errPermanent) {
+			hs.logger.Error("Dropping %d events: %v", len(batch), err)
+			continue
+		}
+		if err != nil {
+			hs.logger.Error("HTTP output unavailable, spooling %d events: %v", len(records)-start, err)
+			if err := hs.spool.Append(records[start:]); err != nil {
+				return fmt.Errorf("failed to spool events: %w", err)
+			}
+			return nil
+		}
+	}
+
+	hs.logger.Info("Successfully shipped %d events over HTTP", len(records))
+	return nil
+}
+
+// drainSpool renvoie le backlog du spool jusqu'au premier échec temporaire
+func (hs *HTTPShipper) drainSpool() {
+	before := hs.spool.Depth()
+
+	err := hs.spool.Drain(hs.batchSize, func(records [][]byte) error {
+		err := hs.send(records)
+		if errors.Is(err, errPermanent) {
+			hs.logger.Error("Dropping %d spooled events: %v", len(records), err)
+			return nil
+		}
+		return err
+	})
+
+	after := hs.spool.Depth()
+	if err != nil {
+		hs.logger.Error("HTTP output still unavailable, %d events remain spooled: %v", after, err)
+	}
+	if before > after {
+		hs.logger.Info("Drained %d spooled events over HTTP", before-after)
+	}
+}
+
+// send envoie un lot. Un lot refusé pour sa taille est coupé en deux et
+// chaque moitié renvoyée ; un événement seul trop volumineux est abandonné.
+// Si la seconde moitié échoue, la première sera renvoyée avec elle.
+func (hs *HTTPShipper) send(records [][]byte) error {
+	err := hs.post(records)
+	if !errors.Is(err, errTooLarge) {
+		return err
+	}
+	if len(records) == 1 {
+		return fmt.Errorf("%w: %v", errPermanent, err)
+	}
+
+	half := len(records) / 2
+	hs.logger.Debug("HTTP output refused %d events as too large, splitting the batch", len(records))
+	for _, part := range [][][]byte{records[:half], records[half:]} {
+		err := hs.send(part)
+		if errors.Is(err, errPermanent) {
+			hs.logger.Error("Dropping %d events: %v", len(part), err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// post envoie un lot NDJSON
+func (hs *HTTPShipper) post(records [][]byte) error {
+	var body bytes.Buffer
+	var w io.Writer = &body
+	var zw *gzip.Writer
+	if hs.options.Gzip {
+		zw = gzip.NewWriter(&body)
+		w = zw
+	}
+	for _, record := range records {
+		w.Write(record)
+		w.Write([]byte{'\n'})
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("failed to compress batch: %w", err)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, hs.options.URL, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if zw != nil {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if hs.options.APIKey != "" {
+		req.Header.Set("X-API-Key", hs.options.APIKey)
+	}
+
+	resp, err := hs.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post events: %w", err)
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestEntityTooLarge:
+		return fmt.Errorf("%w: %s: %s", errTooLarge, resp.Status, bytes.TrimSpace(detail))
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("http output returned %s: %s", resp.Status, bytes.TrimSpace(detail))
+	default:
+		return fmt.Errorf("%w: %s: %s", errPermanent, resp.Status, bytes.TrimSpace(detail))
+	}
+}
+
+// SpoolDepth retourne le nombre d'événements en attente dans le spool
+func (hs *HTTPShipper) SpoolDepth() int {
+	if hs.spool == nil {
+		return 0
+	}
+	return hs.spool.Depth()
+}
+
+// Close ferme le spool ; son contenu reste sur disque
+func (hs *HTTPShipper) Close() error {
+	hs.client.CloseIdleConnections()
+	if hs.spool != nil {
+		return hs.spool.Close()
+	}
+	return nil
+}
@@ -4,38 +4,115 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/segmentio/kafka-go"
 	"github.com/luigi/xdr-platform/agent/models"
 	"github.com/luigi/xdr-platform/agent/utils"
 )
 
-// KafkaShipper envoie les événements vers Kafka
+// KafkaShipper envoie les événements vers Kafka.
+// Quand Kafka est injoignable, les événements sont conservés dans le spool
+// disque et renvoyés dans l'ordre dès que la connexion revient.
 type KafkaShipper struct {
-	writer *kafka.Writer
-	logger *utils.Logger
-	topic  string
+	mu        sync.Mutex // sérialise les envois pour préserver l'ordre
+	writer    *kafka.Writer
+	dlqWriter *kafka.Writer
+	spool     *Spool
+	logger    *utils.Logger
+	topic     string
+	dlqTopic  string
+	batchSize int
 }
 
-// NewKafkaShipper crée un nouveau shipper Kafka
-func NewKafkaShipper(brokers []string, topic string, logger *utils.Logger) (*KafkaShipper, error) {
-	if len(brokers) == 0 {
+// NewKafkaShipper crée un nouveau shipper Kafka ; spool peut être nil.
+// En mode asynchrone, les lots dont l'envoi échoue sont remis dans le spool
+// à leur échec : l'ordre des événements n'est alors plus garanti.
+func NewKafkaShipper(options KafkaOptions, spool *Spool, logger *utils.Logger) (*KafkaShipper, error) {
+	if len(options.Brokers) == 0 {
 		return nil, fmt.Errorf("kafka brokers list is empty")
 	}
+	if options.BatchSize <= 0 {
+		return nil, fmt.Errorf("kafka batch size must be positive")
+	}
+
+	transport, err := kafkaTransport(options.Security)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := kafkaCompression(options.Compression)
+	if err != nil {
+		return nil, err
+	}
+	acks, err := kafkaRequiredAcks(options.RequiredAcks)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KafkaShipper{
+		spool:     spool,
+		logger:    logger,
+		topic:     options.Topic,
+		dlqTopic:  options.DLQTopic,
+		batchSize: options.BatchSize,
+	}
 
-	writer := &kafka.Writer{
-		Addr:     kafka.TCP(brokers...),
-		Topic:    topic,
-		Balancer: &kafka.LeastBytes{},
+	ks.writer = &kafka.Writer{
+		Addr:         kafka.TCP(options.Brokers...),
+		Topic:        options.Topic,
+		Balancer:     &kafka.LeastBytes{},
+		Transport:    transport,
+		Compression:  compression,
+		RequiredAcks: acks,
+		BatchSize:    options.BatchSize,
+		BatchTimeout: options.BatchTimeout,
+		Async:        options.Async,
 	}
+	if options.Async {
+		ks.writer.Completion = ks.completeAsync
+	}
+
+	// Les événements rejetés restent envoyés de façon synchrone
+	ks.dlqWriter = &kafka.Writer{
+		Addr:         kafka.TCP(options.Brokers...),
+		Topic:        options.DLQTopic,
+		Balancer:     &kafka.LeastBytes{},
+		Transport:    transport,
+		Compression:  compression,
+		RequiredAcks: acks,
+	}
+
+	logger.Info("Kafka shipper initialized with brokers: %v, topic: %s, dead-letter topic: %s, tls: %t, sasl: %s, compression: %s, acks: %s, async: %t",
+		options.Brokers, options.Topic, options.DLQTopic, options.Security.TLS, valueOrNone(options.Security.SASLMechanism),
+		valueOrNone(options.Compression), valueOrDefault(options.RequiredAcks, "all"), options.Async)
+
+	return ks, nil
+}
 
-	logger.Info("Kafka shipper initialized with brokers: %v, topic: %s", brokers, topic)
+// completeAsync remet dans le spool les messages dont l'envoi asynchrone a échoué
+func (ks *KafkaShipper) completeAsync(messages []kafka.Message, err error) {
+	if err == nil {
+		return
+	}
+	if ks.spool == nil {
+		ks.logger.Error("Failed to deliver %d events to Kafka topic '%s': %v", len(messages), ks.topic, err)
+		return
+	}
+	ks.logger.Error("Failed to deliver %d events to Kafka, spooling them: %v", len(messages), err)
+	if err := ks.spool.Append(messageValues(messages)); err != nil {
+		ks.logger.Error("Failed to spool %d undelivered events: %v", len(messages), err)
+	}
+}
 
-	return &KafkaShipper{
-		writer: writer,
-		logger: logger,
-		topic:  topic,
-	}, nil
+func valueOrNone(value string) string {
+	return valueOrDefault(value, "none")
+}
+
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
 }
 
 // Ship envoie un lot d'événements vers Kafka
@@ -44,13 +121,18 @@ func (ks *KafkaShipper) Ship(events []*models.Event) error {
 		return nil
 	}
 
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
 	messages := make([]kafka.Message, 0, len(events))
+	var rejected []*models.RejectedEvent
 
 	for _, event := range events {
 		// Sérialiser l'événement en JSON
 		data, err := json.Marshal(event)
 		if err != nil {
-			ks.logger.Error("Failed to marshal event: %v", err)
+			ks.logger.Error("Failed to marshal event, routing to dead-letter topic: %v", err)
+			rejected = append(rejected, marshalRejected(event, err, ks.topic, ks.logger))
 			continue
 		}
 
@@ -63,21 +145,154 @@ func (ks *KafkaShipper) Ship(events []*models.Event) error {
 		messages = append(messages, message)
 	}
 
-	// Envoyer tous les messages en batch
-	err := ks.writer.WriteMessages(context.Background(), messages...)
-	if err != nil {
-		return fmt.Errorf("failed to write messages to kafka: %w", err)
+	ks.shipRejected(rejected)
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	if ks.spool == nil {
+		if err := ks.write(messages); err != nil {
+			return err
+		}
+		ks.logger.Info("Successfully shipped %d events to Kafka topic '%s'", len(messages), ks.topic)
+		return nil
+	}
+
+	// Un backlog existe : les nouveaux événements passent derrière pour garder l'ordre
+	if ks.spool.Depth() > 0 {
+		if err := ks.spool.Append(messageValues(messages)); err != nil {
+			return fmt.Errorf("failed to spool events: %w", err)
+		}
+		ks.drainSpool()
+		return nil
+	}
+
+	// Envoyer les messages par lots, le reste part dans le spool en cas d'échec
+	for start := 0; start < len(messages); start += ks.batchSize {
+		end := start + ks.batchSize
+		if end > len(messages) {
+			end = len(messages)
+		}
+
+		if err := ks.writer.WriteMessages(context.Background(), messages[start:end]...); err != nil {
+			ks.logger.Error("Kafka unavailable, spooling %d events: %v", len(messages)-start, err)
+			if err := ks.spool.Append(messageValues(messages[start:])); err != nil {
+				return fmt.Errorf("failed to spool events: %w", err)
+			}
+			return nil
+		}
 	}
 
 	ks.logger.Info("Successfully shipped %d events to Kafka topic '%s'", len(messages), ks.topic)
 	return nil
 }
 
-// Close ferme la connexion Kafka
+// SpoolDepth retourne le nombre d'événements en attente dans le spool
+func (ks *KafkaShipper) SpoolDepth() int {
+	if ks.spool == nil {
+		return 0
+	}
+	return ks.spool.Depth()
+}
+
+// write envoie les messages vers Kafka par lots de batchSize
+func (ks *KafkaShipper) write(messages []kafka.Message) error {
+	for start := 0; start < len(messages); start += ks.batchSize {
+		end := start + ks.batchSize
+		if end > len(messages) {
+			end = len(messages)
+		}
+
+		if err := ks.writer.WriteMessages(context.Background(), messages[start:end]...); err != nil {
+			return fmt.Errorf("failed to write messages to kafka: %w", err)
+		}
+	}
+	return nil
+}
+
+// drainSpool renvoie le backlog du spool vers Kafka jusqu'au premier échec
+func (ks *KafkaShipper) drainSpool() {
+	before := ks.spool.Depth()
+
+	err := ks.spool.Drain(ks.batchSize, func(records [][]byte) error {
+		messages := make([]kafka.Message, 0, len(records))
+		for _, record := range records {
+			// Retrouver la clé de partitionnement depuis l'événement sérialisé
+			var key struct {
+				AgentID string `json:"agent_id"`
+			}
+			json.Unmarshal(record, &key)
+
+			messages = append(messages, kafka.Message{
+				Key:   []byte(key.AgentID),
+				Value: record,
+			})
+		}
+		return ks.writer.WriteMessages(context.Background(), messages...)
+	})
+
+	after := ks.spool.Depth()
+	if err != nil {
+		ks.logger.Error("Kafka still unavailable, %d events remain spooled: %v", after, err)
+	}
+	if before > after {
+		ks.logger.Info("Drained %d spooled events to Kafka topic '%s'", before-after, ks.topic)
+	}
+}
+
+// messageValues extrait les événements sérialisés des messages Kafka
+func messageValues(messages []kafka.Message) [][]byte {
+	values := make([][]byte, len(messages))
+	for i, message := range messages {
+		values[i] = message.Value
+	}
+	return values
+}
+
+// shipRejected envoie les événements non sérialisables vers le topic dead-letter
+func (ks *KafkaShipper) shipRejected(rejected []*models.RejectedEvent) {
+	if len(rejected) == 0 {
+		return
+	}
+
+	messages := make([]kafka.Message, 0, len(rejected))
+	for _, r := range rejected {
+		data, err := json.Marshal(r)
+		if err != nil {
+			ks.logger.Error("Failed to marshal rejected event: %v", err)
+			continue
+		}
+		messages = append(messages, kafka.Message{
+			Key:   []byte(r.AgentID),
+			Value: data,
+		})
+	}
+
+	if err := ks.dlqWriter.WriteMessages(context.Background(), messages...); err != nil {
+		ks.logger.Error("Failed to write %d rejected events to dead-letter topic '%s': %v", len(messages), ks.dlqTopic, err)
+		return
+	}
+
+	ks.logger.Info("Routed %d rejected events to dead-letter topic '%s'", len(messages), ks.dlqTopic)
+}
+
+// Close ferme la connexion Kafka ; le spool reste sur disque. Les writers
+// sont fermés avant le spool : en mode asynchrone, les lots encore en vol
+// dont l'envoi échoue y sont remis.
 func (ks *KafkaShipper) Close() error {
+	var err error
 	if ks.writer != nil {
 		ks.logger.Info("Closing Kafka writer...")
-		return ks.writer.Close()
+		err = ks.writer.Close()
 	}
-	return nil
+	if ks.dlqWriter != nil {
+		ks.dlqWriter.Close()
+	}
+	if ks.spool != nil {
+		if spoolErr := ks.spool.Close(); spoolErr != nil {
+			ks.logger.Error("Failed to close spool: %v", spoolErr)
+		}
+	}
+	return err
 }
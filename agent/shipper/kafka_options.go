@@ -0,0 +1,135 @@
+package shipper
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// KafkaOptions configure le shipper Kafka
+type KafkaOptions struct {
+	Brokers      []string
+	Topic        string
+	DLQTopic     string
+	BatchSize    int           // messages par écriture (envoi et vidage du spool)
+	BatchTimeout time.Duration // délai maximal avant l'envoi d'un lot incomplet
+	Compression  string        // none, gzip, snappy, lz4, zstd
+	RequiredAcks string        // none, leader, all
+	Async        bool          // WriteMessages n'attend pas l'accusé de réception
+	Security     KafkaSecurity
+}
+
+// KafkaSecurity configure le chiffrement et l'authentification auprès des brokers
+type KafkaSecurity struct {
+	TLS                bool
+	CAFile             string // autorités de certification (PEM), sinon celles du système
+	CertFile           string // certificat client (TLS mutuel)
+	KeyFile            string
+	InsecureSkipVerify bool
+
+	SASLMechanism string // plain, scram-sha-256, scram-sha-512 ; vide : pas de SASL
+	SASLUsername  string
+	SASLPassword  string
+}
+
+// kafkaTransport construit le transport des writers Kafka (TLS et SASL)
+func kafkaTransport(security KafkaSecurity) (*kafka.Transport, error) {
+	tlsConfig, err := security.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	mechanism, err := security.saslMechanism()
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Transport{TLS: tlsConfig, SASL: mechanism}, nil
+}
+
+// tlsConfig retourne la configuration TLS, ou nil si TLS est désactivé
+func (s KafkaSecurity) tlsConfig() (*tls.Config, error) {
+	if !s.TLS {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: s.InsecureSkipVerify,
+	}
+
+	if s.CAFile != "" {
+		pem, err := os.ReadFile(s.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kafka CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", s.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if s.CertFile != "" || s.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kafka client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// saslMechanism retourne le mécanisme SASL, ou nil si SASL est désactivé
+func (s KafkaSecurity) saslMechanism() (sasl.Mechanism, error) {
+	switch s.SASLMechanism {
+	case "":
+		return nil, nil
+	case "plain":
+		return plain.Mechanism{Username: s.SASLUsername, Password: s.SASLPassword}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, s.SASLUsername, s.SASLPassword)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, s.SASLUsername, s.SASLPassword)
+	default:
+		return nil, fmt.Errorf("unknown kafka SASL mechanism %q", s.SASLMechanism)
+	}
+}
+
+// kafkaCompression convertit le nom d'un codec de compression
+func kafkaCompression(name string) (kafka.Compression, error) {
+	switch name {
+	case "", "none":
+		return 0, nil
+	case "gzip":
+		return kafka.Gzip, nil
+	case "snappy":
+		return kafka.Snappy, nil
+	case "lz4":
+		return kafka.Lz4, nil
+	case "zstd":
+		return kafka.Zstd, nil
+	default:
+		return 0, fmt.Errorf("unknown kafka compression codec %q", name)
+	}
+}
+
+// kafkaRequiredAcks convertit le niveau d'accusé de réception attendu
+func kafkaRequiredAcks(name string) (kafka.RequiredAcks, error) {
+	switch name {
+	case "none":
+		return kafka.RequireNone, nil
+	case "leader":
+		return kafka.RequireOne, nil
+	case "", "all":
+		return kafka.RequireAll, nil
+	default:
+		return 0, fmt.Errorf("unknown kafka required acks %q (expected none, leader or all)", name)
+	}
+}
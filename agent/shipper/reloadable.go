@@ -0,0 +1,72 @@
+package shipper
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// ReloadableShipper délègue à un shipper remplaçable à chaud, lors d'un
+// rechargement de la configuration
+type ReloadableShipper struct {
+	logger *utils.Logger
+
+	mu      sync.RWMutex
+	current Shipper
+}
+
+// NewReloadableShipper crée un shipper remplaçable autour de current
+func NewReloadableShipper(current Shipper, logger *utils.Logger) *ReloadableShipper {
+	return &ReloadableShipper{current: current, logger: logger}
+}
+
+// Ship envoie les événements au shipper courant
+func (rs *ReloadableShipper) Ship(events []*models.Event) error {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.current.Ship(events)
+}
+
+// SpoolDepth retourne la profondeur du spool du shipper courant
+func (rs *ReloadableShipper) SpoolDepth() int {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.current.SpoolDepth()
+}
+
+// Reload remplace le shipper courant. Les envois en cours se terminent, puis
+// l'ancien shipper est fermé avant la création du nouveau : les spools sont
+// vidés sur disque et rouverts par le nouveau shipper, sans perte
+// d'événements. Si build échoue, restore recrée l'ancien shipper ; si
+// celui-ci échoue à son tour, l'agent s'arrête plutôt que de tourner sans
+// sortie.
+func (rs *ReloadableShipper) Reload(build, restore func() (Shipper, error)) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if err := rs.current.Close(); err != nil {
+		rs.logger.Error("Failed to close previous outputs: %v", err)
+	}
+
+	next, err := build()
+	if err == nil {
+		rs.current = next
+		return nil
+	}
+
+	previous, restoreErr := restore()
+	if restoreErr != nil {
+		rs.logger.Fatal("Failed to restore previous outputs after failed reload: %v (reload error: %v)", restoreErr, err)
+	}
+	rs.current = previous
+	return fmt.Errorf("previous outputs restored: %w", err)
+}
+
+// Close ferme le shipper courant
+func (rs *ReloadableShipper) Close() error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.current.Close()
+}
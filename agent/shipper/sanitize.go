@@ -0,0 +1,113 @@
+package shipper
+
+import (
+	"encoding"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// marshalSanitized sérialise en JSON une valeur que json.Marshal refuse : les
+// flottants NaN ou infinis deviennent null, les canaux, fonctions et nombres
+// complexes aussi. Le résultat reste décodable, ce qui permet de rejouer un
+// événement mis en dead-letter après l'avoir corrigé.
+func marshalSanitized(v interface{}) ([]byte, error) {
+	return json.Marshal(sanitizeJSON(reflect.ValueOf(v)))
+}
+
+// sanitizeJSON convertit une valeur en arbre de maps, listes et scalaires
+// sérialisables, en suivant les tags json des structures
+func sanitizeJSON(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		if (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
+			return nil
+		}
+		if _, err := json.Marshal(v.Interface()); err == nil {
+			return v.Interface()
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return sanitizeJSON(v.Elem())
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil
+		}
+		return v.Interface()
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key, ok := sanitizeJSON(iter.Key()).(string)
+			if !ok {
+				key = stringKey(iter.Key())
+			}
+			out[key] = sanitizeJSON(iter.Value())
+		}
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface() // []byte, encodé en base64
+		}
+		fallthrough
+	case reflect.Array:
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = sanitizeJSON(v.Index(i))
+		}
+		return out
+	case reflect.Struct:
+		out := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" && opts == "" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if strings.Contains(opts, "omitempty") && v.Field(i).IsZero() {
+				continue
+			}
+			out[name] = sanitizeJSON(v.Field(i))
+		}
+		return out
+	default:
+		return v.Interface()
+	}
+}
+
+// stringKey rend une clé de map comme le ferait encoding/json
+func stringKey(key reflect.Value) string {
+	data, err := json.Marshal(key.Interface())
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(string(data), `"`)
+}
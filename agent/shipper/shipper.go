@@ -0,0 +1,82 @@
+package shipper
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// Shipper envoie les événements de l'agent vers une destination
+type Shipper interface {
+	// Ship envoie un lot d'événements. Un shipper avec spool ne retourne pas
+	// d'erreur quand la destination est injoignable : les événements sont
+	// conservés sur disque et renvoyés plus tard.
+	Ship(events []*models.Event) error
+
+	// SpoolDepth retourne le nombre d'événements en attente d'envoi
+	SpoolDepth() int
+
+	Close() error
+}
+
+// MultiShipper envoie chaque lot à plusieurs destinations (fan-out).
+// L'échec d'une destination n'empêche pas l'envoi vers les autres.
+type MultiShipper struct {
+	shippers []Shipper
+}
+
+// NewMultiShipper crée un shipper qui envoie vers toutes les destinations
+func NewMultiShipper(shippers ...Shipper) *MultiShipper {
+	return &MultiShipper{shippers: shippers}
+}
+
+// Ship envoie les événements à chaque destination
+func (ms *MultiShipper) Ship(events []*models.Event) error {
+	var errs []error
+	for _, s := range ms.shippers {
+		if err := s.Ship(events); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SpoolDepth retourne le total des événements en attente de toutes les destinations
+func (ms *MultiShipper) SpoolDepth() int {
+	depth := 0
+	for _, s := range ms.shippers {
+		depth += s.SpoolDepth()
+	}
+	return depth
+}
+
+// Close ferme toutes les destinations
+func (ms *MultiShipper) Close() error {
+	var errs []error
+	for _, s := range ms.shippers {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// marshalEvents sérialise les événements en JSON pour la sortie output ; les
+// événements non sérialisables sont écrits dans le fichier dead-letter
+func marshalEvents(events []*models.Event, output string, deadLetter *DeadLetterFile, logger *utils.Logger) [][]byte {
+	records := make([][]byte, 0, len(events))
+	var rejected []*models.RejectedEvent
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			logger.Error("Failed to marshal event from agent %s, routing it to dead-letter: %v", event.AgentID, err)
+			rejected = append(rejected, marshalRejected(event, err, output, logger))
+			continue
+		}
+		records = append(records, data)
+	}
+	deadLetter.Write(rejected)
+	return records
+}
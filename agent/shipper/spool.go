@@ -0,0 +1,393 @@
+package shipper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+const (
+	segmentPrefix = "segment-"
+	segmentSuffix = ".ndjson"
+
+	// quarantineSuffix est ajouté aux segments illisibles, qui ne sont plus
+	// rechargés ni comptés dans les limites du spool
+	quarantineSuffix = ".corrupt"
+
+	// maxRecordBytes borne la taille d'un événement dans le spool
+	maxRecordBytes = 16 * 1024 * 1024
+)
+
+// segment est un fichier du spool contenant un événement JSON par ligne
+type segment struct {
+	seq     uint64
+	path    string
+	events  int
+	bytes   int64
+	modTime time.Time
+}
+
+// Spool est une file persistante sur disque, découpée en segments,
+// qui conserve les événements tant que la destination est injoignable.
+// Les segments sont vidés dans l'ordre d'écriture (FIFO).
+type Spool struct {
+	mu            sync.Mutex
+	logger        *utils.Logger
+	dir           string
+	segmentEvents int
+	maxBytes      int64
+	maxAge        time.Duration
+
+	segments []*segment // du plus ancien au plus récent
+	active   *os.File   // fichier ouvert du dernier segment, nil si scellé
+	nextSeq  uint64
+}
+
+// NewSpool ouvre (ou crée) le spool dans dir et recharge les segments existants.
+// segmentEvents borne le nombre d'événements par segment, maxBytes et maxAge
+// bornent la taille totale et l'âge des segments conservés.
+func NewSpool(dir string, segmentEvents int, maxBytes int64, maxAge time.Duration, logger *utils.Logger) (*Spool, error) {
+	if segmentEvents <= 0 {
+		return nil, fmt.Errorf("spool segment size must be positive")
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	s := &Spool{
+		logger:        logger,
+		dir:           dir,
+		segmentEvents: segmentEvents,
+		maxBytes:      maxBytes,
+		maxAge:        maxAge,
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	if depth := s.Depth(); depth > 0 {
+		logger.Info("Spool recovered %d events in %d segments from %s", depth, len(s.segments), dir)
+	}
+
+	return s, nil
+}
+
+// load recharge les segments présents sur disque
+func (s *Spool) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		path := filepath.Join(s.dir, name)
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat spool segment: %w", err)
+		}
+
+		lines, skipped, err := readSegment(path)
+		if err != nil {
+			s.quarantine(path, err)
+			continue
+		}
+		if skipped > 0 {
+			s.logger.Error("Spool segment %s has %d unreadable lines, they will be skipped", name, skipped)
+		}
+
+		s.segments = append(s.segments, &segment{
+			seq:     seq,
+			path:    path,
+			events:  len(lines),
+			bytes:   info.Size(),
+			modTime: info.ModTime(),
+		})
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+	}
+
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+	return nil
+}
+
+// Append ajoute des événements sérialisés (une ligne JSON chacun) à la fin du
+// spool. Un événement de plus de maxRecordBytes est abandonné et journalisé :
+// il ne pourrait pas être relu.
+func (s *Spool) Append(records [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, record := range records {
+		if len(record) > maxRecordBytes {
+			s.logger.Error("Dropping event of %d bytes, over the %d bytes spool record limit", len(record), maxRecordBytes)
+			continue
+		}
+
+		if s.active == nil || s.segments[len(s.segments)-1].events >= s.segmentEvents {
+			if err := s.openSegment(); err != nil {
+				return err
+			}
+		}
+
+		seg := s.segments[len(s.segments)-1]
+		n, err := s.active.Write(append(record, '\n'))
+		if err != nil {
+			return fmt.Errorf("failed to write to spool segment: %w", err)
+		}
+		seg.events++
+		seg.bytes += int64(n)
+		seg.modTime = time.Now()
+	}
+
+	if s.active != nil {
+		if err := s.active.Sync(); err != nil {
+			return fmt.Errorf("failed to sync spool segment: %w", err)
+		}
+	}
+
+	s.enforceLimits()
+	return nil
+}
+
+// Drain envoie le contenu du spool, du plus ancien au plus récent, par lots
+// d'au plus batchSize événements. Il s'arrête au premier échec de send : les
+// événements non envoyés restent dans le spool. Un segment illisible est mis
+// en quarantaine plutôt que de bloquer les suivants.
+func (s *Spool) Drain(batchSize int, send func(records [][]byte) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enforceLimits()
+
+	for len(s.segments) > 0 {
+		seg := s.segments[0]
+
+		// Le segment actif est scellé avant d'être lu
+		if len(s.segments) == 1 && s.active != nil {
+			if err := s.sealActive(); err != nil {
+				return err
+			}
+		}
+
+		records, skipped, err := readSegment(seg.path)
+		if err != nil {
+			s.quarantine(seg.path, err)
+			s.segments = s.segments[1:]
+			continue
+		}
+		if skipped > 0 {
+			s.logger.Error("Skipped %d unreadable lines of spool segment %s", skipped, filepath.Base(seg.path))
+		}
+
+		for start := 0; start < len(records); start += batchSize {
+			end := start + batchSize
+			if end > len(records) {
+				end = len(records)
+			}
+
+			if err := send(records[start:end]); err != nil {
+				if start > 0 {
+					if rerr := s.rewriteSegment(seg, records[start:]); rerr != nil {
+						s.logger.Error("Failed to rewrite spool segment %s: %v", seg.path, rerr)
+					}
+				}
+				return err
+			}
+		}
+
+		if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove drained spool segment: %w", err)
+		}
+		s.segments = s.segments[1:]
+	}
+
+	return nil
+}
+
+// Depth retourne le nombre d'événements en attente dans le spool
+func (s *Spool) Depth() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	depth := 0
+	for _, seg := range s.segments {
+		depth += seg.events
+	}
+	return depth
+}
+
+// Close ferme le segment actif ; les données restent sur disque
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return nil
+	}
+	return s.sealActive()
+}
+
+// openSegment scelle le segment actif et en ouvre un nouveau
+func (s *Spool) openSegment() error {
+	if s.active != nil {
+		if err := s.sealActive(); err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(s.dir, fmt.Sprintf("%s%020d%s", segmentPrefix, s.nextSeq, segmentSuffix))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+
+	s.segments = append(s.segments, &segment{seq: s.nextSeq, path: path, modTime: time.Now()})
+	s.active = f
+	s.nextSeq++
+	return nil
+}
+
+// sealActive ferme le fichier du segment actif
+func (s *Spool) sealActive() error {
+	err := s.active.Close()
+	s.active = nil
+	if err != nil {
+		return fmt.Errorf("failed to close spool segment: %w", err)
+	}
+	return nil
+}
+
+// rewriteSegment remplace atomiquement le contenu d'un segment partiellement envoyé
+func (s *Spool) rewriteSegment(seg *segment, records [][]byte) error {
+	tmp := seg.path + ".tmp"
+
+	var buf bytes.Buffer
+	for _, record := range records {
+		buf.Write(record)
+		buf.WriteByte('\n')
+	}
+
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, seg.path); err != nil {
+		return err
+	}
+
+	seg.events = len(records)
+	seg.bytes = int64(buf.Len())
+	return nil
+}
+
+// quarantine renomme un segment illisible pour qu'il ne soit plus relu ; il
+// reste sur disque pour analyse
+func (s *Spool) quarantine(path string, cause error) {
+	if err := os.Rename(path, path+quarantineSuffix); err != nil {
+		s.logger.Error("Failed to quarantine unreadable spool segment %s (%v): %v", filepath.Base(path), cause, err)
+		return
+	}
+	s.logger.Error("Quarantined unreadable spool segment %s: %v", filepath.Base(path)+quarantineSuffix, cause)
+}
+
+// enforceLimits supprime les segments les plus anciens au-delà des plafonds
+// de taille et d'âge. Le segment en cours d'écriture n'est jamais supprimé.
+func (s *Spool) enforceLimits() {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.bytes
+	}
+
+	for len(s.segments) > 1 || (len(s.segments) == 1 && s.active == nil) {
+		seg := s.segments[0]
+
+		tooBig := s.maxBytes > 0 && total > s.maxBytes
+		tooOld := s.maxAge > 0 && time.Since(seg.modTime) > s.maxAge
+		if !tooBig && !tooOld {
+			return
+		}
+
+		if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+			s.logger.Error("Failed to remove spool segment %s: %v", seg.path, err)
+			return
+		}
+
+		reason := "size"
+		if tooOld {
+			reason = "age"
+		}
+		s.logger.Error("Spool %s cap reached, dropped %d events from %s", reason, seg.events, filepath.Base(seg.path))
+
+		total -= seg.bytes
+		s.segments = s.segments[1:]
+	}
+}
+
+// readSegment lit les événements d'un segment. Les lignes trop longues, qui ne
+// sont pas du JSON ou tronquées par un arrêt brutal (dernière ligne sans fin de
+// ligne) sont ignorées et comptées dans skipped ; une erreur n'est retournée
+// que si le fichier lui-même ne peut pas être lu.
+func readSegment(path string) (records [][]byte, skipped int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReaderSize(f, 64*1024)
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong && len(line)+len(chunk) <= maxRecordBytes+1 {
+			line = append(line, chunk...)
+		} else {
+			// La suite de la ligne est lue mais pas conservée
+			tooLong, line = true, nil
+		}
+
+		switch {
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case errors.Is(err, io.EOF):
+			if tooLong || len(line) > 0 {
+				skipped++
+			}
+			return records, skipped, nil
+		case err != nil:
+			return nil, 0, fmt.Errorf("failed to read spool segment: %w", err)
+		}
+
+		switch record := bytes.TrimSuffix(line, []byte("\n")); {
+		case tooLong || (len(record) > 0 && !json.Valid(record)):
+			skipped++
+		case len(record) > 0:
+			records = append(records, append([]byte(nil), record...))
+		}
+		line, tooLong = line[:0], false
+	}
+}
@@ -0,0 +1,57 @@
+package shipper
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+// StdoutShipper écrit les événements en NDJSON sur la sortie standard,
+// pour le débogage ou la collecte par un autre outil (conteneurs, pipes)
+type StdoutShipper struct {
+	mu         sync.Mutex
+	out        io.Writer
+	deadLetter *DeadLetterFile
+	logger     *utils.Logger
+}
+
+// NewStdoutShipper crée un shipper vers la sortie standard
+func NewStdoutShipper(deadLetter *DeadLetterFile, logger *utils.Logger) *StdoutShipper {
+	return &StdoutShipper{out: os.Stdout, deadLetter: deadLetter, logger: logger}
+}
+
+// Ship écrit une ligne JSON par événement
+func (ss *StdoutShipper) Ship(events []*models.Event) error {
+	records := marshalEvents(events, "stdout", ss.deadLetter, ss.logger)
+	if len(records) == 0 {
+		return nil
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	w := bufio.NewWriter(ss.out)
+	for _, record := range records {
+		w.Write(record)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write events to stdout: %w", err)
+	}
+	return nil
+}
+
+// SpoolDepth retourne 0 : la sortie standard n'a pas de spool
+func (ss *StdoutShipper) SpoolDepth() int {
+	return 0
+}
+
+// Close ne fait rien : la sortie standard reste ouverte
+func (ss *StdoutShipper) Close() error {
+	return nil
+}
@@ -0,0 +1,214 @@
+package shipper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luigi/xdr-platform/agent/models"
+	"github.com/luigi/xdr-platform/agent/utils"
+)
+
+const (
+	syslogAppName      = "xdr-agent"
+	syslogWriteTimeout = 10 * time.Second
+
+	// syslogSDID identifie les données structurées de l'agent ; 32473 est le
+	// numéro d'entreprise réservé à la documentation (RFC 5612)
+	syslogSDID = "xdr@32473"
+)
+
+// syslogFacilities associe les noms de facility à leur code (RFC 5424 §6.2.1)
+var syslogFacilities = map[string]int{
+	"user": 1, "daemon": 3, "auth": 4, "authpriv": 10,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSeverities associe la sévérité d'un événement à celle de syslog
+var syslogSeverities = map[models.Severity]int{
+	models.SeverityLow:      6, // informational
+	models.SeverityMedium:   5, // notice
+	models.SeverityHigh:     4, // warning
+	models.SeverityCritical: 2, // critical
+}
+
+// SyslogShipper envoie chaque événement comme un message RFC 5424 dont le
+// corps est l'événement JSON. En TCP, les messages sont délimités par leur
+// longueur (octet counting, RFC 6587) ; en UDP, un message par datagramme.
+type SyslogShipper struct {
+	mu       sync.Mutex
+	network  string
+	address  string
+	facility int
+	procID   string
+	conn     net.Conn
+
+	deadLetter *DeadLetterFile
+	logger     *utils.Logger
+}
+
+// NewSyslogShipper crée un shipper syslog vers address ("tcp" ou "udp").
+// La connexion est établie au premier envoi.
+func NewSyslogShipper(network, address, facility string, deadLetter *DeadLetterFile, logger *utils.Logger) (*SyslogShipper, error) {
+	if network != "tcp" && network != "udp" {
+		return nil, fmt.Errorf("syslog network must be \"tcp\" or \"udp\", got %q", network)
+	}
+	if address == "" {
+		return nil, fmt.Errorf("syslog address cannot be empty")
+	}
+	code, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", facility)
+	}
+
+	logger.Info("Syslog shipper initialized with %s://%s, facility: %s", network, address, facility)
+
+	return &SyslogShipper{
+		network:  network,
+		address:  address,
+		facility: code,
+		procID:   strconv.Itoa(os.Getpid()),
+
+		deadLetter: deadLetter,
+		logger:     logger,
+	}, nil
+}
+
+// Ship envoie les événements ; en TCP, une connexion perdue est rétablie une fois
+func (ss *SyslogShipper) Ship(events []*models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	// Les événements non sérialisables partent en dead-letter avant l'envoi
+	var rejected []*models.RejectedEvent
+	records := make([][]byte, len(events))
+	for i, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			ss.logger.Error("Failed to marshal event from agent %s, routing it to dead-letter: %v", event.AgentID, err)
+			rejected = append(rejected, marshalRejected(event, err, "syslog", ss.logger))
+			continue
+		}
+		records[i] = data
+	}
+	ss.deadLetter.Write(rejected)
+
+	sent := 0
+	for i, event := range events {
+		if records[i] == nil {
+			continue
+		}
+
+		message := ss.format(event, records[i])
+		if err := ss.write(message); err != nil {
+			ss.closeConn()
+			if ss.network == "udp" {
+				return fmt.Errorf("failed to send syslog messages, %d of %d sent: %w", sent, len(events), err)
+			}
+			if err := ss.write(message); err != nil {
+				ss.closeConn()
+				return fmt.Errorf("failed to send syslog messages, %d of %d sent: %w", sent, len(events), err)
+			}
+		}
+		sent++
+	}
+
+	ss.logger.Info("Successfully shipped %d events to syslog %s", sent, ss.address)
+	return nil
+}
+
+// write envoie un message en ouvrant la connexion si nécessaire
+func (ss *SyslogShipper) write(message []byte) error {
+	if ss.conn == nil {
+		conn, err := net.DialTimeout(ss.network, ss.address, syslogWriteTimeout)
+		if err != nil {
+			return err
+		}
+		ss.conn = conn
+	}
+
+	if ss.network == "tcp" {
+		message = append([]byte(strconv.Itoa(len(message))+" "), message...)
+	}
+
+	ss.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout))
+	_, err := ss.conn.Write(message)
+	return err
+}
+
+// format construit le message RFC 5424 d'un événement
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func (ss *SyslogShipper) format(event *models.Event, data []byte) []byte {
+	severity, ok := syslogSeverities[event.Severity]
+	if !ok {
+		severity = 6
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s [%s agent_id=\"%s\" severity=\"%s\"] ",
+		ss.facility*8+severity,
+		event.Timestamp.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		syslogHeaderField(event.Hostname, 255),
+		syslogAppName,
+		ss.procID,
+		syslogHeaderField(string(event.EventType), 32),
+		syslogSDID,
+		syslogParamValue(event.AgentID),
+		syslogParamValue(string(event.Severity)),
+	)
+	b.Write(data)
+	return []byte(b.String())
+}
+
+// syslogHeaderField remplace les caractères interdits dans l'en-tête (ASCII
+// imprimable sans espace) et tronque à limit ; une valeur vide devient "-"
+func syslogHeaderField(value string, limit int) string {
+	if value == "" {
+		return "-"
+	}
+	field := []byte(value)
+	for i, c := range field {
+		if c < 33 || c > 126 {
+			field[i] = '_'
+		}
+	}
+	if len(field) > limit {
+		field = field[:limit]
+	}
+	return string(field)
+}
+
+// syslogParamValue échappe une valeur de donnée structurée
+func syslogParamValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+func (ss *SyslogShipper) closeConn() {
+	if ss.conn != nil {
+		ss.conn.Close()
+		ss.conn = nil
+	}
+}
+
+// SpoolDepth retourne 0 : la sortie syslog n'a pas de spool
+func (ss *SyslogShipper) SpoolDepth() int {
+	return 0
+}
+
+// Close ferme la connexion
+func (ss *SyslogShipper) Close() error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.closeConn()
+	return nil
+}
@@ -40,3 +40,10 @@ func (l *Logger) Debug(format string, v ...interface{}) {
 func (l *Logger) Fatal(format string, v ...interface{}) {
 	l.errorLogger.Fatalf(format, v...)
 }
+
+// UseStderr redirige les messages d'information et de debug vers la sortie
+// d'erreur, pour laisser la sortie standard aux événements
+func (l *Logger) UseStderr() {
+	l.infoLogger.SetOutput(os.Stderr)
+	l.debugLogger.SetOutput(os.Stderr)
+}
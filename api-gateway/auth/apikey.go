@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// apiKeyEntry est une clé d'API telle que déclarée dans le fichier de clés.
+// Seule l'empreinte SHA-256 de la clé est stockée.
+type apiKeyEntry struct {
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	KeySHA256 string `json:"key_sha256"`
+}
+
+// apiKey est une clé d'API chargée
+type apiKey struct {
+	name string
+	role Role
+	hash []byte
+}
+
+// APIKeyStore vérifie les clés d'API présentées dans l'en-tête X-API-Key
+type APIKeyStore struct {
+	keys []apiKey
+}
+
+// LoadAPIKeys charge un fichier JSON de la forme
+// [{"name": "soc-dashboard", "role": "viewer", "key_sha256": "<hex>"}]
+func LoadAPIKeys(path string) (*APIKeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+
+	var entries []apiKeyEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys file: %w", err)
+	}
+
+	store := &APIKeyStore{}
+	seen := make(map[string]bool)
+	for i, entry := range entries {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("API key %d: name is required", i)
+		}
+		if seen[entry.Name] {
+			return nil, fmt.Errorf("API key %q: duplicate name", entry.Name)
+		}
+		seen[entry.Name] = true
+
+		role, err := ParseRole(entry.Role)
+		if err != nil {
+			return nil, fmt.Errorf("API key %q: %w", entry.Name, err)
+		}
+		hash, err := hex.DecodeString(entry.KeySHA256)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("API key %q: key_sha256 must be a hex-encoded SHA-256 digest", entry.Name)
+		}
+
+		store.keys = append(store.keys, apiKey{name: entry.Name, role: role, hash: hash})
+	}
+
+	return store, nil
+}
+
+// Len retourne le nombre de clés chargées
+func (s *APIKeyStore) Len() int {
+	return len(s.keys)
+}
+
+// Authenticate retourne le principal associé à la clé, ou nil si elle est inconnue.
+// Toutes les empreintes sont comparées en temps constant.
+func (s *APIKeyStore) Authenticate(key string) *Principal {
+	sum := sha256.Sum256([]byte(key))
+
+	var found *apiKey
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare(sum[:], s.keys[i].hash) == 1 {
+			found = &s.keys[i]
+		}
+	}
+	if found == nil {
+		return nil
+	}
+
+	return &Principal{Subject: found.name, Role: found.role, Method: MethodAPIKey}
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+const (
+	// auditQueueSize borne les entrées en attente d'écriture
+	auditQueueSize = 4096
+
+	// auditBatchSize est le nombre maximal d'entrées écrites par lot
+	auditBatchSize = 200
+
+	// auditFlushInterval est le délai maximal avant l'écriture d'un lot
+	auditFlushInterval = 2 * time.Second
+)
+
+// AuditStore persiste les entrées du journal d'audit
+type AuditStore interface {
+	InsertAuditEntries(ctx context.Context, entries []*models.AuditEntry) error
+}
+
+// AuditTrail journalise chaque requête de l'API de façon asynchrone, pour ne
+// pas ajouter l'écriture en base à la latence des requêtes.
+type AuditTrail struct {
+	store  AuditStore
+	logger *log.Logger
+
+	entries chan *models.AuditEntry
+	done    chan struct{}
+
+	mu      sync.Mutex
+	dropped int
+}
+
+// NewAuditTrail crée le journal d'audit et démarre son écriture en arrière-plan
+func NewAuditTrail(store AuditStore, logger *log.Logger) *AuditTrail {
+	a := &AuditTrail{
+		store:   store,
+		logger:  logger,
+		entries: make(chan *models.AuditEntry, auditQueueSize),
+		done:    make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Middleware enregistre la requête une fois traitée, y compris les refus
+// d'authentification. Il doit précéder Authenticate dans la chaîne.
+func (a *AuditTrail) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+
+		// Les valeurs de fiber.Ctx sont réutilisées après la requête : on les copie
+		entry := &models.AuditEntry{
+			OccurredAt: start,
+			Method:     strings.Clone(c.Method()),
+			Path:       strings.Clone(c.Path()),
+			Query:      redactQuery(c.Request().URI().QueryString()),
+			Status:     status,
+			RemoteIP:   strings.Clone(c.IP()),
+			UserAgent:  strings.Clone(c.Get(fiber.HeaderUserAgent)),
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if principal := PrincipalFrom(c); principal != nil {
+			entry.Subject = principal.Subject
+			entry.Role = string(principal.Role)
+			entry.AuthMethod = principal.Method
+		}
+
+		select {
+		case a.entries <- entry:
+		default:
+			a.mu.Lock()
+			a.dropped++
+			a.mu.Unlock()
+		}
+
+		return err
+	}
+}
+
+// Close écrit les entrées en attente et arrête le journal
+func (a *AuditTrail) Close() {
+	close(a.entries)
+	<-a.done
+}
+
+// run regroupe les entrées par lots et les écrit en base
+func (a *AuditTrail) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(auditFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*models.AuditEntry, 0, auditBatchSize)
+	for {
+		select {
+		case entry, ok := <-a.entries:
+			if !ok {
+				a.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= auditBatchSize {
+				batch = a.flush(batch)
+			}
+
+		case <-ticker.C:
+			batch = a.flush(batch)
+		}
+	}
+}
+
+// flush écrit un lot ; en cas d'échec, les entrées sont écrites dans les logs
+// pour que la trace ne soit pas perdue
+func (a *AuditTrail) flush(batch []*models.AuditEntry) []*models.AuditEntry {
+	a.mu.Lock()
+	dropped := a.dropped
+	a.dropped = 0
+	a.mu.Unlock()
+
+	if dropped > 0 {
+		a.logger.Printf("Audit queue full, dropped %d entries", dropped)
+	}
+
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := a.store.InsertAuditEntries(ctx, batch); err != nil {
+		a.logger.Printf("Failed to write %d audit entries: %v", len(batch), err)
+		for _, e := range batch {
+			a.logger.Printf("AUDIT %s subject=%q role=%s %s %s?%s status=%d ip=%s",
+				e.OccurredAt.Format(time.RFC3339), e.Subject, e.Role, e.Method, e.Path, e.Query, e.Status, e.RemoteIP)
+		}
+	}
+	return batch[:0]
+}
+
+// redactQuery masque les identifiants passés en paramètres de requête
+func redactQuery(raw []byte) string {
+	query := string(raw)
+	if !strings.Contains(query, QueryAPIKey+"=") && !strings.Contains(query, QueryAccessToken+"=") {
+		return query
+	}
+
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+		if name == QueryAPIKey || name == QueryAccessToken {
+			params[i] = name + "=REDACTED"
+		}
+	}
+	return strings.Join(params, "&")
+}
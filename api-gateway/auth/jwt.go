@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// jwtLeeway tolère un décalage d'horloge sur exp et nbf
+const jwtLeeway = 30 * time.Second
+
+// ErrInvalidToken est retourné pour tout jeton refusé
+var ErrInvalidToken = errors.New("invalid token")
+
+// JWTConfig configure la vérification des jetons
+type JWTConfig struct {
+	HS256Secret string // secret partagé des jetons HS256
+	JWKSFile    string // fichier JWKS local contenant les clés publiques RS256
+	Issuer      string // claim iss attendu (vide : non vérifié)
+	Audience    string // claim aud attendu (vide : non vérifié)
+	RoleClaim   string // claim portant le rôle (chaîne ou liste)
+}
+
+// JWTVerifier vérifie les jetons HS256 et RS256.
+// Les algorithmes sont fixés par la configuration : un jeton ne peut pas
+// choisir une autre méthode de vérification que celle de sa clé.
+type JWTVerifier struct {
+	hsSecret  []byte
+	rsaKeys   map[string]*rsa.PublicKey // par kid
+	issuer    string
+	audience  string
+	roleClaim string
+}
+
+// NewJWTVerifier crée un vérificateur ; au moins un secret ou un JWKS est requis
+func NewJWTVerifier(cfg JWTConfig) (*JWTVerifier, error) {
+	v := &JWTVerifier{
+		issuer:    cfg.Issuer,
+		audience:  cfg.Audience,
+		roleClaim: cfg.RoleClaim,
+	}
+	if v.roleClaim == "" {
+		v.roleClaim = "role"
+	}
+
+	if cfg.HS256Secret != "" {
+		if len(cfg.HS256Secret) < 32 {
+			return nil, fmt.Errorf("HS256 secret must be at least 32 bytes")
+		}
+		v.hsSecret = []byte(cfg.HS256Secret)
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.rsaKeys = keys
+	}
+
+	if v.hsSecret == nil && len(v.rsaKeys) == 0 {
+		return nil, fmt.Errorf("no JWT verification key configured")
+	}
+
+	return v, nil
+}
+
+// jwk est une clé d'un JWKS ; seules les clés RSA de signature sont retenues
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// loadJWKS charge les clés publiques RSA d'un fichier JWKS
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for i, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") || (key.Alg != "" && key.Alg != "RS256") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %d: invalid modulus: %w", i, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("JWKS key %d: invalid exponent", i)
+		}
+
+		pub := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		if pub.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("JWKS key %d: RSA keys must be at least 2048 bits", i)
+		}
+		if _, dup := keys[key.Kid]; dup {
+			return nil, fmt.Errorf("JWKS key %d: duplicate kid %q", i, key.Kid)
+		}
+		keys[key.Kid] = pub
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS file contains no RSA signing key")
+	}
+	return keys, nil
+}
+
+// Verify valide la signature et les claims d'un jeton et retourne son principal
+func (v *JWTVerifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: invalid header", ErrInvalidToken)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid signature encoding", ErrInvalidToken)
+	}
+
+	if err := v.verifySignature(header.Alg, header.Kid, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: invalid claims", ErrInvalidToken)
+	}
+
+	return v.checkClaims(claims)
+}
+
+// verifySignature vérifie la signature selon l'algorithme annoncé, s'il est configuré
+func (v *JWTVerifier) verifySignature(alg, kid, signed string, signature []byte) error {
+	switch alg {
+	case "HS256":
+		if v.hsSecret == nil {
+			return fmt.Errorf("%w: HS256 tokens are not accepted", ErrInvalidToken)
+		}
+		mac := hmac.New(sha256.New, v.hsSecret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+
+	case "RS256":
+		key, ok := v.rsaKeys[kid]
+		if !ok {
+			return fmt.Errorf("%w: unknown key id %q", ErrInvalidToken, kid)
+		}
+		digest := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, alg)
+}
+
+// checkClaims vérifie exp, nbf, iss, aud et extrait le sujet et le rôle
+func (v *JWTVerifier) checkClaims(claims map[string]interface{}) (*Principal, error) {
+	now := time.Now()
+
+	exp, ok := numericClaim(claims, "exp")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing exp claim", ErrInvalidToken)
+	}
+	if now.After(exp.Add(jwtLeeway)) {
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(jwtLeeway).Before(nbf) {
+		return nil, fmt.Errorf("%w: token not yet valid", ErrInvalidToken)
+	}
+
+	if v.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.issuer {
+			return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+		}
+	}
+	if v.audience != "" && !claimContains(claims["aud"], v.audience) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+
+	// Le rôle retenu est le plus élevé des rôles connus du claim
+	var role Role
+	var values []interface{}
+	switch r := claims[v.roleClaim].(type) {
+	case string:
+		values = []interface{}{r}
+	case []interface{}:
+		values = r
+	}
+	for _, value := range values {
+		s, _ := value.(string)
+		if candidate, err := ParseRole(s); err == nil && (role == "" || roleLevels[candidate] > roleLevels[role]) {
+			role = candidate
+		}
+	}
+	if role == "" {
+		return nil, fmt.Errorf("%w: no known role in %s claim", ErrInvalidToken, v.roleClaim)
+	}
+
+	return &Principal{Subject: subject, Role: role, Method: MethodJWT}, nil
+}
+
+// decodeSegment décode un segment base64url JSON du jeton
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// numericClaim lit un claim NumericDate (secondes depuis l'epoch)
+func numericClaim(claims map[string]interface{}, name string) (time.Time, bool) {
+	number, ok := claims[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// claimContains indique si un claim chaîne ou liste contient value
+func claimContains(claim interface{}, value string) bool {
+	switch c := claim.(type) {
+	case string:
+		return c == value
+	case []interface{}:
+		for _, item := range c {
+			if s, _ := item.(string); s == value {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// principalKey est la clé du principal dans les Locals de la requête
+	principalKey = "auth.principal"
+
+	// Paramètres d'authentification des flux temps réel
+	QueryAPIKey      = "api_key"
+	QueryAccessToken = "access_token"
+)
+
+// Authenticator authentifie les requêtes par clé d'API (X-API-Key) ou
+// par jeton JWT (Authorization: Bearer).
+type Authenticator struct {
+	apiKeys *APIKeyStore // nil si aucune clé n'est configurée
+	jwt     *JWTVerifier // nil si JWT n'est pas configuré
+	enabled bool
+	logger  *log.Logger
+}
+
+// NewAuthenticator crée l'authentificateur. Si enabled est faux, toutes les
+// requêtes sont acceptées avec un principal anonyme administrateur.
+func NewAuthenticator(apiKeys *APIKeyStore, jwt *JWTVerifier, enabled bool, logger *log.Logger) *Authenticator {
+	return &Authenticator{
+		apiKeys: apiKeys,
+		jwt:     jwt,
+		enabled: enabled,
+		logger:  logger,
+	}
+}
+
+// Authenticate exige un appelant authentifié et le place dans les Locals
+func (a *Authenticator) Authenticate() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !a.enabled {
+			c.Locals(principalKey, &Principal{Subject: "anonymous", Role: RoleAdmin, Method: MethodAnonymous})
+			return c.Next()
+		}
+
+		principal, reason := a.authenticate(c)
+		if principal == nil {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="xdr-api"`)
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   "Authentication required",
+				"details": reason,
+			})
+		}
+
+		c.Locals(principalKey, principal)
+		return c.Next()
+	}
+}
+
+// authenticate identifie l'appelant ; en cas d'échec, retourne la raison
+func (a *Authenticator) authenticate(c *fiber.Ctx) (*Principal, string) {
+	if key := c.Get("X-API-Key"); key != "" {
+		if a.apiKeys == nil {
+			return nil, "API keys are not enabled"
+		}
+		if principal := a.apiKeys.Authenticate(key); principal != nil {
+			return principal, ""
+		}
+		return nil, "invalid API key"
+	}
+
+	if header := c.Get(fiber.HeaderAuthorization); header != "" {
+		token, found := strings.CutPrefix(header, "Bearer ")
+		if !found {
+			return nil, "unsupported authorization scheme"
+		}
+		if a.jwt == nil {
+			return nil, "bearer tokens are not enabled"
+		}
+		principal, err := a.jwt.Verify(strings.TrimSpace(token))
+		if err != nil {
+			a.logger.Printf("Rejected bearer token from %s: %v", c.IP(), err)
+			return nil, "invalid or expired token"
+		}
+		return principal, ""
+	}
+
+	// EventSource et WebSocket ne permettent pas d'envoyer d'en-têtes : les
+	// flux acceptent les identifiants en paramètres (masqués dans l'audit)
+	if isStreamRequest(c) {
+		if key := c.Query(QueryAPIKey); key != "" && a.apiKeys != nil {
+			if principal := a.apiKeys.Authenticate(key); principal != nil {
+				return principal, ""
+			}
+			return nil, "invalid API key"
+		}
+		if token := c.Query(QueryAccessToken); token != "" && a.jwt != nil {
+			principal, err := a.jwt.Verify(token)
+			if err != nil {
+				a.logger.Printf("Rejected stream token from %s: %v", c.IP(), err)
+				return nil, "invalid or expired token"
+			}
+			return principal, ""
+		}
+	}
+
+	return nil, "missing X-API-Key or Authorization header"
+}
+
+// isStreamRequest reconnaît les requêtes SSE et WebSocket
+func isStreamRequest(c *fiber.Ctx) bool {
+	if c.Method() != fiber.MethodGet {
+		return false
+	}
+	return strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream") ||
+		strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket")
+}
+
+// RequireRole refuse les appelants dont le rôle est inférieur à required
+func RequireRole(required Role) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := PrincipalFrom(c)
+		if principal == nil || !principal.Role.Allows(required) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":         "Insufficient role",
+				"required_role": required,
+			})
+		}
+		return c.Next()
+	}
+}
+
+// RequireAgent réserve une route aux agents et aux administrateurs.
+// Un agent n'agit qu'en son nom (voir Principal.CanActFor).
+func RequireAgent() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := PrincipalFrom(c)
+		if principal == nil || (principal.Role != RoleAgent && !principal.Role.Allows(RoleAdmin)) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":         "Insufficient role",
+				"required_role": []Role{RoleAgent, RoleAdmin},
+			})
+		}
+		return c.Next()
+	}
+}
+
+// PrincipalFrom retourne l'appelant authentifié de la requête, ou nil
+func PrincipalFrom(c *fiber.Ctx) *Principal {
+	principal, _ := c.Locals(principalKey).(*Principal)
+	return principal
+}
+
+// SetPrincipal associe un appelant à la requête, pour les routes qui
+// l'authentifient elles-mêmes (connexion par mot de passe)
+func SetPrincipal(c *fiber.Ctx, principal *Principal) {
+	c.Locals(principalKey, principal)
+}
@@ -0,0 +1,66 @@
+// Package auth authentifie les requêtes de l'API (clés d'API, JWT et connexion
+// des utilisateurs), applique les rôles par groupe de routes et journalise les accès.
+package auth
+
+import "fmt"
+
+// Role est le niveau d'autorisation d'un appelant
+type Role string
+
+const (
+	// RoleAgent envoie des événements (POST /ingest) en son seul nom : le
+	// sujet du principal est l'agent_id. Il n'a accès à aucune lecture.
+	RoleAgent Role = "agent"
+	// RoleViewer consulte les événements, statistiques et alertes
+	RoleViewer Role = "viewer"
+	// RoleAnalyst traite les alertes (statut, assignation, commentaires)
+	RoleAnalyst Role = "analyst"
+	// RoleAdmin gère la quarantaine et consulte le journal d'audit
+	RoleAdmin Role = "admin"
+)
+
+// roleLevels ordonne les rôles : un rôle inclut les droits des rôles inférieurs
+var roleLevels = map[Role]int{
+	RoleAgent:   0,
+	RoleViewer:  1,
+	RoleAnalyst: 2,
+	RoleAdmin:   3,
+}
+
+// ParseRole convertit une chaîne en rôle connu
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleLevels[role]; !ok {
+		return "", fmt.Errorf("unknown role %q (expected agent, viewer, analyst or admin)", s)
+	}
+	return role, nil
+}
+
+// Allows indique si le rôle donne accès aux routes exigeant required
+func (r Role) Allows(required Role) bool {
+	return roleLevels[r] >= roleLevels[required]
+}
+
+// Principal identifie l'appelant authentifié d'une requête
+type Principal struct {
+	Subject string `json:"subject"`
+	Role    Role   `json:"role"`
+	Method  string `json:"method"` // api_key, jwt, password, anonymous
+}
+
+// CanActFor indique si l'appelant peut envoyer des événements au nom de
+// agentID : un agent pour lui-même, un administrateur pour tout agent
+func (p *Principal) CanActFor(agentID string) bool {
+	if p.Role == RoleAgent {
+		return p.Subject == agentID
+	}
+	return p.Role.Allows(RoleAdmin)
+}
+
+// Méthodes d'authentification
+const (
+	MethodAPIKey    = "api_key"
+	MethodJWT       = "jwt"
+	MethodPassword  = "password" // connexion POST /auth/login
+	MethodAnonymous = "anonymous"
+)
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TokenIssuer signe en HS256 les jetons des utilisateurs connectés. Les jetons
+// émis portent les claims attendus par le JWTVerifier configuré avec le même
+// JWTConfig.
+type TokenIssuer struct {
+	secret    []byte
+	issuer    string
+	audience  string
+	roleClaim string
+	ttl       time.Duration
+}
+
+// NewTokenIssuer crée l'émetteur ; le secret HS256 de cfg est requis
+func NewTokenIssuer(cfg JWTConfig, ttl time.Duration) (*TokenIssuer, error) {
+	if len(cfg.HS256Secret) < 32 {
+		return nil, fmt.Errorf("issuing tokens requires an HS256 secret of at least 32 bytes")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("token lifetime must be positive")
+	}
+
+	roleClaim := cfg.RoleClaim
+	if roleClaim == "" {
+		roleClaim = "role"
+	}
+
+	return &TokenIssuer{
+		secret:    []byte(cfg.HS256Secret),
+		issuer:    cfg.Issuer,
+		audience:  cfg.Audience,
+		roleClaim: roleClaim,
+		ttl:       ttl,
+	}, nil
+}
+
+// Issue signe un jeton pour le principal et retourne sa date d'expiration
+func (t *TokenIssuer) Issue(principal *Principal) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(t.ttl)
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate token id: %w", err)
+	}
+
+	claims := map[string]interface{}{
+		"sub":       principal.Subject,
+		t.roleClaim: string(principal.Role),
+		"iat":       now.Unix(),
+		"exp":       expiresAt.Unix(),
+		"jti":       hex.EncodeToString(id),
+	}
+	if t.issuer != "" {
+		claims["iss"] = t.issuer
+	}
+	if t.audience != "" {
+		claims["aud"] = t.audience
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to encode token claims: %w", err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(signed))
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), expiresAt, nil
+}
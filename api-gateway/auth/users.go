@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// userEntry est un utilisateur tel que déclaré dans le fichier des utilisateurs.
+// Seule l'empreinte bcrypt du mot de passe est stockée.
+type userEntry struct {
+	Username       string `json:"username"`
+	Role           string `json:"role"`
+	PasswordBcrypt string `json:"password_bcrypt"`
+}
+
+// user est un utilisateur chargé
+type user struct {
+	role Role
+	hash []byte
+}
+
+// UserStore vérifie les identifiants des utilisateurs du tableau de bord
+type UserStore struct {
+	users map[string]user
+	// dummyHash est comparé pour les utilisateurs inconnus, afin que la durée
+	// de la réponse ne révèle pas l'existence d'un compte
+	dummyHash []byte
+}
+
+// LoadUsers charge un fichier JSON de la forme
+// [{"username": "alice", "role": "analyst", "password_bcrypt": "$2y$12$..."}]
+func LoadUsers(path string) (*UserStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+
+	var entries []userEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse users file: %w", err)
+	}
+
+	store := &UserStore{users: make(map[string]user)}
+	cost := bcrypt.DefaultCost
+	for i, entry := range entries {
+		if entry.Username == "" {
+			return nil, fmt.Errorf("user %d: username is required", i)
+		}
+		if _, dup := store.users[entry.Username]; dup {
+			return nil, fmt.Errorf("user %q: duplicate username", entry.Username)
+		}
+
+		role, err := ParseRole(entry.Role)
+		if err != nil {
+			return nil, fmt.Errorf("user %q: %w", entry.Username, err)
+		}
+		if role == RoleAgent {
+			return nil, fmt.Errorf("user %q: the agent role is reserved to agents", entry.Username)
+		}
+
+		hash := []byte(entry.PasswordBcrypt)
+		userCost, err := bcrypt.Cost(hash)
+		if err != nil {
+			return nil, fmt.Errorf("user %q: password_bcrypt must be a bcrypt hash", entry.Username)
+		}
+		cost = max(cost, userCost)
+
+		store.users[entry.Username] = user{role: role, hash: hash}
+	}
+
+	// Même coût que le plus coûteux des comptes réels
+	dummy, err := bcrypt.GenerateFromPassword([]byte("xdr-unknown-user"), cost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare password verification: %w", err)
+	}
+	store.dummyHash = dummy
+
+	return store, nil
+}
+
+// Len retourne le nombre d'utilisateurs chargés
+func (s *UserStore) Len() int {
+	return len(s.users)
+}
+
+// Authenticate retourne le principal de l'utilisateur si le mot de passe est
+// correct, nil sinon
+func (s *UserStore) Authenticate(username, password string) *Principal {
+	u, ok := s.users[username]
+	if !ok {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return nil
+	}
+	if bcrypt.CompareHashAndPassword(u.hash, []byte(password)) != nil {
+		return nil
+	}
+
+	return &Principal{Subject: username, Role: u.role, Method: MethodPassword}
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config contient la configuration du service d'ingestion
@@ -15,10 +18,34 @@ type Config struct {
 	DatabaseUser     string
 	DatabasePassword string
 
+	TimelineAggregates bool // agrégats continus pour la timeline
+
+	// Statut des agents : stale après AgentStaleHeartbeats intervalles de
+	// heartbeat sans nouvelles, offline après AgentOfflineHeartbeats ;
+	// AgentHeartbeatInterval s'applique aux agents qui ne publient pas le leur
+	AgentHeartbeatInterval time.Duration
+	AgentStaleHeartbeats   int
+	AgentOfflineHeartbeats int
+
 	// Kafka configuration
 	KafkaBrokers        []string
 	KafkaTopicRawEvents string
 	KafkaGroupID        string
+	KafkaCompression    string // none, gzip, snappy, lz4, zstd
+
+	// Kafka TLS et SASL
+	KafkaTLS                   bool
+	KafkaTLSCAFile             string
+	KafkaTLSCertFile           string
+	KafkaTLSKeyFile            string
+	KafkaTLSInsecureSkipVerify bool
+	KafkaSASLMechanism         string // plain, scram-sha-256, scram-sha-512
+	KafkaSASLUsername          string
+	KafkaSASLPassword          string
+
+	// Ingestion HTTP (POST /api/v1/ingest)
+	IngestMaxBytes  int64 // taille maximale d'un lot décompressé
+	IngestMaxEvents int
 
 	// Service configuration
 	ServiceName    string
@@ -26,12 +53,36 @@ type Config struct {
 	FlushInterval  int // en secondes
 	WorkerCount    int
 
+	// Authentication configuration
+	AuthEnabled        bool
+	APIKeysFile        string // fichier JSON des clés d'API (empreintes SHA-256)
+	JWTHS256Secret     string
+	JWTJWKSFile        string // clés publiques RS256
+	JWTIssuer          string
+	JWTAudience        string
+	JWTRoleClaim       string
+	UsersFile          string        // utilisateurs du tableau de bord (empreintes bcrypt)
+	TokenTTL           time.Duration // durée de validité des jetons délivrés à la connexion
+	CORSAllowedOrigins string
+
 	// Logging
 	LogLevel string
 }
 
 // LoadConfig charge la configuration depuis les variables d'environnement
 func LoadConfig() (*Config, error) {
+	// Durée de validité des jetons de connexion
+	tokenTTL, err := time.ParseDuration(getEnvOrDefault("AUTH_TOKEN_TTL", "8h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUTH_TOKEN_TTL: %w", err)
+	}
+
+	// Agent heartbeat interval
+	agentHeartbeatInterval, err := time.ParseDuration(getEnvOrDefault("AGENT_HEARTBEAT_INTERVAL", "60s"))
+	if err != nil {
+		agentHeartbeatInterval = 60 * time.Second
+	}
+
 	config := &Config{
 		// Database
 		DatabaseHost:     getEnvOrDefault("DATABASE_HOST", "localhost"),
@@ -40,10 +91,32 @@ func LoadConfig() (*Config, error) {
 		DatabaseUser:     getEnvOrDefault("DATABASE_USER", "xdr_admin"),
 		DatabasePassword: getEnvOrDefault("DATABASE_PASSWORD", "xdr_secure_password_2024"),
 
+		TimelineAggregates: getEnvOrDefault("TIMELINE_AGGREGATES", "true") == "true",
+
+		// Agents
+		AgentHeartbeatInterval: agentHeartbeatInterval,
+		AgentStaleHeartbeats:   getEnvIntOrDefault("AGENT_STALE_HEARTBEATS", 2),
+		AgentOfflineHeartbeats: getEnvIntOrDefault("AGENT_OFFLINE_HEARTBEATS", 5),
+
 		// Kafka
 		KafkaBrokers:        []string{getEnvOrDefault("KAFKA_BROKERS", "localhost:9092")},
 		KafkaTopicRawEvents: getEnvOrDefault("KAFKA_TOPIC_RAW_EVENTS", "raw-events"),
 		KafkaGroupID:        getEnvOrDefault("KAFKA_GROUP_ID", "xdr-ingestion-service"),
+		KafkaCompression:    getEnvOrDefault("KAFKA_COMPRESSION", "none"),
+
+		// Kafka TLS et SASL
+		KafkaTLS:                   getEnvOrDefault("KAFKA_TLS_ENABLED", "false") == "true",
+		KafkaTLSCAFile:             os.Getenv("KAFKA_TLS_CA_FILE"),
+		KafkaTLSCertFile:           os.Getenv("KAFKA_TLS_CERT_FILE"),
+		KafkaTLSKeyFile:            os.Getenv("KAFKA_TLS_KEY_FILE"),
+		KafkaTLSInsecureSkipVerify: getEnvOrDefault("KAFKA_TLS_INSECURE_SKIP_VERIFY", "false") == "true",
+		KafkaSASLMechanism:         strings.ToLower(os.Getenv("KAFKA_SASL_MECHANISM")),
+		KafkaSASLUsername:          os.Getenv("KAFKA_SASL_USERNAME"),
+		KafkaSASLPassword:          os.Getenv("KAFKA_SASL_PASSWORD"),
+
+		// Ingestion HTTP
+		IngestMaxBytes:  int64(getEnvIntOrDefault("INGEST_MAX_BYTES", 16*1024*1024)),
+		IngestMaxEvents: getEnvIntOrDefault("INGEST_MAX_EVENTS", 5000),
 
 		// Service
 		ServiceName:   "ingestion-service",
@@ -51,6 +124,18 @@ func LoadConfig() (*Config, error) {
 		FlushInterval: 5,
 		WorkerCount:   4,
 
+		// Authentication
+		AuthEnabled:        getEnvOrDefault("AUTH_ENABLED", "true") == "true",
+		APIKeysFile:        os.Getenv("AUTH_API_KEYS_FILE"),
+		JWTHS256Secret:     os.Getenv("AUTH_JWT_HS256_SECRET"),
+		JWTJWKSFile:        os.Getenv("AUTH_JWT_JWKS_FILE"),
+		JWTIssuer:          os.Getenv("AUTH_JWT_ISSUER"),
+		JWTAudience:        os.Getenv("AUTH_JWT_AUDIENCE"),
+		JWTRoleClaim:       getEnvOrDefault("AUTH_JWT_ROLE_CLAIM", "role"),
+		UsersFile:          os.Getenv("AUTH_USERS_FILE"),
+		TokenTTL:           tokenTTL,
+		CORSAllowedOrigins: getEnvOrDefault("CORS_ALLOWED_ORIGINS", "http://localhost:5173"),
+
 		// Logging
 		LogLevel: getEnvOrDefault("LOG_LEVEL", "info"),
 	}
@@ -77,6 +162,15 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return value
 }
 
+// getEnvIntOrDefault retourne la valeur entière d'une variable d'environnement ou une valeur par défaut
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 // Validate valide la configuration
 func (c *Config) Validate() error {
 	if c.DatabaseURL == "" {
@@ -88,18 +182,71 @@ func (c *Config) Validate() error {
 	if c.KafkaTopicRawEvents == "" {
 		return fmt.Errorf("kafka_topic_raw_events cannot be empty")
 	}
+	if c.IngestMaxBytes <= 0 || c.IngestMaxEvents <= 0 {
+		return fmt.Errorf("ingest_max_bytes and ingest_max_events must be positive")
+	}
+	if err := c.validateKafka(); err != nil {
+		return err
+	}
+	if c.AuthEnabled && c.APIKeysFile == "" && c.JWTHS256Secret == "" && c.JWTJWKSFile == "" {
+		return fmt.Errorf("authentication is enabled but no API keys file, HS256 secret or JWKS file is configured")
+	}
+	if c.AuthEnabled && c.UsersFile != "" && c.JWTHS256Secret == "" {
+		return fmt.Errorf("auth_users_file requires auth_jwt_hs256_secret to sign login tokens")
+	}
+	if c.TokenTTL <= 0 {
+		return fmt.Errorf("auth_token_ttl must be positive")
+	}
+	if strings.Contains(c.CORSAllowedOrigins, "*") {
+		return fmt.Errorf("cors_allowed_origins must list explicit origins")
+	}
+	if c.AgentHeartbeatInterval <= 0 {
+		return fmt.Errorf("agent_heartbeat_interval must be positive")
+	}
+	if c.AgentStaleHeartbeats <= 0 || c.AgentOfflineHeartbeats <= c.AgentStaleHeartbeats {
+		return fmt.Errorf("agent_offline_heartbeats must be greater than agent_stale_heartbeats, both positive")
+	}
+	return nil
+}
+
+// validateKafka valide la compression, TLS et SASL du publisher d'ingestion
+func (c *Config) validateKafka() error {
+	switch c.KafkaCompression {
+	case "none", "gzip", "snappy", "lz4", "zstd":
+	default:
+		return fmt.Errorf("kafka_compression must be none, gzip, snappy, lz4 or zstd, got %q", c.KafkaCompression)
+	}
+	if (c.KafkaTLSCertFile == "") != (c.KafkaTLSKeyFile == "") {
+		return fmt.Errorf("kafka_tls_cert_file and kafka_tls_key_file must be set together")
+	}
+	if !c.KafkaTLS && (c.KafkaTLSCAFile != "" || c.KafkaTLSCertFile != "") {
+		return fmt.Errorf("kafka TLS files are set but kafka_tls_enabled is false")
+	}
+	switch c.KafkaSASLMechanism {
+	case "":
+	case "plain", "scram-sha-256", "scram-sha-512":
+		if c.KafkaSASLUsername == "" || c.KafkaSASLPassword == "" {
+			return fmt.Errorf("kafka_sasl_username and kafka_sasl_password are required by SASL %s", c.KafkaSASLMechanism)
+		}
+		if c.KafkaSASLMechanism == "plain" && !c.KafkaTLS {
+			return fmt.Errorf("SASL plain sends the password in clear text, enable kafka_tls_enabled")
+		}
+	default:
+		return fmt.Errorf("kafka_sasl_mechanism must be plain, scram-sha-256 or scram-sha-512, got %q", c.KafkaSASLMechanism)
+	}
 	return nil
 }
 
 // String retourne une représentation string de la config
 func (c *Config) String() string {
 	return fmt.Sprintf(
-		"Service{Name: %s, DB: %s:%s/%s, Kafka: %v, Topic: %s}",
+		"Service{Name: %s, DB: %s:%s/%s, Kafka: %v, Topic: %s, Auth: %t}",
 		c.ServiceName,
 		c.DatabaseHost,
 		c.DatabasePort,
 		c.DatabaseName,
 		c.KafkaBrokers,
 		c.KafkaTopicRawEvents,
+		c.AuthEnabled,
 	)
 }
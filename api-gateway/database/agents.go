@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+// AgentStatusPolicy fixe les seuils du statut des agents, en nombre
+// d'intervalles de heartbeat écoulés depuis le dernier signe de vie
+type AgentStatusPolicy struct {
+	DefaultInterval   time.Duration // pour les agents qui ne publient pas leur intervalle
+	StaleHeartbeats   int
+	OfflineHeartbeats int
+}
+
+// agentSelect dérive le statut de chaque agent ; $1, $2 et $3 sont
+// l'intervalle par défaut et les seuils de la politique. Un agent qui n'a
+// jamais envoyé de heartbeat est jugé sur son dernier événement.
+const agentSelect = `
+	SELECT * FROM (
+		SELECT
+			agent_id, hostname, ip_address, os_type, os_version, kernel_version,
+			agent_version, collectors,
+			COALESCE(heartbeat_interval_seconds, $1) AS heartbeat_interval_seconds,
+			COALESCE(event_rate, 0) AS event_rate, events_total,
+			COALESCE(spool_depth, 0) AS spool_depth,
+			registered_at, previous_agent_id,
+			first_seen, last_seen, last_heartbeat, silent_alert_at,
+			CASE
+				WHEN COALESCE(last_heartbeat, last_seen) >= NOW() - make_interval(secs => COALESCE(heartbeat_interval_seconds, $1) * $2) THEN 'online'
+				WHEN COALESCE(last_heartbeat, last_seen) >= NOW() - make_interval(secs => COALESCE(heartbeat_interval_seconds, $1) * $3) THEN 'stale'
+				ELSE 'offline'
+			END AS status
+		FROM agents
+	) a
+`
+
+// args retourne les paramètres $1 à $3 de agentSelect
+func (p AgentStatusPolicy) args() []interface{} {
+	return []interface{}{p.DefaultInterval.Seconds(), p.StaleHeartbeats, p.OfflineHeartbeats}
+}
+
+// GetAgents retourne les agents filtrés par critères, triés par hostname
+func (ts *TimescaleDB) GetAgents(ctx context.Context, policy AgentStatusPolicy, filters map[string]interface{}, limit int, offset int) ([]*models.Agent, error) {
+	query := agentSelect + " WHERE 1=1"
+	args := policy.args()
+	argPos := len(args) + 1
+
+	// Filtres d'égalité sur les colonnes
+	for _, column := range []string{"status", "hostname", "os_type", "agent_version"} {
+		if value, ok := filters[column].(string); ok && value != "" {
+			query += fmt.Sprintf(" AND %s = $%d", column, argPos)
+			args = append(args, value)
+			argPos++
+		}
+	}
+
+	query += fmt.Sprintf(" ORDER BY hostname, agent_id LIMIT $%d OFFSET $%d", argPos, argPos+1)
+	args = append(args, limit, offset)
+
+	rows, err := ts.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query agents: %w", err)
+	}
+	defer rows.Close()
+
+	var agents []*models.Agent
+	for rows.Next() {
+		agent, err := scanAgent(rows)
+		if err != nil {
+			return nil, err
+		}
+		agents = append(agents, agent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return agents, nil
+}
+
+// GetAgent retourne un agent par son ID
+func (ts *TimescaleDB) GetAgent(ctx context.Context, policy AgentStatusPolicy, agentID string) (*models.Agent, error) {
+	query := agentSelect + " WHERE agent_id = $4"
+
+	agent, err := scanAgent(ts.db.QueryRowContext(ctx, query, append(policy.args(), agentID)...))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return agent, err
+}
+
+// GetAgentStatusCounts retourne le nombre d'agents par statut
+func (ts *TimescaleDB) GetAgentStatusCounts(ctx context.Context, policy AgentStatusPolicy) (map[string]int, error) {
+	query := "SELECT status, COUNT(*) FROM (" + agentSelect + ") s GROUP BY status"
+
+	rows, err := ts.db.QueryContext(ctx, query, policy.args()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query agent status counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int{
+		models.AgentStatusOnline:  0,
+		models.AgentStatusStale:   0,
+		models.AgentStatusOffline: 0,
+	}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan agent status count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return counts, nil
+}
+
+// scanAgent lit un agent dans l'ordre des colonnes de agentSelect
+func scanAgent(row rowScanner) (*models.Agent, error) {
+	a := &models.Agent{}
+	var ipAddress, osType, osVersion, kernelVersion, agentVersion, previousAgentID sql.NullString
+	var registeredAt, lastHeartbeat, silentAlertAt sql.NullTime
+
+	err := row.Scan(
+		&a.AgentID,
+		&a.Hostname,
+		&ipAddress,
+		&osType,
+		&osVersion,
+		&kernelVersion,
+		&agentVersion,
+		pq.Array(&a.Collectors),
+		&a.HeartbeatInterval,
+		&a.EventRate,
+		&a.EventsTotal,
+		&a.SpoolDepth,
+		&registeredAt,
+		&previousAgentID,
+		&a.FirstSeen,
+		&a.LastSeen,
+		&lastHeartbeat,
+		&silentAlertAt,
+		&a.Status,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan agent: %w", err)
+	}
+
+	a.IPAddress = ipAddress.String
+	a.OSType = osType.String
+	a.OSVersion = osVersion.String
+	a.KernelVersion = kernelVersion.String
+	a.AgentVersion = agentVersion.String
+	a.PreviousAgentID = previousAgentID.String
+	if registeredAt.Valid {
+		a.RegisteredAt = &registeredAt.Time
+	}
+	if lastHeartbeat.Valid {
+		a.LastHeartbeat = &lastHeartbeat.Time
+	}
+	if silentAlertAt.Valid {
+		a.SilentAlertAt = &silentAlertAt.Time
+	}
+
+	return a, nil
+}
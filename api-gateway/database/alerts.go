@@ -0,0 +1,357 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+const alertColumns = `
+	id, created_at, updated_at, rule_id, rule_title, rule_level,
+	description, tags, event_id, event_timestamp, agent_id, hostname,
+	event_type, status, assignee, resolution
+`
+
+// GetAlerts retourne les alertes filtrées par critères, les plus récentes d'abord
+func (ts *TimescaleDB) GetAlerts(ctx context.Context, filters map[string]interface{}, limit int, offset int) ([]*models.Alert, error) {
+	query := "SELECT" + alertColumns + "FROM alerts WHERE 1=1"
+	args := []interface{}{}
+	argPos := 1
+
+	// Filtres d'égalité sur les colonnes
+	for _, column := range []string{"status", "rule_level", "rule_id", "hostname", "agent_id", "assignee"} {
+		if value, ok := filters[column].(string); ok && value != "" {
+			query += fmt.Sprintf(" AND %s = $%d", column, argPos)
+			args = append(args, value)
+			argPos++
+		}
+	}
+
+	// Filtre par tag (ex. technique MITRE attack.t1059)
+	if tag, ok := filters["tag"].(string); ok && tag != "" {
+		query += fmt.Sprintf(" AND tags @> ARRAY[$%d]::text[]", argPos)
+		args = append(args, tag)
+		argPos++
+	}
+
+	// Filtre par période de création
+	if startTime, ok := filters["start_time"].(time.Time); ok {
+		query += fmt.Sprintf(" AND created_at >= $%d", argPos)
+		args = append(args, startTime)
+		argPos++
+	}
+
+	if endTime, ok := filters["end_time"].(time.Time); ok {
+		query += fmt.Sprintf(" AND created_at <= $%d", argPos)
+		args = append(args, endTime)
+		argPos++
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", argPos, argPos+1)
+	args = append(args, limit, offset)
+
+	rows, err := ts.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []*models.Alert
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return alerts, nil
+}
+
+// GetAlert retourne une alerte par son ID
+func (ts *TimescaleDB) GetAlert(ctx context.Context, id int64) (*models.Alert, error) {
+	query := "SELECT" + alertColumns + "FROM alerts WHERE id = $1"
+
+	alert, err := scanAlert(ts.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return alert, err
+}
+
+// GetAlertEvent retourne l'événement de raw_events qui a déclenché l'alerte
+func (ts *TimescaleDB) GetAlertEvent(ctx context.Context, alert *models.Alert) (*models.Event, error) {
+	query := eventSelect + " WHERE id = $1 AND timestamp = $2"
+
+	rows, err := ts.db.QueryContext(ctx, query, alert.EventID, alert.EventTimestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert event: %w", err)
+	}
+	defer rows.Close()
+
+	events, err := ts.scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		// L'événement a pu être supprimé par la politique de rétention
+		return nil, ErrNotFound
+	}
+	return events[0], nil
+}
+
+// UpdateAlertStatus fait passer une alerte de from à to et historise la transition.
+// Retourne ErrConflict si le statut a changé entre-temps.
+func (ts *TimescaleDB) UpdateAlertStatus(ctx context.Context, id int64, from, to, resolution, actor, note string) (*models.Alert, error) {
+	tx, err := ts.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE alerts
+		SET status = $3, resolution = $4, updated_at = NOW()
+		WHERE id = $1 AND status = $2
+		RETURNING` + alertColumns
+
+	alert, err := scanAlert(tx.QueryRowContext(ctx, query, id, from, to, nullString(resolution)))
+	if err == sql.ErrNoRows {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	history := `
+		INSERT INTO alert_state_history (alert_id, from_status, to_status, actor, note, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	if _, err := tx.ExecContext(ctx, history, id, from, to, nullString(actor), nullString(note), alert.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to record alert state change: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return alert, nil
+}
+
+// SetAlertAssignee assigne une alerte à un analyste (vide : désassigne)
+func (ts *TimescaleDB) SetAlertAssignee(ctx context.Context, id int64, assignee string) (*models.Alert, error) {
+	query := `
+		UPDATE alerts
+		SET assignee = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING` + alertColumns
+
+	alert, err := scanAlert(ts.db.QueryRowContext(ctx, query, id, nullString(assignee)))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return alert, err
+}
+
+// GetAlertHistory retourne l'historique des statuts d'une alerte, du plus ancien au plus récent
+func (ts *TimescaleDB) GetAlertHistory(ctx context.Context, id int64) ([]*models.AlertStateChange, error) {
+	query := `
+		SELECT id, alert_id, from_status, to_status, actor, note, changed_at
+		FROM alert_state_history
+		WHERE alert_id = $1
+		ORDER BY changed_at, id
+	`
+
+	rows, err := ts.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*models.AlertStateChange
+	for rows.Next() {
+		change := &models.AlertStateChange{}
+		var actor, note sql.NullString
+
+		if err := rows.Scan(&change.ID, &change.AlertID, &change.FromStatus, &change.ToStatus, &actor, &note, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert state change: %w", err)
+		}
+		change.Actor = actor.String
+		change.Note = note.String
+
+		history = append(history, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return history, nil
+}
+
+// AddAlertComment ajoute un commentaire d'analyste à une alerte
+func (ts *TimescaleDB) AddAlertComment(ctx context.Context, id int64, author, body string) (*models.AlertComment, error) {
+	query := `
+		INSERT INTO alert_comments (alert_id, author, body)
+		SELECT id, $2, $3 FROM alerts WHERE id = $1
+		RETURNING id, alert_id, author, body, created_at
+	`
+
+	comment := &models.AlertComment{}
+	err := ts.db.QueryRowContext(ctx, query, id, author, body).Scan(
+		&comment.ID, &comment.AlertID, &comment.Author, &comment.Body, &comment.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert alert comment: %w", err)
+	}
+
+	return comment, nil
+}
+
+// GetAlertComments retourne les commentaires d'une alerte, du plus ancien au plus récent
+func (ts *TimescaleDB) GetAlertComments(ctx context.Context, id int64) ([]*models.AlertComment, error) {
+	query := `
+		SELECT id, alert_id, author, body, created_at
+		FROM alert_comments
+		WHERE alert_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := ts.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []*models.AlertComment
+	for rows.Next() {
+		comment := &models.AlertComment{}
+		if err := rows.Scan(&comment.ID, &comment.AlertID, &comment.Author, &comment.Body, &comment.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert comment: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return comments, nil
+}
+
+// GetAlertMetrics calcule, par niveau de sévérité, le délai moyen de prise en
+// charge (MTTA : première sortie du statut new) et de clôture (MTTC) des
+// alertes créées depuis since, à partir de l'historique des statuts.
+func (ts *TimescaleDB) GetAlertMetrics(ctx context.Context, since time.Time) ([]*models.AlertMetrics, error) {
+	query := `
+		SELECT
+			a.rule_level,
+			COUNT(*) AS total,
+			COUNT(ack.changed_at) AS acknowledged,
+			COUNT(closed.changed_at) AS closed,
+			AVG(EXTRACT(EPOCH FROM ack.changed_at - a.created_at)) AS mtta,
+			AVG(EXTRACT(EPOCH FROM closed.changed_at - a.created_at)) AS mttc
+		FROM alerts a
+		LEFT JOIN (
+			SELECT alert_id, MIN(changed_at) AS changed_at
+			FROM alert_state_history
+			WHERE from_status = $2
+			GROUP BY alert_id
+		) ack ON ack.alert_id = a.id
+		LEFT JOIN (
+			SELECT alert_id, MIN(changed_at) AS changed_at
+			FROM alert_state_history
+			WHERE to_status = $3
+			GROUP BY alert_id
+		) closed ON closed.alert_id = a.id
+		WHERE a.created_at >= $1
+		GROUP BY a.rule_level
+		ORDER BY a.rule_level
+	`
+
+	rows, err := ts.db.QueryContext(ctx, query, since, models.AlertStatusNew, models.AlertStatusClosed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert metrics: %w", err)
+	}
+	defer rows.Close()
+
+	var metrics []*models.AlertMetrics
+	for rows.Next() {
+		m := &models.AlertMetrics{}
+		var mtta, mttc sql.NullFloat64
+
+		if err := rows.Scan(&m.Level, &m.Total, &m.Acknowledged, &m.Closed, &mtta, &mttc); err != nil {
+			return nil, fmt.Errorf("failed to scan alert metrics: %w", err)
+		}
+		if mtta.Valid {
+			m.MTTASeconds = &mtta.Float64
+		}
+		if mttc.Valid {
+			m.MTTCSeconds = &mttc.Float64
+		}
+
+		metrics = append(metrics, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return metrics, nil
+}
+
+// scanAlert scanne une ligne de alerts
+func scanAlert(row rowScanner) (*models.Alert, error) {
+	a := &models.Alert{}
+	var description, assignee, resolution sql.NullString
+
+	err := row.Scan(
+		&a.ID,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+		&a.RuleID,
+		&a.RuleTitle,
+		&a.RuleLevel,
+		&description,
+		pq.Array(&a.Tags),
+		&a.EventID,
+		&a.EventTimestamp,
+		&a.AgentID,
+		&a.Hostname,
+		&a.EventType,
+		&a.Status,
+		&assignee,
+		&resolution,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan alert: %w", err)
+	}
+
+	a.Description = description.String
+	a.Assignee = assignee.String
+	a.Resolution = resolution.String
+
+	return a, nil
+}
+
+// nullString convertit une chaîne vide en NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
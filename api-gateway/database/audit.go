@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+// InsertAuditEntries enregistre un lot d'entrées du journal d'audit via COPY
+func (ts *TimescaleDB) InsertAuditEntries(ctx context.Context, entries []*models.AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := ts.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("audit_log",
+		"occurred_at", "subject", "role", "auth_method", "method", "path",
+		"query", "status", "remote_ip", "user_agent", "duration_ms"))
+	if err != nil {
+		return fmt.Errorf("failed to prepare audit copy: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		if _, err := stmt.ExecContext(ctx,
+			e.OccurredAt, nullString(e.Subject), nullString(e.Role), nullString(e.AuthMethod),
+			e.Method, e.Path, nullString(e.Query), e.Status, e.RemoteIP,
+			nullString(e.UserAgent), e.DurationMs,
+		); err != nil {
+			return fmt.Errorf("failed to copy audit entry: %w", err)
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to flush audit copy: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetAuditEntries retourne les entrées du journal d'audit filtrées par critères
+func (ts *TimescaleDB) GetAuditEntries(ctx context.Context, filters map[string]interface{}, limit int, offset int) ([]*models.AuditEntry, error) {
+	query := `
+		SELECT id, occurred_at, subject, role, auth_method, method, path,
+			query, status, remote_ip, user_agent, duration_ms
+		FROM audit_log
+		WHERE 1=1
+	`
+	args := []interface{}{}
+	argPos := 1
+
+	// Filtre par appelant
+	if subject, ok := filters["subject"].(string); ok && subject != "" {
+		query += fmt.Sprintf(" AND subject = $%d", argPos)
+		args = append(args, subject)
+		argPos++
+	}
+
+	// Filtre par préfixe de chemin
+	if path, ok := filters["path"].(string); ok && path != "" {
+		query += fmt.Sprintf(" AND path LIKE $%d || '%%'", argPos)
+		args = append(args, path)
+		argPos++
+	}
+
+	// Filtre par code de statut HTTP
+	if status, ok := filters["status"].(int); ok {
+		query += fmt.Sprintf(" AND status = $%d", argPos)
+		args = append(args, status)
+		argPos++
+	}
+
+	// Filtre par période
+	if startTime, ok := filters["start_time"].(time.Time); ok {
+		query += fmt.Sprintf(" AND occurred_at >= $%d", argPos)
+		args = append(args, startTime)
+		argPos++
+	}
+
+	if endTime, ok := filters["end_time"].(time.Time); ok {
+		query += fmt.Sprintf(" AND occurred_at <= $%d", argPos)
+		args = append(args, endTime)
+		argPos++
+	}
+
+	query += fmt.Sprintf(" ORDER BY occurred_at DESC, id DESC LIMIT $%d OFFSET $%d", argPos, argPos+1)
+	args = append(args, limit, offset)
+
+	rows, err := ts.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.AuditEntry
+	for rows.Next() {
+		e := &models.AuditEntry{}
+		var subject, role, authMethod, queryString, userAgent sql.NullString
+
+		if err := rows.Scan(
+			&e.ID, &e.OccurredAt, &subject, &role, &authMethod, &e.Method, &e.Path,
+			&queryString, &e.Status, &e.RemoteIP, &userAgent, &e.DurationMs,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		e.Subject = subject.String
+		e.Role = role.String
+		e.AuthMethod = authMethod.String
+		e.Query = queryString.String
+		e.UserAgent = userAgent.String
+
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return entries, nil
+}
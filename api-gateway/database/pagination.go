@@ -0,0 +1,229 @@
+package database
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+// ErrInvalidCursor est retourné pour un curseur illisible
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Sens de parcours d'un curseur
+const (
+	CursorNext = "next" // événements plus anciens que le curseur
+	CursorPrev = "prev" // événements plus récents que le curseur
+)
+
+// eventSelect lit les colonnes attendues par scanEvents
+const eventSelect = `
+	SELECT
+		id, timestamp, agent_id, hostname, event_type, severity,
+		raw_data, source_ip, destination_ip, process_name,
+		process_pid, username, tags, metadata
+	FROM raw_events
+`
+
+// Cursor est une position dans la liste des événements triée par
+// (timestamp, id) décroissants
+type Cursor struct {
+	Timestamp time.Time
+	ID        int64
+	Direction string
+}
+
+// cursorPayload est la forme encodée d'un curseur ; elle est opaque pour les clients
+type cursorPayload struct {
+	T time.Time `json:"t"`
+	I int64     `json:"i"`
+	D string    `json:"d"`
+}
+
+// Encode retourne la représentation opaque du curseur
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(cursorPayload{T: c.Timestamp, I: c.ID, D: c.Direction})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor lit un curseur retourné par une page précédente
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var p cursorPayload
+	if err := json.Unmarshal(data, &p); err != nil || p.T.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	if p.D != CursorNext && p.D != CursorPrev {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{Timestamp: p.T, ID: p.I, Direction: p.D}, nil
+}
+
+// Page décrit la page demandée. Offset n'est utilisé qu'en l'absence de
+// curseur, pour les clients qui paginent encore par décalage.
+type Page struct {
+	Limit     int
+	Offset    int
+	Cursor    *Cursor
+	WithCount bool // estimer le nombre total d'événements
+}
+
+// EventPage est une page d'événements triés du plus récent au plus ancien.
+// NextCursor est vide s'il n'y a pas d'événement plus ancien ; PrevCursor
+// permet de récupérer les événements arrivés depuis.
+type EventPage struct {
+	Events           []*models.Event
+	NextCursor       string
+	PrevCursor       string
+	ApproximateCount *int64
+}
+
+// queryEventPage lit les événements vérifiant where (paramètres args, vide pour
+// tous) en parcourant le jeu de clés (timestamp, id) à partir du curseur de page
+func (ts *TimescaleDB) queryEventPage(ctx context.Context, where string, args []interface{}, page Page) (*EventPage, error) {
+	result := &EventPage{}
+	if page.WithCount {
+		count, err := ts.estimateEventCount(ctx, where, args)
+		if err != nil {
+			return nil, err
+		}
+		result.ApproximateCount = &count
+	}
+
+	query := eventSelect + " WHERE TRUE"
+	if where != "" {
+		query += " AND (" + where + ")"
+	}
+	args = append([]interface{}{}, args...)
+	argPos := len(args) + 1
+	order := "DESC"
+	cursor := page.Cursor
+
+	if cursor != nil {
+		// La condition redondante sur timestamp seul permet l'exclusion de chunks
+		op, bound := "<", "<="
+		if cursor.Direction == CursorPrev {
+			op, bound, order = ">", ">=", "ASC"
+		}
+		query += fmt.Sprintf(" AND timestamp %s $%d AND (timestamp, id) %s ($%d, $%d)", bound, argPos, op, argPos, argPos+1)
+		args = append(args, cursor.Timestamp, cursor.ID)
+		argPos += 2
+	}
+
+	// Une ligne de plus indique s'il existe une page suivante
+	query += fmt.Sprintf(" ORDER BY timestamp %s, id %s LIMIT $%d", order, order, argPos)
+	args = append(args, page.Limit+1)
+	if cursor == nil && page.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argPos+1)
+		args = append(args, page.Offset)
+	}
+
+	rows, err := ts.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events, err := ts.scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(events) > page.Limit
+	if hasMore {
+		events = events[:page.Limit]
+	}
+	if order == "ASC" {
+		for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+			events[i], events[j] = events[j], events[i]
+		}
+	}
+
+	result.Events = events
+	if len(events) == 0 {
+		// Page vide : la position reçue reste valable pour revenir en arrière
+		// ou attendre de nouveaux événements
+		if cursor != nil {
+			result.PrevCursor = (&Cursor{Timestamp: cursor.Timestamp, ID: cursor.ID, Direction: CursorPrev}).Encode()
+		}
+		return result, nil
+	}
+
+	first, last := events[0], events[len(events)-1]
+	result.PrevCursor = (&Cursor{Timestamp: first.Timestamp, ID: first.ID, Direction: CursorPrev}).Encode()
+
+	// En remontant, il existe toujours des événements plus anciens : au moins le curseur
+	if hasMore || (cursor != nil && cursor.Direction == CursorPrev) {
+		result.NextCursor = (&Cursor{Timestamp: last.Timestamp, ID: last.ID, Direction: CursorNext}).Encode()
+	}
+
+	return result, nil
+}
+
+// estimateEventCount estime le nombre d'événements vérifiant where (paramètres
+// args) sans les compter : approximate_row_count sur la table entière, et
+// l'estimation du planificateur pour une requête filtrée
+func (ts *TimescaleDB) estimateEventCount(ctx context.Context, where string, args []interface{}) (int64, error) {
+	if where == "" {
+		var count int64
+		if err := ts.db.QueryRowContext(ctx, "SELECT approximate_row_count('raw_events')").Scan(&count); err != nil {
+			return 0, fmt.Errorf("failed to estimate event count: %w", err)
+		}
+		return count, nil
+	}
+
+	var plan []byte
+	err := ts.db.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) SELECT 1 FROM raw_events WHERE "+where, args...).Scan(&plan)
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate event count: %w", err)
+	}
+
+	var explain []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &explain); err != nil || len(explain) == 0 {
+		return 0, fmt.Errorf("failed to parse query plan: %v", err)
+	}
+	return int64(explain[0].Plan.Rows), nil
+}
+
+// eventFilterConditions traduit les filtres de /events/filter en conditions
+// SQL dont les paramètres sont numérotés à partir de $argPos
+func eventFilterConditions(filters map[string]interface{}, argPos int) (string, []interface{}) {
+	var conditions []string
+	args := []interface{}{}
+
+	// Filtres d'égalité sur les colonnes
+	for _, column := range []string{"event_type", "severity", "hostname"} {
+		if value, ok := filters[column].(string); ok && value != "" {
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", column, argPos))
+			args = append(args, value)
+			argPos++
+		}
+	}
+
+	// Filtre par période
+	if startTime, ok := filters["start_time"].(time.Time); ok {
+		conditions = append(conditions, fmt.Sprintf("timestamp >= $%d", argPos))
+		args = append(args, startTime)
+		argPos++
+	}
+
+	if endTime, ok := filters["end_time"].(time.Time); ok {
+		conditions = append(conditions, fmt.Sprintf("timestamp <= $%d", argPos))
+		args = append(args, endTime)
+		argPos++
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+// ErrNotFound est retourné quand l'élément demandé n'existe pas
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict est retourné quand l'élément a été modifié entre la lecture et l'écriture
+var ErrConflict = errors.New("conflict")
+
+const rejectedEventColumns = `
+	id, rejected_at, stage, reason, payload, agent_id,
+	source_topic, source_partition, source_offset,
+	status, replayed_at, replay_error
+`
+
+// GetRejectedEvents retourne les événements en quarantaine filtrés par critères
+func (ts *TimescaleDB) GetRejectedEvents(ctx context.Context, filters map[string]interface{}, limit int, offset int) ([]*models.RejectedEvent, error) {
+	query := "SELECT" + rejectedEventColumns + "FROM rejected_events WHERE 1=1"
+	args := []interface{}{}
+	argPos := 1
+
+	// Filtre par étape de rejet
+	if stage, ok := filters["stage"].(string); ok && stage != "" {
+		query += fmt.Sprintf(" AND stage = $%d", argPos)
+		args = append(args, stage)
+		argPos++
+	}
+
+	// Filtre par statut
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query += fmt.Sprintf(" AND status = $%d", argPos)
+		args = append(args, status)
+		argPos++
+	}
+
+	// Filtre par agent
+	if agentID, ok := filters["agent_id"].(string); ok && agentID != "" {
+		query += fmt.Sprintf(" AND agent_id = $%d", argPos)
+		args = append(args, agentID)
+		argPos++
+	}
+
+	query += fmt.Sprintf(" ORDER BY rejected_at DESC LIMIT $%d OFFSET $%d", argPos, argPos+1)
+	args = append(args, limit, offset)
+
+	rows, err := ts.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rejected events: %w", err)
+	}
+	defer rows.Close()
+
+	var rejected []*models.RejectedEvent
+	for rows.Next() {
+		r, err := scanRejectedEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		rejected = append(rejected, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return rejected, nil
+}
+
+// GetRejectedEvent retourne un événement en quarantaine par son ID
+func (ts *TimescaleDB) GetRejectedEvent(ctx context.Context, id int64) (*models.RejectedEvent, error) {
+	query := "SELECT" + rejectedEventColumns + "FROM rejected_events WHERE id = $1"
+
+	r, err := scanRejectedEvent(ts.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return r, err
+}
+
+// ReplayRejectedEvent revendique l'entrée, appelle publish pour réinjecter
+// l'événement dans le pipeline d'ingestion puis, une fois la publication
+// acquittée, marque l'entrée comme rejouée avec le payload publié.
+// L'entrée reste verrouillée pendant la publication : retourne ErrConflict si
+// elle a déjà été rejouée, y compris par un rejeu concurrent.
+func (ts *TimescaleDB) ReplayRejectedEvent(ctx context.Context, id int64, payload string, publish func(ctx context.Context) error) (*models.RejectedEvent, error) {
+	tx, err := ts.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var claimed int64
+	err = tx.QueryRowContext(ctx,
+		"SELECT id FROM rejected_events WHERE id = $1 AND status <> $2 FOR UPDATE",
+		id, models.RejectedStatusReplayed,
+	).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return nil, ErrConflict
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim rejected event: %w", err)
+	}
+
+	if err := publish(ctx); err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE rejected_events
+		SET status = $2, payload = $3, replayed_at = NOW(), replay_error = NULL
+		WHERE id = $1
+		RETURNING` + rejectedEventColumns
+
+	rejected, err := scanRejectedEvent(tx.QueryRowContext(ctx, query, id, models.RejectedStatusReplayed, []byte(payload)))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return rejected, nil
+}
+
+// RecordReplayError enregistre l'échec d'un rejeu
+func (ts *TimescaleDB) RecordReplayError(ctx context.Context, id int64, replayErr error) error {
+	query := "UPDATE rejected_events SET replay_error = $2 WHERE id = $1"
+
+	if _, err := ts.db.ExecContext(ctx, query, id, replayErr.Error()); err != nil {
+		return fmt.Errorf("failed to record replay error: %w", err)
+	}
+	return nil
+}
+
+// rowScanner est implémenté par *sql.Row et *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRejectedEvent scanne une ligne de rejected_events
+func scanRejectedEvent(row rowScanner) (*models.RejectedEvent, error) {
+	r := &models.RejectedEvent{}
+	var agentID, replayError sql.NullString
+	var replayedAt sql.NullTime
+
+	err := row.Scan(
+		&r.ID,
+		&r.RejectedAt,
+		&r.Stage,
+		&r.Reason,
+		&r.Payload,
+		&agentID,
+		&r.SourceTopic,
+		&r.SourcePartition,
+		&r.SourceOffset,
+		&r.Status,
+		&replayedAt,
+		&replayError,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan rejected event: %w", err)
+	}
+
+	if agentID.Valid {
+		r.AgentID = agentID.String
+	}
+	if replayedAt.Valid {
+		r.ReplayedAt = &replayedAt.Time
+	}
+	if replayError.Valid {
+		r.ReplayError = replayError.String
+	}
+
+	return r, nil
+}
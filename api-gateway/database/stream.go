@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+// GetEventsByIDs relit des événements qui viennent d'être insérés.
+// L'intervalle [from, to] limite la recherche aux chunks concernés.
+func (ts *TimescaleDB) GetEventsByIDs(ctx context.Context, ids []int64, from, to time.Time) ([]*models.Event, error) {
+	query := eventSelect + " WHERE id = ANY($1) AND timestamp BETWEEN $2 AND $3 ORDER BY timestamp, id"
+
+	rows, err := ts.db.QueryContext(ctx, query, pq.Array(ids), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events by id: %w", err)
+	}
+	defer rows.Close()
+
+	return ts.scanEvents(rows)
+}
+
+// GetAlertsByEventIDs retourne les alertes déclenchées par des événements
+func (ts *TimescaleDB) GetAlertsByEventIDs(ctx context.Context, eventIDs []int64) ([]*models.Alert, error) {
+	query := "SELECT" + alertColumns + "FROM alerts WHERE event_id = ANY($1) ORDER BY id"
+
+	rows, err := ts.db.QueryContext(ctx, query, pq.Array(eventIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts by event id: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAlerts(rows)
+}
+
+// GetAlertsByIDs relit des alertes levées sans nouvel événement
+func (ts *TimescaleDB) GetAlertsByIDs(ctx context.Context, ids []int64) ([]*models.Alert, error) {
+	query := "SELECT" + alertColumns + "FROM alerts WHERE id = ANY($1) ORDER BY id"
+
+	rows, err := ts.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts by id: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAlerts(rows)
+}
+
+func scanAlerts(rows *sql.Rows) ([]*models.Alert, error) {
+	var alerts []*models.Alert
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,221 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MinTimelineBucket et MaxTimelineBucket bornent la taille d'un intervalle
+	MinTimelineBucket = 10 * time.Second
+	MaxTimelineBucket = 30 * 24 * time.Hour
+
+	// MaxTimelineBuckets borne le nombre d'intervalles d'une timeline
+	MaxTimelineBuckets = 5000
+)
+
+// timelineAggregate est un agrégat continu TimescaleDB des comptes d'événements
+// par event_type, severity et hostname
+type timelineAggregate struct {
+	view     string
+	bucket   time.Duration
+	interval string // taille de l'intervalle, en littéral SQL
+
+	// Politique de rafraîchissement
+	startOffset string
+	endOffset   string
+	schedule    string
+}
+
+// timelineAggregates sont triés du plus grossier au plus fin : la timeline
+// utilise le premier dont l'intervalle divise celui demandé.
+var timelineAggregates = []timelineAggregate{
+	{view: "events_per_day", bucket: 24 * time.Hour, interval: "1 day", startOffset: "7 days", endOffset: "1 day", schedule: "1 hour"},
+	{view: "events_per_hour", bucket: time.Hour, interval: "1 hour", startOffset: "3 days", endOffset: "1 hour", schedule: "30 minutes"},
+	{view: "events_per_minute", bucket: time.Minute, interval: "1 minute", startOffset: "2 hours", endOffset: "1 minute", schedule: "1 minute"},
+}
+
+var bucketPattern = regexp.MustCompile(`^(\d+)\s*([a-z]+)$`)
+
+var bucketUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// ParseTimelineBucket convertit un intervalle ("1 hour", "15 minutes", "5m",
+// "1d"...) en durée. Seuls ces formats sont acceptés : l'intervalle n'est
+// jamais transmis tel quel à la base.
+func ParseTimelineBucket(s string) (time.Duration, error) {
+	match := bucketPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if match == nil {
+		return 0, fmt.Errorf("invalid interval %q (expected e.g. \"1 hour\", \"15 minutes\", \"5m\")", s)
+	}
+
+	unit, ok := bucketUnits[match[2]]
+	if !ok {
+		return 0, fmt.Errorf("invalid interval unit %q (expected seconds, minutes, hours, days or weeks)", match[2])
+	}
+	count, err := strconv.Atoi(match[1])
+	if err != nil || count <= 0 {
+		return 0, fmt.Errorf("invalid interval %q", s)
+	}
+
+	bucket := time.Duration(count) * unit
+	if bucket < MinTimelineBucket || bucket > MaxTimelineBucket {
+		return 0, fmt.Errorf("interval must be between %s and %s", MinTimelineBucket, MaxTimelineBucket)
+	}
+	return bucket, nil
+}
+
+// EnsureTimelineAggregates crée les agrégats continus de la timeline et leurs
+// politiques de rafraîchissement s'ils n'existent pas. Il retourne les vues
+// créées, qui doivent être remplies par RefreshTimelineAggregate.
+// En cas d'échec, la timeline continue d'interroger raw_events.
+func (ts *TimescaleDB) EnsureTimelineAggregates(ctx context.Context) ([]string, error) {
+	var created []string
+	for _, agg := range timelineAggregates {
+		var exists bool
+		err := ts.db.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM timescaledb_information.continuous_aggregates
+				WHERE view_name = $1
+			)`, agg.view).Scan(&exists)
+		if err != nil {
+			return created, fmt.Errorf("failed to look up continuous aggregate %s: %w", agg.view, err)
+		}
+
+		if !exists {
+			// materialized_only = false : les données pas encore matérialisées
+			// sont agrégées à la volée depuis raw_events
+			query := fmt.Sprintf(`
+				CREATE MATERIALIZED VIEW %s
+				WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+				SELECT
+					time_bucket(INTERVAL '%s', timestamp) AS bucket,
+					event_type,
+					severity,
+					hostname,
+					COUNT(*) AS event_count
+				FROM raw_events
+				GROUP BY bucket, event_type, severity, hostname
+				WITH NO DATA
+			`, agg.view, agg.interval)
+			if _, err := ts.db.ExecContext(ctx, query); err != nil {
+				return created, fmt.Errorf("failed to create continuous aggregate %s: %w", agg.view, err)
+			}
+			created = append(created, agg.view)
+		}
+
+		query := fmt.Sprintf(`
+			SELECT add_continuous_aggregate_policy('%s',
+				start_offset => INTERVAL '%s',
+				end_offset => INTERVAL '%s',
+				schedule_interval => INTERVAL '%s',
+				if_not_exists => true)
+		`, agg.view, agg.startOffset, agg.endOffset, agg.schedule)
+		if _, err := ts.db.ExecContext(ctx, query); err != nil {
+			return created, fmt.Errorf("failed to add refresh policy to %s: %w", agg.view, err)
+		}
+	}
+
+	ts.timelineAggregates = true
+	return created, nil
+}
+
+// RefreshTimelineAggregate matérialise tout l'historique d'un agrégat.
+// La politique ne rafraîchit que la fenêtre récente : sans ce remplissage
+// initial, les événements antérieurs à la création ne seraient jamais matérialisés.
+func (ts *TimescaleDB) RefreshTimelineAggregate(ctx context.Context, view string) error {
+	for _, agg := range timelineAggregates {
+		if agg.view != view {
+			continue
+		}
+		query := fmt.Sprintf(`CALL refresh_continuous_aggregate('%s', NULL, NOW() - INTERVAL '%s')`, agg.view, agg.endOffset)
+		if _, err := ts.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to refresh continuous aggregate %s: %w", view, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown continuous aggregate %q", view)
+}
+
+// timelineSource retourne l'agrégat le plus grossier compatible avec bucket,
+// ou raw_events
+func (ts *TimescaleDB) timelineSource(bucket time.Duration) (view string, column string, count string) {
+	if ts.timelineAggregates {
+		for _, agg := range timelineAggregates {
+			if bucket%agg.bucket == 0 {
+				return agg.view, "bucket", "SUM(event_count)"
+			}
+		}
+	}
+	return "raw_events", "timestamp", "COUNT(*)"
+}
+
+// GetEventsByTimeRange retourne les événements groupés par intervalle de temps
+// sur la fenêtre [now - window, now]. Il retourne aussi la table interrogée.
+func (ts *TimescaleDB) GetEventsByTimeRange(ctx context.Context, bucket time.Duration, window time.Duration) ([]map[string]interface{}, string, error) {
+	if bucket < MinTimelineBucket || bucket > MaxTimelineBucket || bucket%time.Second != 0 {
+		return nil, "", fmt.Errorf("invalid bucket size %s", bucket)
+	}
+	if window <= 0 || window/bucket > MaxTimelineBuckets {
+		return nil, "", fmt.Errorf("time range of %s with %s buckets exceeds %d buckets", window, bucket, MaxTimelineBuckets)
+	}
+
+	// Sur un agrégat, la fenêtre est alignée sur ses intervalles : le premier
+	// intervalle partiel est exclu plutôt que compté en entier
+	source, column, count := ts.timelineSource(bucket)
+	query := fmt.Sprintf(`
+		SELECT
+			time_bucket($1::interval, %s) AS bucket,
+			event_type,
+			severity,
+			%s as count
+		FROM %s
+		WHERE %s >= NOW() - $2::interval
+		GROUP BY 1, event_type, severity
+		ORDER BY bucket DESC
+	`, column, count, source, column)
+
+	rows, err := ts.db.QueryContext(ctx, query, pgInterval(bucket), pgInterval(window))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query time range stats: %w", err)
+	}
+	defer rows.Close()
+
+	var results []map[string]interface{}
+	for rows.Next() {
+		var bucket time.Time
+		var eventType, severity string
+		var count int64
+
+		if err := rows.Scan(&bucket, &eventType, &severity, &count); err != nil {
+			return nil, "", fmt.Errorf("failed to scan time range row: %w", err)
+		}
+
+		results = append(results, map[string]interface{}{
+			"timestamp":  bucket,
+			"event_type": eventType,
+			"severity":   severity,
+			"count":      count,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return results, source, nil
+}
+
+// pgInterval formate une durée en intervalle PostgreSQL
+func pgInterval(d time.Duration) string {
+	return fmt.Sprintf("%d seconds", int64(d/time.Second))
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/luigi/xdr-platform/api/database"
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+// agentRecentAlerts est le nombre d'alertes récentes retournées avec un agent
+const agentRecentAlerts = 10
+
+// AgentsHandler gère l'inventaire des agents
+type AgentsHandler struct {
+	db     *database.TimescaleDB
+	policy database.AgentStatusPolicy
+}
+
+// NewAgentsHandler crée un nouveau handler pour les agents
+func NewAgentsHandler(db *database.TimescaleDB, policy database.AgentStatusPolicy) *AgentsHandler {
+	return &AgentsHandler{db: db, policy: policy}
+}
+
+// GetAgents retourne l'inventaire des agents et le nombre d'agents par statut
+// GET /api/v1/agents?status=offline&hostname=web-01&os_type=linux&agent_version=1.0.0&limit=100&offset=0
+func (h *AgentsHandler) GetAgents(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filters := make(map[string]interface{})
+
+	for _, key := range []string{"status", "hostname", "os_type", "agent_version"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+
+	if status, ok := filters["status"].(string); ok && !models.IsValidAgentStatus(status) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid status (expected online, stale or offline)",
+		})
+	}
+
+	// Pagination
+	limit := c.QueryInt("limit", 100)
+	if limit > 1000 {
+		limit = 1000
+	}
+	offset := c.QueryInt("offset", 0)
+
+	agents, err := h.db.GetAgents(ctx, h.policy, filters, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve agents",
+			"details": err.Error(),
+		})
+	}
+
+	summary, err := h.db.GetAgentStatusCounts(ctx, h.policy)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve agent status counts",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"count":   len(agents),
+		"filters": filters,
+		"summary": summary,
+		"agents":  agents,
+	})
+}
+
+// GetAgent retourne un agent avec ses alertes les plus récentes
+// GET /api/v1/agents/:id
+func (h *AgentsHandler) GetAgent(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	agentID := c.Params("id")
+
+	agent, err := h.db.GetAgent(ctx, h.policy, agentID)
+	if err == database.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Agent not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve agent",
+			"details": err.Error(),
+		})
+	}
+
+	alerts, err := h.db.GetAlerts(ctx, map[string]interface{}{"agent_id": agentID}, agentRecentAlerts, 0)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve agent alerts",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"agent":   agent,
+		"alerts":  alerts,
+	})
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/luigi/xdr-platform/api/auth"
+	"github.com/luigi/xdr-platform/api/database"
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+// AlertsHandler gère les requêtes liées aux alertes de détection
+type AlertsHandler struct {
+	db *database.TimescaleDB
+}
+
+// NewAlertsHandler crée un nouveau handler pour les alertes
+func NewAlertsHandler(db *database.TimescaleDB) *AlertsHandler {
+	return &AlertsHandler{db: db}
+}
+
+// GetAlerts retourne la liste des alertes
+// GET /api/v1/alerts?status=new&rule_level=high&rule_id=...&hostname=web-01&agent_id=...&assignee=alice&tag=attack.t1059&start_time=...&end_time=...&limit=50&offset=0
+func (h *AlertsHandler) GetAlerts(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filters := make(map[string]interface{})
+
+	for _, key := range []string{"status", "rule_level", "rule_id", "hostname", "agent_id", "assignee", "tag"} {
+		if value := c.Query(key); value != "" {
+			filters[key] = value
+		}
+	}
+
+	if status, ok := filters["status"].(string); ok && !models.IsValidAlertStatus(status) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid status (expected new, acknowledged, investigating or closed)",
+		})
+	}
+
+	// Filtres temporels
+	if startTime := c.Query("start_time"); startTime != "" {
+		if t, err := time.Parse(time.RFC3339, startTime); err == nil {
+			filters["start_time"] = t
+		}
+	}
+
+	if endTime := c.Query("end_time"); endTime != "" {
+		if t, err := time.Parse(time.RFC3339, endTime); err == nil {
+			filters["end_time"] = t
+		}
+	}
+
+	// Pagination
+	limit := c.QueryInt("limit", 50)
+	if limit > 1000 {
+		limit = 1000
+	}
+	offset := c.QueryInt("offset", 0)
+
+	alerts, err := h.db.GetAlerts(ctx, filters, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve alerts",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"count":   len(alerts),
+		"filters": filters,
+		"alerts":  alerts,
+	})
+}
+
+// GetAlert retourne une alerte avec son événement déclencheur, son historique et ses commentaires
+// GET /api/v1/alerts/:id
+func (h *AlertsHandler) GetAlert(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	alert, err := h.lookupAlert(ctx, c)
+	if err != nil || alert == nil {
+		return err
+	}
+
+	event, err := h.db.GetAlertEvent(ctx, alert)
+	if err != nil && err != database.ErrNotFound {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve alert event",
+			"details": err.Error(),
+		})
+	}
+
+	history, err := h.db.GetAlertHistory(ctx, alert.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve alert history",
+			"details": err.Error(),
+		})
+	}
+
+	comments, err := h.db.GetAlertComments(ctx, alert.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve alert comments",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"alert":    alert,
+		"event":    event,
+		"history":  history,
+		"comments": comments,
+	})
+}
+
+// UpdateAlertStatus fait avancer une alerte dans son cycle de vie
+// POST /api/v1/alerts/:id/status {"status": "closed", "resolution": "false_positive", "note": "..."}
+// L'auteur du changement est l'appelant authentifié.
+func (h *AlertsHandler) UpdateAlertStatus(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var body struct {
+		Status     string `json:"status"`
+		Resolution string `json:"resolution"`
+		Note       string `json:"note"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	if !models.IsValidAlertStatus(body.Status) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid status (expected acknowledged, investigating or closed)",
+		})
+	}
+
+	// Une résolution est obligatoire à la clôture et uniquement à la clôture
+	if body.Status == models.AlertStatusClosed {
+		if !models.IsValidAlertResolution(body.Resolution) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Closing an alert requires a resolution (true_positive, benign, false_positive or duplicate)",
+			})
+		}
+	} else if body.Resolution != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Resolution is only allowed when closing an alert",
+		})
+	}
+
+	alert, err := h.lookupAlert(ctx, c)
+	if err != nil || alert == nil {
+		return err
+	}
+
+	if !models.CanTransitionAlert(alert.Status, body.Status) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Invalid status transition from " + alert.Status + " to " + body.Status,
+		})
+	}
+
+	updated, err := h.db.UpdateAlertStatus(ctx, alert.ID, alert.Status, body.Status, body.Resolution, auth.PrincipalFrom(c).Subject, body.Note)
+	if err == database.ErrConflict {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Alert status was changed concurrently, reload and retry",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to update alert status",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"alert":   updated,
+	})
+}
+
+// AssignAlert assigne une alerte à un analyste ; un assignee vide la désassigne
+// PUT /api/v1/alerts/:id/assignee {"assignee": "alice"}
+func (h *AlertsHandler) AssignAlert(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid alert id",
+		})
+	}
+
+	var body struct {
+		Assignee string `json:"assignee"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	alert, err := h.db.SetAlertAssignee(ctx, int64(id), strings.TrimSpace(body.Assignee))
+	if err == database.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Alert not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to assign alert",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"alert":   alert,
+	})
+}
+
+// AddAlertComment ajoute un commentaire d'analyste
+// POST /api/v1/alerts/:id/comments {"body": "..."}
+// L'auteur du commentaire est l'appelant authentifié.
+func (h *AlertsHandler) AddAlertComment(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid alert id",
+		})
+	}
+
+	var body struct {
+		Body string `json:"body"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	body.Body = strings.TrimSpace(body.Body)
+	if body.Body == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "body is required",
+		})
+	}
+
+	comment, err := h.db.AddAlertComment(ctx, int64(id), auth.PrincipalFrom(c).Subject, body.Body)
+	if err == database.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Alert not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to add alert comment",
+			"details": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"comment": comment,
+	})
+}
+
+// GetAlertMetrics retourne le MTTA et le MTTC par niveau de sévérité
+// GET /api/v1/alerts/metrics?hours=168
+func (h *AlertsHandler) GetAlertMetrics(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hours := c.QueryInt("hours", 24*7)
+	if hours <= 0 || hours > 24*365 {
+		hours = 24 * 7
+	}
+
+	metrics, err := h.db.GetAlertMetrics(ctx, time.Now().Add(-time.Duration(hours)*time.Hour))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to compute alert metrics",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"hours":   hours,
+		"metrics": metrics,
+	})
+}
+
+// lookupAlert charge l'alerte désignée par le paramètre :id.
+// Si elle est introuvable ou en cas d'erreur, la réponse est déjà écrite et
+// l'alerte retournée est nil.
+func (h *AlertsHandler) lookupAlert(ctx context.Context, c *fiber.Ctx) (*models.Alert, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid alert id",
+		})
+	}
+
+	alert, err := h.db.GetAlert(ctx, int64(id))
+	if err == database.ErrNotFound {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Alert not found",
+		})
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve alert",
+			"details": err.Error(),
+		})
+	}
+
+	return alert, nil
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/luigi/xdr-platform/api/database"
+)
+
+// AuditHandler expose le journal d'audit des accès à l'API
+type AuditHandler struct {
+	db *database.TimescaleDB
+}
+
+// NewAuditHandler crée un nouveau handler pour le journal d'audit
+func NewAuditHandler(db *database.TimescaleDB) *AuditHandler {
+	return &AuditHandler{db: db}
+}
+
+// GetAuditEntries retourne les entrées du journal d'audit
+// GET /api/v1/audit?subject=alice&path=/api/v1/events&status=403&start_time=...&end_time=...&limit=100&offset=0
+func (h *AuditHandler) GetAuditEntries(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filters := make(map[string]interface{})
+
+	if subject := c.Query("subject"); subject != "" {
+		filters["subject"] = subject
+	}
+
+	if path := c.Query("path"); path != "" {
+		filters["path"] = path
+	}
+
+	if status := c.QueryInt("status", 0); status > 0 {
+		filters["status"] = status
+	}
+
+	// Filtres temporels
+	if startTime := c.Query("start_time"); startTime != "" {
+		if t, err := time.Parse(time.RFC3339, startTime); err == nil {
+			filters["start_time"] = t
+		}
+	}
+
+	if endTime := c.Query("end_time"); endTime != "" {
+		if t, err := time.Parse(time.RFC3339, endTime); err == nil {
+			filters["end_time"] = t
+		}
+	}
+
+	// Pagination
+	limit := c.QueryInt("limit", 100)
+	if limit > 1000 {
+		limit = 1000
+	}
+	offset := c.QueryInt("offset", 0)
+
+	entries, err := h.db.GetAuditEntries(ctx, filters, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve audit log",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"count":   len(entries),
+		"filters": filters,
+		"entries": entries,
+	})
+}
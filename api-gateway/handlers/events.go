@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/luigi/xdr-platform/api/database"
 )
 
+// maxTimelineHours borne la fenêtre de la timeline (un an, servi par l'agrégat journalier)
+const maxTimelineHours = 24 * 365
+
 // EventsHandler gère les requêtes liées aux événements
 type EventsHandler struct {
 	db *database.TimescaleDB
@@ -18,20 +22,30 @@ func NewEventsHandler(db *database.TimescaleDB) *EventsHandler {
 	return &EventsHandler{db: db}
 }
 
-// GetEvents retourne une liste paginée d'événements
-// GET /api/v1/events?limit=100&offset=0&event_type=system&severity=high
+// GetEvents retourne une page d'événements, du plus récent au plus ancien
+// GET /api/v1/events?limit=100&cursor=...&event_type=system&severity=high&with_count=true
+// Les curseurs next_cursor et prev_cursor de la réponse donnent la page
+// suivante (plus ancienne) et précédente (plus récente). offset reste accepté
+// sans curseur mais devient coûteux sur les pages lointaines.
 func (h *EventsHandler) GetEvents(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Récupérer les paramètres de pagination
-	limit := c.QueryInt("limit", 50)
-	if limit > 1000 {
-		limit = 1000 // Maximum 1000 événements par requête
+	page, err := parsePage(c, 50)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid cursor",
+			"details": err.Error(),
+		})
+	}
+
+	filters, err := eventFilters(c)
+	if err != nil {
+		return invalidFilters(c, err)
 	}
 
 	// Récupérer les événements
-	events, err := h.db.GetRecentEvents(ctx, limit)
+	result, err := h.db.GetFilteredEvents(ctx, filters, page)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to retrieve events",
@@ -39,11 +53,7 @@ func (h *EventsHandler) GetEvents(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"count":   len(events),
-		"events":  events,
-	})
+	return c.JSON(eventPageResponse(result, filters))
 }
 
 // GetEventCount retourne le nombre total d'événements
@@ -115,12 +125,91 @@ func (h *EventsHandler) HealthCheck(c *fiber.Ctx) error {
 }
 
 // GetFilteredEvents retourne des événements filtrés
-// GET /api/v1/events/filter?event_type=system&severity=high&limit=50
+// GET /api/v1/events/filter?event_type=system&severity=high&limit=50&cursor=...
 func (h *EventsHandler) GetFilteredEvents(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	// Construire les filtres
+	filters, err := eventFilters(c)
+	if err != nil {
+		return invalidFilters(c, err)
+	}
+
+	// Pagination
+	page, err := parsePage(c, 50)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid cursor",
+			"details": err.Error(),
+		})
+	}
+
+	// Récupérer les événements filtrés
+	result, err := h.db.GetFilteredEvents(ctx, filters, page)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve filtered events",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(eventPageResponse(result, filters))
+}
+
+// parsePage lit limit, offset, cursor et with_count
+func parsePage(c *fiber.Ctx, defaultLimit int) (database.Page, error) {
+	page := database.Page{
+		Limit:     c.QueryInt("limit", defaultLimit),
+		Offset:    c.QueryInt("offset", 0),
+		WithCount: c.QueryBool("with_count", false),
+	}
+	if page.Limit <= 0 {
+		page.Limit = defaultLimit
+	}
+	if page.Limit > 1000 {
+		page.Limit = 1000 // Maximum 1000 événements par requête
+	}
+	if page.Offset < 0 {
+		page.Offset = 0
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		decoded, err := database.DecodeCursor(cursor)
+		if err != nil {
+			return page, err
+		}
+		page.Cursor = decoded
+	}
+	return page, nil
+}
+
+// eventPageResponse construit la réponse d'une page d'événements ; un curseur
+// absent est retourné à null
+func eventPageResponse(result *database.EventPage, filters map[string]interface{}) fiber.Map {
+	response := fiber.Map{
+		"success":     true,
+		"count":       len(result.Events),
+		"filters":     filters,
+		"events":      result.Events,
+		"next_cursor": nil,
+		"prev_cursor": nil,
+	}
+	if result.NextCursor != "" {
+		response["next_cursor"] = result.NextCursor
+	}
+	if result.PrevCursor != "" {
+		response["prev_cursor"] = result.PrevCursor
+	}
+	if result.ApproximateCount != nil {
+		response["approximate_count"] = *result.ApproximateCount
+	}
+	return response
+}
+
+// eventFilters lit les filtres communs à /events/filter et aux flux temps réel.
+// Une date invalide est une erreur : l'ignorer ferait chercher sur toute la période.
+func eventFilters(c *fiber.Ctx) (map[string]interface{}, error) {
 	filters := make(map[string]interface{})
 	
 	if eventType := c.Query("event_type"); eventType != "" {
@@ -137,51 +226,64 @@ func (h *EventsHandler) GetFilteredEvents(c *fiber.Ctx) error {
 
 	// Filtres temporels
 	if startTime := c.Query("start_time"); startTime != "" {
-		if t, err := time.Parse(time.RFC3339, startTime); err == nil {
-			filters["start_time"] = t
+		t, err := time.Parse(time.RFC3339, startTime)
+		if err != nil {
+			return nil, fmt.Errorf("start_time must be an RFC 3339 timestamp: %w", err)
 		}
+		filters["start_time"] = t
 	}
 
 	if endTime := c.Query("end_time"); endTime != "" {
-		if t, err := time.Parse(time.RFC3339, endTime); err == nil {
-			filters["end_time"] = t
+		t, err := time.Parse(time.RFC3339, endTime)
+		if err != nil {
+			return nil, fmt.Errorf("end_time must be an RFC 3339 timestamp: %w", err)
 		}
+		filters["end_time"] = t
 	}
 
-	// Pagination
-	limit := c.QueryInt("limit", 50)
-	if limit > 1000 {
-		limit = 1000
-	}
-	offset := c.QueryInt("offset", 0)
-
-	// Récupérer les événements filtrés
-	events, err := h.db.GetFilteredEvents(ctx, filters, limit, offset)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve filtered events",
-			"details": err.Error(),
-		})
-	}
+	return filters, nil
+}
 
-	return c.JSON(fiber.Map{
-		"success": true,
-		"count":   len(events),
-		"filters": filters,
-		"events":  events,
+// invalidFilters répond 400 à des filtres illisibles
+func invalidFilters(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":   "Invalid filters",
+		"details": err.Error(),
 	})
 }
 
 // GetTimeRangeStats retourne des stats par intervalle de temps
-// GET /api/v1/events/timeline?interval=1h&hours=24
+// GET /api/v1/events/timeline?interval=1 hour&hours=24
+// L'intervalle accepte "15 minutes", "1 hour", "5m", "1d"... et est servi par
+// un agrégat continu lorsque sa taille le permet.
 func (h *EventsHandler) GetTimeRangeStats(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	interval := c.Query("interval", "1 hour")
+	bucket, err := database.ParseTimelineBucket(interval)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid interval",
+			"details": err.Error(),
+		})
+	}
+
 	hours := c.QueryInt("hours", 24)
+	if hours < 1 || hours > maxTimelineHours {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("hours must be between 1 and %d", maxTimelineHours),
+		})
+	}
+
+	window := time.Duration(hours) * time.Hour
+	if window/bucket > database.MaxTimelineBuckets {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("interval %s over %d hours exceeds %d buckets, use a larger interval", interval, hours, database.MaxTimelineBuckets),
+		})
+	}
 
-	stats, err := h.db.GetEventsByTimeRange(ctx, interval, hours)
+	stats, source, err := h.db.GetEventsByTimeRange(ctx, bucket, window)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to get timeline stats",
@@ -192,7 +294,9 @@ func (h *EventsHandler) GetTimeRangeStats(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"success": true,
 		"interval": interval,
+		"bucket_seconds": int64(bucket / time.Second),
 		"hours": hours,
+		"source": source,
 		"data": stats,
 	})
 }
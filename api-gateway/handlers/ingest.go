@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/luigi/xdr-platform/api/auth"
+	"github.com/luigi/xdr-platform/api/ingest"
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+// Résultat de l'ingestion d'un événement
+const (
+	ingestAccepted = "accepted"
+	ingestRejected = "rejected"
+)
+
+// ingestResult est le résultat d'un événement du lot, dans l'ordre du lot
+type ingestResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// IngestHandler reçoit les lots d'événements des agents qui n'ont pas accès à Kafka
+type IngestHandler struct {
+	publisher ingest.Publisher
+	maxBytes  int64
+	maxEvents int
+}
+
+// NewIngestHandler crée un nouveau handler d'ingestion HTTP.
+// maxBytes borne la taille décompressée d'un lot, maxEvents son nombre d'événements.
+func NewIngestHandler(publisher ingest.Publisher, maxBytes int64, maxEvents int) *IngestHandler {
+	return &IngestHandler{
+		publisher: publisher,
+		maxBytes:  maxBytes,
+		maxEvents: maxEvents,
+	}
+}
+
+// IngestEvents valide un lot d'événements (NDJSON ou tableau JSON, gzip
+// accepté) et transmet les événements valides. Un agent ne peut envoyer que
+// ses propres événements. La réponse donne le résultat de chaque événement ;
+// elle est en 422 si aucun n'est accepté.
+// POST /api/v1/ingest
+func (h *IngestHandler) IngestEvents(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Corps brut : c.Body() décompresserait sans limite de taille
+	records, err := ingest.Decode(c.Request().Body(), c.Get(fiber.HeaderContentEncoding), h.maxBytes, h.maxEvents)
+	if errors.Is(err, ingest.ErrTooLarge) || errors.Is(err, ingest.ErrTooManyEvents) {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error":      "Batch too large",
+			"details":    err.Error(),
+			"max_bytes":  h.maxBytes,
+			"max_events": h.maxEvents,
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+
+	principal := auth.PrincipalFrom(c)
+
+	results := make([]ingestResult, len(records))
+	accepted := make([]*models.Event, 0, len(records))
+	for i, record := range records {
+		results[i] = ingestResult{Index: i, Status: ingestAccepted}
+
+		event, err := decodeIngestEvent(record, principal)
+		if err != nil {
+			results[i].Status = ingestRejected
+			results[i].Error = err.Error()
+			continue
+		}
+		accepted = append(accepted, event)
+	}
+
+	if len(accepted) == 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":    "No valid event in batch",
+			"accepted": 0,
+			"rejected": len(records),
+			"results":  results,
+		})
+	}
+
+	if err := h.publisher.Publish(ctx, accepted); err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":   "Failed to store events, retry later",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"accepted": len(accepted),
+		"rejected": len(records) - len(accepted),
+		"results":  results,
+	})
+}
+
+// decodeIngestEvent décode et valide un événement du lot
+func decodeIngestEvent(record json.RawMessage, principal *auth.Principal) (*models.Event, error) {
+	var event models.Event
+	if err := json.Unmarshal(record, &event); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+	// L'identifiant est attribué par la base
+	event.ID = 0
+
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+	if principal == nil || !principal.CanActFor(event.AgentID) {
+		return nil, fmt.Errorf("not allowed to send events for agent_id %q", event.AgentID)
+	}
+	return &event, nil
+}
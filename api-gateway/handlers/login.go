@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/luigi/xdr-platform/api/auth"
+)
+
+// LoginHandler connecte les utilisateurs du tableau de bord et leur délivre
+// un jeton JWT à présenter dans Authorization: Bearer
+type LoginHandler struct {
+	users  *auth.UserStore
+	tokens *auth.TokenIssuer
+}
+
+// NewLoginHandler crée un nouveau handler de connexion
+func NewLoginHandler(users *auth.UserStore, tokens *auth.TokenIssuer) *LoginHandler {
+	return &LoginHandler{users: users, tokens: tokens}
+}
+
+// loginRequest est le corps de POST /api/v1/auth/login
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Login vérifie les identifiants et retourne un jeton
+// POST /api/v1/auth/login {"username": "alice", "password": "..."}
+func (h *LoginHandler) Login(c *fiber.Ctx) error {
+	var req loginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+	}
+	if req.Username == "" || req.Password == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "username and password are required",
+		})
+	}
+
+	principal := h.users.Authenticate(req.Username, req.Password)
+	if principal == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid username or password",
+		})
+	}
+	auth.SetPrincipal(c, principal)
+
+	token, expiresAt, err := h.tokens.Issue(principal)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to issue token",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_at":   expiresAt.UTC().Format(time.RFC3339),
+		"subject":      principal.Subject,
+		"role":         principal.Role,
+	})
+}
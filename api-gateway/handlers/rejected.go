@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/luigi/xdr-platform/api/database"
+	"github.com/luigi/xdr-platform/api/ingest"
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+// RejectedHandler gère les requêtes liées aux événements en quarantaine
+type RejectedHandler struct {
+	db        *database.TimescaleDB
+	publisher ingest.Publisher // réinjection des événements rejoués
+}
+
+// NewRejectedHandler crée un nouveau handler pour les événements rejetés
+func NewRejectedHandler(db *database.TimescaleDB, publisher ingest.Publisher) *RejectedHandler {
+	return &RejectedHandler{db: db, publisher: publisher}
+}
+
+// GetRejectedEvents retourne la liste des événements rejetés
+// GET /api/v1/rejected?stage=decode&status=pending&agent_id=agent-1&limit=50&offset=0
+func (h *RejectedHandler) GetRejectedEvents(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filters := make(map[string]interface{})
+
+	if stage := c.Query("stage"); stage != "" {
+		filters["stage"] = stage
+	}
+
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+
+	if agentID := c.Query("agent_id"); agentID != "" {
+		filters["agent_id"] = agentID
+	}
+
+	// Pagination
+	limit := c.QueryInt("limit", 50)
+	offset := c.QueryInt("offset", 0)
+	if limit < 0 || offset < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "limit and offset must not be negative",
+		})
+	}
+	if limit == 0 {
+		limit = 50
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	rejected, err := h.db.GetRejectedEvents(ctx, filters, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve rejected events",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"count":    len(rejected),
+		"filters":  filters,
+		"rejected": rejected,
+	})
+}
+
+// GetRejectedEvent retourne un événement rejeté
+// GET /api/v1/rejected/:id
+func (h *RejectedHandler) GetRejectedEvent(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid rejected event id",
+		})
+	}
+
+	rejected, err := h.db.GetRejectedEvent(ctx, int64(id))
+	if err == database.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Rejected event not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve rejected event",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"rejected": rejected,
+	})
+}
+
+// ReplayRejectedEvent republie un événement rejeté sur le topic raw-events :
+// il repasse par le service d'ingestion (détection, inventaire des agents,
+// flux temps réel). Le corps est optionnel : {"payload": {...}} remplace le
+// payload d'origine par une version corrigée.
+// POST /api/v1/rejected/:id/replay
+func (h *RejectedHandler) ReplayRejectedEvent(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid rejected event id",
+		})
+	}
+
+	var body struct {
+		Payload json.RawMessage `json:"payload"`
+	}
+	if len(c.Body()) > 0 {
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+		}
+	}
+
+	rejected, err := h.db.GetRejectedEvent(ctx, int64(id))
+	if err == database.ErrNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Rejected event not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to retrieve rejected event",
+			"details": err.Error(),
+		})
+	}
+
+	// Réponse rapide ; les rejeux concurrents sont départagés par
+	// ReplayRejectedEvent, qui revendique l'entrée avant de publier
+	if rejected.Status == models.RejectedStatusReplayed {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Rejected event has already been replayed",
+		})
+	}
+
+	payload := rejected.Payload
+	if len(body.Payload) > 0 {
+		payload = string(body.Payload)
+	}
+
+	// Décoder et valider le payload avant de le publier
+	var event models.Event
+	var publishErr error
+	replayErr := json.Unmarshal([]byte(payload), &event)
+	if replayErr == nil {
+		replayErr = event.Validate()
+	}
+	if replayErr == nil {
+		_, replayErr = h.db.ReplayRejectedEvent(ctx, rejected.ID, payload, func(ctx context.Context) error {
+			publishErr = h.publisher.Publish(ctx, []*models.Event{&event})
+			return publishErr
+		})
+	}
+
+	if replayErr == database.ErrConflict {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Rejected event has already been replayed",
+		})
+	}
+	if replayErr != nil {
+		if err := h.db.RecordReplayError(ctx, rejected.ID, replayErr); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "Failed to record replay error",
+				"details": err.Error(),
+			})
+		}
+		// Un échec de publication n'est pas dû au payload : le rejeu peut être retenté
+		status := fiber.StatusUnprocessableEntity
+		if publishErr != nil {
+			status = fiber.StatusServiceUnavailable
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error":   "Failed to replay rejected event",
+			"details": replayErr.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"id":      rejected.ID,
+		"event":   event,
+	})
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/luigi/xdr-platform/api/database"
+	"github.com/luigi/xdr-platform/api/search"
+)
+
+// SearchHandler gère la recherche d'événements par requête textuelle
+type SearchHandler struct {
+	db *database.TimescaleDB
+}
+
+// NewSearchHandler crée un nouveau handler de recherche
+func NewSearchHandler(db *database.TimescaleDB) *SearchHandler {
+	return &SearchHandler{db: db}
+}
+
+// SearchEvents recherche les événements correspondant à une requête
+// GET /api/v1/search?q=severity:high AND process_name:ssh*&start_time=...&end_time=...&limit=100&cursor=...
+func (h *SearchHandler) SearchEvents(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	q := c.Query("q")
+	node, err := search.Parse(q)
+	if err != nil {
+		return searchError(c, err)
+	}
+
+	compiled, err := search.Compile(node, 1)
+	if err != nil {
+		return searchError(c, err)
+	}
+
+	filters := make(map[string]interface{})
+
+	// Filtres temporels
+	if startTime := c.Query("start_time"); startTime != "" {
+		if t, err := time.Parse(time.RFC3339, startTime); err == nil {
+			filters["start_time"] = t
+		}
+	}
+
+	if endTime := c.Query("end_time"); endTime != "" {
+		if t, err := time.Parse(time.RFC3339, endTime); err == nil {
+			filters["end_time"] = t
+		}
+	}
+
+	// Pagination
+	page, err := parsePage(c, 100)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid cursor",
+			"details": err.Error(),
+		})
+	}
+
+	result, err := h.db.SearchEvents(ctx, compiled, filters, page)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to search events",
+			"details": err.Error(),
+		})
+	}
+
+	response := eventPageResponse(result, filters)
+	response["query"] = q
+	response["parsed"] = node.String()
+	return c.JSON(response)
+}
+
+// searchError retourne une erreur de syntaxe avec sa position dans la requête
+func searchError(c *fiber.Ctx, err error) error {
+	var queryErr *search.Error
+	if !errors.As(err, &queryErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "Invalid search query",
+			"details": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":    "Invalid search query",
+		"details":  queryErr.Message,
+		"position": queryErr.Offset,
+	})
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/luigi/xdr-platform/api/stream"
+)
+
+const (
+	// sseHeartbeatInterval garde la connexion ouverte à travers les proxys
+	sseHeartbeatInterval = 15 * time.Second
+
+	// websocketPingInterval détecte les clients WebSocket disparus
+	websocketPingInterval = 30 * time.Second
+
+	websocketWriteTimeout = 10 * time.Second
+
+	// maxClientMessage borne la taille des messages envoyés par le client,
+	// qui ne sont lus que pour traiter ping et close
+	maxClientMessage = 64 << 10
+
+	// subscriptionLocal transmet l'abonnement à la connexion WebSocket
+	subscriptionLocal = "stream.subscription"
+)
+
+// StreamHandler diffuse les événements et alertes en temps réel
+type StreamHandler struct {
+	hub            *stream.Hub
+	allowedOrigins map[string]bool
+	websocket      fiber.Handler
+}
+
+// NewStreamHandler crée un nouveau handler de streaming. allowedOrigins est la
+// liste CORS : les navigateurs n'appliquent pas CORS aux WebSockets.
+func NewStreamHandler(hub *stream.Hub, allowedOrigins string) *StreamHandler {
+	origins := make(map[string]bool)
+	for _, origin := range strings.Split(allowedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins[origin] = true
+		}
+	}
+	h := &StreamHandler{hub: hub, allowedOrigins: origins}
+	// L'origine est vérifiée avant la mise à niveau par originAllowed, qui
+	// accepte aussi la même origine
+	h.websocket = websocket.New(h.serveWebSocket)
+	return h
+}
+
+// StreamEvents diffuse les nouveaux événements et alertes en Server-Sent Events
+// GET /api/v1/events/stream?event_type=process&severity=high&hostname=web-01
+func (h *StreamHandler) StreamEvents(c *fiber.Ctx) error {
+	sub, err := h.subscribe(c)
+	if err != nil {
+		return err
+	}
+	if sub == nil {
+		return nil
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // désactive la mise en tampon de nginx
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+
+		ticker := time.NewTicker(sseHeartbeatInterval)
+		defer ticker.Stop()
+
+		fmt.Fprint(w, "retry: 5000\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case msg, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				var data []byte
+				if msg.Event != nil {
+					data, _ = json.Marshal(msg.Event)
+				} else {
+					data, _ = json.Marshal(msg.Alert)
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, data)
+
+			case <-ticker.C:
+				if dropped := sub.Dropped(); dropped > 0 {
+					fmt.Fprintf(w, "event: dropped\ndata: {\"count\":%d}\n\n", dropped)
+				} else {
+					fmt.Fprint(w, ": ping\n\n")
+				}
+			}
+
+			// Une erreur d'écriture signifie que le client s'est déconnecté
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// StreamEventsWebSocket est l'équivalent WebSocket de StreamEvents : chaque
+// message est un objet {"type": "event"|"alert"|"dropped", ...}
+// GET /api/v1/events/ws?event_type=process&severity=high&hostname=web-01
+func (h *StreamHandler) StreamEventsWebSocket(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{
+			"error": "Expected a WebSocket upgrade request",
+		})
+	}
+
+	if origin := c.Get(fiber.HeaderOrigin); origin != "" && !h.originAllowed(c, origin) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Origin not allowed",
+		})
+	}
+
+	sub, err := h.subscribe(c)
+	if err != nil {
+		return err
+	}
+	if sub == nil {
+		return nil
+	}
+
+	c.Locals(subscriptionLocal, sub)
+	if err := h.websocket(c); err != nil {
+		sub.Close()
+		return err
+	}
+	return nil
+}
+
+// serveWebSocket diffuse l'abonnement sur une connexion WebSocket établie
+func (h *StreamHandler) serveWebSocket(conn *websocket.Conn) {
+	sub := conn.Locals(subscriptionLocal).(*stream.Subscription)
+	defer sub.Close()
+
+	// Les messages du client sont ignorés ; la lecture traite ping et close
+	closed := make(chan struct{})
+	conn.SetReadLimit(maxClientMessage)
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(websocketPingInterval)
+	defer ticker.Stop()
+
+	write := func(data []byte) error {
+		conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+		return conn.WriteMessage(websocket.TextMessage, data)
+	}
+
+	for {
+		select {
+		case msg, ok := <-sub.C:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+					time.Now().Add(websocketWriteTimeout))
+				return
+			}
+			data, _ := json.Marshal(msg)
+			if err := write(data); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			if dropped := sub.Dropped(); dropped > 0 {
+				if err := write([]byte(fmt.Sprintf(`{"type":"dropped","count":%d}`, dropped))); err != nil {
+					return
+				}
+			}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(websocketWriteTimeout)); err != nil {
+				return
+			}
+
+		case <-closed:
+			return
+		}
+	}
+}
+
+// subscribe abonne le client avec les filtres de /events/filter ; si
+// l'abonnement est refusé, la réponse est déjà écrite et sub est nil
+func (h *StreamHandler) subscribe(c *fiber.Ctx) (*stream.Subscription, error) {
+	// Les valeurs de fiber.Ctx sont réutilisées après la requête, alors que
+	// le filtre vit aussi longtemps que le flux
+	filters, err := eventFilters(c)
+	if err != nil {
+		return nil, invalidFilters(c, err)
+	}
+	filter := stream.FilterFromMap(filters)
+	filter.EventType = strings.Clone(filter.EventType)
+	filter.Severity = strings.Clone(filter.Severity)
+	filter.Hostname = strings.Clone(filter.Hostname)
+
+	sub, err := h.hub.Subscribe(filter)
+	if err != nil {
+		return nil, c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":   "Stream unavailable",
+			"details": err.Error(),
+		})
+	}
+	return sub, nil
+}
+
+// originAllowed accepte les connexions de même origine et les origines CORS autorisées
+func (h *StreamHandler) originAllowed(c *fiber.Ctx, origin string) bool {
+	if h.allowedOrigins[origin] {
+		return true
+	}
+	_, host, found := strings.Cut(origin, "://")
+	return found && strings.EqualFold(host, c.Hostname())
+}
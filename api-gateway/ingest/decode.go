@@ -0,0 +1,99 @@
+// Package ingest décode les lots d'événements reçus par HTTP et les transmet
+// au pipeline, via Kafka ou directement en base.
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	// ErrTooLarge signale un corps décompressé au-delà de la limite
+	ErrTooLarge = errors.New("request body too large")
+	// ErrTooManyEvents signale un lot au-delà du nombre maximal d'événements
+	ErrTooManyEvents = errors.New("too many events in batch")
+)
+
+// Decode découpe un corps de requête en événements JSON bruts. Le corps est
+// soit un tableau JSON, soit du NDJSON (un événement par ligne, lignes vides
+// ignorées), éventuellement compressé en gzip (Content-Encoding: gzip).
+// maxBytes borne la taille décompressée et maxEvents le nombre d'événements.
+func Decode(body []byte, contentEncoding string, maxBytes int64, maxEvents int) ([]json.RawMessage, error) {
+	data, err := decompress(body, contentEncoding, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("request body is empty")
+	}
+
+	if trimmed[0] == '[' {
+		var records []json.RawMessage
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+		if len(records) > maxEvents {
+			return nil, ErrTooManyEvents
+		}
+		return records, nil
+	}
+
+	var records []json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(trimmed)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if len(records) == maxEvents {
+			return nil, ErrTooManyEvents
+		}
+		// Le scanner réutilise son buffer : la ligne est copiée
+		records = append(records, json.RawMessage(bytes.Clone(line)))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON body: %w", err)
+	}
+
+	return records, nil
+}
+
+// decompress applique Content-Encoding en bornant la taille décompressée
+func decompress(body []byte, contentEncoding string, maxBytes int64) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		if int64(len(body)) > maxBytes {
+			return nil, ErrTooLarge
+		}
+		return body, nil
+
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer zr.Close()
+
+		// Lire un octet de plus que la limite pour détecter un dépassement
+		data, err := io.ReadAll(io.LimitReader(zr, maxBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		if int64(len(data)) > maxBytes {
+			return nil, ErrTooLarge
+		}
+		return data, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", contentEncoding)
+	}
+}
@@ -0,0 +1,74 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/luigi/xdr-platform/api/models"
+	"github.com/segmentio/kafka-go"
+)
+
+// Publisher transmet un lot d'événements validés ; une erreur signifie
+// qu'aucun événement du lot ne doit être considéré comme accepté
+type Publisher interface {
+	Publish(ctx context.Context, events []*models.Event) error
+	Close() error
+}
+
+// KafkaPublisher publie les événements sur le topic des événements bruts
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher crée un publisher Kafka. L'écriture attend l'accusé de
+// réception de tous les réplicas : un événement accepté n'est pas perdu.
+func NewKafkaPublisher(brokers []string, topic string, compression string, security KafkaSecurity) (*KafkaPublisher, error) {
+	codec, err := kafkaCompression(compression)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := security.transport()
+	if err != nil {
+		return nil, err
+	}
+
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.LeastBytes{},
+			Transport:    transport,
+			Compression:  codec,
+			RequiredAcks: kafka.RequireAll,
+			BatchTimeout: 10 * time.Millisecond,
+		},
+	}, nil
+}
+
+// Publish écrit les événements sur le topic
+func (p *KafkaPublisher) Publish(ctx context.Context, events []*models.Event) error {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event: %w", err)
+		}
+		messages = append(messages, kafka.Message{
+			Key:   []byte(event.AgentID),
+			Value: data,
+			Time:  event.Timestamp,
+		})
+	}
+
+	if err := p.writer.WriteMessages(ctx, messages...); err != nil {
+		return fmt.Errorf("failed to publish events to kafka: %w", err)
+	}
+	return nil
+}
+
+// Close vide et ferme le writer Kafka
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,95 @@
+package ingest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// KafkaSecurity configure le chiffrement et l'authentification auprès des brokers
+type KafkaSecurity struct {
+	TLS                bool
+	CAFile             string // autorités de certification (PEM), sinon celles du système
+	CertFile           string // certificat client (TLS mutuel)
+	KeyFile            string
+	InsecureSkipVerify bool
+
+	SASLMechanism string // plain, scram-sha-256, scram-sha-512 ; vide : pas de SASL
+	SASLUsername  string
+	SASLPassword  string
+}
+
+// transport construit le transport du writer Kafka
+func (s KafkaSecurity) transport() (*kafka.Transport, error) {
+	transport := &kafka.Transport{}
+
+	if s.TLS {
+		config := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: s.InsecureSkipVerify,
+		}
+		if s.CAFile != "" {
+			pem, err := os.ReadFile(s.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read kafka CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificate found in %s", s.CAFile)
+			}
+			config.RootCAs = pool
+		}
+		if s.CertFile != "" {
+			cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load kafka client certificate: %w", err)
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLS = config
+	}
+
+	var mechanism sasl.Mechanism
+	var err error
+	switch s.SASLMechanism {
+	case "":
+	case "plain":
+		mechanism = plain.Mechanism{Username: s.SASLUsername, Password: s.SASLPassword}
+	case "scram-sha-256":
+		mechanism, err = scram.Mechanism(scram.SHA256, s.SASLUsername, s.SASLPassword)
+	case "scram-sha-512":
+		mechanism, err = scram.Mechanism(scram.SHA512, s.SASLUsername, s.SASLPassword)
+	default:
+		err = fmt.Errorf("unknown kafka SASL mechanism %q", s.SASLMechanism)
+	}
+	if err != nil {
+		return nil, err
+	}
+	transport.SASL = mechanism
+
+	return transport, nil
+}
+
+// kafkaCompression convertit le nom d'un codec de compression
+func kafkaCompression(name string) (kafka.Compression, error) {
+	switch name {
+	case "", "none":
+		return 0, nil
+	case "gzip":
+		return kafka.Gzip, nil
+	case "snappy":
+		return kafka.Snappy, nil
+	case "lz4":
+		return kafka.Lz4, nil
+	case "zstd":
+		return kafka.Zstd, nil
+	default:
+		return 0, fmt.Errorf("unknown kafka compression codec %q", name)
+	}
+}
@@ -1,20 +1,25 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	fiberlogger "github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	
+	"github.com/luigi/xdr-platform/api/auth"
 	"github.com/luigi/xdr-platform/api/config"
 	"github.com/luigi/xdr-platform/api/database"
 	"github.com/luigi/xdr-platform/api/handlers"
+	"github.com/luigi/xdr-platform/api/ingest"
 	"github.com/luigi/xdr-platform/api/routes"
+	"github.com/luigi/xdr-platform/api/stream"
 )
 
 func main() {
@@ -44,9 +49,56 @@ func main() {
 
 	logger.Println("Connected to TimescaleDB successfully")
 
+	// Agrégats continus de la timeline
+	if cfg.TimelineAggregates {
+		setupTimelineAggregates(db, logger)
+	}
+
+	// Authentification
+	authenticator, err := newAuthenticator(cfg, logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure authentication: %v", err)
+	}
+
+	loginHandler, err := newLoginHandler(cfg, logger)
+	if err != nil {
+		logger.Fatalf("Failed to configure user login: %v", err)
+	}
+
+	auditTrail := auth.NewAuditTrail(db, logger)
+	defer auditTrail.Close()
+
+	// Streaming temps réel : une seule écoute LISTEN/NOTIFY pour tous les clients
+	hub := stream.NewHub()
+	listener, err := stream.NewListener(cfg.DatabaseURL, db, hub, logger)
+	if err != nil {
+		logger.Printf("WARNING: failed to listen for new events, live streams will stay idle: %v", err)
+	} else {
+		defer listener.Close()
+	}
+
+	// Les événements reçus par POST /api/v1/ingest et les rejeux sont publiés
+	// sur raw-events : seul le service d'ingestion écrit dans raw_events
+	publisher, err := ingest.NewKafkaPublisher(cfg.KafkaBrokers, cfg.KafkaTopicRawEvents, cfg.KafkaCompression, ingest.KafkaSecurity{
+		TLS:                cfg.KafkaTLS,
+		CAFile:             cfg.KafkaTLSCAFile,
+		CertFile:           cfg.KafkaTLSCertFile,
+		KeyFile:            cfg.KafkaTLSKeyFile,
+		InsecureSkipVerify: cfg.KafkaTLSInsecureSkipVerify,
+		SASLMechanism:      cfg.KafkaSASLMechanism,
+		SASLUsername:       cfg.KafkaSASLUsername,
+		SASLPassword:       cfg.KafkaSASLPassword,
+	})
+	if err != nil {
+		logger.Fatalf("Failed to create Kafka publisher: %v", err)
+	}
+	defer publisher.Close()
+
 	// Créer l'application Fiber
 	app := fiber.New(fiber.Config{
 		AppName: "XDR API Gateway v1.0",
+		// Un lot d'ingestion non compressé peut atteindre INGEST_MAX_BYTES
+		BodyLimit: int(max(cfg.IngestMaxBytes, fiber.DefaultBodyLimit)),
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -64,16 +116,27 @@ func main() {
 		Format: "[${time}] ${status} - ${latency} ${method} ${path}\n",
 	}))
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins: cfg.CORSAllowedOrigins,
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-API-Key",
 		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
 	}))
 
 	// Créer les handlers
 	eventsHandler := handlers.NewEventsHandler(db)
+	rejectedHandler := handlers.NewRejectedHandler(db, publisher)
+	alertsHandler := handlers.NewAlertsHandler(db)
+	searchHandler := handlers.NewSearchHandler(db)
+	streamHandler := handlers.NewStreamHandler(hub, cfg.CORSAllowedOrigins)
+	auditHandler := handlers.NewAuditHandler(db)
+	agentsHandler := handlers.NewAgentsHandler(db, database.AgentStatusPolicy{
+		DefaultInterval:   cfg.AgentHeartbeatInterval,
+		StaleHeartbeats:   cfg.AgentStaleHeartbeats,
+		OfflineHeartbeats: cfg.AgentOfflineHeartbeats,
+	})
+	ingestHandler := handlers.NewIngestHandler(publisher, cfg.IngestMaxBytes, cfg.IngestMaxEvents)
 
 	// Configurer les routes
-	routes.SetupRoutes(app, eventsHandler)
+	routes.SetupRoutes(app, authenticator, auditTrail, loginHandler, eventsHandler, rejectedHandler, alertsHandler, searchHandler, streamHandler, auditHandler, agentsHandler, ingestHandler)
 
 	// Route par défaut
 	app.Get("/", func(c *fiber.Ctx) error {
@@ -83,9 +146,16 @@ func main() {
 			"status": "running",
 			"endpoints": fiber.Map{
 				"health": "/health",
+				"login":  "/api/v1/auth/login",
 				"events": "/api/v1/events",
 				"count":  "/api/v1/events/count",
 				"stats":  "/api/v1/events/stats",
+				"rejected": "/api/v1/rejected",
+				"alerts":   "/api/v1/alerts",
+				"search":   "/api/v1/search",
+				"stream":   "/api/v1/events/stream",
+				"audit":    "/api/v1/audit",
+				"ingest":   "/api/v1/ingest",
 			},
 		})
 	})
@@ -113,10 +183,100 @@ func main() {
 	<-sigChan
 	logger.Println("Received shutdown signal, stopping API...")
 
-	// Arrêt gracieux
+	// Arrêt gracieux : les flux en cours sont fermés d'abord, sinon
+	// Shutdown attendrait leur fin
+	hub.Close()
 	if err := app.Shutdown(); err != nil {
 		logger.Printf("Error during shutdown: %v", err)
 	}
 
 	logger.Println("API Gateway stopped")
 }
+
+// newAuthenticator charge les clés d'API et les clés de vérification JWT configurées
+func newAuthenticator(cfg *config.Config, logger *log.Logger) (*auth.Authenticator, error) {
+	if !cfg.AuthEnabled {
+		logger.Println("WARNING: authentication is disabled, every request is granted the admin role")
+		return auth.NewAuthenticator(nil, nil, false, logger), nil
+	}
+
+	var apiKeys *auth.APIKeyStore
+	if cfg.APIKeysFile != "" {
+		store, err := auth.LoadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = store
+		logger.Printf("Loaded %d API keys", store.Len())
+	}
+
+	var verifier *auth.JWTVerifier
+	if cfg.JWTHS256Secret != "" || cfg.JWTJWKSFile != "" {
+		v, err := auth.NewJWTVerifier(auth.JWTConfig{
+			HS256Secret: cfg.JWTHS256Secret,
+			JWKSFile:    cfg.JWTJWKSFile,
+			Issuer:      cfg.JWTIssuer,
+			Audience:    cfg.JWTAudience,
+			RoleClaim:   cfg.JWTRoleClaim,
+		})
+		if err != nil {
+			return nil, err
+		}
+		verifier = v
+		logger.Println("JWT authentication enabled")
+	}
+
+	return auth.NewAuthenticator(apiKeys, verifier, true, logger), nil
+}
+
+// newLoginHandler charge les utilisateurs du tableau de bord ; nil si la
+// connexion par mot de passe n'est pas configurée
+func newLoginHandler(cfg *config.Config, logger *log.Logger) (*handlers.LoginHandler, error) {
+	if !cfg.AuthEnabled || cfg.UsersFile == "" {
+		return nil, nil
+	}
+
+	users, err := auth.LoadUsers(cfg.UsersFile)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := auth.NewTokenIssuer(auth.JWTConfig{
+		HS256Secret: cfg.JWTHS256Secret,
+		Issuer:      cfg.JWTIssuer,
+		Audience:    cfg.JWTAudience,
+		RoleClaim:   cfg.JWTRoleClaim,
+	}, cfg.TokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Printf("Loaded %d dashboard users, login tokens are valid for %v", users.Len(), cfg.TokenTTL)
+	return handlers.NewLoginHandler(users, tokens), nil
+}
+
+// setupTimelineAggregates crée les agrégats continus de la timeline et remplit
+// en arrière-plan ceux qui viennent d'être créés. En cas d'échec, la timeline
+// interroge directement raw_events.
+func setupTimelineAggregates(db *database.TimescaleDB, logger *log.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	created, err := db.EnsureTimelineAggregates(ctx)
+	if err != nil {
+		logger.Printf("WARNING: timeline continuous aggregates unavailable, falling back to raw_events: %v", err)
+	}
+
+	for _, view := range created {
+		go func(view string) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+			defer cancel()
+
+			start := time.Now()
+			if err := db.RefreshTimelineAggregate(ctx, view); err != nil {
+				logger.Printf("Failed to backfill %s: %v", view, err)
+				return
+			}
+			logger.Printf("Backfilled %s in %v", view, time.Since(start))
+		}(view)
+	}
+}
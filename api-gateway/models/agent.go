@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Statuts d'un agent, dérivés de son dernier heartbeat
+const (
+	AgentStatusOnline  = "online"
+	AgentStatusStale   = "stale"
+	AgentStatusOffline = "offline"
+)
+
+// IsValidAgentStatus indique si le statut d'agent est reconnu
+func IsValidAgentStatus(status string) bool {
+	switch status {
+	case AgentStatusOnline, AgentStatusStale, AgentStatusOffline:
+		return true
+	}
+	return false
+}
+
+// Agent représente un agent de l'inventaire, tenu à jour par l'ingestion à
+// partir des événements et heartbeats reçus
+type Agent struct {
+	AgentID           string     `json:"agent_id"`
+	Hostname          string     `json:"hostname"`
+	IPAddress         string     `json:"ip_address,omitempty"`
+	OSType            string     `json:"os_type,omitempty"`
+	OSVersion         string     `json:"os_version,omitempty"`
+	KernelVersion     string     `json:"kernel_version,omitempty"`
+	AgentVersion      string     `json:"agent_version,omitempty"`
+	Collectors        []string   `json:"collectors"`
+	HeartbeatInterval float64    `json:"heartbeat_interval_seconds"`
+	EventRate         float64    `json:"event_rate"` // événements collectés par minute, publié par l'agent
+	EventsTotal       int64      `json:"events_total"`
+	SpoolDepth        int        `json:"spool_depth"`
+	RegisteredAt      *time.Time `json:"registered_at,omitempty"`
+	PreviousAgentID   string     `json:"previous_agent_id,omitempty"` // ID remplacé par un réenrôlement
+	FirstSeen         time.Time  `json:"first_seen"`
+	LastSeen          time.Time  `json:"last_seen"`
+	LastHeartbeat     *time.Time `json:"last_heartbeat"`
+	Status            string     `json:"status"`
+	SilentAlertAt     *time.Time `json:"silent_alert_at,omitempty"` // alerte "agent silencieux" en cours
+}
@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// Statuts du cycle de vie d'une alerte
+const (
+	AlertStatusNew           = "new"
+	AlertStatusAcknowledged  = "acknowledged"
+	AlertStatusInvestigating = "investigating"
+	AlertStatusClosed        = "closed"
+)
+
+// alertStatusOrder fixe l'ordre du cycle de vie : une alerte ne peut qu'avancer
+var alertStatusOrder = map[string]int{
+	AlertStatusNew:           0,
+	AlertStatusAcknowledged:  1,
+	AlertStatusInvestigating: 2,
+	AlertStatusClosed:        3,
+}
+
+// Résolutions possibles à la clôture d'une alerte
+const (
+	AlertResolutionTruePositive  = "true_positive"
+	AlertResolutionBenign        = "benign"
+	AlertResolutionFalsePositive = "false_positive"
+	AlertResolutionDuplicate     = "duplicate"
+)
+
+// IsValidAlertStatus indique si le statut fait partie du cycle de vie
+func IsValidAlertStatus(status string) bool {
+	_, ok := alertStatusOrder[status]
+	return ok
+}
+
+// IsValidAlertResolution indique si la résolution est reconnue
+func IsValidAlertResolution(resolution string) bool {
+	switch resolution {
+	case AlertResolutionTruePositive, AlertResolutionBenign, AlertResolutionFalsePositive, AlertResolutionDuplicate:
+		return true
+	}
+	return false
+}
+
+// CanTransitionAlert indique si une alerte peut passer de from à to.
+// Le cycle new → acknowledged → investigating → closed ne se parcourt que
+// vers l'avant ; une étape peut être sautée (ex. clôture d'un faux positif).
+func CanTransitionAlert(from, to string) bool {
+	fromOrder, okFrom := alertStatusOrder[from]
+	toOrder, okTo := alertStatusOrder[to]
+	return okFrom && okTo && toOrder > fromOrder
+}
+
+// Alert représente une alerte levée par une règle de détection
+type Alert struct {
+	ID             int64     `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	RuleID         string    `json:"rule_id"`
+	RuleTitle      string    `json:"rule_title"`
+	RuleLevel      string    `json:"rule_level"`
+	Description    string    `json:"description,omitempty"`
+	Tags           []string  `json:"tags,omitempty"`
+	EventID        int64     `json:"event_id"`
+	EventTimestamp time.Time `json:"event_timestamp"`
+	AgentID        string    `json:"agent_id"`
+	Hostname       string    `json:"hostname"`
+	EventType      string    `json:"event_type"`
+	Status         string    `json:"status"`
+	Assignee       string    `json:"assignee,omitempty"`
+	Resolution     string    `json:"resolution,omitempty"`
+}
+
+// AlertStateChange est une entrée de l'historique des statuts d'une alerte
+type AlertStateChange struct {
+	ID         int64     `json:"id"`
+	AlertID    int64     `json:"alert_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Actor      string    `json:"actor,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
+
+// AlertComment est un commentaire d'analyste sur une alerte
+type AlertComment struct {
+	ID        int64     `json:"id"`
+	AlertID   int64     `json:"alert_id"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AlertMetrics regroupe les délais de traitement des alertes d'un niveau de sévérité
+type AlertMetrics struct {
+	Level        string   `json:"level"`
+	Total        int64    `json:"total"`
+	Acknowledged int64    `json:"acknowledged"`
+	Closed       int64    `json:"closed"`
+	MTTASeconds  *float64 `json:"mtta_seconds"` // mean time to acknowledge, nil si aucune alerte prise en charge
+	MTTCSeconds  *float64 `json:"mttc_seconds"` // mean time to close, nil si aucune alerte close
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AuditEntry trace un appel à l'API : qui a demandé quoi, et avec quel résultat
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Subject    string    `json:"subject,omitempty"` // vide si l'authentification a échoué
+	Role       string    `json:"role,omitempty"`
+	AuthMethod string    `json:"auth_method,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+	Status     int       `json:"status"`
+	RemoteIP   string    `json:"remote_ip"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
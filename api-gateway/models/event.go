@@ -1,15 +1,21 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // EventType représente le type d'événement collecté
 type EventType string
 
 const (
-	EventTypeSystem  EventType = "system"
-	EventTypeNetwork EventType = "network"
-	EventTypeProcess EventType = "process"
-	EventTypeFile    EventType = "file"
+	EventTypeSystem      EventType = "system"
+	EventTypeNetwork     EventType = "network"
+	EventTypeProcess     EventType = "process"
+	EventTypeFile        EventType = "file"
+	EventTypeAuth        EventType = "auth"
+	EventTypeJournal     EventType = "journal"
+	EventTypePersistence EventType = "persistence"
 )
 
 // Severity représente la sévérité d'un événement
@@ -24,6 +30,7 @@ const (
 
 // Event représente un événement de sécurité collecté
 type Event struct {
+	ID             int64                  `json:"id,omitempty"`
 	Timestamp      time.Time              `json:"timestamp"`
 	AgentID        string                 `json:"agent_id"`
 	Hostname       string                 `json:"hostname"`
@@ -38,3 +45,28 @@ type Event struct {
 	Tags           []string               `json:"tags,omitempty"`
 	Metadata       map[string]interface{} `json:"metadata,omitempty"`
 }
+
+// Validate vérifie qu'un événement peut être persisté
+func (e *Event) Validate() error {
+	if e.Timestamp.IsZero() {
+		return fmt.Errorf("timestamp is required")
+	}
+	if e.AgentID == "" {
+		return fmt.Errorf("agent_id is required")
+	}
+	if e.Hostname == "" {
+		return fmt.Errorf("hostname is required")
+	}
+	switch e.EventType {
+	case EventTypeSystem, EventTypeNetwork, EventTypeProcess, EventTypeFile, EventTypeAuth, EventTypeJournal,
+		EventTypePersistence:
+	default:
+		return fmt.Errorf("unknown event_type %q", e.EventType)
+	}
+	switch e.Severity {
+	case SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical:
+	default:
+		return fmt.Errorf("unknown severity %q", e.Severity)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Statuts d'un événement en quarantaine
+const (
+	RejectedStatusPending  = "pending"
+	RejectedStatusReplayed = "replayed"
+)
+
+// RejectedEvent représente un événement rejeté par le pipeline d'ingestion
+type RejectedEvent struct {
+	ID              int64      `json:"id"`
+	RejectedAt      time.Time  `json:"rejected_at"`
+	Stage           string     `json:"stage"`
+	Reason          string     `json:"reason"`
+	Payload         string     `json:"payload"`
+	AgentID         string     `json:"agent_id,omitempty"`
+	SourceTopic     string     `json:"source_topic"`
+	SourcePartition int        `json:"source_partition"`
+	SourceOffset    int64      `json:"source_offset"`
+	Status          string     `json:"status"`
+	ReplayedAt      *time.Time `json:"replayed_at,omitempty"`
+	ReplayError     string     `json:"replay_error,omitempty"`
+}
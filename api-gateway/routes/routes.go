@@ -1,27 +1,77 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/luigi/xdr-platform/api/auth"
 	"github.com/luigi/xdr-platform/api/handlers"
 )
 
-// SetupRoutes configure toutes les routes de l'API
-func SetupRoutes(app *fiber.App, eventsHandler *handlers.EventsHandler) {
+// SetupRoutes configure toutes les routes de l'API.
+// Toutes les routes /api/v1 sont authentifiées et journalisées ; le rôle
+// minimal est fixé par groupe de routes. Seule la connexion des utilisateurs
+// (loginHandler, nil si elle n'est pas configurée) est publique.
+func SetupRoutes(app *fiber.App, authenticator *auth.Authenticator, auditTrail *auth.AuditTrail, loginHandler *handlers.LoginHandler, eventsHandler *handlers.EventsHandler, rejectedHandler *handlers.RejectedHandler, alertsHandler *handlers.AlertsHandler, searchHandler *handlers.SearchHandler, streamHandler *handlers.StreamHandler, auditHandler *handlers.AuditHandler, agentsHandler *handlers.AgentsHandler, ingestHandler *handlers.IngestHandler) {
 	// Route de health check
 	app.Get("/health", eventsHandler.HealthCheck)
 
+	// Connexion des utilisateurs, enregistrée avant le groupe authentifié.
+	// Les tentatives sont limitées par adresse IP.
+	if loginHandler != nil {
+		app.Post("/api/v1/auth/login", auditTrail.Middleware(), limiter.New(limiter.Config{
+			Max:        10,
+			Expiration: time.Minute,
+		}), loginHandler.Login) // POST /api/v1/auth/login
+	}
+
 	// Groupe API v1
-	api := app.Group("/api/v1")
+	api := app.Group("/api/v1", auditTrail.Middleware(), authenticator.Authenticate())
 
 	// Routes pour les événements
-	events := api.Group("/events")
+	events := api.Group("/events", auth.RequireRole(auth.RoleViewer))
 	events.Get("/", eventsHandler.GetEvents)                    // GET /api/v1/events
 	events.Get("/count", eventsHandler.GetEventCount)          // GET /api/v1/events/count
 	events.Get("/stats", eventsHandler.GetEventStats)          // GET /api/v1/events/stats
 	events.Get("/filter", eventsHandler.GetFilteredEvents)     // GET /api/v1/events/filter
 	events.Get("/timeline", eventsHandler.GetTimeRangeStats)   // GET /api/v1/events/timeline
+	events.Get("/stream", streamHandler.StreamEvents)          // GET /api/v1/events/stream (SSE)
+	events.Get("/ws", streamHandler.StreamEventsWebSocket)     // GET /api/v1/events/ws (WebSocket)
 	
+	// Recherche par requête (severity:high AND process_name:ssh* ...)
+	api.Get("/search", auth.RequireRole(auth.RoleViewer), searchHandler.SearchEvents) // GET /api/v1/search
+
 	// Routes pour les statistiques détaillées
-	stats := api.Group("/stats")
+	stats := api.Group("/stats", auth.RequireRole(auth.RoleViewer))
 	stats.Get("/detailed", eventsHandler.GetDetailedStats)     // GET /api/v1/stats/detailed
+
+	// Routes pour les événements en quarantaine (dead-letter)
+	rejected := api.Group("/rejected", auth.RequireRole(auth.RoleAdmin))
+	rejected.Get("/", rejectedHandler.GetRejectedEvents)             // GET /api/v1/rejected
+	rejected.Get("/:id", rejectedHandler.GetRejectedEvent)           // GET /api/v1/rejected/:id
+	rejected.Post("/:id/replay", rejectedHandler.ReplayRejectedEvent) // POST /api/v1/rejected/:id/replay
+
+	// Routes pour les alertes de détection
+	// Lecture pour les viewers, traitement réservé aux analystes
+	alerts := api.Group("/alerts", auth.RequireRole(auth.RoleViewer))
+	analyst := auth.RequireRole(auth.RoleAnalyst)
+	alerts.Get("/", alertsHandler.GetAlerts)                               // GET /api/v1/alerts
+	alerts.Get("/metrics", alertsHandler.GetAlertMetrics)                  // GET /api/v1/alerts/metrics
+	alerts.Get("/:id", alertsHandler.GetAlert)                             // GET /api/v1/alerts/:id
+	alerts.Post("/:id/status", analyst, alertsHandler.UpdateAlertStatus)   // POST /api/v1/alerts/:id/status
+	alerts.Put("/:id/assignee", analyst, alertsHandler.AssignAlert)        // PUT /api/v1/alerts/:id/assignee
+	alerts.Post("/:id/comments", analyst, alertsHandler.AddAlertComment)   // POST /api/v1/alerts/:id/comments
+
+	// Inventaire des agents
+	agents := api.Group("/agents", auth.RequireRole(auth.RoleViewer))
+	agents.Get("/", agentsHandler.GetAgents)    // GET /api/v1/agents
+	agents.Get("/:id", agentsHandler.GetAgent)  // GET /api/v1/agents/:id
+
+	// Ingestion HTTP pour les agents sans accès à Kafka
+	api.Post("/ingest", auth.RequireAgent(), ingestHandler.IngestEvents) // POST /api/v1/ingest
+
+	// Journal d'audit des accès
+	audit := api.Group("/audit", auth.RequireRole(auth.RoleAdmin))
+	audit.Get("/", auditHandler.GetAuditEntries) // GET /api/v1/audit
 }
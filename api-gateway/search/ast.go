@@ -0,0 +1,99 @@
+// Package search analyse le langage de recherche des événements et le
+// compile en clause WHERE SQL paramétrée.
+//
+//	severity:high AND process_name:ssh* AND NOT tags:heartbeat
+//	source_ip:10.0.0.0/8 AND raw_data.process.cpu_percent>50
+package search
+
+import "fmt"
+
+// Node est un nœud de l'arbre syntaxique d'une recherche
+type Node interface {
+	// Pos est la position (en octets, à partir de 0) du nœud dans la requête
+	Pos() int
+	String() string
+}
+
+// And est vrai si tous ses termes sont vrais
+type And struct {
+	Terms []Node
+}
+
+// Or est vrai si au moins un de ses termes est vrai
+type Or struct {
+	Terms []Node
+}
+
+// Not inverse son terme
+type Not struct {
+	Term   Node
+	Offset int
+}
+
+// Operator est l'opérateur d'une comparaison
+type Operator string
+
+const (
+	OpMatch        Operator = ":"
+	OpGreater      Operator = ">"
+	OpGreaterEqual Operator = ">="
+	OpLess         Operator = "<"
+	OpLessEqual    Operator = "<="
+)
+
+// Value est la valeur d'une comparaison telle que saisie
+type Value struct {
+	Text   string
+	Quoted bool // une valeur entre guillemets n'est jamais interprétée comme motif
+	Offset int
+}
+
+// Comparison compare un champ à une valeur
+type Comparison struct {
+	Field  Field
+	Op     Operator
+	Value  Value
+	Offset int
+}
+
+func (n *And) Pos() int        { return n.Terms[0].Pos() }
+func (n *Or) Pos() int         { return n.Terms[0].Pos() }
+func (n *Not) Pos() int        { return n.Offset }
+func (n *Comparison) Pos() int { return n.Offset }
+
+func (n *And) String() string { return joinNodes("AND", n.Terms) }
+func (n *Or) String() string  { return joinNodes("OR", n.Terms) }
+func (n *Not) String() string { return "(NOT " + n.Term.String() + ")" }
+
+func (n *Comparison) String() string {
+	value := n.Value.Text
+	if n.Value.Quoted {
+		value = fmt.Sprintf("%q", value)
+	}
+	return n.Field.Name + string(n.Op) + value
+}
+
+func joinNodes(op string, terms []Node) string {
+	s := "("
+	for i, term := range terms {
+		if i > 0 {
+			s += " " + op + " "
+		}
+		s += term.String()
+	}
+	return s + ")"
+}
+
+// Error est une erreur de syntaxe ou de typage d'une recherche
+type Error struct {
+	Offset  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Offset, e.Message)
+}
+
+func errorf(offset int, format string, args ...interface{}) *Error {
+	return &Error{Offset: offset, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,330 @@
+package search
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+// Query est une recherche compilée en clause WHERE paramétrée
+type Query struct {
+	Where string
+	Args  []interface{}
+}
+
+// Les colonnes IP sont stockées en TEXT : seules les valeurs qui sont des
+// adresses IPv4 ou IPv6 valides sont converties en inet pour les comparaisons
+// CIDR. Le motif doit être exact, une seule ligne que le cast refuse ferait
+// échouer toute la recherche.
+const (
+	decOctet = `(25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])`
+	ipv4     = decOctet + `(\.` + decOctet + `){3}`
+	hextet   = `[0-9A-Fa-f]{1,4}`
+	ls32     = `(` + hextet + `:` + hextet + `|` + ipv4 + `)`
+
+	// Grammaire IPv6address de la RFC 3986
+	ipv6 = `((` + hextet + `:){6}` + ls32 +
+		`|::(` + hextet + `:){5}` + ls32 +
+		`|(` + hextet + `)?::(` + hextet + `:){4}` + ls32 +
+		`|((` + hextet + `:){0,1}` + hextet + `)?::(` + hextet + `:){3}` + ls32 +
+		`|((` + hextet + `:){0,2}` + hextet + `)?::(` + hextet + `:){2}` + ls32 +
+		`|((` + hextet + `:){0,3}` + hextet + `)?::` + hextet + `:` + ls32 +
+		`|((` + hextet + `:){0,4}` + hextet + `)?::` + ls32 +
+		`|((` + hextet + `:){0,5}` + hextet + `)?::` + hextet +
+		`|((` + hextet + `:){0,6}` + hextet + `)?::)`
+
+	ipv4Pattern = `'^` + ipv4 + `$'`
+	ipv6Pattern = `'^` + ipv6 + `$'`
+)
+
+var eventTypes = []models.EventType{
+	models.EventTypeSystem, models.EventTypeNetwork, models.EventTypeProcess, models.EventTypeFile,
+	models.EventTypeAuth, models.EventTypeJournal, models.EventTypePersistence,
+}
+
+// severities sont triées par ordre croissant de gravité
+var severities = []models.Severity{
+	models.SeverityLow, models.SeverityMedium, models.SeverityHigh, models.SeverityCritical,
+}
+
+// compiler traduit l'arbre en SQL ; les valeurs saisies ne sont jamais
+// concaténées à la requête, elles sont toutes passées en paramètres.
+type compiler struct {
+	firstArg int
+	args     []interface{}
+}
+
+// Compile traduit une recherche en clause WHERE dont les paramètres sont
+// numérotés à partir de $firstArg. Les erreurs de typage sont de type *Error.
+func Compile(node Node, firstArg int) (*Query, error) {
+	c := &compiler{firstArg: firstArg}
+	where, err := c.compile(node)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{Where: where, Args: c.args}, nil
+}
+
+// arg ajoute un paramètre et retourne son emplacement
+func (c *compiler) arg(value interface{}) string {
+	c.args = append(c.args, value)
+	return fmt.Sprintf("$%d", c.firstArg+len(c.args)-1)
+}
+
+func (c *compiler) compile(node Node) (string, error) {
+	switch n := node.(type) {
+	case *And:
+		return c.compileTerms(n.Terms, " AND ")
+	case *Or:
+		return c.compileTerms(n.Terms, " OR ")
+	case *Not:
+		term, err := c.compile(n.Term)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + term, nil
+	case *Comparison:
+		return c.compileComparison(n)
+	}
+	return "", errorf(node.Pos(), "unsupported expression %s", node)
+}
+
+func (c *compiler) compileTerms(terms []Node, op string) (string, error) {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		part, err := c.compile(term)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = part
+	}
+	return "(" + strings.Join(parts, op) + ")", nil
+}
+
+// compileComparison produit une condition qui n'est jamais NULL, pour que
+// NOT retienne aussi les événements où le champ est absent.
+func (c *compiler) compileComparison(n *Comparison) (string, error) {
+	expr, err := c.comparisonExpr(n)
+	if err != nil {
+		return "", err
+	}
+	if n.Field.Nullable {
+		expr = "COALESCE(" + expr + ", FALSE)"
+	}
+	return "(" + expr + ")", nil
+}
+
+func (c *compiler) comparisonExpr(n *Comparison) (string, error) {
+	field, value := n.Field, n.Value
+	wildcard := !value.Quoted && strings.ContainsAny(value.Text, "*?")
+
+	// Expression SQL du champ ; pour JSONB, le chemin est lui aussi un paramètre
+	column, jsonValue := field.Column, ""
+	if field.Kind == KindJSON {
+		path := c.arg(pq.Array(field.Path))
+		jsonValue = fmt.Sprintf("%s #> %s::text[]", field.Column, path)
+		column = fmt.Sprintf("(%s #>> %s::text[])", field.Column, path)
+	}
+
+	if n.Op != OpMatch {
+		if wildcard {
+			return "", errorf(value.Offset, "wildcards are only allowed with ':'")
+		}
+		return c.orderingExpr(n, column, jsonValue)
+	}
+
+	// champ:* teste la présence du champ
+	if !value.Quoted && value.Text == "*" {
+		switch field.Kind {
+		case KindTags:
+			return "cardinality(tags) > 0", nil
+		case KindJSON:
+			return jsonValue + " IS NOT NULL", nil
+		}
+		return column + " IS NOT NULL", nil
+	}
+
+	switch field.Kind {
+	case KindTime, KindInteger:
+		if wildcard {
+			return "", errorf(value.Offset, "wildcards are not supported on field %q", field.Name)
+		}
+		v, err := parseTyped(field, value)
+		if err != nil {
+			return "", err
+		}
+		return column + " = " + c.arg(v), nil
+
+	case KindEventType, KindSeverity:
+		if !wildcard && !knownEnumValue(field.Kind, value.Text) {
+			return "", errorf(value.Offset, "unknown %s %q (expected %s)", field.Name, value.Text, enumValues(field.Kind))
+		}
+
+	case KindIP:
+		if !wildcard && !value.Quoted && strings.Contains(value.Text, "/") {
+			return c.cidrExpr(column, value)
+		}
+		if !wildcard && net.ParseIP(value.Text) == nil {
+			return "", errorf(value.Offset, "invalid IP address %q for field %q", value.Text, field.Name)
+		}
+
+	case KindTags:
+		if wildcard {
+			return "EXISTS (SELECT 1 FROM unnest(tags) AS tag WHERE tag LIKE " + c.arg(likePattern(value.Text)) + ")", nil
+		}
+		return c.arg(value.Text) + " = ANY(tags)", nil
+
+	case KindJSON:
+		if !wildcard && !value.Quoted && strings.Contains(value.Text, "/") {
+			if _, _, err := net.ParseCIDR(value.Text); err == nil {
+				return c.cidrExpr(column, value)
+			}
+		}
+	}
+
+	if wildcard {
+		return column + " LIKE " + c.arg(likePattern(value.Text)), nil
+	}
+	return column + " = " + c.arg(value.Text), nil
+}
+
+// orderingExpr compile >, >=, < et <= sur les champs ordonnés
+func (c *compiler) orderingExpr(n *Comparison, column, jsonValue string) (string, error) {
+	field, value := n.Field, n.Value
+
+	switch field.Kind {
+	case KindTime, KindInteger:
+		v, err := parseTyped(field, value)
+		if err != nil {
+			return "", err
+		}
+		return column + " " + string(n.Op) + " " + c.arg(v), nil
+
+	case KindSeverity:
+		// severity>=high devient severity = ANY('{high,critical}') pour garder l'index
+		if !knownEnumValue(KindSeverity, value.Text) {
+			return "", errorf(value.Offset, "unknown severity %q (expected %s)", value.Text, enumValues(KindSeverity))
+		}
+		var matching []string
+		for i, severity := range severities {
+			if compareRanks(i, severityRank(value.Text), n.Op) {
+				matching = append(matching, string(severity))
+			}
+		}
+		if len(matching) == 0 {
+			return "FALSE", nil
+		}
+		return "severity = ANY(" + c.arg(pq.Array(matching)) + "::text[])", nil
+
+	case KindJSON:
+		number, err := strconv.ParseFloat(value.Text, 64)
+		if err != nil {
+			return "", errorf(value.Offset, "operator %s on %q requires a number, got %q", n.Op, field.Name, value.Text)
+		}
+		return fmt.Sprintf("CASE WHEN jsonb_typeof(%s) = 'number' THEN %s::numeric %s %s END",
+			jsonValue, column, n.Op, c.arg(number)), nil
+	}
+
+	return "", errorf(n.Offset, "operator %s is not supported on field %q", n.Op, field.Name)
+}
+
+// cidrExpr teste l'appartenance d'une adresse stockée en texte à un réseau
+func (c *compiler) cidrExpr(column string, value Value) (string, error) {
+	_, network, err := net.ParseCIDR(value.Text)
+	if err != nil {
+		return "", errorf(value.Offset, "invalid CIDR %q", value.Text)
+	}
+	return fmt.Sprintf("CASE WHEN %s ~ %s OR %s ~ %s THEN %s::inet <<= %s::cidr END",
+		column, ipv4Pattern, column, ipv6Pattern, column, c.arg(network.String())), nil
+}
+
+// parseTyped convertit la valeur d'un champ horodaté ou entier
+func parseTyped(field Field, value Value) (interface{}, error) {
+	if field.Kind == KindTime {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+			if t, err := time.Parse(layout, value.Text); err == nil {
+				return t, nil
+			}
+		}
+		return nil, errorf(value.Offset, "invalid time %q for field %q (expected RFC3339 or YYYY-MM-DD)", value.Text, field.Name)
+	}
+
+	v, err := strconv.ParseInt(value.Text, 10, 64)
+	if err != nil {
+		return nil, errorf(value.Offset, "invalid integer %q for field %q", value.Text, field.Name)
+	}
+	return v, nil
+}
+
+// likePattern traduit * et ? en motif LIKE en échappant les caractères spéciaux
+func likePattern(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch r {
+		case '*':
+			b.WriteByte('%')
+		case '?':
+			b.WriteByte('_')
+		case '%', '_', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func knownEnumValue(kind FieldKind, value string) bool {
+	if kind == KindSeverity {
+		return severityRank(value) >= 0
+	}
+	for _, eventType := range eventTypes {
+		if string(eventType) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func enumValues(kind FieldKind) string {
+	var values []string
+	if kind == KindSeverity {
+		for _, severity := range severities {
+			values = append(values, string(severity))
+		}
+	} else {
+		for _, eventType := range eventTypes {
+			values = append(values, string(eventType))
+		}
+	}
+	return strings.Join(values, ", ")
+}
+
+func severityRank(value string) int {
+	for i, severity := range severities {
+		if string(severity) == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func compareRanks(a, b int, op Operator) bool {
+	switch op {
+	case OpGreater:
+		return a > b
+	case OpGreaterEqual:
+		return a >= b
+	case OpLess:
+		return a < b
+	case OpLessEqual:
+		return a <= b
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package search
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestCompile(t *testing.T) {
+	cidr := func(column, arg string) string {
+		return "CASE WHEN " + column + " ~ " + ipv4Pattern + " OR " + column + " ~ " + ipv6Pattern +
+			" THEN " + column + "::inet <<= " + arg + "::cidr END"
+	}
+
+	tests := []struct {
+		input     string
+		firstArg  int
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{"hostname:web-01", 1, "(hostname = $1)", []interface{}{"web-01"}},
+		{"hostname:*", 1, "(hostname IS NOT NULL)", nil},
+		{"event_type:proc*", 1, "(event_type LIKE $1)", []interface{}{"proc%"}},
+		{"username:root", 1, "(COALESCE(username = $1, FALSE))", []interface{}{"root"}},
+
+		// Jokers traduits en LIKE, caractères spéciaux de LIKE échappés
+		{"process_name:ssh*", 1, "(COALESCE(process_name LIKE $1, FALSE))", []interface{}{"ssh%"}},
+		{`process_name:50%_a?\b*`, 1, "(COALESCE(process_name LIKE $1, FALSE))", []interface{}{`50\%\_a_\\b%`}},
+		{`process_name:"ssh*"`, 1, "(COALESCE(process_name = $1, FALSE))", []interface{}{"ssh*"}},
+		{`process_name:"*"`, 1, "(COALESCE(process_name = $1, FALSE))", []interface{}{"*"}},
+
+		// Tags
+		{"tags:*", 1, "(COALESCE(cardinality(tags) > 0, FALSE))", nil},
+		{"tags:heartbeat", 1, "(COALESCE($1 = ANY(tags), FALSE))", []interface{}{"heartbeat"}},
+		{"tags:auth_*", 1, "(COALESCE(EXISTS (SELECT 1 FROM unnest(tags) AS tag WHERE tag LIKE $1), FALSE))", []interface{}{`auth\_%`}},
+
+		// Champs ordonnés
+		{"severity>=high", 1, "(severity = ANY($1::text[]))", []interface{}{pq.Array([]string{"high", "critical"})}},
+		{"severity<medium", 1, "(severity = ANY($1::text[]))", []interface{}{pq.Array([]string{"low"})}},
+		{"severity>critical", 1, "(FALSE)", nil},
+		{"process_pid>100", 1, "(COALESCE(process_pid > $1, FALSE))", []interface{}{int64(100)}},
+		{"process_pid:42", 1, "(COALESCE(process_pid = $1, FALSE))", []interface{}{int64(42)}},
+		{"timestamp>=2024-01-02", 1, "(timestamp >= $1)", []interface{}{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}},
+
+		// Adresses IP et réseaux, normalisés
+		{"source_ip:10.1.2.3", 1, "(COALESCE(source_ip = $1, FALSE))", []interface{}{"10.1.2.3"}},
+		{"source_ip:10.*", 1, "(COALESCE(source_ip LIKE $1, FALSE))", []interface{}{"10.%"}},
+		{"source_ip:10.1.2.3/8", 1, "(COALESCE(" + cidr("source_ip", "$1") + ", FALSE))", []interface{}{"10.0.0.0/8"}},
+		{"destination_ip:fe80::1/10", 1, "(COALESCE(" + cidr("destination_ip", "$1") + ", FALSE))", []interface{}{"fe80::/10"}},
+
+		// Chemins JSONB, passés en paramètre
+		{"raw_data.process.name:bash", 1, "(COALESCE((raw_data #>> $1::text[]) = $2, FALSE))",
+			[]interface{}{pq.Array([]string{"process", "name"}), "bash"}},
+		{"metadata.site:*", 1, "(COALESCE(metadata #> $1::text[] IS NOT NULL, FALSE))",
+			[]interface{}{pq.Array([]string{"site"})}},
+		{"raw_data.process.cpu_percent>50.5", 1,
+			"(COALESCE(CASE WHEN jsonb_typeof(raw_data #> $1::text[]) = 'number' THEN (raw_data #>> $1::text[])::numeric > $2 END, FALSE))",
+			[]interface{}{pq.Array([]string{"process", "cpu_percent"}), 50.5}},
+		{"raw_data.network.dest_ip:192.168.0.0/16", 1, "(COALESCE(" + cidr("(raw_data #>> $1::text[])", "$2") + ", FALSE))",
+			[]interface{}{pq.Array([]string{"network", "dest_ip"}), "192.168.0.0/16"}},
+		{"raw_data.file.path:/tmp/a/b", 1, "(COALESCE((raw_data #>> $1::text[]) = $2, FALSE))",
+			[]interface{}{pq.Array([]string{"file", "path"}), "/tmp/a/b"}},
+
+		// Combinaisons, paramètres numérotés à partir de firstArg
+		{"severity:high AND NOT (hostname:a OR hostname:b*)", 3,
+			"((severity = $3) AND NOT ((hostname = $4) OR (hostname LIKE $5)))",
+			[]interface{}{"high", "a", "b%"}},
+		{"NOT username:root", 1, "NOT (COALESCE(username = $1, FALSE))", []interface{}{"root"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.input, err)
+			}
+			query, err := Compile(node, tt.firstArg)
+			if err != nil {
+				t.Fatalf("Compile(%q) returned error: %v", tt.input, err)
+			}
+			if query.Where != tt.wantWhere {
+				t.Errorf("Compile(%q).Where =\n\t%s\nwant\n\t%s", tt.input, query.Where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(query.Args, tt.wantArgs) {
+				t.Errorf("Compile(%q).Args = %#v, want %#v", tt.input, query.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantOffset int
+		wantErr    string
+	}{
+		{"process_pid>1*", 12, "wildcards are only allowed with ':'"},
+		{"process_pid:1*", 12, `wildcards are not supported on field "process_pid"`},
+		{"process_pid:abc", 12, `invalid integer "abc"`},
+		{"timestamp>yesterday", 10, `invalid time "yesterday"`},
+		{"severity:urgent", 9, `unknown severity "urgent" (expected low, medium, high, critical)`},
+		{"severity>=urgent", 10, `unknown severity "urgent"`},
+		{"event_type:bogus", 11, `unknown event_type "bogus"`},
+		{"source_ip:10.0.0.300", 10, `invalid IP address "10.0.0.300"`},
+		{`source_ip:"10.0.0.0/8"`, 10, `invalid IP address "10.0.0.0/8"`},
+		{"source_ip:10.0.0.0/33", 10, `invalid CIDR "10.0.0.0/33"`},
+		{"hostname>a", 0, `operator > is not supported on field "hostname"`},
+		{"tags<=a", 0, `operator <= is not supported on field "tags"`},
+		{"raw_data.process.pid>many", 21, `requires a number, got "many"`},
+		{"hostname:a AND NOT severity:urgent", 28, "unknown severity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.input, err)
+			}
+			_, err = Compile(node, 1)
+			var searchErr *Error
+			if !errors.As(err, &searchErr) {
+				t.Fatalf("Compile(%q) error = %v, want a *Error", tt.input, err)
+			}
+			if searchErr.Offset != tt.wantOffset || !strings.Contains(searchErr.Message, tt.wantErr) {
+				t.Errorf("Compile(%q) error = %q at %d, want %q at %d",
+					tt.input, searchErr.Message, searchErr.Offset, tt.wantErr, tt.wantOffset)
+			}
+		})
+	}
+}
+
+// TestIPPatterns vérifie que les motifs qui protègent les casts inet
+// acceptent exactement les adresses valides
+func TestIPPatterns(t *testing.T) {
+	ipv4Re := regexp.MustCompile(strings.Trim(ipv4Pattern, "'"))
+	ipv6Re := regexp.MustCompile(strings.Trim(ipv6Pattern, "'"))
+
+	inputs := []string{
+		"0.0.0.0", "10.1.2.3", "255.255.255.255", "192.168.1.10",
+		"256.1.1.1", "999.1.1.1", "1.2.3", "1.2.3.4.5", "01.2.3.4", "1.2.3.4 ", "1..2.3",
+		"::", "::1", "fe80::1", "2001:db8::8a2e:370:7334", "2001:0db8:0000:0000:0000:ff00:0042:8329",
+		"1:2:3:4:5:6:7::", "::2:3:4:5:6:7:8", "1::8", "::ffff:192.0.2.1", "64:ff9b::192.0.2.33",
+		"1:2:3:4:5:6:7:8:9", "1:2:3:4:5:6:7", ":::", "1::2::3", "12345::", "g::1", "fe80::1%eth0",
+		"::ffff:256.0.0.1", "12:30:00", "", "localhost", "-", "unknown",
+	}
+
+	for _, input := range inputs {
+		addr, err := netip.ParseAddr(input)
+		want := err == nil && addr.Zone() == ""
+		got := ipv4Re.MatchString(input) || ipv6Re.MatchString(input)
+		if got != want {
+			t.Errorf("IP patterns on %q = %t, want %t", input, got, want)
+		}
+	}
+}
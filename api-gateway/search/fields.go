@@ -0,0 +1,94 @@
+package search
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FieldKind détermine les opérateurs et valeurs acceptés par un champ
+type FieldKind int
+
+const (
+	KindText FieldKind = iota
+	KindEventType
+	KindSeverity
+	KindInteger
+	KindTime
+	KindIP
+	KindTags
+	KindJSON
+)
+
+// Field est un champ résolu : une colonne de raw_events ou un chemin JSONB
+type Field struct {
+	Name     string
+	Column   string
+	Kind     FieldKind
+	Nullable bool
+	Path     []string // chemin dans la colonne JSONB (KindJSON uniquement)
+}
+
+// columns liste les colonnes interrogeables de raw_events
+var columns = map[string]Field{
+	"timestamp":      {Column: "timestamp", Kind: KindTime},
+	"agent_id":       {Column: "agent_id", Kind: KindText},
+	"hostname":       {Column: "hostname", Kind: KindText},
+	"event_type":     {Column: "event_type", Kind: KindEventType},
+	"severity":       {Column: "severity", Kind: KindSeverity},
+	"source_ip":      {Column: "source_ip", Kind: KindIP, Nullable: true},
+	"destination_ip": {Column: "destination_ip", Kind: KindIP, Nullable: true},
+	"process_name":   {Column: "process_name", Kind: KindText, Nullable: true},
+	"process_pid":    {Column: "process_pid", Kind: KindInteger, Nullable: true},
+	"username":       {Column: "username", Kind: KindText, Nullable: true},
+	"tags":           {Column: "tags", Kind: KindTags, Nullable: true},
+}
+
+// jsonColumns sont les colonnes JSONB interrogeables par chemin
+var jsonColumns = map[string]bool{
+	"raw_data": true,
+	"metadata": true,
+}
+
+// maxPathDepth borne la profondeur d'un chemin JSONB
+const maxPathDepth = 8
+
+var pathSegmentPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// resolveField associe un nom de champ à sa colonne
+func resolveField(name string, offset int) (Field, error) {
+	if field, ok := columns[name]; ok {
+		field.Name = name
+		return field, nil
+	}
+
+	column, rest, found := strings.Cut(name, ".")
+	if !jsonColumns[column] {
+		return Field{}, errorf(offset, "unknown field %q (expected one of %s, or a raw_data./metadata. path)",
+			name, strings.Join(columnNames(), ", "))
+	}
+	if !found || rest == "" {
+		return Field{}, errorf(offset, "field %q requires a path, e.g. %s.process.name", name, column)
+	}
+
+	path := strings.Split(rest, ".")
+	if len(path) > maxPathDepth {
+		return Field{}, errorf(offset, "path %q is deeper than %d levels", name, maxPathDepth)
+	}
+	for _, segment := range path {
+		if !pathSegmentPattern.MatchString(segment) {
+			return Field{}, errorf(offset, "invalid path segment %q in %q", segment, name)
+		}
+	}
+
+	return Field{Name: name, Column: column, Kind: KindJSON, Nullable: true, Path: path}, nil
+}
+
+func columnNames() []string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,306 @@
+package search
+
+import (
+	"strings"
+)
+
+const (
+	// maxQueryLength borne la taille d'une recherche
+	maxQueryLength = 4096
+
+	// maxDepth borne l'imbrication des parenthèses et des NOT
+	maxDepth = 32
+
+	// maxComparisons borne le nombre de comparaisons d'une recherche
+	maxComparisons = 100
+)
+
+// parser analyse une recherche :
+//
+//	expr       := and { "OR" and }
+//	and        := unary { ["AND"] unary }
+//	unary      := "NOT" unary | primary
+//	primary    := "(" expr ")" | comparison
+//	comparison := field (":" | ">" | ">=" | "<" | "<=") value
+//	value      := word | '"' chars '"'
+//
+// Les mots-clés ne sont pas sensibles à la casse ; deux termes juxtaposés
+// sont combinés par AND.
+type parser struct {
+	input       string
+	pos         int
+	depth       int
+	comparisons int
+}
+
+// Parse analyse une recherche et retourne son arbre syntaxique.
+// Les erreurs retournées sont de type *Error.
+func Parse(input string) (Node, error) {
+	if len(input) > maxQueryLength {
+		return nil, errorf(maxQueryLength, "query is longer than %d characters", maxQueryLength)
+	}
+
+	p := &parser{input: input}
+	p.skipSpace()
+	if p.eof() {
+		return nil, errorf(0, "empty query")
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if !p.eof() {
+		if p.input[p.pos] == ')' {
+			return nil, errorf(p.pos, "unexpected ')' without matching '('")
+		}
+		return nil, errorf(p.pos, "unexpected %q", p.rest())
+	}
+	return node, nil
+}
+
+func (p *parser) parseOr() (Node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	terms := []Node{first}
+	for p.acceptKeyword("OR") {
+		term, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return &Or{Terms: terms}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	terms := []Node{first}
+	for {
+		p.skipSpace()
+		if p.eof() || p.input[p.pos] == ')' || p.peekKeyword("OR") {
+			break
+		}
+		p.acceptKeyword("AND")
+
+		term, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return &And{Terms: terms}, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	p.skipSpace()
+	offset := p.pos
+	if !p.acceptKeyword("NOT") {
+		return p.parsePrimary()
+	}
+
+	if err := p.enter(offset); err != nil {
+		return nil, err
+	}
+	term, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	p.depth--
+
+	return &Not{Term: term, Offset: offset}, nil
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	p.skipSpace()
+	if p.eof() {
+		return nil, errorf(p.pos, "unexpected end of query, expected a search term")
+	}
+
+	switch p.input[p.pos] {
+	case '(':
+		open := p.pos
+		if err := p.enter(open); err != nil {
+			return nil, err
+		}
+		p.pos++
+
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		if p.eof() || p.input[p.pos] != ')' {
+			return nil, errorf(open, "missing ')' for '(' opened here")
+		}
+		p.pos++
+		p.depth--
+		return node, nil
+
+	case ')':
+		return nil, errorf(p.pos, "unexpected ')', expected a search term")
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	offset := p.pos
+	name := p.scanWhile(isFieldChar)
+	if name == "" {
+		return nil, errorf(offset, "expected a field name, found %q", p.rest())
+	}
+	for _, keyword := range []string{"AND", "OR"} {
+		if strings.EqualFold(name, keyword) {
+			return nil, errorf(offset, "unexpected %s, expected a search term", keyword)
+		}
+	}
+
+	p.skipSpace()
+	op, ok := p.scanOperator()
+	if !ok {
+		return nil, errorf(p.pos, "expected ':', '>', '>=', '<' or '<=' after field %q", name)
+	}
+
+	p.skipSpace()
+	value, err := p.scanValue(name)
+	if err != nil {
+		return nil, err
+	}
+
+	field, err := resolveField(name, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	p.comparisons++
+	if p.comparisons > maxComparisons {
+		return nil, errorf(offset, "query has more than %d comparisons", maxComparisons)
+	}
+
+	return &Comparison{Field: field, Op: op, Value: value, Offset: offset}, nil
+}
+
+func (p *parser) scanOperator() (Operator, bool) {
+	for _, op := range []Operator{OpGreaterEqual, OpLessEqual, OpMatch, OpGreater, OpLess} {
+		if strings.HasPrefix(p.input[p.pos:], string(op)) {
+			p.pos += len(op)
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// scanValue lit un mot ou une chaîne entre guillemets (\" et \\ y sont échappés)
+func (p *parser) scanValue(field string) (Value, error) {
+	offset := p.pos
+	if p.eof() || p.input[p.pos] == '(' || p.input[p.pos] == ')' {
+		return Value{}, errorf(offset, "missing value for field %q", field)
+	}
+
+	if p.input[p.pos] != '"' {
+		return Value{Text: p.scanWhile(isValueChar), Offset: offset}, nil
+	}
+
+	var b strings.Builder
+	for p.pos++; !p.eof(); p.pos++ {
+		switch c := p.input[p.pos]; c {
+		case '"':
+			p.pos++
+			return Value{Text: b.String(), Quoted: true, Offset: offset}, nil
+		case '\\':
+			if p.pos+1 < len(p.input) && (p.input[p.pos+1] == '"' || p.input[p.pos+1] == '\\') {
+				p.pos++
+				b.WriteByte(p.input[p.pos])
+				continue
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return Value{}, errorf(offset, "unterminated quoted value")
+}
+
+// enter incrémente la profondeur d'imbrication
+func (p *parser) enter(offset int) error {
+	p.depth++
+	if p.depth > maxDepth {
+		return errorf(offset, "query is nested deeper than %d levels", maxDepth)
+	}
+	return nil
+}
+
+// peekKeyword indique si le prochain mot est keyword
+func (p *parser) peekKeyword(keyword string) bool {
+	p.skipSpace()
+	end := p.pos + len(keyword)
+	if end > len(p.input) || !strings.EqualFold(p.input[p.pos:end], keyword) {
+		return false
+	}
+	return end == len(p.input) || isSpace(p.input[end]) || p.input[end] == '(' || p.input[end] == ')'
+}
+
+// acceptKeyword consomme keyword s'il est le prochain mot
+func (p *parser) acceptKeyword(keyword string) bool {
+	if !p.peekKeyword(keyword) {
+		return false
+	}
+	p.pos += len(keyword)
+	return true
+}
+
+func (p *parser) scanWhile(accept func(byte) bool) string {
+	start := p.pos
+	for !p.eof() && accept(p.input[p.pos]) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *parser) skipSpace() {
+	p.scanWhile(isSpace)
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+// rest retourne le début du texte restant, pour les messages d'erreur
+func (p *parser) rest() string {
+	rest := p.input[p.pos:]
+	if len(rest) > 20 {
+		rest = rest[:20] + "..."
+	}
+	return rest
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isFieldChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-'
+}
+
+func isValueChar(c byte) bool {
+	return !isSpace(c) && c != '(' && c != ')' && c != '"'
+}
@@ -0,0 +1,106 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"hostname:web-01", "hostname:web-01"},
+		{"  process_pid > 10 ", "process_pid>10"},
+		{"raw_data.process.cpu_percent>=50", "raw_data.process.cpu_percent>=50"},
+		{"timestamp<=2024-01-02", "timestamp<=2024-01-02"},
+
+		// Deux termes juxtaposés sont combinés par AND
+		{"severity:high process_name:ssh*", "(severity:high AND process_name:ssh*)"},
+		{"hostname:a AND(hostname:b)", "(hostname:a AND hostname:b)"},
+
+		// AND est prioritaire sur OR, NOT sur AND
+		{"hostname:a OR hostname:b hostname:c", "(hostname:a OR (hostname:b AND hostname:c))"},
+		{"hostname:a AND hostname:b OR hostname:c", "((hostname:a AND hostname:b) OR hostname:c)"},
+		{"NOT hostname:a AND hostname:b", "((NOT hostname:a) AND hostname:b)"},
+		{"NOT NOT hostname:a", "(NOT (NOT hostname:a))"},
+		{"hostname:a or hostname:b or hostname:c", "(hostname:a OR hostname:b OR hostname:c)"},
+
+		// Les parenthèses changent la priorité ; les mots-clés ignorent la casse
+		{"(hostname:a OR hostname:b) hostname:c", "((hostname:a OR hostname:b) AND hostname:c)"},
+		{"not (hostname:a or hostname:b)", "(NOT (hostname:a OR hostname:b))"},
+		{"((hostname:a))", "hostname:a"},
+
+		// Un mot-clé collé à un autre mot n'en est pas un
+		{"hostname:ORACLE hostname:NOTE", "(hostname:ORACLE AND hostname:NOTE)"},
+
+		// Valeurs entre guillemets : espaces, \" et \\ échappés, autres \ conservés
+		{`username:"john doe"`, `username:"john doe"`},
+		{`username:"a \"b\" \\ c"`, `username:"a \"b\" \\ c"`},
+		{`username:"C:\Users"`, `username:"C:\\Users"`},
+		{`process_name:"ssh*"`, `process_name:"ssh*"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.input, err)
+			}
+			if got := node.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantOffset int
+		wantErr    string
+	}{
+		{"empty", "", 0, "empty query"},
+		{"blank", "   ", 0, "empty query"},
+		{"too long", "hostname:" + strings.Repeat("a", maxQueryLength), maxQueryLength, "query is longer than 4096 characters"},
+		{"unmatched close", "hostname:a)", 10, "unexpected ')' without matching '('"},
+		{"unclosed open", "hostname:b (hostname:a", 11, "missing ')' for '(' opened here"},
+		{"empty parentheses", "()", 1, "unexpected ')', expected a search term"},
+		{"trailing and", "hostname:a AND", 14, "unexpected end of query, expected a search term"},
+		{"trailing not", "NOT ", 4, "unexpected end of query, expected a search term"},
+		{"double or", "hostname:a OR OR hostname:b", 14, "unexpected OR, expected a search term"},
+		{"leading and", "and hostname:a", 0, "unexpected AND, expected a search term"},
+		{"no field", "!hostname:a", 0, `expected a field name, found "!hostname:a"`},
+		{"no operator", "hostname", 8, `expected ':', '>', '>=', '<' or '<=' after field "hostname"`},
+		{"bad operator", "hostname=a", 8, `expected ':', '>', '>=', '<' or '<=' after field "hostname"`},
+		{"no value", "hostname:", 9, `missing value for field "hostname"`},
+		{"parenthesis value", "hostname:(a)", 9, `missing value for field "hostname"`},
+		{"unterminated quote", `username:"abc`, 9, "unterminated quoted value"},
+		{"escaped closing quote", `username:"abc\"`, 9, "unterminated quoted value"},
+		{"unknown field", "host:a", 0, `unknown field "host"`},
+		{"json without path", "hostname:a raw_data:x", 11, `field "raw_data" requires a path`},
+		{"empty path segment", "raw_data.a..b:x", 0, `invalid path segment "" in "raw_data.a..b"`},
+		{"field character", "metadata.a$b:x", 10, `expected ':', '>', '>=', '<' or '<=' after field "metadata.a"`},
+		{"keyword prefix", "hostname:a ORx", 14, `expected ':', '>', '>=', '<' or '<=' after field "ORx"`},
+		{"deep path", "raw_data.a.b.c.d.e.f.g.h.i:x", 0, "deeper than 8 levels"},
+		{"deep nesting", strings.Repeat("(", maxDepth+1) + "hostname:a", maxDepth, "nested deeper than 32 levels"},
+		{"deep not", strings.Repeat("NOT ", maxDepth+1) + "hostname:a", 4 * maxDepth, "nested deeper than 32 levels"},
+		{"too many comparisons", strings.Repeat("hostname:a ", maxComparisons) + "hostname:b", 11 * maxComparisons, "more than 100 comparisons"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			var searchErr *Error
+			if !errors.As(err, &searchErr) {
+				t.Fatalf("Parse(%q) error = %v, want a *Error", tt.input, err)
+			}
+			if searchErr.Offset != tt.wantOffset || !strings.Contains(searchErr.Message, tt.wantErr) {
+				t.Errorf("Parse(%q) error = %q at %d, want %q at %d",
+					tt.input, searchErr.Message, searchErr.Offset, tt.wantErr, tt.wantOffset)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+// Package stream diffuse en temps réel les événements et alertes ingérés aux
+// clients SSE et WebSocket. Une seule source (LISTEN/NOTIFY) alimente tous les
+// abonnés : le nombre de clients n'augmente pas la charge sur TimescaleDB.
+package stream
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+const (
+	// MaxSubscribers borne le nombre de clients connectés
+	MaxSubscribers = 1000
+
+	// subscriberBuffer est le nombre de messages en attente par client ;
+	// au-delà, les messages d'un client trop lent sont abandonnés
+	subscriberBuffer = 256
+)
+
+var (
+	// ErrTooManySubscribers est retourné lorsque MaxSubscribers est atteint
+	ErrTooManySubscribers = errors.New("too many stream subscribers")
+
+	// ErrHubClosed est retourné après l'arrêt du hub
+	ErrHubClosed = errors.New("stream hub is closed")
+)
+
+// Message types
+const (
+	MessageEvent = "event"
+	MessageAlert = "alert"
+)
+
+// Message est un événement ou une alerte diffusé aux abonnés
+type Message struct {
+	Type  string        `json:"type"`
+	Event *models.Event `json:"event,omitempty"`
+	Alert *models.Alert `json:"alert,omitempty"`
+}
+
+// Filter reprend les filtres de GET /api/v1/events/filter
+type Filter struct {
+	EventType string
+	Severity  string
+	Hostname  string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// FilterFromMap construit un filtre à partir des filtres d'un handler
+func FilterFromMap(filters map[string]interface{}) Filter {
+	var f Filter
+	f.EventType, _ = filters["event_type"].(string)
+	f.Severity, _ = filters["severity"].(string)
+	f.Hostname, _ = filters["hostname"].(string)
+	f.StartTime, _ = filters["start_time"].(time.Time)
+	f.EndTime, _ = filters["end_time"].(time.Time)
+	return f
+}
+
+// Match indique si un message passe le filtre. Pour une alerte, la sévérité
+// est comparée au niveau de la règle et la période au timestamp de l'événement.
+func (f Filter) Match(msg *Message) bool {
+	var eventType, severity, hostname string
+	var timestamp time.Time
+
+	switch {
+	case msg.Event != nil:
+		eventType, severity, hostname = string(msg.Event.EventType), string(msg.Event.Severity), msg.Event.Hostname
+		timestamp = msg.Event.Timestamp
+	case msg.Alert != nil:
+		eventType, severity, hostname = msg.Alert.EventType, msg.Alert.RuleLevel, msg.Alert.Hostname
+		timestamp = msg.Alert.EventTimestamp
+	default:
+		return false
+	}
+
+	if f.EventType != "" && f.EventType != eventType {
+		return false
+	}
+	if f.Severity != "" && f.Severity != severity {
+		return false
+	}
+	if f.Hostname != "" && f.Hostname != hostname {
+		return false
+	}
+	if !f.StartTime.IsZero() && timestamp.Before(f.StartTime) {
+		return false
+	}
+	if !f.EndTime.IsZero() && timestamp.After(f.EndTime) {
+		return false
+	}
+	return true
+}
+
+// Subscription est l'abonnement d'un client. C est fermé à l'arrêt du hub.
+type Subscription struct {
+	C      <-chan *Message
+	ch     chan *Message
+	filter Filter
+	hub    *Hub
+
+	mu      sync.Mutex
+	dropped int
+}
+
+// Dropped retourne et remet à zéro le nombre de messages abandonnés
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dropped := s.dropped
+	s.dropped = 0
+	return dropped
+}
+
+// Close désabonne le client
+func (s *Subscription) Close() {
+	s.hub.unsubscribe(s)
+}
+
+// Hub diffuse les messages à tous les abonnés dont le filtre correspond
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewHub crée un hub sans abonné
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscribe abonne un client
+func (h *Hub) Subscribe(filter Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+	if len(h.subscribers) >= MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+
+	ch := make(chan *Message, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, hub: h}
+	h.subscribers[sub] = struct{}{}
+	return sub, nil
+}
+
+func (h *Hub) unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, sub)
+}
+
+// Close ferme les abonnements en cours pour que les flux se terminent
+// avant l'arrêt du serveur
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subscribers {
+		close(sub.ch)
+		delete(h.subscribers, sub)
+	}
+}
+
+// Len retourne le nombre d'abonnés
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers)
+}
+
+// Broadcast envoie un message aux abonnés sans jamais bloquer sur un client lent
+func (h *Hub) Broadcast(msg *Message) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers {
+		if !sub.filter.Match(msg) {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			sub.mu.Lock()
+			sub.dropped++
+			sub.mu.Unlock()
+		}
+	}
+}
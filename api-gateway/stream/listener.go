@@ -0,0 +1,174 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luigi/xdr-platform/api/models"
+)
+
+const (
+	// EventsChannel est le canal NOTIFY sur lequel le service d'ingestion annonce
+	// chaque lot inséré
+	EventsChannel = "xdr_events"
+
+	// maxNotificationIDs borne le nombre d'événements relus par notification
+	maxNotificationIDs = 10000
+)
+
+// Store relit les lignes annoncées par une notification
+type Store interface {
+	GetEventsByIDs(ctx context.Context, ids []int64, from, to time.Time) ([]*models.Event, error)
+	GetAlertsByEventIDs(ctx context.Context, eventIDs []int64) ([]*models.Alert, error)
+	GetAlertsByIDs(ctx context.Context, ids []int64) ([]*models.Alert, error)
+}
+
+// notification est la charge utile émise par le service d'ingestion. AlertIDs
+// annonce des alertes levées sans nouvel événement (agents silencieux).
+type notification struct {
+	IDs      [][2]int64 `json:"ids"`
+	From     time.Time  `json:"from"`
+	To       time.Time  `json:"to"`
+	Alerts   int        `json:"alerts"`
+	AlertIDs [][2]int64 `json:"alert_ids"`
+}
+
+// Listener écoute les notifications d'insertion et diffuse les lignes sur le hub
+type Listener struct {
+	listener *pq.Listener
+	store    Store
+	hub      *Hub
+	logger   *log.Logger
+	done     chan struct{}
+}
+
+// NewListener se connecte à la base et écoute EventsChannel
+func NewListener(databaseURL string, store Store, hub *Hub, logger *log.Logger) (*Listener, error) {
+	pl := pq.NewListener(databaseURL, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			logger.Printf("Stream listener disconnected: %v", err)
+		case pq.ListenerEventReconnected:
+			logger.Println("Stream listener reconnected, events inserted meanwhile were not streamed")
+		case pq.ListenerEventConnectionAttemptFailed:
+			logger.Printf("Stream listener connection attempt failed: %v", err)
+		}
+	})
+
+	if err := pl.Listen(EventsChannel); err != nil {
+		pl.Close()
+		return nil, err
+	}
+
+	l := &Listener{
+		listener: pl,
+		store:    store,
+		hub:      hub,
+		logger:   logger,
+		done:     make(chan struct{}),
+	}
+	go l.run()
+	return l, nil
+}
+
+// Close arrête l'écoute
+func (l *Listener) Close() error {
+	err := l.listener.Close()
+	<-l.done
+	return err
+}
+
+func (l *Listener) run() {
+	defer close(l.done)
+
+	// Un ping régulier détecte les connexions mortes
+	ticker := time.NewTicker(90 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case n, ok := <-l.listener.Notify:
+			if !ok {
+				return
+			}
+			// n est nil après une reconnexion
+			if n != nil {
+				l.handle(n.Extra)
+			}
+
+		case <-ticker.C:
+			go l.listener.Ping()
+		}
+	}
+}
+
+// handle relit les événements et alertes d'un lot, une seule fois pour tous les abonnés
+func (l *Listener) handle(payload string) {
+	if l.hub.Len() == 0 {
+		return
+	}
+
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		l.logger.Printf("Invalid stream notification: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if ids := expandRanges(n.IDs); len(ids) > 0 {
+		l.broadcastEvents(ctx, ids, n)
+	}
+
+	if ids := expandRanges(n.AlertIDs); len(ids) > 0 {
+		alerts, err := l.store.GetAlertsByIDs(ctx, ids)
+		if err != nil {
+			l.logger.Printf("Failed to load streamed alerts: %v", err)
+			return
+		}
+		l.broadcastAlerts(alerts)
+	}
+}
+
+// broadcastEvents diffuse les événements d'un lot et les alertes qu'ils ont déclenchées
+func (l *Listener) broadcastEvents(ctx context.Context, ids []int64, n notification) {
+	events, err := l.store.GetEventsByIDs(ctx, ids, n.From, n.To)
+	if err != nil {
+		l.logger.Printf("Failed to load streamed events: %v", err)
+		return
+	}
+	for _, event := range events {
+		l.hub.Broadcast(&Message{Type: MessageEvent, Event: event})
+	}
+
+	if n.Alerts == 0 {
+		return
+	}
+	alerts, err := l.store.GetAlertsByEventIDs(ctx, ids)
+	if err != nil {
+		l.logger.Printf("Failed to load streamed alerts: %v", err)
+		return
+	}
+	l.broadcastAlerts(alerts)
+}
+
+func (l *Listener) broadcastAlerts(alerts []*models.Alert) {
+	for _, alert := range alerts {
+		l.hub.Broadcast(&Message{Type: MessageAlert, Alert: alert})
+	}
+}
+
+// expandRanges développe des plages d'IDs, dans la limite de maxNotificationIDs
+func expandRanges(ranges [][2]int64) []int64 {
+	var ids []int64
+	for _, r := range ranges {
+		for id := r[0]; id <= r[1] && len(ids) < maxNotificationIDs; id++ {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -30,6 +30,8 @@ SELECT create_hypertable('raw_events', 'timestamp');
 
 -- Indexes for performance
 CREATE INDEX idx_raw_events_timestamp ON raw_events (timestamp DESC);
+-- Pagination par curseur sur (timestamp, id)
+CREATE INDEX idx_raw_events_timestamp_id ON raw_events (timestamp DESC, id DESC);
 CREATE INDEX idx_raw_events_event_type ON raw_events (event_type);
 CREATE INDEX idx_raw_events_severity ON raw_events (severity);
 CREATE INDEX idx_raw_events_hostname ON raw_events (hostname);
@@ -44,6 +46,135 @@ SELECT add_compression_policy('raw_events', INTERVAL '7 days');
 -- Retention policy (drop chunks older than 90 days)
 SELECT add_retention_policy('raw_events', INTERVAL '90 days');
 
+-- Timeline continuous aggregates (events_per_minute, events_per_hour,
+-- events_per_day) are created by the API gateway at startup, e.g.:
+--
+-- CREATE MATERIALIZED VIEW events_per_hour
+-- WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+-- SELECT time_bucket(INTERVAL '1 hour', timestamp) AS bucket,
+--        event_type, severity, hostname, COUNT(*) AS event_count
+-- FROM raw_events
+-- GROUP BY bucket, event_type, severity, hostname
+-- WITH NO DATA;
+
+-- Dead-letter quarantine for events rejected by the ingestion pipeline
+CREATE TABLE rejected_events (
+    id BIGSERIAL PRIMARY KEY,
+    rejected_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    stage TEXT NOT NULL,              -- decode, validate, insert, marshal (agent)
+    reason TEXT NOT NULL,
+    payload BYTEA NOT NULL,           -- original Kafka message value, possibly not valid UTF-8
+    agent_id TEXT,
+    source_topic TEXT NOT NULL,
+    source_partition INTEGER NOT NULL,
+    source_offset BIGINT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',  -- pending, replayed
+    replayed_at TIMESTAMPTZ,
+    replay_error TEXT
+);
+
+CREATE INDEX idx_rejected_events_rejected_at ON rejected_events (rejected_at DESC);
+CREATE INDEX idx_rejected_events_status ON rejected_events (status);
+
+-- Alerts raised by the Sigma detection engine of the ingestion pipeline
+CREATE TABLE alerts (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    rule_id TEXT NOT NULL,
+    rule_title TEXT NOT NULL,
+    rule_level TEXT NOT NULL,         -- informational, low, medium, high, critical
+    description TEXT,
+    tags TEXT[],                      -- Sigma tags, MITRE ATT&CK as attack.*
+    event_id BIGINT NOT NULL,         -- triggering raw_events (id, timestamp)
+    event_timestamp TIMESTAMPTZ NOT NULL,
+    agent_id TEXT NOT NULL,
+    hostname TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'new',  -- new, acknowledged, investigating, closed
+    assignee TEXT,
+    resolution TEXT,                  -- true_positive, benign, false_positive, duplicate (closed only)
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_alerts_created_at ON alerts (created_at DESC);
+CREATE INDEX idx_alerts_status ON alerts (status);
+CREATE INDEX idx_alerts_assignee ON alerts (assignee);
+CREATE INDEX idx_alerts_rule_id ON alerts (rule_id);
+CREATE INDEX idx_alerts_rule_level ON alerts (rule_level);
+CREATE INDEX idx_alerts_hostname ON alerts (hostname);
+CREATE INDEX idx_alerts_tags ON alerts USING GIN (tags);
+
+-- Alert lifecycle: every status transition, used for MTTA / MTTC reporting
+CREATE TABLE alert_state_history (
+    id BIGSERIAL PRIMARY KEY,
+    alert_id BIGINT NOT NULL REFERENCES alerts (id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    actor TEXT,
+    note TEXT,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_alert_state_history_alert_id ON alert_state_history (alert_id, changed_at);
+
+-- Analyst comments on alerts
+CREATE TABLE alert_comments (
+    id BIGSERIAL PRIMARY KEY,
+    alert_id BIGINT NOT NULL REFERENCES alerts (id) ON DELETE CASCADE,
+    author TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_alert_comments_alert_id ON alert_comments (alert_id, created_at);
+
+-- Agent inventory, maintained by the ingestion pipeline from received events
+-- heartbeats and registrations (raw_data.agent). Status (online, stale, offline) is derived
+-- by the API gateway from last_heartbeat and heartbeat_interval_seconds.
+CREATE TABLE agents (
+    agent_id TEXT PRIMARY KEY,
+    hostname TEXT NOT NULL,
+    ip_address TEXT,
+    os_type TEXT,
+    os_version TEXT,
+    kernel_version TEXT,
+    agent_version TEXT,
+    collectors TEXT[],
+    heartbeat_interval_seconds DOUBLE PRECISION,
+    event_rate DOUBLE PRECISION,      -- events collected per minute, reported by the agent
+    events_total BIGINT NOT NULL DEFAULT 0,
+    spool_depth INTEGER,
+    registered_at TIMESTAMPTZ,        -- latest registration event (first start or re-enrollment)
+    previous_agent_id TEXT,           -- ID replaced by the latest re-enrollment
+    first_seen TIMESTAMPTZ NOT NULL,
+    last_seen TIMESTAMPTZ NOT NULL,   -- latest event of any kind
+    last_heartbeat TIMESTAMPTZ,
+    last_heartbeat_event_id BIGINT,   -- referenced by the "agent silent" alert
+    silent_alert_at TIMESTAMPTZ       -- set when the alert is raised, cleared on the next heartbeat
+);
+
+CREATE INDEX idx_agents_hostname ON agents (hostname);
+CREATE INDEX idx_agents_last_heartbeat ON agents (last_heartbeat);
+
+-- Audit trail of API calls (written by the API gateway)
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    subject TEXT,
+    role TEXT,
+    auth_method TEXT,
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    query TEXT,
+    status INT NOT NULL,
+    remote_ip TEXT NOT NULL,
+    user_agent TEXT,
+    duration_ms BIGINT NOT NULL
+);
+
+CREATE INDEX idx_audit_log_occurred_at ON audit_log (occurred_at DESC);
+CREATE INDEX idx_audit_log_subject ON audit_log (subject, occurred_at DESC);
+
 -- Sample data generation (for testing)
 DO $$
 DECLARE
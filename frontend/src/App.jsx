@@ -1,6 +1,6 @@
 import { useState, useEffect } from 'react'
 import axios from 'axios'
-import { Activity, Database, Shield, AlertTriangle, CheckCircle, XCircle, Download } from 'lucide-react'
+import { Activity, Database, Shield, AlertTriangle, CheckCircle, XCircle, Download, LogOut } from 'lucide-react'
 import './App.css'
 import './App-enhanced.css'
 import EventsTable from './components/EventsTable'
@@ -8,10 +8,42 @@ import StatsCards from './components/StatsCards'
 import EventsChart from './components/EventsChart'
 import TimelineChart from './components/TimelineChart'
 import Filters from './components/Filters'
+import Login from './components/Login'
 
 const API_BASE_URL = ''
 
+// Session de l'utilisateur connecté, conservée le temps de l'onglet
+const SESSION_KEY = 'xdr.session'
+
+const loadSession = () => {
+  try {
+    const session = JSON.parse(sessionStorage.getItem(SESSION_KEY))
+    if (session && new Date(session.expires_at) > new Date()) {
+      return session
+    }
+  } catch {
+    // session illisible : reconnexion
+  }
+  sessionStorage.removeItem(SESSION_KEY)
+  return null
+}
+
+// Jeton présenté à chaque requête de l'API
+const applySession = (session) => {
+  if (session) {
+    axios.defaults.headers.common['Authorization'] = `Bearer ${session.access_token}`
+  } else {
+    delete axios.defaults.headers.common['Authorization']
+  }
+}
+
+applySession(loadSession())
+
 function App() {
+  const [session, setSession] = useState(loadSession)
+  // Vrai dès que l'API a refusé une requête faute d'authentification
+  const [authRequired, setAuthRequired] = useState(false)
+  const [authMessage, setAuthMessage] = useState(null)
   const [events, setEvents] = useState([])
   const [filteredEvents, setFilteredEvents] = useState([])
   const [stats, setStats] = useState(null)
@@ -61,21 +93,76 @@ function App() {
     setLoading(false)
   }
 
-  // Charger les données au démarrage
+  // Connexion et déconnexion
+  const handleLogin = (newSession) => {
+    sessionStorage.setItem(SESSION_KEY, JSON.stringify(newSession))
+    applySession(newSession)
+    setSession(newSession)
+    setAuthRequired(false)
+    setAuthMessage(null)
+  }
+
+  const handleLogout = () => {
+    sessionStorage.removeItem(SESSION_KEY)
+    applySession(null)
+    setSession(null)
+    setAuthRequired(true)
+    setAuthMessage(null)
+  }
+
+  // Un refus 401 (jeton expiré ou absent) renvoie vers la connexion
   useEffect(() => {
-    fetchData()
+    const interceptor = axios.interceptors.response.use(
+      response => response,
+      err => {
+        if (err.response?.status === 401 && !err.config?.url?.endsWith('/auth/login')) {
+          if (sessionStorage.getItem(SESSION_KEY)) {
+            setAuthMessage('Your session has expired, please sign in again')
+          }
+          sessionStorage.removeItem(SESSION_KEY)
+          applySession(null)
+          setSession(null)
+          setAuthRequired(true)
+        }
+        return Promise.reject(err)
+      }
+    )
+    return () => axios.interceptors.response.eject(interceptor)
   }, [])
 
-  // Auto-refresh toutes les 10 secondes
+  // Charger les données au démarrage et après chaque connexion
   useEffect(() => {
-    if (!autoRefresh) return
+    if (authRequired) return
+    fetchData()
+  }, [session, authRequired])
+
+  // Nouveaux événements poussés par le serveur (Server-Sent Events).
+  // EventSource ne permet pas d'en-têtes : le jeton passe en paramètre.
+  useEffect(() => {
+    if (!autoRefresh || authRequired) return
+
+    const params = session ? `?access_token=${encodeURIComponent(session.access_token)}` : ''
+    const source = new EventSource(`${API_BASE_URL}/api/v1/events/stream${params}`)
+
+    source.addEventListener('event', (message) => {
+      const event = JSON.parse(message.data)
+      setEvents(prev => [event, ...prev].slice(0, 200))
+    })
+
+    return () => source.close()
+  }, [autoRefresh, session, authRequired])
+
+  // Auto-refresh des statistiques toutes les 10 secondes
+  useEffect(() => {
+    if (!autoRefresh || authRequired) return
 
     const interval = setInterval(() => {
-      fetchData()
+      fetchStats()
+      fetchTimeline()
     }, 10000)
 
     return () => clearInterval(interval)
-  }, [autoRefresh])
+  }, [autoRefresh, authRequired])
 
   // Appliquer les filtres
   useEffect(() => {
@@ -196,6 +283,10 @@ function App() {
   const severityCounts = getEventsBySeverity()
   const typeCounts = getEventsByType()
 
+  if (authRequired) {
+    return <Login apiBaseUrl={API_BASE_URL} onLogin={handleLogin} message={authMessage} />
+  }
+
   return (
     <div className="app">
       {/* Header */}
@@ -223,6 +314,12 @@ function App() {
               <Download size={16} />
               Export CSV
             </button>
+            {session && (
+              <button className="refresh-btn" onClick={handleLogout} title={`${session.subject} (${session.role})`}>
+                <LogOut size={16} />
+                Sign out
+              </button>
+            )}
           </div>
         </div>
       </header>
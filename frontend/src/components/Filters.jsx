@@ -100,6 +100,9 @@ function Filters({ onFilterChange, onReset }) {
                 <option value="network">Network</option>
                 <option value="process">Process</option>
                 <option value="file">File</option>
+                <option value="auth">Auth</option>
+                <option value="journal">Journal</option>
+                <option value="persistence">Persistence</option>
               </select>
             </div>
 
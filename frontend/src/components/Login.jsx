@@ -0,0 +1,71 @@
+import { useState } from 'react'
+import axios from 'axios'
+import { Shield, LogIn } from 'lucide-react'
+
+// Formulaire de connexion : échange les identifiants contre un jeton JWT
+function Login({ apiBaseUrl, onLogin, message }) {
+  const [username, setUsername] = useState('')
+  const [password, setPassword] = useState('')
+  const [submitting, setSubmitting] = useState(false)
+  const [error, setError] = useState(null)
+
+  const handleSubmit = async (e) => {
+    e.preventDefault()
+    setSubmitting(true)
+    setError(null)
+    try {
+      const response = await axios.post(`${apiBaseUrl}/api/v1/auth/login`, { username, password })
+      setPassword('')
+      onLogin(response.data)
+    } catch (err) {
+      if (err.response?.status === 401) {
+        setError('Invalid username or password')
+      } else if (err.response?.status === 429) {
+        setError('Too many attempts, try again in a minute')
+      } else {
+        setError('Login failed')
+      }
+    } finally {
+      setSubmitting(false)
+    }
+  }
+
+  return (
+    <div className="login-container">
+      <form className="login-form" onSubmit={handleSubmit}>
+        <div className="login-title">
+          <Shield className="logo-icon" size={32} />
+          <h1>XDR Platform</h1>
+        </div>
+        {message && !error && <p className="login-message">{message}</p>}
+        {error && <p className="login-error">{error}</p>}
+        <label>
+          Username
+          <input
+            type="text"
+            autoComplete="username"
+            value={username}
+            onChange={(e) => setUsername(e.target.value)}
+            required
+          />
+        </label>
+        <label>
+          Password
+          <input
+            type="password"
+            autoComplete="current-password"
+            value={password}
+            onChange={(e) => setPassword(e.target.value)}
+            required
+          />
+        </label>
+        <button type="submit" disabled={submitting}>
+          <LogIn size={16} />
+          {submitting ? 'Signing in...' : 'Sign in'}
+        </button>
+      </form>
+    </div>
+  )
+}
+
+export default Login
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config contient la configuration du service d'ingestion
+type Config struct {
+	// Database configuration
+	DatabaseURL      string
+	DatabaseHost     string
+	DatabasePort     string
+	DatabaseName     string
+	DatabaseUser     string
+	DatabasePassword string
+	InsertMode       string // copy ou rows
+	InsertChunkSize  int
+
+	// Kafka configuration
+	KafkaBrokers        []string
+	KafkaTopicRawEvents string
+	KafkaGroupID        string
+	KafkaTopicDLQ       string
+	KafkaCompression    string // codec du topic dead-letter : none, gzip, snappy, lz4, zstd
+	KafkaRequiredAcks   string // none, leader, all
+
+	// Kafka TLS et SASL
+	KafkaTLS                   bool
+	KafkaTLSCAFile             string
+	KafkaTLSCertFile           string
+	KafkaTLSKeyFile            string
+	KafkaTLSInsecureSkipVerify bool
+	KafkaSASLMechanism         string // plain, scram-sha-256, scram-sha-512
+	KafkaSASLUsername          string
+	KafkaSASLPassword          string
+
+	// Service configuration
+	ServiceName   string
+	BatchSize     int
+	FlushInterval time.Duration
+	WorkerCount   int
+
+	// Detection configuration
+	EnableDetection   bool
+	DetectionRulesDir string
+
+	// Agent monitoring : un agent est hors ligne après AgentOfflineHeartbeats
+	// intervalles de heartbeat sans nouvelles ; AgentHeartbeatInterval
+	// s'applique aux agents qui ne publient pas le leur
+	AgentHeartbeatInterval time.Duration
+	AgentOfflineHeartbeats int
+	AgentWatchInterval     time.Duration
+
+	// Logging
+	LogLevel string
+}
+
+// LoadConfig charge la configuration depuis les variables d'environnement
+func LoadConfig() (*Config, error) {
+	// Flush interval
+	flushInterval, err := time.ParseDuration(getEnvOrDefault("INGESTION_FLUSH_INTERVAL", "5s"))
+	if err != nil {
+		flushInterval = 5 * time.Second
+	}
+
+	// Agent monitoring
+	agentHeartbeatInterval, err := time.ParseDuration(getEnvOrDefault("AGENT_HEARTBEAT_INTERVAL", "60s"))
+	if err != nil {
+		agentHeartbeatInterval = 60 * time.Second
+	}
+
+	agentWatchInterval, err := time.ParseDuration(getEnvOrDefault("AGENT_WATCH_INTERVAL", "1m"))
+	if err != nil {
+		agentWatchInterval = time.Minute
+	}
+
+	config := &Config{
+		// Database
+		DatabaseHost:     getEnvOrDefault("DATABASE_HOST", "localhost"),
+		DatabasePort:     getEnvOrDefault("DATABASE_PORT", "5432"),
+		DatabaseName:     getEnvOrDefault("DATABASE_NAME", "xdr_events"),
+		DatabaseUser:     getEnvOrDefault("DATABASE_USER", "xdr_admin"),
+		DatabasePassword: getEnvOrDefault("DATABASE_PASSWORD", "xdr_secure_password_2024"),
+		InsertMode:       getEnvOrDefault("DATABASE_INSERT_MODE", "copy"),
+		InsertChunkSize:  getEnvIntOrDefault("DATABASE_INSERT_CHUNK_SIZE", 1000),
+
+		// Kafka
+		KafkaBrokers:        []string{getEnvOrDefault("KAFKA_BROKERS", "localhost:9092")},
+		KafkaTopicRawEvents: getEnvOrDefault("KAFKA_TOPIC_RAW_EVENTS", "raw-events"),
+		KafkaGroupID:        getEnvOrDefault("KAFKA_GROUP_ID", "xdr-ingestion-service"),
+		KafkaTopicDLQ:       getEnvOrDefault("KAFKA_TOPIC_DLQ", "raw-events-dlq"),
+		KafkaCompression:    getEnvOrDefault("KAFKA_COMPRESSION", "none"),
+		KafkaRequiredAcks:   getEnvOrDefault("KAFKA_REQUIRED_ACKS", "all"),
+
+		// Kafka TLS et SASL
+		KafkaTLS:                   getEnvOrDefault("KAFKA_TLS_ENABLED", "false") == "true",
+		KafkaTLSCAFile:             os.Getenv("KAFKA_TLS_CA_FILE"),
+		KafkaTLSCertFile:           os.Getenv("KAFKA_TLS_CERT_FILE"),
+		KafkaTLSKeyFile:            os.Getenv("KAFKA_TLS_KEY_FILE"),
+		KafkaTLSInsecureSkipVerify: getEnvOrDefault("KAFKA_TLS_INSECURE_SKIP_VERIFY", "false") == "true",
+		KafkaSASLMechanism:         strings.ToLower(os.Getenv("KAFKA_SASL_MECHANISM")),
+		KafkaSASLUsername:          os.Getenv("KAFKA_SASL_USERNAME"),
+		KafkaSASLPassword:          os.Getenv("KAFKA_SASL_PASSWORD"),
+
+		// Service
+		ServiceName:   "ingestion-service",
+		BatchSize:     getEnvIntOrDefault("INGESTION_BATCH_SIZE", 100),
+		FlushInterval: flushInterval,
+		WorkerCount:   getEnvIntOrDefault("INGESTION_WORKER_COUNT", 4),
+
+		// Detection
+		EnableDetection:   getEnvOrDefault("ENABLE_DETECTION", "true") == "true",
+		DetectionRulesDir: getEnvOrDefault("DETECTION_RULES_DIR", "rules"),
+
+		// Agent monitoring
+		AgentHeartbeatInterval: agentHeartbeatInterval,
+		AgentOfflineHeartbeats: getEnvIntOrDefault("AGENT_OFFLINE_HEARTBEATS", 5),
+		AgentWatchInterval:     agentWatchInterval,
+
+		// Logging
+		LogLevel: getEnvOrDefault("LOG_LEVEL", "info"),
+	}
+
+	// Construire l'URL de connexion PostgreSQL
+	config.DatabaseURL = fmt.Sprintf(
+		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		config.DatabaseUser,
+		config.DatabasePassword,
+		config.DatabaseHost,
+		config.DatabasePort,
+		config.DatabaseName,
+	)
+
+	return config, nil
+}
+
+// getEnvOrDefault retourne la valeur d'une variable d'environnement ou une valeur par défaut
+func getEnvOrDefault(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvIntOrDefault retourne la valeur entière d'une variable d'environnement ou une valeur par défaut
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// Validate valide la configuration
+func (c *Config) Validate() error {
+	if c.DatabaseURL == "" {
+		return fmt.Errorf("database_url cannot be empty")
+	}
+	if c.InsertMode != "copy" && c.InsertMode != "rows" {
+		return fmt.Errorf("insert_mode must be \"copy\" or \"rows\", got %q", c.InsertMode)
+	}
+	if c.InsertChunkSize <= 0 {
+		return fmt.Errorf("insert_chunk_size must be positive")
+	}
+	if len(c.KafkaBrokers) == 0 {
+		return fmt.Errorf("kafka_brokers cannot be empty")
+	}
+	if c.KafkaTopicRawEvents == "" {
+		return fmt.Errorf("kafka_topic_raw_events cannot be empty")
+	}
+	if c.KafkaTopicDLQ == "" {
+		return fmt.Errorf("kafka_topic_dlq cannot be empty")
+	}
+	if c.KafkaGroupID == "" {
+		return fmt.Errorf("kafka_group_id cannot be empty")
+	}
+	if err := c.validateKafkaSecurity(); err != nil {
+		return err
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("batch_size must be positive")
+	}
+	if c.FlushInterval <= 0 {
+		return fmt.Errorf("flush_interval must be positive")
+	}
+	if c.WorkerCount <= 0 {
+		return fmt.Errorf("worker_count must be positive")
+	}
+	if c.EnableDetection && c.DetectionRulesDir == "" {
+		return fmt.Errorf("detection_rules_dir cannot be empty when detection is enabled")
+	}
+	if c.AgentHeartbeatInterval <= 0 {
+		return fmt.Errorf("agent_heartbeat_interval must be positive")
+	}
+	if c.AgentOfflineHeartbeats <= 0 {
+		return fmt.Errorf("agent_offline_heartbeats must be positive")
+	}
+	if c.AgentWatchInterval <= 0 {
+		return fmt.Errorf("agent_watch_interval must be positive")
+	}
+	return nil
+}
+
+// validateKafkaSecurity valide la compression, les acks, TLS et SASL
+func (c *Config) validateKafkaSecurity() error {
+	switch c.KafkaCompression {
+	case "none", "gzip", "snappy", "lz4", "zstd":
+	default:
+		return fmt.Errorf("kafka_compression must be none, gzip, snappy, lz4 or zstd, got %q", c.KafkaCompression)
+	}
+	switch c.KafkaRequiredAcks {
+	case "none", "leader", "all":
+	default:
+		return fmt.Errorf("kafka_required_acks must be none, leader or all, got %q", c.KafkaRequiredAcks)
+	}
+	if (c.KafkaTLSCertFile == "") != (c.KafkaTLSKeyFile == "") {
+		return fmt.Errorf("kafka_tls_cert_file and kafka_tls_key_file must be set together")
+	}
+	if !c.KafkaTLS && (c.KafkaTLSCAFile != "" || c.KafkaTLSCertFile != "") {
+		return fmt.Errorf("kafka TLS files are set but kafka_tls_enabled is false")
+	}
+	switch c.KafkaSASLMechanism {
+	case "":
+	case "plain", "scram-sha-256", "scram-sha-512":
+		if c.KafkaSASLUsername == "" || c.KafkaSASLPassword == "" {
+			return fmt.Errorf("kafka_sasl_username and kafka_sasl_password are required by SASL %s", c.KafkaSASLMechanism)
+		}
+		if c.KafkaSASLMechanism == "plain" && !c.KafkaTLS {
+			return fmt.Errorf("SASL plain sends the password in clear text, enable kafka_tls_enabled")
+		}
+	default:
+		return fmt.Errorf("kafka_sasl_mechanism must be plain, scram-sha-256 or scram-sha-512, got %q", c.KafkaSASLMechanism)
+	}
+	return nil
+}
+
+// String retourne une représentation string de la config
+func (c *Config) String() string {
+	return fmt.Sprintf(
+		"Service{Name: %s, DB: %s:%s/%s, Kafka: %v, Topic: %s, Group: %s, Batch: %d, Flush: %s, Workers: %d, Detection: %t}",
+		c.ServiceName,
+		c.DatabaseHost,
+		c.DatabasePort,
+		c.DatabaseName,
+		c.KafkaBrokers,
+		c.KafkaTopicRawEvents,
+		c.KafkaGroupID,
+		c.BatchSize,
+		c.FlushInterval,
+		c.WorkerCount,
+		c.EnableDetection,
+	)
+}
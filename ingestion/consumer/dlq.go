@@ -0,0 +1,151 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/luigi/xdr-platform/ingestion/models"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// headerStored marque les rejets publiés par ce service : ils sont déjà
+	// enregistrés dans rejected_events et le worker dead-letter les ignore
+	headerStored = "xdr-rejected-stored"
+
+	// deadLetterGroupSuffix distingue le consumer group du topic dead-letter
+	// de celui du topic des événements bruts
+	deadLetterGroupSuffix = "-dlq"
+)
+
+// DeadLetterWriter publie les événements rejetés sur le topic dead-letter
+type DeadLetterWriter struct {
+	writer *kafka.Writer
+	logger *log.Logger
+	topic  string
+}
+
+// NewDeadLetterWriter crée un nouveau writer dead-letter
+func NewDeadLetterWriter(brokers []string, topic string, transport *kafka.Transport, compression kafka.Compression, acks kafka.RequiredAcks, logger *log.Logger) *DeadLetterWriter {
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.LeastBytes{},
+		Transport:    transport,
+		Compression:  compression,
+		RequiredAcks: acks,
+	}
+
+	return &DeadLetterWriter{
+		writer: writer,
+		logger: logger,
+		topic:  topic,
+	}
+}
+
+// Publish envoie les événements rejetés vers le topic dead-letter
+func (dw *DeadLetterWriter) Publish(ctx context.Context, rejected []*models.RejectedEvent) error {
+	if len(rejected) == 0 {
+		return nil
+	}
+
+	messages := make([]kafka.Message, 0, len(rejected))
+	for _, r := range rejected {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to marshal rejected event: %w", err)
+		}
+
+		messages = append(messages, kafka.Message{
+			Key:     []byte(r.AgentID),
+			Value:   data,
+			Headers: []kafka.Header{{Key: headerStored, Value: []byte("true")}},
+		})
+	}
+
+	if err := dw.writer.WriteMessages(ctx, messages...); err != nil {
+		return fmt.Errorf("failed to write messages to dead-letter topic: %w", err)
+	}
+
+	dw.logger.Printf("Routed %d rejected events to dead-letter topic '%s'", len(messages), dw.topic)
+	return nil
+}
+
+// Close ferme le writer dead-letter
+func (dw *DeadLetterWriter) Close() error {
+	return dw.writer.Close()
+}
+
+// runDeadLetterWorker enregistre dans rejected_events les rejets que les
+// agents publient eux-mêmes sur le topic dead-letter (événements impossibles
+// à sérialiser, étape marshal). Les rejets de ce service, déjà en base, sont
+// ignorés. L'offset n'est committé qu'après l'enregistrement.
+func (kc *KafkaConsumer) runDeadLetterWorker(ctx context.Context) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        kc.cfg.KafkaBrokers,
+		GroupID:        kc.cfg.KafkaGroupID + deadLetterGroupSuffix,
+		Topic:          kc.cfg.KafkaTopicDLQ,
+		Dialer:         kc.security.dialer(),
+		MinBytes:       1,
+		MaxBytes:       10e6,
+		CommitInterval: 0,
+	})
+	defer reader.Close()
+
+	kc.logger.Printf("Dead-letter worker started on topic '%s'", kc.cfg.KafkaTopicDLQ)
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				kc.logger.Println("Dead-letter worker stopped")
+				return
+			}
+			kc.logger.Printf("Failed to fetch dead-letter message: %v", err)
+			if !sleepContext(ctx, retryBackoff) {
+				return
+			}
+			continue
+		}
+
+		if rejected := decodeDeadLetter(msg); rejected != nil {
+			if !kc.retry(ctx, "store agent rejected event", func() error {
+				return kc.db.InsertRejectedEvents(ctx, []*models.RejectedEvent{rejected})
+			}) {
+				return
+			}
+			kc.logger.Printf("Stored rejected event from agent %q (stage %s): %s", rejected.AgentID, rejected.Stage, rejected.Reason)
+		}
+
+		if err := reader.CommitMessages(ctx, msg); err != nil {
+			// Le rejet sera relu et enregistré une seconde fois au pire
+			kc.logger.Printf("Failed to commit dead-letter offset: %v", err)
+		}
+	}
+}
+
+// decodeDeadLetter retourne l'entrée à enregistrer pour un message du topic
+// dead-letter, ou nil s'il a été publié par ce service. Un message illisible
+// est enregistré tel quel à l'étape decode plutôt que perdu.
+func decodeDeadLetter(msg kafka.Message) *models.RejectedEvent {
+	for _, header := range msg.Headers {
+		if header.Key == headerStored {
+			return nil
+		}
+	}
+
+	var rejected models.RejectedEvent
+	if err := json.Unmarshal(msg.Value, &rejected); err != nil {
+		return newRejectedEvent(msg, models.RejectStageDecode, fmt.Errorf("invalid dead-letter message: %w", err), "")
+	}
+	if rejected.Stage == "" {
+		rejected.Stage = models.RejectStageMarshal
+	}
+	if rejected.RejectedAt.IsZero() {
+		rejected.RejectedAt = time.Now()
+	}
+	return &rejected
+}
@@ -0,0 +1,340 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/luigi/xdr-platform/ingestion/config"
+	"github.com/luigi/xdr-platform/ingestion/database"
+	"github.com/luigi/xdr-platform/ingestion/detection"
+	"github.com/luigi/xdr-platform/ingestion/models"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// retryBackoff est le délai entre deux tentatives d'insertion ou de lecture
+	retryBackoff = 2 * time.Second
+
+	// shutdownFlushTimeout borne le dernier flush lors de l'arrêt du service
+	shutdownFlushTimeout = 10 * time.Second
+)
+
+// KafkaConsumer consomme le topic des événements bruts et les persiste dans TimescaleDB
+type KafkaConsumer struct {
+	cfg      *config.Config
+	security *kafkaSecurity
+	db       *database.TimescaleDB
+	dlq      *DeadLetterWriter
+	detector *detection.Engine // nil si la détection est désactivée
+	logger   *log.Logger
+}
+
+// NewKafkaConsumer crée un nouveau consumer Kafka.
+// detector peut être nil : aucune règle n'est alors évaluée.
+func NewKafkaConsumer(cfg *config.Config, db *database.TimescaleDB, detector *detection.Engine, logger *log.Logger) (*KafkaConsumer, error) {
+	if len(cfg.KafkaBrokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers list is empty")
+	}
+
+	security, err := newKafkaSecurity(cfg)
+	if err != nil {
+		return nil, err
+	}
+	compression, err := kafkaCompression(cfg.KafkaCompression)
+	if err != nil {
+		return nil, err
+	}
+	acks, err := kafkaRequiredAcks(cfg.KafkaRequiredAcks)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Printf("Kafka consumer initialized with brokers: %v, topic: %s, group: %s, tls: %t, sasl: %s",
+		cfg.KafkaBrokers, cfg.KafkaTopicRawEvents, cfg.KafkaGroupID, cfg.KafkaTLS, cfg.KafkaSASLMechanism)
+
+	return &KafkaConsumer{
+		cfg:      cfg,
+		security: security,
+		db:       db,
+		dlq:      NewDeadLetterWriter(cfg.KafkaBrokers, cfg.KafkaTopicDLQ, security.transport(), compression, acks, logger),
+		detector: detector,
+		logger:   logger,
+	}, nil
+}
+
+// Close ferme le writer dead-letter
+func (kc *KafkaConsumer) Close() error {
+	return kc.dlq.Close()
+}
+
+// Run démarre les workers et le worker dead-letter, et bloque jusqu'à
+// l'annulation du contexte. Chaque worker possède son propre reader dans le
+// consumer group, Kafka répartit donc les partitions entre eux.
+func (kc *KafkaConsumer) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		kc.runDeadLetterWorker(ctx)
+	}()
+
+	for i := 0; i < kc.cfg.WorkerCount; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			kc.runWorker(ctx, id)
+		}(i)
+	}
+
+	wg.Wait()
+	kc.logger.Println("All ingestion workers stopped")
+}
+
+// runWorker lit les messages, les regroupe par taille ou par durée et les persiste
+func (kc *KafkaConsumer) runWorker(ctx context.Context, id int) {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:  kc.cfg.KafkaBrokers,
+		GroupID:  kc.cfg.KafkaGroupID,
+		Topic:    kc.cfg.KafkaTopicRawEvents,
+		Dialer:   kc.security.dialer(),
+		MinBytes: 1,
+		MaxBytes: 10e6,
+		// CommitInterval à 0 : les commits sont synchrones et explicites
+		CommitInterval: 0,
+	})
+	defer reader.Close()
+
+	kc.logger.Printf("Worker %d started", id)
+
+	messages := make(chan kafka.Message, kc.cfg.BatchSize)
+	go kc.fetchMessages(ctx, reader, messages)
+
+	ticker := time.NewTicker(kc.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]kafka.Message, 0, kc.cfg.BatchSize)
+
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				// Arrêt : dernier flush avec un contexte indépendant
+				flushCtx, cancel := context.WithTimeout(context.Background(), shutdownFlushTimeout)
+				kc.flush(flushCtx, reader, batch)
+				cancel()
+				kc.logger.Printf("Worker %d stopped", id)
+				return
+			}
+
+			batch = append(batch, msg)
+			if len(batch) >= kc.cfg.BatchSize {
+				batch = kc.flush(ctx, reader, batch)
+			}
+
+		case <-ticker.C:
+			batch = kc.flush(ctx, reader, batch)
+		}
+	}
+}
+
+// fetchMessages lit les messages sans les committer et les transmet au worker
+func (kc *KafkaConsumer) fetchMessages(ctx context.Context, reader *kafka.Reader, out chan<- kafka.Message) {
+	defer close(out)
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			kc.logger.Printf("Failed to fetch message: %v", err)
+			if !sleepContext(ctx, retryBackoff) {
+				return
+			}
+			continue
+		}
+
+		select {
+		case out <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// flush décode et valide le batch, évalue les règles de détection, insère
+// les événements et leurs alertes en base puis committe les offsets.
+// Les messages invalides ou impossibles à insérer partent en dead-letter
+// (topic et table rejected_events) au lieu de bloquer le batch.
+// Les offsets ne sont committés qu'après un commit réussi de la transaction :
+// en cas de crash, les messages non committés seront relus.
+// Retourne le batch vidé, ou le batch intact si la persistance n'a pas abouti.
+func (kc *KafkaConsumer) flush(ctx context.Context, reader *kafka.Reader, batch []kafka.Message) []kafka.Message {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	events := make([]*models.Event, 0, len(batch))
+	sources := make([]kafka.Message, 0, len(batch))
+	var rejected []*models.RejectedEvent
+
+	for _, msg := range batch {
+		var event models.Event
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			rejected = append(rejected, newRejectedEvent(msg, models.RejectStageDecode, err, ""))
+			continue
+		}
+		if err := event.Validate(); err != nil {
+			rejected = append(rejected, newRejectedEvent(msg, models.RejectStageValidate, err, event.AgentID))
+			continue
+		}
+		events = append(events, &event)
+		sources = append(sources, msg)
+	}
+
+	alerts := kc.detect(events)
+
+	insertRejected, ok := kc.insertEvents(ctx, events, alerts, sources)
+	if !ok {
+		return batch
+	}
+	rejected = append(rejected, insertRejected...)
+
+	if len(rejected) > 0 {
+		if !kc.retry(ctx, "publish rejected events", func() error { return kc.dlq.Publish(ctx, rejected) }) {
+			return batch
+		}
+		if !kc.retry(ctx, "store rejected events", func() error { return kc.db.InsertRejectedEvents(ctx, rejected) }) {
+			return batch
+		}
+	}
+
+	if err := reader.CommitMessages(ctx, batch...); err != nil {
+		// Les événements sont en base : ils seront relus et réinsérés au pire
+		kc.logger.Printf("Failed to commit offsets: %v", err)
+	}
+
+	kc.logger.Printf("Ingested %d events, rejected %d, raised %d alerts (%d messages)",
+		len(events)-len(insertRejected), len(rejected), len(alerts), len(batch))
+	return batch[:0]
+}
+
+// detect évalue les règles de détection sur les événements du batch
+func (kc *KafkaConsumer) detect(events []*models.Event) []*models.Alert {
+	if kc.detector == nil {
+		return nil
+	}
+
+	var alerts []*models.Alert
+	for _, event := range events {
+		alerts = append(alerts, kc.detector.Evaluate(event)...)
+	}
+	return alerts
+}
+
+// insertEvents insère le batch et ses alertes en une transaction. Si la base
+// refuse les données elles-mêmes, les événements sont réinsérés un par un
+// (avec leurs alertes) pour isoler les lignes fautives, qui sont retournées
+// comme rejetées.
+// Retourne false si le contexte est annulé avant la fin de l'insertion.
+func (kc *KafkaConsumer) insertEvents(ctx context.Context, events []*models.Event, alerts []*models.Alert, sources []kafka.Message) ([]*models.RejectedEvent, bool) {
+	var batchErr error
+	ok := kc.retry(ctx, "insert batch", func() error {
+		batchErr = kc.db.InsertEventsWithAlerts(ctx, events, alerts)
+		if database.IsDataError(batchErr) {
+			// Inutile de réessayer, on passe à l'isolation ligne par ligne
+			return nil
+		}
+		return batchErr
+	})
+	if !ok {
+		return nil, false
+	}
+	if batchErr == nil {
+		return nil, true
+	}
+
+	kc.logger.Printf("Batch rejected by database, isolating faulty events: %v", batchErr)
+
+	var rejected []*models.RejectedEvent
+	for i, event := range events {
+		var err error
+		eventAlerts := alertsFor(alerts, event)
+		ok := kc.retry(ctx, "insert event", func() error {
+			err = kc.db.InsertEventsWithAlerts(ctx, []*models.Event{event}, eventAlerts)
+			if database.IsDataError(err) {
+				return nil
+			}
+			return err
+		})
+		if !ok {
+			return nil, false
+		}
+		if err != nil {
+			rejected = append(rejected, newRejectedEvent(sources[i], models.RejectStageInsert, err, event.AgentID))
+		}
+	}
+
+	return rejected, true
+}
+
+// alertsFor retourne les alertes déclenchées par un événement
+func alertsFor(alerts []*models.Alert, event *models.Event) []*models.Alert {
+	var matched []*models.Alert
+	for _, alert := range alerts {
+		if alert.Event == event {
+			matched = append(matched, alert)
+		}
+	}
+	return matched
+}
+
+// retry exécute fn jusqu'à ce qu'elle réussisse ou que le contexte soit annulé.
+// Une erreur de données ne disparaîtra pas en réessayant : elle est
+// journalisée et l'opération abandonnée, sans quoi la partition resterait
+// bloquée et les offsets du batch ne seraient jamais committés.
+func (kc *KafkaConsumer) retry(ctx context.Context, what string, fn func() error) bool {
+	for {
+		err := fn()
+		if err == nil {
+			return true
+		}
+		if database.IsDataError(err) {
+			kc.logger.Printf("Dropping data refused by database, failed to %s: %v", what, err)
+			return true
+		}
+		kc.logger.Printf("Failed to %s: %v", what, err)
+		if !sleepContext(ctx, retryBackoff) {
+			return false
+		}
+	}
+}
+
+// newRejectedEvent construit l'entrée dead-letter d'un message Kafka
+func newRejectedEvent(msg kafka.Message, stage string, reason error, agentID string) *models.RejectedEvent {
+	return &models.RejectedEvent{
+		RejectedAt:      time.Now(),
+		Stage:           stage,
+		Reason:          reason.Error(),
+		Payload:         string(msg.Value),
+		AgentID:         agentID,
+		SourceTopic:     msg.Topic,
+		SourcePartition: msg.Partition,
+		SourceOffset:    msg.Offset,
+	}
+}
+
+// sleepContext attend la durée donnée, retourne false si le contexte est annulé
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
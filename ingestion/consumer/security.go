@@ -0,0 +1,118 @@
+package consumer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/luigi/xdr-platform/ingestion/config"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// kafkaSecurity regroupe la configuration TLS et SASL commune au reader et
+// au writer dead-letter
+type kafkaSecurity struct {
+	tls       *tls.Config    // nil si TLS est désactivé
+	mechanism sasl.Mechanism // nil si SASL est désactivé
+}
+
+// newKafkaSecurity charge les certificats et prépare le mécanisme SASL
+func newKafkaSecurity(cfg *config.Config) (*kafkaSecurity, error) {
+	security := &kafkaSecurity{}
+
+	if cfg.KafkaTLS {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: cfg.KafkaTLSInsecureSkipVerify,
+		}
+		if cfg.KafkaTLSCAFile != "" {
+			pem, err := os.ReadFile(cfg.KafkaTLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read kafka CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificate found in %s", cfg.KafkaTLSCAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if cfg.KafkaTLSCertFile != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.KafkaTLSCertFile, cfg.KafkaTLSKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load kafka client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		security.tls = tlsConfig
+	}
+
+	var err error
+	switch cfg.KafkaSASLMechanism {
+	case "":
+	case "plain":
+		security.mechanism = plain.Mechanism{Username: cfg.KafkaSASLUsername, Password: cfg.KafkaSASLPassword}
+	case "scram-sha-256":
+		security.mechanism, err = scram.Mechanism(scram.SHA256, cfg.KafkaSASLUsername, cfg.KafkaSASLPassword)
+	case "scram-sha-512":
+		security.mechanism, err = scram.Mechanism(scram.SHA512, cfg.KafkaSASLUsername, cfg.KafkaSASLPassword)
+	default:
+		err = fmt.Errorf("unknown kafka SASL mechanism %q", cfg.KafkaSASLMechanism)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return security, nil
+}
+
+// dialer retourne le dialer des readers
+func (s *kafkaSecurity) dialer() *kafka.Dialer {
+	return &kafka.Dialer{
+		Timeout:       10 * time.Second,
+		DualStack:     true,
+		TLS:           s.tls,
+		SASLMechanism: s.mechanism,
+	}
+}
+
+// transport retourne le transport des writers
+func (s *kafkaSecurity) transport() *kafka.Transport {
+	return &kafka.Transport{TLS: s.tls, SASL: s.mechanism}
+}
+
+// kafkaCompression convertit le nom d'un codec de compression
+func kafkaCompression(name string) (kafka.Compression, error) {
+	switch name {
+	case "", "none":
+		return 0, nil
+	case "gzip":
+		return kafka.Gzip, nil
+	case "snappy":
+		return kafka.Snappy, nil
+	case "lz4":
+		return kafka.Lz4, nil
+	case "zstd":
+		return kafka.Zstd, nil
+	default:
+		return 0, fmt.Errorf("unknown kafka compression codec %q", name)
+	}
+}
+
+// kafkaRequiredAcks convertit le niveau d'accusé de réception attendu
+func kafkaRequiredAcks(name string) (kafka.RequiredAcks, error) {
+	switch name {
+	case "none":
+		return kafka.RequireNone, nil
+	case "leader":
+		return kafka.RequireOne, nil
+	case "", "all":
+		return kafka.RequireAll, nil
+	default:
+		return 0, fmt.Errorf("unknown kafka required acks %q (expected none, leader or all)", name)
+	}
+}
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luigi/xdr-platform/ingestion/models"
+)
+
+// Alerte levée lorsqu'un agent cesse d'envoyer des heartbeats. Un agent
+// arrêté ou désinstallé est un moyen classique d'aveugler la détection.
+const (
+	SilentAgentRuleID    = "xdr-agent-silent"
+	silentAgentRuleTitle = "Agent stopped sending heartbeats"
+	silentAgentRuleLevel = "high"
+)
+
+var silentAgentTags = []string{"attack.defense_evasion", "attack.t1562.001"}
+
+// agentActivity résume l'activité d'un agent dans un lot d'événements
+type agentActivity struct {
+	hostname  string
+	firstSeen time.Time
+	lastSeen  time.Time
+	count     int
+	heartbeat *models.Event // heartbeat le plus récent du lot
+	info      *models.AgentInfo
+
+	registration *models.Event // enregistrement le plus récent du lot
+	previousID   string
+}
+
+// updateAgents tient à jour l'inventaire des agents : dernière activité et
+// nombre d'événements pour chaque agent du lot, description et dernier
+// heartbeat pour ceux qui ont envoyé un heartbeat ou un enregistrement
+func updateAgents(ctx context.Context, tx *sql.Tx, events []*models.Event) error {
+	activity := make(map[string]*agentActivity)
+	for _, event := range events {
+		a, ok := activity[event.AgentID]
+		if !ok {
+			a = &agentActivity{firstSeen: event.Timestamp}
+			activity[event.AgentID] = a
+		}
+		a.count++
+		if event.Timestamp.Before(a.firstSeen) {
+			a.firstSeen = event.Timestamp
+		}
+		if !event.Timestamp.Before(a.lastSeen) {
+			a.lastSeen = event.Timestamp
+			a.hostname = event.Hostname
+		}
+		info := event.HeartbeatInfo()
+		if info == nil {
+			continue
+		}
+		if a.heartbeat == nil || !event.Timestamp.Before(a.heartbeat.Timestamp) {
+			a.heartbeat = event
+			a.info = info
+		}
+		if info.Registration && (a.registration == nil || !event.Timestamp.Before(a.registration.Timestamp)) {
+			a.registration = event
+			a.previousID = info.PreviousAgentID
+		}
+	}
+
+	// Toujours verrouiller les lignes dans le même ordre entre workers
+	agentIDs := make([]string, 0, len(activity))
+	for agentID := range activity {
+		agentIDs = append(agentIDs, agentID)
+	}
+	sort.Strings(agentIDs)
+
+	upsert := `
+		INSERT INTO agents (agent_id, hostname, first_seen, last_seen, events_total)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (agent_id) DO UPDATE SET
+			hostname = CASE WHEN EXCLUDED.last_seen >= agents.last_seen
+				THEN EXCLUDED.hostname ELSE agents.hostname END,
+			first_seen = LEAST(agents.first_seen, EXCLUDED.first_seen),
+			last_seen = GREATEST(agents.last_seen, EXCLUDED.last_seen),
+			events_total = agents.events_total + EXCLUDED.events_total
+	`
+
+	// Un heartbeat plus ancien que le dernier connu (spool vidé en retard) est ignoré
+	heartbeat := `
+		UPDATE agents SET
+			last_heartbeat = $2,
+			last_heartbeat_event_id = $3,
+			ip_address = NULLIF($4, ''),
+			os_type = NULLIF($5, ''),
+			os_version = NULLIF($6, ''),
+			kernel_version = NULLIF($7, ''),
+			agent_version = NULLIF($8, ''),
+			collectors = $9,
+			heartbeat_interval_seconds = NULLIF($10::double precision, 0),
+			event_rate = $11,
+			spool_depth = $12,
+			silent_alert_at = NULL
+		WHERE agent_id = $1 AND (last_heartbeat IS NULL OR last_heartbeat <= $2)
+	`
+
+	registration := `
+		UPDATE agents SET
+			registered_at = $2,
+			previous_agent_id = COALESCE(NULLIF($3, ''), previous_agent_id)
+		WHERE agent_id = $1
+	`
+
+	for _, agentID := range agentIDs {
+		a := activity[agentID]
+		if _, err := tx.ExecContext(ctx, upsert, agentID, a.hostname, a.firstSeen, a.lastSeen, a.count); err != nil {
+			return fmt.Errorf("failed to upsert agent: %w", err)
+		}
+
+		if a.registration != nil {
+			if _, err := tx.ExecContext(ctx, registration, agentID, a.registration.Timestamp, a.previousID); err != nil {
+				return fmt.Errorf("failed to update agent registration: %w", err)
+			}
+		}
+
+		if a.heartbeat == nil {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, heartbeat,
+			agentID,
+			a.heartbeat.Timestamp,
+			a.heartbeat.ID,
+			a.info.IPAddress,
+			a.info.OSType,
+			a.info.OSVersion,
+			a.info.KernelVersion,
+			a.info.AgentVersion,
+			pq.Array(a.info.Collectors),
+			a.info.HeartbeatInterval,
+			a.info.EventRate,
+			a.info.SpoolDepth,
+		); err != nil {
+			return fmt.Errorf("failed to update agent heartbeat: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RaiseSilentAgentAlerts lève une alerte pour chaque agent sans heartbeat
+// depuis offlineAfter intervalles de heartbeat. L'intervalle est celui publié
+// par l'agent, ou defaultInterval pour les agents qui ne le publient pas.
+// L'alerte référence le dernier heartbeat reçu ; elle n'est levée qu'une fois
+// jusqu'au retour de l'agent, y compris avec plusieurs instances d'ingestion.
+func (ts *TimescaleDB) RaiseSilentAgentAlerts(ctx context.Context, now time.Time, defaultInterval time.Duration, offlineAfter int) ([]*models.Alert, error) {
+	tx, err := ts.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE agents SET silent_alert_at = $1
+		WHERE silent_alert_at IS NULL
+			AND last_heartbeat_event_id IS NOT NULL
+			AND last_heartbeat < $1::timestamptz - make_interval(secs => COALESCE(heartbeat_interval_seconds, $2) * $3)
+		RETURNING agent_id, hostname, last_heartbeat, last_heartbeat_event_id
+	`
+
+	rows, err := tx.QueryContext(ctx, query, now, defaultInterval.Seconds(), offlineAfter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query silent agents: %w", err)
+	}
+
+	var alerts []*models.Alert
+	for rows.Next() {
+		alert := &models.Alert{
+			CreatedAt: now,
+			RuleID:    SilentAgentRuleID,
+			RuleTitle: silentAgentRuleTitle,
+			RuleLevel: silentAgentRuleLevel,
+			Tags:      silentAgentTags,
+			EventType: models.EventTypeSystem,
+		}
+		if err := rows.Scan(&alert.AgentID, &alert.Hostname, &alert.EventTimestamp, &alert.EventID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan silent agent: %w", err)
+		}
+		alert.Description = fmt.Sprintf("No heartbeat from agent %s (%s) since %s",
+			alert.AgentID, alert.Hostname, alert.EventTimestamp.UTC().Format(time.RFC3339))
+		alerts = append(alerts, alert)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	rows.Close()
+
+	if err := insertAlerts(ctx, tx, alerts); err != nil {
+		return nil, err
+	}
+
+	// Aucun événement n'est inséré : l'API est prévenue des seules alertes
+	if err := notifyAlerts(ctx, tx, alerts); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return alerts, nil
+}
@@ -0,0 +1,303 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/luigi/xdr-platform/ingestion/models"
+)
+
+// InsertMode sélectionne la stratégie d'insertion des événements
+type InsertMode string
+
+const (
+	// InsertModeCopy charge les événements via le protocole COPY (par défaut)
+	InsertModeCopy InsertMode = "copy"
+	// InsertModeRows exécute un INSERT préparé par événement
+	InsertModeRows InsertMode = "rows"
+
+	// DefaultInsertChunkSize est le nombre de lignes par COPY
+	DefaultInsertChunkSize = 1000
+)
+
+// eventColumns est l'ordre des colonnes utilisé par les deux stratégies d'insertion.
+// Ce service est le seul à écrire dans raw_events : l'API Gateway publie les
+// événements reçus en HTTP et les rejeux sur le topic raw-events.
+var eventColumns = []string{
+	"id", "timestamp", "agent_id", "hostname", "event_type", "severity",
+	"raw_data", "source_ip", "destination_ip", "process_name",
+	"process_pid", "username", "tags", "metadata",
+}
+
+// SetInsertOptions configure la stratégie d'insertion et la taille des chunks COPY
+func (ts *TimescaleDB) SetInsertOptions(mode InsertMode, chunkSize int) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultInsertChunkSize
+	}
+	ts.insertMode = mode
+	ts.insertChunkSize = chunkSize
+}
+
+// InsertEvents insère un batch d'événements dans la base de données.
+// Le batch est écrit dans une seule transaction : soit tous les événements
+// sont insérés, soit aucun.
+func (ts *TimescaleDB) InsertEvents(ctx context.Context, events []*models.Event) error {
+	return ts.InsertEventsWithAlerts(ctx, events, nil)
+}
+
+// InsertEventsWithAlerts insère un batch d'événements et les alertes qu'ils ont
+// déclenchées dans la même transaction, et met à jour l'inventaire des agents.
+// Les IDs des événements sont réservés avant l'insertion pour que chaque
+// alerte référence son événement déclencheur.
+func (ts *TimescaleDB) InsertEventsWithAlerts(ctx context.Context, events []*models.Event, alerts []*models.Alert) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	// Préparer la transaction
+	tx, err := ts.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := assignEventIDs(ctx, tx, events); err != nil {
+		return err
+	}
+
+	if ts.insertMode == InsertModeRows {
+		err = insertEventsRows(ctx, tx, events)
+	} else {
+		err = ts.insertEventsCopy(ctx, tx, events)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := insertAlerts(ctx, tx, alerts); err != nil {
+		return err
+	}
+
+	if err := updateAgents(ctx, tx, events); err != nil {
+		return err
+	}
+
+	if err := notifyInserted(ctx, tx, events, len(alerts)); err != nil {
+		return err
+	}
+
+	// Commit la transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// assignEventIDs réserve un ID de la séquence de raw_events pour chaque événement
+func assignEventIDs(ctx context.Context, tx *sql.Tx, events []*models.Event) error {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT nextval(pg_get_serial_sequence('raw_events', 'id')) FROM generate_series(1, $1)",
+		len(events))
+	if err != nil {
+		return fmt.Errorf("failed to reserve event ids: %w", err)
+	}
+	defer rows.Close()
+
+	i := 0
+	for rows.Next() {
+		if err := rows.Scan(&events[i].ID); err != nil {
+			return fmt.Errorf("failed to scan event id: %w", err)
+		}
+		i++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to reserve event ids: %w", err)
+	}
+	if i != len(events) {
+		return fmt.Errorf("reserved %d event ids for %d events", i, len(events))
+	}
+
+	return nil
+}
+
+// insertEventsCopy charge les événements par chunks via COPY FROM STDIN
+func (ts *TimescaleDB) insertEventsCopy(ctx context.Context, tx *sql.Tx, events []*models.Event) error {
+	for start := 0; start < len(events); start += ts.insertChunkSize {
+		end := start + ts.insertChunkSize
+		if end > len(events) {
+			end = len(events)
+		}
+
+		stmt, err := tx.PrepareContext(ctx, pq.CopyIn("raw_events", eventColumns...))
+		if err != nil {
+			return fmt.Errorf("failed to prepare copy statement: %w", err)
+		}
+
+		for _, event := range events[start:end] {
+			values, err := eventValues(event)
+			if err != nil {
+				stmt.Close()
+				return err
+			}
+			if _, err := stmt.ExecContext(ctx, values...); err != nil {
+				stmt.Close()
+				return fmt.Errorf("failed to copy event: %w", err)
+			}
+		}
+
+		// Un Exec sans argument termine le COPY et remonte les erreurs du serveur
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			stmt.Close()
+			return fmt.Errorf("failed to flush copy: %w", err)
+		}
+		if err := stmt.Close(); err != nil {
+			return fmt.Errorf("failed to close copy statement: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// insertEventsRows insère les événements un par un avec un statement préparé
+func insertEventsRows(ctx context.Context, tx *sql.Tx, events []*models.Event) error {
+	query := `
+		INSERT INTO raw_events (
+			id, timestamp, agent_id, hostname, event_type, severity,
+			raw_data, source_ip, destination_ip, process_name,
+			process_pid, username, tags, metadata
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+		)
+	`
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, event := range events {
+		values, err := eventValues(event)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return fmt.Errorf("failed to insert event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// eventValues convertit un événement en valeurs de colonnes, dans l'ordre de eventColumns
+func eventValues(event *models.Event) ([]interface{}, error) {
+	// Convertir raw_data en JSONB (en texte : COPY encoderait un []byte comme bytea)
+	rawDataJSON, err := json.Marshal(event.RawData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to marshal raw_data: %v", ErrInvalidEvent, err)
+	}
+
+	// Convertir metadata en JSONB (peut être nil)
+	var metadataJSON interface{}
+	if len(event.Metadata) > 0 {
+		data, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to marshal metadata: %v", ErrInvalidEvent, err)
+		}
+		metadataJSON = string(data)
+	}
+
+	// Convertir les valeurs nullables
+	var sourceIP, destIP, processName, username interface{}
+	var processPID interface{}
+
+	if event.SourceIP != "" {
+		sourceIP = event.SourceIP
+	}
+	if event.DestinationIP != "" {
+		destIP = event.DestinationIP
+	}
+	if event.ProcessName != "" {
+		processName = event.ProcessName
+	}
+	if event.ProcessPID != 0 {
+		processPID = event.ProcessPID
+	}
+	if event.Username != "" {
+		username = event.Username
+	}
+
+	return []interface{}{
+		event.ID,
+		event.Timestamp,
+		event.AgentID,
+		event.Hostname,
+		string(event.EventType),
+		string(event.Severity),
+		string(rawDataJSON),
+		sourceIP,
+		destIP,
+		processName,
+		processPID,
+		username,
+		pq.Array(event.Tags),
+		metadataJSON,
+	}, nil
+}
+
+// insertAlerts insère les alertes après avoir résolu la référence vers leur événement
+func insertAlerts(ctx context.Context, tx *sql.Tx, alerts []*models.Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO alerts (
+			created_at, rule_id, rule_title, rule_level, description, tags,
+			event_id, event_timestamp, agent_id, hostname, event_type
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		)
+		RETURNING id
+	`
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare alert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, alert := range alerts {
+		if alert.Event != nil {
+			alert.EventID = alert.Event.ID
+			alert.EventTimestamp = alert.Event.Timestamp
+		}
+
+		var description interface{}
+		if alert.Description != "" {
+			description = alert.Description
+		}
+
+		if err := stmt.QueryRowContext(ctx,
+			alert.CreatedAt,
+			alert.RuleID,
+			alert.RuleTitle,
+			alert.RuleLevel,
+			description,
+			pq.Array(alert.Tags),
+			alert.EventID,
+			alert.EventTimestamp,
+			alert.AgentID,
+			alert.Hostname,
+			string(alert.EventType),
+		).Scan(&alert.ID); err != nil {
+			return fmt.Errorf("failed to insert alert: %w", err)
+		}
+	}
+
+	return nil
+}
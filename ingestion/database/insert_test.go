@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/luigi/xdr-platform/ingestion/models"
+)
+
+// benchDatabaseEnv désigne la base PostgreSQL/TimescaleDB, initialisée avec
+// docs/schema.sql, contre laquelle le benchmark d'insertion est exécuté
+const benchDatabaseEnv = "XDR_BENCH_DATABASE_URL"
+
+const benchAgentID = "insert-bench"
+
+// BenchmarkInsertEvents compare le débit des deux stratégies d'insertion.
+// Chaque itération insère un batch ; le débit est rapporté en events/s.
+//
+//	XDR_BENCH_DATABASE_URL=postgres://... go test -run '^$' -bench InsertEvents ./database
+func BenchmarkInsertEvents(b *testing.B) {
+	databaseURL := os.Getenv(benchDatabaseEnv)
+	if databaseURL == "" {
+		b.Skipf("%s not set", benchDatabaseEnv)
+	}
+
+	db, err := NewTimescaleDB(databaseURL)
+	if err != nil {
+		b.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	for _, mode := range []InsertMode{InsertModeRows, InsertModeCopy} {
+		for _, batchSize := range []int{100, 500, 2000} {
+			b.Run(fmt.Sprintf("mode=%s/batch=%d", mode, batchSize), func(b *testing.B) {
+				db.SetInsertOptions(mode, DefaultInsertChunkSize)
+				b.Cleanup(func() { cleanupBenchEvents(b, db.db) })
+
+				batches := make([][]*models.Event, b.N)
+				for i := range batches {
+					batches[i] = generateBenchEvents(batchSize)
+				}
+
+				ctx := context.Background()
+				b.ResetTimer()
+				for _, batch := range batches {
+					if err := db.InsertEvents(ctx, batch); err != nil {
+						b.Fatalf("insert failed: %v", err)
+					}
+				}
+				b.StopTimer()
+
+				b.ReportMetric(float64(b.N*batchSize)/b.Elapsed().Seconds(), "events/s")
+			})
+		}
+	}
+}
+
+// cleanupBenchEvents supprime les événements générés et l'agent qu'ils ont
+// fait apparaître dans l'inventaire
+func cleanupBenchEvents(b *testing.B, db *sql.DB) {
+	for _, query := range []string{
+		"DELETE FROM raw_events WHERE agent_id = $1",
+		"DELETE FROM agents WHERE agent_id = $1",
+	} {
+		if _, err := db.Exec(query, benchAgentID); err != nil {
+			b.Errorf("failed to clean up benchmark events: %v", err)
+		}
+	}
+}
+
+// generateBenchEvents produit des événements processus représentatifs de ce que l'agent envoie
+func generateBenchEvents(n int) []*models.Event {
+	now := time.Now()
+	events := make([]*models.Event, n)
+
+	for i := range events {
+		events[i] = &models.Event{
+			Timestamp:   now.Add(-time.Duration(i) * time.Millisecond),
+			AgentID:     benchAgentID,
+			Hostname:    fmt.Sprintf("bench-host-%02d", i%50),
+			EventType:   models.EventTypeProcess,
+			Severity:    models.SeverityLow,
+			ProcessName: "nginx",
+			ProcessPID:  1000 + i%30000,
+			Username:    "www-data",
+			RawData: map[string]interface{}{
+				"process": map[string]interface{}{
+					"pid":             1000 + i%30000,
+					"name":            "nginx",
+					"command_line":    "nginx: worker process",
+					"executable_path": "/usr/sbin/nginx",
+					"cpu_percent":     float64(i%100) / 10,
+					"memory_percent":  1.2,
+					"num_threads":     4,
+					"status":          "S",
+				},
+			},
+			Tags: []string{"process_monitoring", "benchmark"},
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/luigi/xdr-platform/ingestion/models"
+)
+
+const (
+	// EventsChannel est le canal LISTEN/NOTIFY des événements insérés,
+	// écouté par l'API gateway pour le streaming temps réel
+	EventsChannel = "xdr_events"
+
+	// maxNotifyPayload reste sous la limite de 8000 octets de NOTIFY
+	maxNotifyPayload = 7500
+)
+
+// insertedNotification décrit un lot inséré : les IDs (en plages) et l'intervalle
+// de timestamps permettent à l'API de relire les lignes sans parcourir tous les
+// chunks. Alerts est le nombre d'alertes du lot entier, pour que l'API ne
+// cherche les alertes que lorsqu'il y en a. AlertIDs annonce des alertes levées
+// sans nouvel événement (agents silencieux) ; IDs est alors vide.
+type insertedNotification struct {
+	IDs      [][2]int64 `json:"ids"`
+	From     time.Time  `json:"from"`
+	To       time.Time  `json:"to"`
+	Alerts   int        `json:"alerts"`
+	AlertIDs [][2]int64 `json:"alert_ids,omitempty"`
+}
+
+// notifyInserted signale les événements insérés. La notification est émise
+// dans la transaction : elle n'est délivrée qu'au commit.
+func notifyInserted(ctx context.Context, tx *sql.Tx, events []*models.Event, alertCount int) error {
+	ids := make([]int64, len(events))
+	from, to := events[0].Timestamp, events[0].Timestamp
+	for i, event := range events {
+		ids[i] = event.ID
+		if event.Timestamp.Before(from) {
+			from = event.Timestamp
+		}
+		if event.Timestamp.After(to) {
+			to = event.Timestamp
+		}
+	}
+
+	return notifyRanges(ctx, tx, idRanges(ids), func(ranges [][2]int64) insertedNotification {
+		return insertedNotification{IDs: ranges, From: from, To: to, Alerts: alertCount}
+	})
+}
+
+// notifyAlerts signale des alertes insérées sans événement, dans la transaction
+func notifyAlerts(ctx context.Context, tx *sql.Tx, alerts []*models.Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(alerts))
+	for i, alert := range alerts {
+		ids[i] = alert.ID
+	}
+
+	return notifyRanges(ctx, tx, idRanges(ids), func(ranges [][2]int64) insertedNotification {
+		return insertedNotification{AlertIDs: ranges, Alerts: len(alerts)}
+	})
+}
+
+// notifyRanges émet les notifications construites par build ; un lot très
+// fragmenté est découpé en plusieurs notifications
+func notifyRanges(ctx context.Context, tx *sql.Tx, ranges [][2]int64, build func([][2]int64) insertedNotification) error {
+	for len(ranges) > 0 {
+		n := len(ranges)
+		var payload []byte
+		for {
+			var err error
+			payload, err = json.Marshal(build(ranges[:n]))
+			if err != nil {
+				return fmt.Errorf("failed to encode notification: %w", err)
+			}
+			if len(payload) <= maxNotifyPayload || n == 1 {
+				break
+			}
+			n /= 2
+		}
+
+		if _, err := tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", EventsChannel, string(payload)); err != nil {
+			return fmt.Errorf("failed to notify inserted events: %w", err)
+		}
+
+		ranges = ranges[n:]
+	}
+
+	return nil
+}
+
+// idRanges regroupe des IDs en plages contiguës [début, fin]
+func idRanges(ids []int64) [][2]int64 {
+	sorted := append([]int64(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var ranges [][2]int64
+	for _, id := range sorted {
+		if last := len(ranges) - 1; last >= 0 && id <= ranges[last][1]+1 {
+			ranges[last][1] = id
+			continue
+		}
+		ranges = append(ranges, [2]int64{id, id})
+	}
+	return ranges
+}
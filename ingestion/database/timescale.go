@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/luigi/xdr-platform/ingestion/models"
+)
+
+// ErrInvalidEvent signale un événement qui ne pourra jamais être inséré tel quel
+var ErrInvalidEvent = errors.New("invalid event")
+
+// TimescaleDB gère la connexion à la base de données
+type TimescaleDB struct {
+	db              *sql.DB
+	insertMode      InsertMode
+	insertChunkSize int
+}
+
+// NewTimescaleDB crée une nouvelle connexion à TimescaleDB
+func NewTimescaleDB(databaseURL string) (*TimescaleDB, error) {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+
+	// Configurer le pool de connexions
+	db.SetMaxOpenConns(25)
+	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	// Tester la connexion
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return &TimescaleDB{
+		db:              db,
+		insertMode:      InsertModeCopy,
+		insertChunkSize: DefaultInsertChunkSize,
+	}, nil
+}
+
+// InsertRejectedEvents enregistre des événements rejetés dans la table de quarantaine
+func (ts *TimescaleDB) InsertRejectedEvents(ctx context.Context, rejected []*models.RejectedEvent) error {
+	if len(rejected) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO rejected_events (
+			rejected_at, stage, reason, payload, agent_id,
+			source_topic, source_partition, source_offset
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+	`
+
+	tx, err := ts.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, r := range rejected {
+		var agentID interface{}
+		if r.AgentID != "" {
+			agentID = r.AgentID
+		}
+
+		_, err = stmt.ExecContext(
+			ctx,
+			r.RejectedAt,
+			r.Stage,
+			r.Reason,
+			[]byte(r.Payload), // bytea : le message peut contenir des octets nuls ou invalides en UTF-8
+			agentID,
+			r.SourceTopic,
+			r.SourcePartition,
+			r.SourceOffset,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert rejected event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// IsDataError indique si l'erreur provient des données elles-mêmes
+// (sérialisation, type invalide, contrainte violée) plutôt que de la base.
+// Réessayer la même insertion ne servirait à rien.
+func IsDataError(err error) bool {
+	if errors.Is(err, ErrInvalidEvent) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "22", "23": // data_exception, integrity_constraint_violation
+			return true
+		}
+	}
+	return false
+}
+
+// Close ferme la connexion à la base de données
+func (ts *TimescaleDB) Close() error {
+	if ts.db != nil {
+		return ts.db.Close()
+	}
+	return nil
+}
+
+// HealthCheck vérifie que la base de données est accessible
+func (ts *TimescaleDB) HealthCheck(ctx context.Context) error {
+	return ts.db.PingContext(ctx)
+}
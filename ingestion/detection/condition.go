@@ -0,0 +1,177 @@
+package detection
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// condition est une expression de condition Sigma compilée
+type condition func(doc document) bool
+
+// conditionParser analyse une condition Sigma :
+//
+//	expr    := and { "or" and }
+//	and     := not { "and" not }
+//	not     := "not" not | primary
+//	primary := "(" expr ")" | ("1" | "any" | "all") "of" (pattern | "them") | identifier
+type conditionParser struct {
+	tokens   []string
+	pos      int
+	searches map[string]*search
+}
+
+// parseCondition compile une condition à partir des recherches nommées de la règle
+func parseCondition(expr string, searches map[string]*search) (condition, error) {
+	if strings.Contains(expr, "|") {
+		return nil, fmt.Errorf("aggregation expressions are not supported: %q", expr)
+	}
+
+	p := &conditionParser{tokens: tokenizeCondition(expr), searches: searches}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty condition")
+	}
+
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d in condition %q", p.tokens[p.pos], p.pos+1, expr)
+	}
+	return cond, nil
+}
+
+// tokenizeCondition découpe une condition en mots et parenthèses
+func tokenizeCondition(expr string) []string {
+	expr = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(expr)
+	return strings.Fields(expr)
+}
+
+// peek retourne le prochain token en minuscules, ou "" en fin de condition
+func (p *conditionParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return strings.ToLower(p.tokens[p.pos])
+}
+
+func (p *conditionParser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(doc document) bool { return l(doc) || right(doc) }
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(doc document) bool { return l(doc) && right(doc) }
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseNot() (condition, error) {
+	if p.peek() == "not" {
+		p.pos++
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(doc document) bool { return !inner(doc) }, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *conditionParser) parsePrimary() (condition, error) {
+	token := p.peek()
+	switch token {
+	case "":
+		return nil, fmt.Errorf("unexpected end of condition")
+
+	case "(":
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return inner, nil
+
+	case "1", "any", "all":
+		if p.pos+2 >= len(p.tokens) || strings.ToLower(p.tokens[p.pos+1]) != "of" {
+			break
+		}
+		pattern := p.tokens[p.pos+2]
+		p.pos += 3
+		return p.quantifier(token == "all", pattern)
+
+	case ")", "and", "or", "of":
+		return nil, fmt.Errorf("unexpected %q in condition", p.tokens[p.pos])
+	}
+
+	name := p.tokens[p.pos]
+	s, ok := p.searches[name]
+	if !ok {
+		return nil, fmt.Errorf("condition references unknown search %q", name)
+	}
+	p.pos++
+	return s.match, nil
+}
+
+// quantifier compile "1 of pattern", "all of pattern" et leurs variantes "them"
+func (p *conditionParser) quantifier(all bool, pattern string) (condition, error) {
+	var names []string
+	for name := range p.searches {
+		if strings.EqualFold(pattern, "them") {
+			if !strings.HasPrefix(name, "_") {
+				names = append(names, name)
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no search matches %q", pattern)
+	}
+	sort.Strings(names)
+
+	matches := make([]condition, len(names))
+	for i, name := range names {
+		matches[i] = p.searches[name].match
+	}
+
+	return func(doc document) bool {
+		for _, match := range matches {
+			if match(doc) != all {
+				return !all
+			}
+		}
+		return all
+	}, nil
+}
@@ -0,0 +1,119 @@
+package detection
+
+import (
+	"strings"
+	"testing"
+)
+
+// testSearches retourne des recherches nommées qui correspondent au
+// document dont la liste "hits" contient leur nom
+func testSearches(names ...string) map[string]*search {
+	searches := make(map[string]*search, len(names))
+	for _, name := range names {
+		name := name
+		searches[name] = &search{keywords: []valueMatcher{func(v string) bool { return v == name }}}
+	}
+	return searches
+}
+
+// testDocument retourne un document qui satisfait les recherches nommées
+func testDocument(hits ...string) document {
+	values := make([]interface{}, len(hits))
+	for i, hit := range hits {
+		values[i] = hit
+	}
+	return document{"hits": values}
+}
+
+func TestParseCondition(t *testing.T) {
+	searches := testSearches("a", "b", "c", "sel_one", "sel_two", "_filter")
+
+	tests := []struct {
+		expr string
+		hits []string
+		want bool
+	}{
+		{"a", []string{"a"}, true},
+		{"a", nil, false},
+
+		// and est prioritaire sur or, not sur and
+		{"a or b and c", []string{"a"}, true},
+		{"a or b and c", []string{"b"}, false},
+		{"a and b or c", []string{"c"}, true},
+		{"not a and b", []string{"b"}, true},
+		{"not a and b", []string{"a", "b"}, false},
+		{"not not a", []string{"a"}, true},
+
+		// Les parenthèses changent la priorité
+		{"(a or b) and c", []string{"a"}, false},
+		{"(a or b) and c", []string{"b", "c"}, true},
+		{"not (a or b)", []string{"b"}, false},
+		{"not (a or b)", []string{"c"}, true},
+		{"((a))", []string{"a"}, true},
+
+		// Les mots-clés ignorent la casse, pas les noms de recherches
+		{"a AND NOT b", []string{"a"}, true},
+		{"a Or b", []string{"b"}, true},
+
+		// Quantificateurs
+		{"1 of sel_*", []string{"sel_two"}, true},
+		{"any of sel_*", nil, false},
+		{"all of sel_*", []string{"sel_one"}, false},
+		{"all of sel_*", []string{"sel_one", "sel_two"}, true},
+		{"ALL OF sel_?ne", []string{"sel_one"}, true},
+		{"all of them", []string{"a", "b", "c", "sel_one", "sel_two"}, true},
+		{"1 of them", []string{"_filter"}, false},
+		{"1 of them and not _filter", []string{"a", "_filter"}, false},
+		{"1 of sel_* and not 1 of _*", []string{"sel_one"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cond, err := parseCondition(tt.expr, searches)
+			if err != nil {
+				t.Fatalf("parseCondition(%q) returned error: %v", tt.expr, err)
+			}
+			if got := cond(testDocument(tt.hits...)); got != tt.want {
+				t.Errorf("condition %q with hits %v = %t, want %t", tt.expr, tt.hits, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	searches := testSearches("a", "b", "sel_one")
+
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"", "empty condition"},
+		{"   ", "empty condition"},
+		{"a | count() > 5", "aggregation expressions are not supported"},
+		{"a and", "unexpected end of condition"},
+		{"not", "unexpected end of condition"},
+		{"(a or b", "missing closing parenthesis"},
+		{"a)", `unexpected ")" at position 2`},
+		{"a b", `unexpected "b" at position 2`},
+		{"and a", `unexpected "and" in condition`},
+		{"a or or b", `unexpected "or" in condition`},
+		{"()", `unexpected ")" in condition`},
+		{"A", `unknown search "A"`},
+		{"a and missing", `unknown search "missing"`},
+		{"1 of other_*", `no search matches "other_*"`},
+		{"all of [", `no search matches "["`},
+		{"1 of", `unknown search "1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := parseCondition(tt.expr, searches)
+			if err == nil {
+				t.Fatalf("parseCondition(%q) succeeded, want error containing %q", tt.expr, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseCondition(%q) error = %q, want it to contain %q", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package detection évalue des règles Sigma sur le flux d'événements ingérés.
+package detection
+
+import (
+	"log"
+	"time"
+
+	"github.com/luigi/xdr-platform/ingestion/models"
+)
+
+// Engine évalue un jeu de règles Sigma chargé au démarrage.
+// Il est en lecture seule après sa création et peut être partagé entre workers.
+type Engine struct {
+	rules []*Rule
+}
+
+// NewEngine charge les règles de dir. Les règles invalides ou non supportées
+// sont journalisées et ignorées.
+func NewEngine(dir string, logger *log.Logger) (*Engine, error) {
+	rules, errs, err := LoadRules(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, err := range errs {
+		logger.Printf("Skipping detection rule: %v", err)
+	}
+	logger.Printf("Loaded %d detection rules from %s (%d skipped)", len(rules), dir, len(errs))
+
+	return &Engine{rules: rules}, nil
+}
+
+// RuleCount retourne le nombre de règles actives
+func (e *Engine) RuleCount() int {
+	return len(e.rules)
+}
+
+// Evaluate retourne une alerte par règle déclenchée par l'événement
+func (e *Engine) Evaluate(event *models.Event) []*models.Alert {
+	if len(e.rules) == 0 {
+		return nil
+	}
+
+	doc := newDocument(event)
+
+	var alerts []*models.Alert
+	for _, rule := range e.rules {
+		if !rule.match(event, doc) {
+			continue
+		}
+		alerts = append(alerts, &models.Alert{
+			CreatedAt:      time.Now(),
+			RuleID:         rule.ID,
+			RuleTitle:      rule.Title,
+			RuleLevel:      rule.Level,
+			Description:    rule.Description,
+			Tags:           rule.Tags,
+			EventTimestamp: event.Timestamp,
+			AgentID:        event.AgentID,
+			Hostname:       event.Hostname,
+			EventType:      event.EventType,
+			Event:          event,
+		})
+	}
+
+	return alerts
+}
@@ -0,0 +1,251 @@
+package detection
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luigi/xdr-platform/ingestion/models"
+)
+
+// logsource associe une catégorie Sigma à un type d'événement et,
+// éventuellement, aux actions de l'agent qui y correspondent (raw_data.<type>.action)
+type logsource struct {
+	eventType models.EventType
+	actions   []string
+}
+
+// logsourceCategories sont les catégories Sigma que les collecteurs de l'agent alimentent
+var logsourceCategories = map[string]logsource{
+	"process_creation":    {eventType: models.EventTypeProcess, actions: []string{"start", "exec"}},
+	"process_termination": {eventType: models.EventTypeProcess, actions: []string{"exit"}},
+	"network_connection":  {eventType: models.EventTypeNetwork, actions: []string{"opened", "connect"}},
+	"file_event":          {eventType: models.EventTypeFile},
+	"file_access":         {eventType: models.EventTypeFile, actions: []string{"access"}},
+	"file_change":         {eventType: models.EventTypeFile, actions: []string{"modify"}},
+	"file_delete":         {eventType: models.EventTypeFile, actions: []string{"delete"}},
+	"persistence":         {eventType: models.EventTypePersistence}, // catégorie propre à l'agent
+}
+
+// logsourceServices sont les services Sigma que les collecteurs de l'agent alimentent
+var logsourceServices = map[string]logsource{
+	"auth":   {eventType: models.EventTypeAuth},
+	"sshd":   {eventType: models.EventTypeAuth},
+	"sudo":   {eventType: models.EventTypeAuth, actions: []string{"sudo"}},
+	"syslog": {eventType: models.EventTypeJournal},
+}
+
+// commonFields traduit les champs Sigma communs à tous les types d'événements
+var commonFields = map[string]string{
+	"Computer":     "hostname",
+	"ComputerName": "hostname",
+	"Hostname":     "hostname",
+	"User":         "username",
+}
+
+// eventTypeFields traduit les champs de la taxonomie Sigma vers les chemins
+// des événements de l'agent, par type d'événement
+var eventTypeFields = map[models.EventType]map[string]string{
+	models.EventTypeProcess: {
+		"Image":             "raw_data.process.executable_path",
+		"CommandLine":       "raw_data.process.command_line",
+		"ProcessId":         "raw_data.process.pid",
+		"ProcessName":       "raw_data.process.name",
+		"ParentProcessId":   "raw_data.process.parent_pid",
+		"ParentImage":       "raw_data.process.parent_chain.0.executable_path",
+		"ParentProcessName": "raw_data.process.parent_chain.0.name",
+		"Ancestors":         "raw_data.process.parent_chain.executable_path",
+		"sha256":            "raw_data.process.executable_hash",
+		"sha1":              "raw_data.process.executable_sha1",
+		"md5":               "raw_data.process.executable_md5",
+	},
+	models.EventTypeNetwork: {
+		"Image":           "raw_data.network.executable_path",
+		"ProcessId":       "raw_data.network.pid",
+		"ProcessName":     "raw_data.network.process_name",
+		"Protocol":        "raw_data.network.protocol",
+		"SourceIp":        "raw_data.network.source_ip",
+		"SourcePort":      "raw_data.network.source_port",
+		"DestinationIp":   "raw_data.network.dest_ip",
+		"DestinationPort": "raw_data.network.dest_port",
+	},
+	models.EventTypeFile: {
+		"TargetFilename": "raw_data.file.path",
+		"sha256":         "raw_data.file.new_hash",
+	},
+	models.EventTypeAuth: {
+		"SourceIp":    "raw_data.auth.source_ip",
+		"SourcePort":  "raw_data.auth.source_port",
+		"TargetUser":  "raw_data.auth.target_user",
+		"CommandLine": "raw_data.auth.command",
+		"Service":     "raw_data.auth.service",
+		"Outcome":     "raw_data.auth.outcome",
+	},
+	models.EventTypeJournal: {
+		"Image":       "raw_data.journal.executable_path",
+		"ProcessId":   "raw_data.journal.pid",
+		"CommandLine": "raw_data.journal.command_line",
+	},
+	models.EventTypePersistence: {
+		"TargetFilename": "raw_data.persistence.path",
+		"sha256":         "raw_data.persistence.hash",
+	},
+}
+
+// fieldPaths retourne les chemins candidats d'un champ Sigma, par ordre de priorité.
+// Un champ sans correspondance est cherché tel quel (colonne ou chemin raw_data.*),
+// puis sous raw_data, puis sous la section du type d'événement.
+func fieldPaths(field string, eventType models.EventType) []string {
+	if path, ok := commonFields[field]; ok {
+		return []string{path}
+	}
+
+	var paths []string
+	if eventType != "" {
+		if path, ok := eventTypeFields[eventType][field]; ok {
+			return []string{path}
+		}
+	} else {
+		for _, fields := range eventTypeFields {
+			if path, ok := fields[field]; ok {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) > 0 {
+			return paths
+		}
+	}
+
+	paths = append(paths, field, "raw_data."+field)
+	if eventType != "" {
+		paths = append(paths, fmt.Sprintf("raw_data.%s.%s", eventType, field))
+	}
+	return paths
+}
+
+// document est la vue d'un événement sur laquelle les règles sont évaluées
+type document map[string]interface{}
+
+// newDocument construit la vue d'un événement, clés identiques au JSON de l'événement
+func newDocument(event *models.Event) document {
+	tags := make([]interface{}, len(event.Tags))
+	for i, tag := range event.Tags {
+		tags[i] = tag
+	}
+
+	return document{
+		"timestamp":      event.Timestamp,
+		"agent_id":       event.AgentID,
+		"hostname":       event.Hostname,
+		"event_type":     string(event.EventType),
+		"severity":       string(event.Severity),
+		"source_ip":      event.SourceIP,
+		"destination_ip": event.DestinationIP,
+		"process_name":   event.ProcessName,
+		"process_pid":    event.ProcessPID,
+		"username":       event.Username,
+		"tags":           tags,
+		"raw_data":       event.RawData,
+		"metadata":       event.Metadata,
+	}
+}
+
+// lookup retourne les valeurs feuilles trouvées au premier chemin existant.
+// Un segment numérique indexe une liste ; sinon le chemin est suivi dans
+// chaque élément de la liste.
+func (d document) lookup(paths []string) ([]string, bool) {
+	for _, path := range paths {
+		var values []string
+		if collect(map[string]interface{}(d), strings.Split(path, "."), &values) {
+			return values, true
+		}
+	}
+	return nil, false
+}
+
+// collect descend dans node le long de segments et accumule les valeurs trouvées
+func collect(node interface{}, segments []string, values *[]string) bool {
+	if len(segments) == 0 {
+		return flatten(node, values)
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[segments[0]]
+		if !ok {
+			return false
+		}
+		return collect(child, segments[1:], values)
+
+	case []interface{}:
+		if index, err := strconv.Atoi(segments[0]); err == nil {
+			if index < 0 || index >= len(n) {
+				return false
+			}
+			return collect(n[index], segments[1:], values)
+		}
+		found := false
+		for _, item := range n {
+			if collect(item, segments, values) {
+				found = true
+			}
+		}
+		return found
+	}
+
+	return false
+}
+
+// flatten convertit une valeur feuille (ou une liste de feuilles) en chaînes
+func flatten(node interface{}, values *[]string) bool {
+	switch v := node.(type) {
+	case nil:
+		return false
+	case string:
+		*values = append(*values, v)
+	case float64:
+		*values = append(*values, strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		*values = append(*values, strconv.FormatBool(v))
+	case time.Time:
+		*values = append(*values, v.Format(time.RFC3339Nano))
+	case []interface{}:
+		found := false
+		for _, item := range v {
+			if flatten(item, values) {
+				found = true
+			}
+		}
+		return found
+	case map[string]interface{}:
+		return false
+	default:
+		*values = append(*values, fmt.Sprint(v))
+	}
+	return true
+}
+
+// stringValues retourne toutes les valeurs chaînes de l'événement, pour les recherches par mots-clés
+func (d document) stringValues() []string {
+	var values []string
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		switch n := node.(type) {
+		case string:
+			if n != "" {
+				values = append(values, n)
+			}
+		case map[string]interface{}:
+			for _, child := range n {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range n {
+				walk(child)
+			}
+		}
+	}
+	walk(map[string]interface{}(d))
+	return values
+}
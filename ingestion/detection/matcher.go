@@ -0,0 +1,262 @@
+package detection
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/luigi/xdr-platform/ingestion/models"
+)
+
+// valueMatcher teste une valeur de l'événement contre une valeur de la règle
+type valueMatcher func(value string) bool
+
+// fieldMatcher est une condition sur un champ : "Field|modifier: valeurs"
+type fieldMatcher struct {
+	field  string
+	paths  []string
+	values []valueMatcher
+	all    bool // modificateur |all : toutes les valeurs doivent correspondre
+	null   bool // valeur null : le champ doit être absent ou vide
+	exists *bool
+}
+
+// match évalue la condition sur le document
+func (fm *fieldMatcher) match(doc document) bool {
+	values, found := doc.lookup(fm.paths)
+
+	if fm.exists != nil {
+		return found == *fm.exists
+	}
+
+	if fm.null {
+		for _, value := range values {
+			if value != "" {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !found {
+		return false
+	}
+
+	if fm.all {
+		for _, matcher := range fm.values {
+			if !anyValue(values, matcher) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, matcher := range fm.values {
+		if anyValue(values, matcher) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyValue indique si au moins une valeur du champ satisfait le matcher
+func anyValue(values []string, matcher valueMatcher) bool {
+	for _, value := range values {
+		if matcher(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// newFieldMatcher compile une clé "Field|mod1|mod2" et sa ou ses valeurs
+func newFieldMatcher(key string, raw interface{}, eventType models.EventType) (*fieldMatcher, error) {
+	parts := strings.Split(key, "|")
+	fm := &fieldMatcher{
+		field: parts[0],
+		paths: fieldPaths(parts[0], eventType),
+	}
+
+	var transform, compare string
+	cased := false
+	for _, modifier := range parts[1:] {
+		switch modifier {
+		case "contains", "startswith", "endswith":
+			transform = modifier
+		case "all":
+			fm.all = true
+		case "cased":
+			cased = true
+		case "re", "cidr", "lt", "lte", "gt", "gte", "exists":
+			compare = modifier
+		default:
+			return nil, fmt.Errorf("field %q: unsupported modifier %q", key, modifier)
+		}
+	}
+
+	rawValues, ok := raw.([]interface{})
+	if !ok {
+		rawValues = []interface{}{raw}
+	}
+
+	if compare == "exists" {
+		exists, ok := raw.(bool)
+		if !ok {
+			return nil, fmt.Errorf("field %q: exists expects true or false", key)
+		}
+		fm.exists = &exists
+		return fm, nil
+	}
+
+	if len(rawValues) == 1 && rawValues[0] == nil {
+		fm.null = true
+		return fm, nil
+	}
+
+	for _, rawValue := range rawValues {
+		if rawValue == nil {
+			return nil, fmt.Errorf("field %q: null is only allowed as a single value", key)
+		}
+		value := scalarString(rawValue)
+
+		var matcher valueMatcher
+		var err error
+		switch compare {
+		case "re":
+			matcher, err = regexpMatcher(value)
+		case "cidr":
+			matcher, err = cidrMatcher(value)
+		case "lt", "lte", "gt", "gte":
+			matcher, err = numericMatcher(compare, value)
+		default:
+			matcher, err = patternMatcher(transform, value, cased)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", key, err)
+		}
+		fm.values = append(fm.values, matcher)
+	}
+
+	return fm, nil
+}
+
+// scalarString convertit une valeur YAML scalaire en chaîne
+func scalarString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// patternMatcher compile une valeur Sigma, avec ses jokers * et ?, en matcher.
+// Sans |cased, la comparaison ignore la casse.
+func patternMatcher(transform, value string, cased bool) (valueMatcher, error) {
+	switch transform {
+	case "contains":
+		value = "*" + value + "*"
+	case "startswith":
+		value = value + "*"
+	case "endswith":
+		value = "*" + value
+	}
+
+	pattern, literal := globToRegexp(value)
+	if literal != "" || pattern == "" {
+		if cased {
+			return func(v string) bool { return v == literal }, nil
+		}
+		return func(v string) bool { return strings.EqualFold(v, literal) }, nil
+	}
+
+	if !cased {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", value, err)
+	}
+	return re.MatchString, nil
+}
+
+// globToRegexp traduit une valeur Sigma en expression régulière ancrée.
+// Si la valeur ne contient aucun joker, elle est retournée telle quelle
+// (échappements résolus) dans literal et pattern est vide.
+func globToRegexp(value string) (pattern string, literal string) {
+	var re, lit strings.Builder
+	wildcard := false
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\' && i+1 < len(value) && strings.IndexByte(`*?\`, value[i+1]) >= 0:
+			i++
+			re.WriteString(regexp.QuoteMeta(string(value[i])))
+			lit.WriteByte(value[i])
+		case c == '*':
+			wildcard = true
+			re.WriteString(".*")
+		case c == '?':
+			wildcard = true
+			re.WriteString(".")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+			lit.WriteByte(c)
+		}
+	}
+
+	if !wildcard {
+		return "", lit.String()
+	}
+	return "(?s)^" + re.String() + "$", ""
+}
+
+// regexpMatcher compile le modificateur |re
+func regexpMatcher(value string) (valueMatcher, error) {
+	re, err := regexp.Compile(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %w", value, err)
+	}
+	return re.MatchString, nil
+}
+
+// cidrMatcher compile le modificateur |cidr
+func cidrMatcher(value string) (valueMatcher, error) {
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %w", value, err)
+	}
+	return func(v string) bool {
+		ip := net.ParseIP(v)
+		return ip != nil && network.Contains(ip)
+	}, nil
+}
+
+// numericMatcher compile les modificateurs |lt, |lte, |gt et |gte
+func numericMatcher(op, value string) (valueMatcher, error) {
+	bound, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q for |%s", value, op)
+	}
+	return func(v string) bool {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return false
+		}
+		switch op {
+		case "lt":
+			return n < bound
+		case "lte":
+			return n <= bound
+		case "gt":
+			return n > bound
+		default:
+			return n >= bound
+		}
+	}, nil
+}
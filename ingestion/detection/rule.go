@@ -0,0 +1,322 @@
+package detection
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/luigi/xdr-platform/ingestion/models"
+	"gopkg.in/yaml.v3"
+)
+
+// sigmaRule est le format YAML d'une règle Sigma
+type sigmaRule struct {
+	Title       string   `yaml:"title"`
+	ID          string   `yaml:"id"`
+	Status      string   `yaml:"status"`
+	Description string   `yaml:"description"`
+	Level       string   `yaml:"level"`
+	Tags        []string `yaml:"tags"`
+	LogSource   struct {
+		Category string `yaml:"category"`
+		Product  string `yaml:"product"`
+		Service  string `yaml:"service"`
+	} `yaml:"logsource"`
+	Detection map[string]interface{} `yaml:"detection"`
+}
+
+// validLevels sont les niveaux Sigma acceptés
+var validLevels = map[string]bool{
+	"informational": true,
+	"low":           true,
+	"medium":        true,
+	"high":          true,
+	"critical":      true,
+}
+
+// search est une recherche nommée de la section detection :
+// une liste de groupes de champs (OU de ET) ou une liste de mots-clés
+type search struct {
+	groups   [][]*fieldMatcher
+	keywords []valueMatcher
+}
+
+// match évalue la recherche sur le document
+func (s *search) match(doc document) bool {
+	if s.keywords != nil {
+		for _, value := range doc.stringValues() {
+			for _, keyword := range s.keywords {
+				if keyword(value) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for _, group := range s.groups {
+		matched := true
+		for _, fm := range group {
+			if !fm.match(doc) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Rule est une règle Sigma compilée
+type Rule struct {
+	ID          string
+	Title       string
+	Description string
+	Level       string
+	Tags        []string
+	Path        string
+
+	eventType models.EventType // vide : tous les types
+	actions   []string         // vide : toutes les actions
+	condition condition
+}
+
+// match indique si l'événement déclenche la règle
+func (r *Rule) match(event *models.Event, doc document) bool {
+	if r.eventType != "" && event.EventType != r.eventType {
+		return false
+	}
+	if len(r.actions) > 0 {
+		section, _ := event.RawData[string(r.eventType)].(map[string]interface{})
+		action, _ := section["action"].(string)
+		if !slices.Contains(r.actions, action) {
+			return false
+		}
+	}
+	return r.condition(doc)
+}
+
+// LoadRules charge récursivement les règles *.yml et *.yaml de dir.
+// Une règle invalide ou non supportée n'interrompt pas le chargement :
+// elle est ignorée et son erreur est retournée dans errs.
+func LoadRules(dir string) (rules []*Rule, errs []error, err error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, nil, fmt.Errorf("failed to open rules directory: %w", err)
+	}
+
+	var paths []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if !d.IsDir() && (ext == ".yml" || ext == ".yaml") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to walk rules directory: %w", err)
+	}
+	sort.Strings(paths)
+
+	seen := make(map[string]string)
+	for _, path := range paths {
+		rule, err := loadRule(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		if other, dup := seen[rule.ID]; dup {
+			errs = append(errs, fmt.Errorf("%s: duplicate rule id %s (already loaded from %s)", path, rule.ID, other))
+			continue
+		}
+		seen[rule.ID] = path
+		rules = append(rules, rule)
+	}
+
+	return rules, errs, nil
+}
+
+// loadRule lit et compile une règle Sigma
+func loadRule(path string) (*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rule: %w", err)
+	}
+
+	var raw sigmaRule
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse rule: %w", err)
+	}
+
+	return compileRule(&raw, path)
+}
+
+// compileRule valide une règle et compile ses recherches et sa condition
+func compileRule(raw *sigmaRule, path string) (*Rule, error) {
+	if raw.ID == "" {
+		return nil, fmt.Errorf("id is required")
+	}
+	if raw.Title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	level := strings.ToLower(raw.Level)
+	if !validLevels[level] {
+		return nil, fmt.Errorf("invalid level %q", raw.Level)
+	}
+
+	rule := &Rule{
+		ID:          raw.ID,
+		Title:       raw.Title,
+		Description: raw.Description,
+		Level:       level,
+		Tags:        raw.Tags,
+		Path:        path,
+	}
+
+	if product := strings.ToLower(raw.LogSource.Product); product != "" && product != "linux" {
+		return nil, fmt.Errorf("unsupported logsource product %q", raw.LogSource.Product)
+	}
+	if service := raw.LogSource.Service; service != "" {
+		source, ok := logsourceServices[service]
+		if !ok {
+			return nil, fmt.Errorf("unsupported logsource service %q", service)
+		}
+		rule.eventType = source.eventType
+		rule.actions = source.actions
+	}
+	if category := raw.LogSource.Category; category != "" {
+		source, ok := logsourceCategories[category]
+		if !ok {
+			return nil, fmt.Errorf("unsupported logsource category %q", category)
+		}
+		rule.eventType = source.eventType
+		rule.actions = source.actions
+	}
+
+	rawCondition, ok := raw.Detection["condition"]
+	if !ok {
+		return nil, fmt.Errorf("detection.condition is required")
+	}
+
+	searches := make(map[string]*search)
+	for name, definition := range raw.Detection {
+		if name == "condition" || name == "timeframe" {
+			continue
+		}
+		s, err := compileSearch(definition, rule.eventType)
+		if err != nil {
+			return nil, fmt.Errorf("search %q: %w", name, err)
+		}
+		searches[name] = s
+	}
+
+	// Plusieurs conditions sont équivalentes à leur disjonction
+	var expressions []string
+	switch c := rawCondition.(type) {
+	case string:
+		expressions = []string{c}
+	case []interface{}:
+		for _, item := range c {
+			expr, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("condition must be a string or a list of strings")
+			}
+			expressions = append(expressions, expr)
+		}
+	default:
+		return nil, fmt.Errorf("condition must be a string or a list of strings")
+	}
+
+	var conditions []condition
+	for _, expr := range expressions {
+		cond, err := parseCondition(expr, searches)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+	}
+
+	rule.condition = func(doc document) bool {
+		for _, cond := range conditions {
+			if cond(doc) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return rule, nil
+}
+
+// compileSearch compile une recherche : map de champs, liste de maps ou liste de mots-clés
+func compileSearch(definition interface{}, eventType models.EventType) (*search, error) {
+	switch d := definition.(type) {
+	case map[string]interface{}:
+		group, err := compileGroup(d, eventType)
+		if err != nil {
+			return nil, err
+		}
+		return &search{groups: [][]*fieldMatcher{group}}, nil
+
+	case []interface{}:
+		s := &search{}
+		for _, item := range d {
+			switch v := item.(type) {
+			case map[string]interface{}:
+				group, err := compileGroup(v, eventType)
+				if err != nil {
+					return nil, err
+				}
+				s.groups = append(s.groups, group)
+			case nil:
+				return nil, fmt.Errorf("null keyword is not supported")
+			default:
+				matcher, err := patternMatcher("contains", scalarString(v), false)
+				if err != nil {
+					return nil, err
+				}
+				s.keywords = append(s.keywords, matcher)
+			}
+		}
+		if s.groups != nil && s.keywords != nil {
+			return nil, fmt.Errorf("cannot mix keywords and field maps")
+		}
+		return s, nil
+
+	case string:
+		matcher, err := patternMatcher("contains", d, false)
+		if err != nil {
+			return nil, err
+		}
+		return &search{keywords: []valueMatcher{matcher}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported search definition")
+}
+
+// compileGroup compile une map de champs, tous requis
+func compileGroup(fields map[string]interface{}, eventType models.EventType) ([]*fieldMatcher, error) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	group := make([]*fieldMatcher, 0, len(keys))
+	for _, key := range keys {
+		fm, err := newFieldMatcher(key, fields[key], eventType)
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, fm)
+	}
+	return group, nil
+}
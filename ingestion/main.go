@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/luigi/xdr-platform/ingestion/config"
+	"github.com/luigi/xdr-platform/ingestion/consumer"
+	"github.com/luigi/xdr-platform/ingestion/database"
+	"github.com/luigi/xdr-platform/ingestion/detection"
+)
+
+func main() {
+	// Logger
+	logger := log.New(os.Stdout, "[INGESTION] ", log.LstdFlags)
+	logger.Println("Starting XDR Ingestion Service...")
+
+	// Charger la configuration
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		logger.Fatalf("Invalid configuration: %v", err)
+	}
+
+	logger.Printf("Configuration loaded: %s", cfg.String())
+
+	// Connexion à TimescaleDB
+	logger.Println("Connecting to TimescaleDB...")
+	db, err := database.NewTimescaleDB(cfg.DatabaseURL)
+	if err != nil {
+		logger.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	db.SetInsertOptions(database.InsertMode(cfg.InsertMode), cfg.InsertChunkSize)
+
+	logger.Println("Connected to TimescaleDB successfully")
+
+	// Charger les règles de détection
+	var detector *detection.Engine
+	if cfg.EnableDetection {
+		detector, err = detection.NewEngine(cfg.DetectionRulesDir, logger)
+		if err != nil {
+			logger.Fatalf("Failed to load detection rules: %v", err)
+		}
+	}
+
+	// Créer le consumer Kafka
+	kafkaConsumer, err := consumer.NewKafkaConsumer(cfg, db, detector, logger)
+	if err != nil {
+		logger.Fatalf("Failed to create Kafka consumer: %v", err)
+	}
+	defer kafkaConsumer.Close()
+
+	// Context pour gérer l'arrêt gracieux
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Channel pour capturer les signaux d'arrêt
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		logger.Println("Received shutdown signal, stopping ingestion...")
+		cancel()
+	}()
+
+	// Surveiller les agents silencieux
+	go watchAgents(ctx, db, cfg, logger)
+
+	// Bloque jusqu'à l'arrêt des workers
+	kafkaConsumer.Run(ctx)
+
+	logger.Println("Ingestion Service stopped")
+}
+
+// watchAgents lève une alerte pour chaque agent qui a cessé d'envoyer des heartbeats
+func watchAgents(ctx context.Context, db *database.TimescaleDB, cfg *config.Config, logger *log.Logger) {
+	ticker := time.NewTicker(cfg.AgentWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			alerts, err := db.RaiseSilentAgentAlerts(ctx, time.Now(), cfg.AgentHeartbeatInterval, cfg.AgentOfflineHeartbeats)
+			if err != nil {
+				logger.Printf("Failed to check silent agents: %v", err)
+				continue
+			}
+			for _, alert := range alerts {
+				logger.Printf("Agent %s (%s) went silent, last heartbeat at %s",
+					alert.AgentID, alert.Hostname, alert.EventTimestamp.Format(time.RFC3339))
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import "encoding/json"
+
+// AgentInfo est la description qu'un agent publie dans ses heartbeats
+// (raw_data.agent)
+type AgentInfo struct {
+	IPAddress         string   `json:"ip_address"`
+	OSType            string   `json:"os_type"`
+	OSVersion         string   `json:"os_version"`
+	KernelVersion     string   `json:"kernel_version"`
+	AgentVersion      string   `json:"agent_version"`
+	HeartbeatInterval float64  `json:"heartbeat_interval_seconds"`
+	Collectors        []string `json:"collectors"`
+	EventRate         float64  `json:"event_rate"` // événements collectés par minute
+
+	SpoolDepth int `json:"-"` // raw_data.spool_depth
+
+	// Registration indique un événement d'enregistrement (nouvel ID d'agent) ;
+	// PreviousAgentID est l'ID remplacé par un réenrôlement
+	Registration    bool   `json:"-"`
+	PreviousAgentID string `json:"-"`
+}
+
+// HeartbeatInfo retourne la description de l'agent si l'événement est un
+// heartbeat ou un enregistrement, nil sinon. Les heartbeats des agents qui ne
+// publient pas encore raw_data.agent ne renseignent que la version et le spool.
+func (e *Event) HeartbeatInfo() *AgentInfo {
+	heartbeat, _ := e.RawData["heartbeat"].(bool)
+	registration, _ := e.RawData["registration"].(bool)
+	if !heartbeat && !registration {
+		return nil
+	}
+
+	info := &AgentInfo{Registration: registration}
+	info.PreviousAgentID, _ = e.RawData["previous_agent_id"].(string)
+	if agent, ok := e.RawData["agent"].(map[string]interface{}); ok {
+		// Un champ mal typé laisse simplement sa valeur par défaut
+		data, _ := json.Marshal(agent)
+		json.Unmarshal(data, info)
+	}
+
+	if info.AgentVersion == "" {
+		info.AgentVersion, _ = e.RawData["version"].(string)
+	}
+	if depth, ok := e.RawData["spool_depth"].(float64); ok {
+		info.SpoolDepth = int(depth)
+	}
+
+	return info
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Alert représente une correspondance entre une règle de détection et un événement
+type Alert struct {
+	ID             int64     `json:"id,omitempty"` // attribué à l'insertion
+	CreatedAt      time.Time `json:"created_at"`
+	RuleID         string    `json:"rule_id"`
+	RuleTitle      string    `json:"rule_title"`
+	RuleLevel      string    `json:"rule_level"` // informational, low, medium, high, critical
+	Description    string    `json:"description,omitempty"`
+	Tags           []string  `json:"tags,omitempty"` // tags Sigma, dont les techniques MITRE ATT&CK (attack.*)
+	EventID        int64     `json:"event_id"`
+	EventTimestamp time.Time `json:"event_timestamp"`
+	AgentID        string    `json:"agent_id"`
+	Hostname       string    `json:"hostname"`
+	EventType      EventType `json:"event_type"`
+
+	// Event est l'événement déclencheur ; son ID n'est connu qu'à l'insertion
+	Event *Event `json:"-"`
+}
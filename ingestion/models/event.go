@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventType représente le type d'événement collecté
+type EventType string
+
+const (
+	EventTypeSystem      EventType = "system"
+	EventTypeNetwork     EventType = "network"
+	EventTypeProcess     EventType = "process"
+	EventTypeFile        EventType = "file"
+	EventTypeAuth        EventType = "auth"
+	EventTypeJournal     EventType = "journal"
+	EventTypePersistence EventType = "persistence"
+)
+
+// Severity représente la sévérité d'un événement
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// Event représente un événement de sécurité collecté
+type Event struct {
+	ID             int64                  `json:"id,omitempty"` // attribué par la base à l'insertion
+	Timestamp      time.Time              `json:"timestamp"`
+	AgentID        string                 `json:"agent_id"`
+	Hostname       string                 `json:"hostname"`
+	EventType      EventType              `json:"event_type"`
+	Severity       Severity               `json:"severity"`
+	RawData        map[string]interface{} `json:"raw_data"`
+	SourceIP       string                 `json:"source_ip,omitempty"`
+	DestinationIP  string                 `json:"destination_ip,omitempty"`
+	ProcessName    string                 `json:"process_name,omitempty"`
+	ProcessPID     int                    `json:"process_pid,omitempty"`
+	Username       string                 `json:"username,omitempty"`
+	Tags           []string               `json:"tags,omitempty"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Validate vérifie qu'un événement décodé peut être persisté
+func (e *Event) Validate() error {
+	if e.Timestamp.IsZero() {
+		return fmt.Errorf("timestamp is required")
+	}
+	if e.AgentID == "" {
+		return fmt.Errorf("agent_id is required")
+	}
+	if e.Hostname == "" {
+		return fmt.Errorf("hostname is required")
+	}
+	switch e.EventType {
+	case EventTypeSystem, EventTypeNetwork, EventTypeProcess, EventTypeFile, EventTypeAuth, EventTypeJournal,
+		EventTypePersistence:
+	default:
+		return fmt.Errorf("unknown event_type %q", e.EventType)
+	}
+	switch e.Severity {
+	case SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical:
+	default:
+		return fmt.Errorf("unknown severity %q", e.Severity)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Étapes du pipeline auxquelles un événement peut être rejeté
+const (
+	RejectStageDecode   = "decode"
+	RejectStageValidate = "validate"
+	RejectStageInsert   = "insert"
+	RejectStageMarshal  = "marshal" // côté agent : événement impossible à sérialiser
+)
+
+// RejectedEvent représente un événement envoyé en dead-letter
+type RejectedEvent struct {
+	RejectedAt      time.Time `json:"rejected_at"`
+	Stage           string    `json:"stage"`
+	Reason          string    `json:"reason"`
+	Payload         string    `json:"payload"`
+	AgentID         string    `json:"agent_id,omitempty"`
+	SourceTopic     string    `json:"source_topic"`
+	SourcePartition int       `json:"source_partition"`
+	SourceOffset    int64     `json:"source_offset"`
+}
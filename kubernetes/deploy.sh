@@ -32,16 +32,16 @@ echo -e "${GREEN}✅ Connecté au cluster${NC}"
 
 # Créer le namespace
 echo -e "${YELLOW}📦 Création du namespace...${NC}"
-kubectl apply -f k8s/00-namespace.yaml
+kubectl apply -f kubernetes/00-namespace.yaml
 
 # Créer les ConfigMaps et Secrets
 echo -e "${YELLOW}🔐 Création des ConfigMaps et Secrets...${NC}"
-kubectl apply -f k8s/01-configmap.yaml
-kubectl apply -f k8s/02-secrets.yaml
+kubectl apply -f kubernetes/01-configmap.yaml
+kubectl apply -f kubernetes/02-secrets.yaml
 
 # Créer les PVCs
 echo -e "${YELLOW}💾 Création des PersistentVolumeClaims...${NC}"
-kubectl apply -f k8s/03-pvcs.yaml
+kubectl apply -f kubernetes/03-pvcs.yaml
 
 # Attendre que les PVCs soient bound
 echo -e "${YELLOW}⏳ Attente du provisionnement des volumes...${NC}"
@@ -49,10 +49,10 @@ kubectl wait --for=condition=Bound pvc --all -n xdr-platform --timeout=300s
 
 # Déployer l'infrastructure (DB, Kafka, Redis)
 echo -e "${YELLOW}🗄️  Déploiement de l'infrastructure...${NC}"
-kubectl apply -f k8s/10-timescaledb.yaml
-kubectl apply -f k8s/11-redis.yaml
-kubectl apply -f k8s/12-zookeeper.yaml
-kubectl apply -f k8s/13-kafka.yaml
+kubectl apply -f kubernetes/10-timescaledb.yaml
+kubectl apply -f kubernetes/11-redis.yaml
+kubectl apply -f kubernetes/12-zookeeper.yaml
+kubectl apply -f kubernetes/13-kafka.yaml
 
 # Attendre que l'infrastructure soit prête
 echo -e "${YELLOW}⏳ Attente du démarrage de l'infrastructure...${NC}"
@@ -63,22 +63,22 @@ kubectl wait --for=condition=available --timeout=300s deployment/kafka -n xdr-pl
 
 # Déployer les services applicatifs
 echo -e "${YELLOW}🚀 Déploiement des services applicatifs...${NC}"
-kubectl apply -f k8s/20-agent.yaml
-kubectl apply -f k8s/21-ingestion.yaml
-kubectl apply -f k8s/22-api-gateway.yaml
-kubectl apply -f k8s/23-frontend.yaml
+kubectl apply -f kubernetes/20-agent.yaml
+kubectl apply -f kubernetes/21-ingestion.yaml
+kubectl apply -f kubernetes/22-api-gateway.yaml
+kubectl apply -f kubernetes/23-frontend.yaml
 
 # Attendre que les services soient prêts
 echo -e "${YELLOW}⏳ Attente du démarrage des services...${NC}"
-kubectl wait --for=condition=available --timeout=300s deployment/xdr-agent -n xdr-platform
+kubectl rollout status --timeout=300s daemonset/xdr-agent -n xdr-platform
 kubectl wait --for=condition=available --timeout=300s deployment/xdr-ingestion -n xdr-platform
 kubectl wait --for=condition=available --timeout=300s deployment/xdr-api-gateway -n xdr-platform
 kubectl wait --for=condition=available --timeout=300s deployment/xdr-frontend -n xdr-platform
 
 # Déployer l'Ingress (optionnel)
-if [ -f "k8s/30-ingress.yaml" ]; then
+if [ -f "kubernetes/30-ingress.yaml" ]; then
     echo -e "${YELLOW}🌐 Déploiement de l'Ingress...${NC}"
-    kubectl apply -f k8s/30-ingress.yaml
+    kubectl apply -f kubernetes/30-ingress.yaml
 fi
 
 echo ""